@@ -2,7 +2,10 @@ module github.com/stanislav-milchev/differ
 
 go 1.23.1
 
-require github.com/r3labs/diff/v3 v3.0.1
+require (
+	github.com/r3labs/diff/v3 v3.0.1
+	golang.org/x/text v0.21.0
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
@@ -0,0 +1,75 @@
+package jsondiff
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// changeID derives a stable identifier for a change from its path, type and
+// JSON-encoded values. encoding/json writes object keys in sorted order, so
+// identical inputs always produce the same ID.
+func changeID(path, changeType string, from, to interface{}) string {
+	h := sha256.New()
+	fromJSON, _ := json.Marshal(from)
+	toJSON, _ := json.Marshal(to)
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s", path, changeType, fromJSON, toJSON)
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// parseAcks collects acknowledged change IDs from a comma-separated list and
+// an optional file containing one ID per line ("#" starts a comment).
+func parseAcks(list, filename string) (map[string]bool, error) {
+	acks := make(map[string]bool)
+	for _, id := range strings.Split(list, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			acks[id] = true
+		}
+	}
+	if filename == "" {
+		return acks, nil
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if id := strings.TrimSpace(line); id != "" {
+			acks[id] = true
+		}
+	}
+	return acks, scanner.Err()
+}
+
+// splitAcknowledged separates acknowledged changes from the rest and
+// returns the acknowledged IDs that matched no change.
+func splitAcknowledged(results []DiffResult, acks map[string]bool) (active, acknowledged []DiffResult, unknown []string) {
+	seen := make(map[string]bool)
+	for _, r := range results {
+		if acks[r.ID] {
+			seen[r.ID] = true
+			acknowledged = append(acknowledged, r)
+		} else {
+			active = append(active, r)
+		}
+	}
+	for id := range acks {
+		if !seen[id] {
+			unknown = append(unknown, id)
+		}
+	}
+	sort.Strings(unknown)
+	return active, acknowledged, unknown
+}
@@ -0,0 +1,164 @@
+package jsondiff
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// changeIDs returns the IDs of the changes in a --format json report, in
+// order.
+func changeIDs(t *testing.T, report string) []string {
+	t.Helper()
+	var out struct {
+		Sections []struct {
+			Changes []struct {
+				ID string `json:"id"`
+			} `json:"changes"`
+		} `json:"sections"`
+	}
+	if err := json.Unmarshal([]byte(report), &out); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, s := range out.Sections {
+		for _, c := range s.Changes {
+			ids = append(ids, c.ID)
+		}
+	}
+	return ids
+}
+
+func TestChangeIDStableAcrossRuns(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json":  `{"a": 1, "obj": {"x": 1, "y": [1, 2]}, "gone": {"k": "v", "j": null}}`,
+		"b.json":  `{"a": 2, "obj": {"x": 1, "y": [1, 3]}}`,
+		"a2.json": `{"gone": {"j": null, "k": "v"}, "obj": {"y": [1, 2], "x": 1}, "a": 1}`,
+	})
+	var runs [][]string
+	for _, a := range []string{"a.json", "a.json", "a2.json"} {
+		res := runCLI(t, dir, "-f", "json", "-o", "out.json", a, "b.json")
+		if res.exit != exitOK {
+			t.Fatalf("exit %d: %s", res.exit, res.stderr)
+		}
+		runs = append(runs, changeIDs(t, readFile(t, dir, "out.json")))
+	}
+	if len(runs[0]) != 3 {
+		t.Fatalf("%d changes, want 3", len(runs[0]))
+	}
+	for i, ids := range runs[1:] {
+		if !reflect.DeepEqual(ids, runs[0]) {
+			t.Errorf("run %d gave IDs %q, first run %q", i+2, ids, runs[0])
+		}
+	}
+
+	// Sections are in key order: a, gone, obj.
+	from := map[string]interface{}{"k": "v", "j": nil}
+	if changeID("gone", "delete", from, nil) != runs[0][1] {
+		t.Error("changeID differs from the ID the command line reported")
+	}
+}
+
+// TestChangeIDsDoNotCollide compares every pair of testdata documents and
+// checks that no two different changes share an ID.
+func TestChangeIDsDoNotCollide(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := make([]interface{}, len(files))
+	for i, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs[i] = mustDecode(t, string(data))
+	}
+	type change struct{ path, typ, from, to, fromType, toType string }
+	seen := make(map[string]change)
+	for i, a := range docs {
+		for j, b := range docs {
+			if i == j {
+				continue
+			}
+			changes, err := collectChanges(diffSeq(context.Background(), a, b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range buildDiffTable(changes, a, b) {
+				c := change{r.Path, r.Type, r.From, r.To, r.FromType, r.ToType}
+				if prev, ok := seen[r.ID]; ok && prev != c {
+					t.Errorf("%s vs %s: ID %s of %+v already given to %+v", files[i], files[j], r.ID, c, prev)
+				}
+				seen[r.ID] = c
+			}
+		}
+	}
+	if len(seen) < 20 {
+		t.Errorf("only %d distinct changes in the corpus", len(seen))
+	}
+}
+
+func TestParseAcks(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "acks")
+	if err := os.WriteFile(file, []byte("# reviewed\nbbb\n  ccc  # flaky\n\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	acks, err := parseAcks(" aaa, ,bbb", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]bool{"aaa": true, "bbb": true, "ccc": true}; !reflect.DeepEqual(acks, want) {
+		t.Errorf("acks %v, want %v", acks, want)
+	}
+	if _, err := parseAcks("", filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("missing acks file accepted")
+	}
+
+	results := []DiffResult{{ID: "aaa"}, {ID: "ddd"}, {ID: "bbb"}}
+	active, acknowledged, unknown := splitAcknowledged(results, acks)
+	if len(active) != 1 || active[0].ID != "ddd" || len(acknowledged) != 2 {
+		t.Errorf("active %v, acknowledged %v", active, acknowledged)
+	}
+	if !reflect.DeepEqual(unknown, []string{"ccc"}) {
+		t.Errorf("unknown %q, want [ccc]", unknown)
+	}
+}
+
+func TestAcknowledgedChangesDoNotFail(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"a": 1, "b": [1, 2], "c": "x"}`,
+		"b.json": `{"a": 2, "b": [1, 3], "c": "x"}`,
+	})
+	res := runCLI(t, dir, "-f", "json", "-o", "out.json", "--fail-on", "changed", "a.json", "b.json")
+	if res.exit != exitChangesFound {
+		t.Fatalf("exit %d before acknowledging, want %d", res.exit, exitChangesFound)
+	}
+	ids := changeIDs(t, readFile(t, dir, "out.json"))
+	if len(ids) != 2 {
+		t.Fatalf("%d changes, want 2", len(ids))
+	}
+
+	res = runCLI(t, dir, "-f", "json", "-o", "out.json", "--fail-on", "changed", "--ack", ids[0], "a.json", "b.json")
+	if res.exit != exitChangesFound {
+		t.Errorf("exit %d with one change left, want %d", res.exit, exitChangesFound)
+	}
+	res = runCLI(t, dir, "-f", "json", "-o", "out.json", "--fail-on", "changed", "--ack", ids[0]+","+ids[1], "a.json", "b.json")
+	if res.exit != exitOK {
+		t.Errorf("exit %d with every change acknowledged: %s", res.exit, res.stderr)
+	}
+	var out struct {
+		Acknowledged []struct {
+			ID string `json:"id"`
+		} `json:"acknowledged"`
+	}
+	if err := json.Unmarshal([]byte(readFile(t, dir, "out.json")), &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Acknowledged) != 2 || len(changeIDs(t, readFile(t, dir, "out.json"))) != 0 {
+		t.Errorf("acknowledged changes not moved to their own section: %+v", out)
+	}
+}
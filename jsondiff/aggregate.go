@@ -0,0 +1,128 @@
+package jsondiff
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Aggregate is the roll-up of every numeric value matching a path pattern
+// in each document.
+type Aggregate struct {
+	Pattern      string   `json:"pattern"`
+	From         float64  `json:"from"`
+	To           float64  `json:"to"`
+	Delta        float64  `json:"delta"`
+	DeltaPercent *float64 `json:"deltaPercent,omitempty"`
+	Notes        []string `json:"notes,omitempty"`
+}
+
+// Summary formats the aggregate as "1,234.00 → 1,310.50, +6.2%".
+func (a Aggregate) Summary() string {
+	s := formatThousands(a.From) + " → " + formatThousands(a.To)
+	if a.DeltaPercent != nil {
+		s += fmt.Sprintf(", %s%%", formatSigned(math.Round(*a.DeltaPercent*10)/10))
+	}
+	return s
+}
+
+// computeAggregates sums the numeric values matching each pattern in both
+// documents. Values present on only one side count as zero on the other;
+// non-numeric matches are skipped and reported through warn.
+func computeAggregates(patterns []string, original, modified interface{}, warn func(string)) []Aggregate {
+	out := make([]Aggregate, 0, len(patterns))
+	for _, pattern := range patterns {
+		pat := splitPath(pattern)
+		agg := Aggregate{Pattern: pattern}
+
+		var skipped int
+		sum := func(doc interface{}, side string) (float64, map[string]bool) {
+			total := 0.0
+			seen := make(map[string]bool)
+			walkLeaves(doc, nil, func(path []string, v interface{}) {
+				if !matchPath(pat, path) {
+					return
+				}
+				f, ok := numericValue(v)
+				if !ok {
+					skipped++
+					warn(fmt.Sprintf("aggregate %s: skipping non-numeric value at %s in %s", pattern, joinPath(path), side))
+					return
+				}
+				total += f
+				seen[joinPath(path)] = true
+			})
+			return total, seen
+		}
+		var seenA, seenB map[string]bool
+		agg.From, seenA = sum(original, "file1")
+		agg.To, seenB = sum(modified, "file2")
+
+		onlyA, onlyB := 0, 0
+		for p := range seenA {
+			if !seenB[p] {
+				onlyA++
+			}
+		}
+		for p := range seenB {
+			if !seenA[p] {
+				onlyB++
+			}
+		}
+		if onlyA > 0 {
+			agg.Notes = append(agg.Notes, fmt.Sprintf("%d value(s) missing from file2 counted as 0", onlyA))
+		}
+		if onlyB > 0 {
+			agg.Notes = append(agg.Notes, fmt.Sprintf("%d value(s) missing from file1 counted as 0", onlyB))
+		}
+		if skipped > 0 {
+			agg.Notes = append(agg.Notes, fmt.Sprintf("%d non-numeric value(s) skipped", skipped))
+		}
+		if len(seenA) == 0 && len(seenB) == 0 {
+			agg.Notes = append(agg.Notes, "no numeric values matched")
+		}
+
+		agg.Delta = agg.To - agg.From
+		if agg.From != 0 {
+			pct := agg.Delta / math.Abs(agg.From) * 100
+			agg.DeltaPercent = &pct
+		}
+		out = append(out, agg)
+	}
+	return out
+}
+
+// walkLeaves calls fn for every non-container value in v, in sorted key order.
+func walkLeaves(v interface{}, path []string, fn func(path []string, v interface{})) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for _, k := range sortedKeys(val) {
+			walkLeaves(val[k], append(path[:len(path):len(path)], k), fn)
+		}
+	case []interface{}:
+		for i, vv := range val {
+			walkLeaves(vv, append(path[:len(path):len(path)], indexSegment(i)), fn)
+		}
+	default:
+		fn(path, v)
+	}
+}
+
+// formatThousands formats f with two decimals and comma thousands separators.
+func formatThousands(f float64) string {
+	s := strconv.FormatFloat(math.Abs(f), 'f', 2, 64)
+	intPart, frac := s[:len(s)-3], s[len(s)-3:]
+	var b strings.Builder
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	sign := ""
+	if f < 0 {
+		sign = "-"
+	}
+	return sign + b.String() + frac
+}
@@ -0,0 +1,136 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNumericValueMixed(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want float64
+		ok   bool
+	}{
+		{2.5, 2.5, true},
+		{3, 3, true},
+		{int64(-4), -4, true},
+		{json.Number("1.50"), 1.5, true},
+		{json.Number("-0"), 0, true},
+		{json.Number("1e400"), 0, false},
+		{"5", 0, false},
+		{true, 0, false},
+		{nil, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := numericValue(tt.v)
+		if ok != tt.ok || ok && got != tt.want {
+			t.Errorf("%#v: %v, %t", tt.v, got, ok)
+		}
+	}
+
+	// An int, a float64 and a json.Number of the same value are equal,
+	// and give no delta.
+	for _, pair := range [][2]interface{}{{2, 2.0}, {2.0, json.Number("2.00")}, {json.Number("2"), int64(2)}} {
+		var r DiffResult
+		setDelta(&r, pair[0], pair[1])
+		if r.Delta != nil {
+			t.Errorf("%#v to %#v: delta %v", pair[0], pair[1], *r.Delta)
+		}
+	}
+	var r DiffResult
+	setDelta(&r, 4, json.Number("5"))
+	if r.Delta == nil || *r.Delta != 1 || r.DeltaPercent == nil || *r.DeltaPercent != 25 {
+		t.Errorf("4 to 5: %+v", r)
+	}
+}
+
+func TestComputeAggregatesMixed(t *testing.T) {
+	original := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"cost": 100},
+			map[string]interface{}{"cost": json.Number("250.50")},
+			map[string]interface{}{"cost": 49.5},
+			map[string]interface{}{"cost": "n/a"},
+		},
+	}
+	modified := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"cost": 120.0},
+			map[string]interface{}{"cost": json.Number("250.5")},
+			map[string]interface{}{"cost": int64(49)},
+			map[string]interface{}{"cost": 30},
+			map[string]interface{}{"cost": json.Number("10")},
+		},
+	}
+	var warnings []string
+	aggs := computeAggregates([]string{"items[*].cost", "missing"}, original, modified, func(msg string) { warnings = append(warnings, msg) })
+	if len(aggs) != 2 {
+		t.Fatalf("%d aggregates", len(aggs))
+	}
+
+	got := aggs[0]
+	if got.From != 400 || got.To != 459.5 || got.Delta != 59.5 {
+		t.Errorf("from %v, to %v, delta %v", got.From, got.To, got.Delta)
+	}
+	if got.DeltaPercent == nil || *got.DeltaPercent != 14.875 {
+		t.Errorf("percent %v", got.DeltaPercent)
+	}
+	if want := "400.00 → 459.50, +14.9%"; got.Summary() != want {
+		t.Errorf("summary %q, want %q", got.Summary(), want)
+	}
+	// items[3] is a string in file1 and items[4] is not there at all, so
+	// both count as missing from it.
+	wantNotes := []string{"2 value(s) missing from file1 counted as 0", "1 non-numeric value(s) skipped"}
+	if !reflect.DeepEqual(got.Notes, wantNotes) {
+		t.Errorf("notes %q, want %q", got.Notes, wantNotes)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "items[3].cost in file1") {
+		t.Errorf("warnings %q", warnings)
+	}
+
+	if none := aggs[1]; none.From != 0 || none.To != 0 || none.DeltaPercent != nil || !reflect.DeepEqual(none.Notes, []string{"no numeric values matched"}) {
+		t.Errorf("no match: %+v", none)
+	}
+}
+
+func TestFormatThousands(t *testing.T) {
+	tests := map[float64]string{
+		0:          "0.00",
+		999.999:    "1,000.00",
+		1234567.5:  "1,234,567.50",
+		-1234.5:    "-1,234.50",
+		123456.789: "123,456.79",
+	}
+	for f, want := range tests {
+		if got := formatThousands(f); got != want {
+			t.Errorf("%v: %q, want %q", f, got, want)
+		}
+	}
+}
+
+// TestAggregateDecimalStrict sums the json.Number values --decimal-strict
+// keeps alongside the float64 ones of the default decoding.
+func TestAggregateDecimalStrict(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"a": {"n": 1.10}, "b": {"n": 2}, "c": {"n": 1e2}}`,
+		"b.json": `{"a": {"n": 1.1}, "b": {"n": 2.50}, "c": {"n": 100.0}}`,
+	})
+	for _, flags := range [][]string{nil, {"--decimal-strict"}} {
+		args := append(append([]string{"-f", "json", "-o", "out.json", "--aggregate", "*.n"}, flags...), "a.json", "b.json")
+		if res := runCLI(t, dir, args...); res.exit != exitOK {
+			t.Fatalf("%q: exit %d: %s", flags, res.exit, res.stderr)
+		}
+		var report jsonReport
+		if err := json.Unmarshal([]byte(readFile(t, dir, "out.json")), &report); err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Aggregates) != 1 {
+			t.Fatalf("%q: aggregates %+v", flags, report.Aggregates)
+		}
+		if got := report.Aggregates[0]; got.From != 103.1 || got.To != 103.6 || got.Notes != nil {
+			t.Errorf("%q: %+v", flags, got)
+		}
+	}
+}
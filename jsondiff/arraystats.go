@@ -0,0 +1,77 @@
+package jsondiff
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ArrayStats summarizes the changes among the elements of one array.
+// Changes below an element count it as modified; changes inside a nested
+// array count towards that array only through its containing element.
+type ArrayStats struct {
+	Path     string `json:"path"`
+	Added    int    `json:"added"`
+	Removed  int    `json:"removed"`
+	Modified int    `json:"modified"`
+}
+
+// String formats the stats for the badge next to the array, e.g.
+// "+3 / −1 items, 2 modified".
+func (s ArrayStats) String() string {
+	text := fmt.Sprintf("+%d / −%d items", s.Added, s.Removed)
+	if s.Modified > 0 {
+		text += fmt.Sprintf(", %d modified", s.Modified)
+	}
+	return text
+}
+
+// buildArrayStats computes ArrayStats for every array with a changed element.
+// A move counts as an addition at its new path and a removal at its old one.
+func buildArrayStats(results []DiffResult) map[string]ArrayStats {
+	stats := make(map[string]ArrayStats)
+	modified := make(map[string]bool)
+	count := func(path, typ string) {
+		segs := splitPath(path)
+		for k, seg := range segs {
+			if !isIndexSegment(seg) {
+				continue
+			}
+			array := joinPath(segs[:k])
+			s := stats[array]
+			s.Path = array
+			switch {
+			case k < len(segs)-1 || typ == "update":
+				element := joinPath(segs[:k+1])
+				if modified[element] {
+					continue
+				}
+				modified[element] = true
+				s.Modified++
+			case typ == "create":
+				s.Added++
+			case typ == "delete":
+				s.Removed++
+			}
+			stats[array] = s
+		}
+	}
+	for _, r := range results {
+		if r.Type == "move" {
+			count(r.Path, "create")
+			count(r.MovedFrom, "delete")
+			continue
+		}
+		count(r.Path, r.Type)
+	}
+	return stats
+}
+
+// sortedArrayStats lists stats by path.
+func sortedArrayStats(stats map[string]ArrayStats) []ArrayStats {
+	out := make([]ArrayStats, 0, len(stats))
+	for _, s := range stats {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
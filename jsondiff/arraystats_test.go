@@ -0,0 +1,43 @@
+package jsondiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildArrayStats(t *testing.T) {
+	results := []DiffResult{
+		{Path: "items[0].name", Type: "update"},
+		{Path: "items[0].tags[1]", Type: "create"},
+		{Path: "items[0].tags[2]", Type: "create"},
+		{Path: "items[2]", Type: "update"},
+		{Path: "items[3]", Type: "create"},
+		{Path: "items[4]", Type: "delete"},
+		{Path: "grid[1][0]", Type: "delete"},
+		{Path: "name", Type: "update"},
+	}
+	want := []ArrayStats{
+		{Path: "grid", Modified: 1},
+		{Path: "grid[1]", Removed: 1},
+		{Path: "items", Added: 1, Removed: 1, Modified: 2},
+		{Path: "items[0].tags", Added: 2},
+	}
+	if got := sortedArrayStats(buildArrayStats(results)); !reflect.DeepEqual(got, want) {
+		t.Errorf("stats\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestArrayStatsString(t *testing.T) {
+	tests := []struct {
+		s    ArrayStats
+		want string
+	}{
+		{ArrayStats{Added: 3, Removed: 1, Modified: 2}, "+3 / −1 items, 2 modified"},
+		{ArrayStats{Added: 1}, "+1 / −0 items"},
+	}
+	for _, tt := range tests {
+		if got := tt.s.String(); got != tt.want {
+			t.Errorf("%+v: %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
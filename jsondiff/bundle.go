@@ -0,0 +1,80 @@
+package jsondiff
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// bundleTime is the modification time stamped on every bundle entry: the
+// SOURCE_DATE_EPOCH of reproducible builds if set, otherwise zero.
+func bundleTime() (time.Time, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return time.Time{}, nil
+	}
+	secs, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q", epoch)
+	}
+	return time.Unix(secs, 0).UTC(), nil
+}
+
+// writeBundle writes a zip holding everything needed to audit a run: the
+// HTML report, the JSON change list, both inputs re-encoded with sorted keys
+// and the run summary. Entries are written in a fixed order with fixed
+// timestamps so the same inputs always produce the same bytes.
+func writeBundle(filename string, r *Report, summary summaryFile) error {
+	mtime, err := bundleTime()
+	if err != nil {
+		return err
+	}
+	// Timing varies between runs and would make bundles irreproducible.
+	summary.DurationMS = 0
+
+	type entry struct {
+		name   string
+		render func(*bytes.Buffer) error
+	}
+	renderWith := func(name string) func(*bytes.Buffer) error {
+		return func(buf *bytes.Buffer) error { return renderers[name].Render(buf, r) }
+	}
+	encode := func(v interface{}) func(*bytes.Buffer) error {
+		return func(buf *bytes.Buffer) error {
+			enc := json.NewEncoder(buf)
+			enc.SetIndent("", "  ")
+			return enc.Encode(v)
+		}
+	}
+	entries := []entry{
+		{"report.html", renderWith("html")},
+		{"changes.json", renderWith("json")},
+		{"a.json", encode(r.A)},
+		{"b.json", encode(r.B)},
+		{"summary.json", encode(summary)},
+	}
+
+	var out bytes.Buffer
+	zw := zip.NewWriter(&out)
+	for _, e := range entries {
+		var buf bytes.Buffer
+		if err := e.render(&buf); err != nil {
+			return fmt.Errorf("%s: %w", e.name, err)
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: e.name, Method: zip.Deflate, Modified: mtime})
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, out.Bytes(), 0o644)
+}
@@ -0,0 +1,103 @@
+package jsondiff
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBundleTime(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+	if got, err := bundleTime(); !got.IsZero() || err != nil {
+		t.Errorf("unset: %v, %v", got, err)
+	}
+	t.Setenv("SOURCE_DATE_EPOCH", "1709296200")
+	if got, err := bundleTime(); !got.Equal(historyTime) || got.Location() != time.UTC || err != nil {
+		t.Errorf("set: %v, %v", got, err)
+	}
+	t.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	if _, err := bundleTime(); err == nil {
+		t.Error("invalid: no error")
+	}
+}
+
+// TestBundleCLI checks the entries of a bundle and that bundling the same
+// inputs twice gives the same bytes.
+func TestBundleCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"z": 1, "a": {"y": true, "x": null}}`,
+		"b.json": `{"z": 2, "a": {"y": true, "x": null}}`,
+	})
+	bundle := func(name, epoch string) cliResult {
+		t.Helper()
+		cmd := exec.Command(os.Args[0], "--bundle", name, "-o", "out.html", "a.json", "b.json")
+		cmd.Env = []string{"SOURCE_DATE_EPOCH=" + epoch}
+		return runCLIWith(t, cmd, dir)
+	}
+	for _, name := range []string{"1.zip", "2.zip"} {
+		if res := bundle(name, "1709296200"); res.exit != exitOK {
+			t.Fatalf("exit %d: %s", res.exit, res.stderr)
+		}
+	}
+	first, second := readFile(t, dir, "1.zip"), readFile(t, dir, "2.zip")
+	if first != second {
+		t.Error("bundles of the same inputs differ")
+	}
+
+	zr, err := zip.NewReader(strings.NewReader(first), int64(len(first)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := make(map[string]string)
+	var names []string
+	for _, f := range zr.File {
+		if !f.Modified.Equal(historyTime) {
+			t.Errorf("%s modified %v", f.Name, f.Modified)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, f.Name)
+		entries[f.Name] = string(data)
+	}
+	if want := []string{"report.html", "changes.json", "a.json", "b.json", "summary.json"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("entries %q, want %q", names, want)
+	}
+	wellFormed["html"](t, entries["report.html"])
+	wellFormed["json"](t, entries["changes.json"])
+	if want := "{\n  \"a\": {\n    \"x\": null,\n    \"y\": true\n  },\n  \"z\": 1\n}\n"; entries["a.json"] != want {
+		t.Errorf("a.json %q, want %q", entries["a.json"], want)
+	}
+	var summary summaryFile
+	if err := json.Unmarshal([]byte(entries["summary.json"]), &summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.DurationMS != 0 || summary.Counts[Changed] != 1 {
+		t.Errorf("summary %+v", summary)
+	}
+
+	// The report in the bundle is the one written with -o.
+	if readFile(t, dir, "out.html") != entries["report.html"] {
+		t.Error("report.html differs from out.html")
+	}
+
+	if res := bundle("bad.zip", "yesterday"); res.exit != exitError || !strings.Contains(res.stderr, `invalid SOURCE_DATE_EPOCH "yesterday"`) {
+		t.Errorf("invalid epoch: exit %d: %s", res.exit, res.stderr)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "bad.zip")); err == nil {
+		t.Error("bundle written despite the invalid epoch")
+	}
+}
@@ -0,0 +1,70 @@
+package jsondiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// logicalClasses are the class names of the tree and table markup that the
+// "classes" section of --config may map to other classes.
+var logicalClasses = []string{
+	"differ-tree", "json-object", "json-array", "json-list", "json-key",
+	"added", "removed", "changed", "moved", "unchanged", "contains-changes", "ghost",
+	"array-gap", "gap-label", "array-stats",
+	"key", "json-string", "json-number", "json-bool", "json-null",
+	"toggle", "change-marker", "sr-only", "approx", "remapped",
+	"differ-table", "toc", "diff-section", "acknowledged", "metadata", "suppressed", "update",
+	"change-id", "path", "location", "moved-from", "folded", "type-change",
+	"delta-up", "delta-down", "badge", "invalid", "nulled", "un-nulled",
+	"moved-path", "paired-element", "set-added", "set-removed", "array-changed", "multiset", "count-diff", "reordered", "gating", "ext-link", "copy-value", "schema-description",
+}
+
+// classMap maps logical class names to the classes emitted for them, as
+// set by the config. The built-in styles and script target the logical
+// names, so a page using a mapping supplies its own styles.
+type classMap map[string]string
+
+// cls returns the classes emitted for the space-separated logical names;
+// names without a mapping are emitted as they are.
+func (m classMap) cls(names string) string {
+	if len(m) == 0 {
+		return names
+	}
+	fields := strings.Fields(names)
+	for i, name := range fields {
+		if c, ok := m[name]; ok {
+			fields[i] = c
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// cls returns the classes emitted for the space-separated logical names
+// under ctx's class mapping.
+func (ctx *renderContext) cls(names string) string {
+	return ctx.classes.cls(names)
+}
+
+// validateClasses checks a class mapping: every key must be a logical class
+// name and every value a non-empty list of classes that cannot break out
+// of a class="..." attribute.
+func validateClasses(m map[string]string) error {
+	known := make(map[string]bool, len(logicalClasses))
+	for _, name := range logicalClasses {
+		known[name] = true
+	}
+	for name, c := range m {
+		if !known[name] {
+			return fmt.Errorf("unknown class %q: must be one of %s", name, strings.Join(logicalClasses, ", "))
+		}
+		if strings.TrimSpace(c) == "" {
+			return fmt.Errorf("class %q is mapped to nothing", name)
+		}
+		if i := strings.IndexFunc(c, func(r rune) bool {
+			return r < ' ' || r == 0x7f || strings.ContainsRune("\"'<>&=`", r)
+		}); i >= 0 {
+			return fmt.Errorf("class %q maps to %q, which contains the invalid character %q", name, c, c[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package jsondiff
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestValidateClasses(t *testing.T) {
+	tests := []struct {
+		classes map[string]string
+		err     string
+	}{
+		{map[string]string{"json-key": "ds-row", "added": "ds-ok text-green"}, ""},
+		{map[string]string{"jsonkey": "x"}, `unknown class "jsonkey"`},
+		{map[string]string{"added": "  "}, "mapped to nothing"},
+		{map[string]string{"added": `x" onclick="y`}, "invalid character"},
+		{map[string]string{"added": "a<b"}, "invalid character"},
+		{map[string]string{"added": "a\tb"}, "invalid character"},
+	}
+	for _, tt := range tests {
+		err := validateClasses(tt.classes)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("validateClasses(%v) = %v, want %q", tt.classes, err, tt.err)
+		}
+	}
+}
+
+var classAttr = regexp.MustCompile(`class="([^"]*)"`)
+
+// TestCustomClassesGolden renders the trees and the table with every
+// logical class mapped and checks that no default class name is emitted.
+func TestCustomClassesGolden(t *testing.T) {
+	mapping := make(map[string]string, len(logicalClasses))
+	for _, name := range logicalClasses {
+		mapping[name] = "ds-" + name
+	}
+	if err := validateClasses(mapping); err != nil {
+		t.Fatal(err)
+	}
+	r := reportFor(t,
+		`{"name": "svc", "replicas": 2, "ports": [80, 443], "old": {"x": 1}, "tags": ["a", "b"], "v": null}`,
+		`{"name": "svc", "replicas": 3, "ports": [80, 8443], "new": true, "tags": ["a", "b"], "v": "x"}`)
+	r.render.classes = mapping
+	var out bytes.Buffer
+	if err := (fragmentRenderer{}).Render(&out, r); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "classes-fragment.html", out.String())
+
+	attrs := classAttr.FindAllStringSubmatch(out.String(), -1)
+	if len(attrs) == 0 {
+		t.Fatal("no class attributes")
+	}
+	for _, m := range attrs {
+		for _, c := range strings.Fields(m[1]) {
+			if !strings.HasPrefix(c, "ds-") {
+				t.Errorf("default class %q emitted in class=%q", c, m[1])
+			}
+		}
+	}
+}
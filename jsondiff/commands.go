@@ -0,0 +1,109 @@
+// Package jsondiff compares JSON documents and reports their differences.
+// Run is the jsondiff command line; the types and functions it is built
+// from are exported for programs embedding the comparison.
+package jsondiff
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// command is a jsondiff subcommand, selected by the first argument.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"diff", "Compare two documents (the default when no command is given)", func(args []string) int { return runDiff(args, nil) }},
+		{"fmt", "Reformat a document canonically", runFmt},
+		{"validate", "Validate documents against a JSON Schema", runValidate},
+		{"timeline", "Show how values change across a series of snapshots", runTimeline},
+		{"diff-of-diffs", "Compare two JSON reports: new, resolved and persisting changes", runDiffOfDiffs},
+		{"schema", "Print the JSON Schema of a machine-readable output", runSchema},
+		{"completion", "Print a shell completion script", func(args []string) int {
+			return runDiff(nil, func(fs *flag.FlagSet) int { return runCompletion(fs, args) })
+		}},
+		{"help", "Show usage of jsondiff or of one command", runHelp},
+	}
+}
+
+func lookupCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// Run runs the jsondiff command line with args, the arguments after the
+// program name, and returns the process exit status; failures are logged
+// before it returns. Arguments not starting with a command name are a diff,
+// so "jsondiff a.json b.json" keeps working.
+func Run(args []string) int {
+	if len(args) > 0 {
+		if c := lookupCommand(args[0]); c != nil {
+			return c.run(args[1:])
+		}
+	}
+	return runDiff(args, nil)
+}
+
+// fatalf logs a failure as log.Fatalf does and returns exitError, for the
+// command to return instead of exiting.
+func fatalf(format string, v ...interface{}) int {
+	log.Printf(format, v...)
+	return exitError
+}
+
+// fatal is fatalf with the arguments formatted as by log.Print.
+func fatal(v ...interface{}) int {
+	log.Print(v...)
+	return exitError
+}
+
+// exitUsage is the status for flags that do not parse, as flag.ExitOnError
+// exits with.
+const exitUsage = 2
+
+// flagExit returns the status for err from parsing a command's flags: the
+// flag set has already reported it, and -h is not a failure.
+func flagExit(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	return exitUsage
+}
+
+// runHelp prints the command list, or the usage of the named command.
+func runHelp(args []string) int {
+	if len(args) == 0 {
+		fmt.Println("Usage: jsondiff [command] [flags] [args]")
+		fmt.Println()
+		fmt.Println("Commands:")
+		for _, c := range commands {
+			fmt.Printf("  %-11s %s\n", c.name, c.summary)
+		}
+		fmt.Println()
+		fmt.Println("Run \"jsondiff help <command>\" for the flags of a command.")
+		return exitOK
+	}
+	c := lookupCommand(args[0])
+	if c == nil || len(args) > 1 {
+		fmt.Fprintf(os.Stderr, "Unknown help topic %q. Run \"jsondiff help\".\n", args[0])
+		return exitError
+	}
+	if c.name == "help" {
+		return runHelp(nil)
+	}
+	// Every command's flag set prints its usage and returns 0 on -h.
+	return c.run([]string{"-h"})
+}
@@ -0,0 +1,112 @@
+package jsondiff
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runInProcess calls Run with args, returning its exit status and what it
+// wrote to stdout; stderr and the log are discarded.
+func runInProcess(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	stdout, stderr, logOut := os.Stdout, os.Stderr, log.Writer()
+	os.Stdout, os.Stderr = out, devNull
+	log.SetOutput(io.Discard)
+	defer func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		log.SetOutput(logOut)
+	}()
+
+	code := Run(args)
+	data, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code, string(data)
+}
+
+// TestRunCommands runs every command in-process, checking that Run returns
+// the status instead of exiting.
+func TestRunCommands(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json":      `{"name": "a", "n": 1}`,
+		"b.json":      `{"name": "b", "n": 1}`,
+		"messy.json":  `{"n":1,"name":"a"}`,
+		"schema.json": `{"type": "object", "required": ["name"]}`,
+		"bad.json":    `{"n": 1}`,
+	})
+	path := func(name string) string { return filepath.Join(dir, name) }
+	// diff-of-diffs compares two JSON reports.
+	if code, _ := runInProcess(t, "--format", "json", "-o", path("before.json"), path("a.json"), path("b.json")); code != exitOK {
+		t.Fatalf("writing before.json: exit %d", code)
+	}
+	if code, _ := runInProcess(t, "--format", "json", "-o", path("after.json"), path("a.json"), path("a.json")); code != exitOK {
+		t.Fatalf("writing after.json: exit %d", code)
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		exit   int
+		stdout string
+	}{
+		{"legacy diff", []string{"--format", "json", "-o", "-", path("a.json"), path("b.json")}, exitOK, `"name"`},
+		{"legacy diff fail-on", []string{"--fail-on", "changed", "-o", path("out.html"), path("a.json"), path("b.json")}, exitChangesFound, ""},
+		{"legacy diff missing file", []string{"-o", path("out.html"), path("a.json"), path("nope.json")}, exitError, ""},
+		{"legacy diff bad flag", []string{"--no-such-flag", path("a.json"), path("b.json")}, exitUsage, ""},
+		{"legacy diff -h", []string{"-h"}, exitOK, ""},
+		{"diff", []string{"diff", "--format", "json", "-o", "-", path("a.json"), path("b.json")}, exitOK, `"name"`},
+		{"diff bad flag", []string{"diff", "--no-such-flag"}, exitUsage, ""},
+		{"fmt", []string{"fmt", path("messy.json")}, exitOK, `"name": "a"`},
+		{"fmt check", []string{"fmt", "--check", path("messy.json")}, exitError, "messy.json"},
+		{"fmt bad flag", []string{"fmt", "--no-such-flag"}, exitUsage, ""},
+		{"validate", []string{"validate", "--schema", path("schema.json"), path("a.json")}, exitOK, ""},
+		{"validate violation", []string{"validate", "--schema", path("schema.json"), path("bad.json")}, exitError, ""},
+		{"validate bad flag", []string{"validate", "--no-such-flag"}, exitUsage, ""},
+		{"timeline", []string{"timeline", "--format", "json", "-o", "-", path("a.json"), path("b.json")}, exitOK, `"name"`},
+		{"timeline bad flag", []string{"timeline", "--no-such-flag"}, exitUsage, ""},
+		{"diff-of-diffs", []string{"diff-of-diffs", "--format", "json", "-o", "-", path("before.json"), path("after.json")}, exitOK, "resolved"},
+		{"diff-of-diffs bad flag", []string{"diff-of-diffs", "--no-such-flag"}, exitUsage, ""},
+		{"schema", []string{"schema", "json"}, exitOK, `"$schema"`},
+		{"schema unknown", []string{"schema", "nope"}, exitError, ""},
+		{"completion", []string{"completion", "bash"}, exitOK, "complete"},
+		{"completion unknown shell", []string{"completion", "tcsh"}, exitError, ""},
+		{"help", []string{"help"}, exitOK, "Commands:"},
+		{"help command", []string{"help", "fmt"}, exitOK, ""},
+		{"help unknown", []string{"help", "nope"}, exitError, ""},
+	}
+	covered := map[string]bool{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout := runInProcess(t, tt.args...)
+			if code != tt.exit {
+				t.Errorf("exit %d, want %d", code, tt.exit)
+			}
+			if !strings.Contains(stdout, tt.stdout) {
+				t.Errorf("stdout %q, want it to contain %q", stdout, tt.stdout)
+			}
+		})
+		if lookupCommand(tt.args[0]) != nil {
+			covered[tt.args[0]] = true
+		}
+	}
+	for _, c := range commands {
+		if !covered[c.name] {
+			t.Errorf("command %s is not run", c.name)
+		}
+	}
+}
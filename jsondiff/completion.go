@@ -0,0 +1,176 @@
+package jsondiff
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// subcommandNames returns the commands offered as the first argument by
+// shell completion, in sorted order.
+func subcommandNames() []string {
+	names := make([]string, len(commands))
+	for i, c := range commands {
+		names[i] = c.name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// completionValues lists the accepted values of enum-like flags.
+var completionValues = map[string]func() []string{
+	"format":            func() []string { return append(rendererNames(), "list") },
+	"f":                 func() []string { return append(rendererNames(), "list") },
+	"sort":              func() []string { return []string{"path", "delta", "document"} },
+	"normalize-unicode": func() []string { return []string{"nfc", "nfd", "none"} },
+	"log-format":        func() []string { return []string{"text", "json"} },
+	"emit-array-mode":   func() []string { return []string{emitArrayNulls, emitArrayKeyed} },
+	"compress":          func() []string { return []string{"gzip", "none"} },
+	"input-format":      func() []string { return []string{inputJSON, inputJSONSeq, inputConcat} },
+	"ignore-values":     valuePresetNames,
+	"fail-on":           func() []string { return failOnKinds },
+	"array-granularity": func() []string { return []string{granularityElements, granularityWhole} },
+}
+
+// runCompletion prints a completion script for the shell named in args,
+// generated from the flags defined on fs.
+func runCompletion(fs *flag.FlagSet, args []string) int {
+	if len(args) == 1 && (args[0] == "-h" || args[0] == "--help") {
+		fmt.Println("Usage: jsondiff completion bash|zsh|fish")
+		return exitOK
+	}
+	if len(args) != 1 {
+		fmt.Println("Usage: jsondiff completion bash|zsh|fish")
+		return exitError
+	}
+	prog := filepath.Base(os.Args[0])
+	var err error
+	switch args[0] {
+	case "bash":
+		err = writeBashCompletion(os.Stdout, prog, fs)
+	case "zsh":
+		err = writeZshCompletion(os.Stdout, prog, fs)
+	case "fish":
+		err = writeFishCompletion(os.Stdout, prog, fs)
+	default:
+		return fatalf("Unsupported shell %q: must be bash, zsh or fish", args[0])
+	}
+	if err != nil {
+		return fatalf("Failed to write completion: %v", err)
+	}
+	return exitOK
+}
+
+// completionFlag describes one flag for the generators.
+type completionFlag struct {
+	name   string
+	usage  string
+	isBool bool
+	values []string
+}
+
+// dashed returns the flag as it is usually typed: -o for one-letter names,
+// --format otherwise. The flag package accepts either prefix.
+func (f completionFlag) dashed() string {
+	if len(f.name) == 1 {
+		return "-" + f.name
+	}
+	return "--" + f.name
+}
+
+func completionFlags(fs *flag.FlagSet) []completionFlag {
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		cf := completionFlag{name: f.Name, usage: f.Usage}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			cf.isBool = true
+		}
+		if values, ok := completionValues[f.Name]; ok {
+			cf.values = values()
+			sort.Strings(cf.values)
+		}
+		flags = append(flags, cf)
+	})
+	return flags
+}
+
+func writeBashCompletion(w io.Writer, prog string, fs *flag.FlagSet) error {
+	flags := completionFlags(fs)
+	fn := "_" + strings.NewReplacer("-", "_", ".", "_").Replace(prog)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# bash completion for %s\n%s() {\n", prog, fn)
+	sb.WriteString("  local cur prev\n")
+	sb.WriteString("  cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	sb.WriteString("  prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	sb.WriteString("  case \"$prev\" in\n")
+	var fileFlags, names []string
+	for _, f := range flags {
+		names = append(names, f.dashed())
+		switch {
+		case f.isBool:
+		case f.values != nil:
+			fmt.Fprintf(&sb, "    -%s|--%s)\n      COMPREPLY=($(compgen -W %q -- \"$cur\"))\n      return ;;\n", f.name, f.name, strings.Join(f.values, " "))
+		default:
+			fileFlags = append(fileFlags, "-"+f.name, "--"+f.name)
+		}
+	}
+	if len(fileFlags) > 0 {
+		fmt.Fprintf(&sb, "    %s)\n      COMPREPLY=($(compgen -f -- \"$cur\"))\n      return ;;\n", strings.Join(fileFlags, "|"))
+	}
+	sb.WriteString("  esac\n")
+	fmt.Fprintf(&sb, "  if [[ \"$cur\" == -* ]]; then\n    COMPREPLY=($(compgen -W %q -- \"$cur\"))\n    return\n  fi\n", strings.Join(names, " "))
+	fmt.Fprintf(&sb, "  COMPREPLY=($(compgen -f -- \"$cur\"))\n  if [[ $COMP_CWORD -eq 1 ]]; then\n    COMPREPLY+=($(compgen -W %q -- \"$cur\"))\n  fi\n}\n", strings.Join(subcommandNames(), " "))
+	fmt.Fprintf(&sb, "complete -o filenames -F %s %s\n", fn, prog)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeZshCompletion(w io.Writer, prog string, fs *flag.FlagSet) error {
+	// _arguments reserves []: and quotes in descriptions.
+	clean := strings.NewReplacer("[", "(", "]", ")", ":", " ", "'", "", "\\", "")
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "#compdef %s\n\n_arguments -s \\\n", prog)
+	for _, f := range completionFlags(fs) {
+		desc := clean.Replace(f.usage)
+		switch {
+		case f.isBool:
+			fmt.Fprintf(&sb, "  '%s[%s]' \\\n", f.dashed(), desc)
+		case f.values != nil:
+			fmt.Fprintf(&sb, "  '%s[%s]:%s:(%s)' \\\n", f.dashed(), desc, f.name, strings.Join(f.values, " "))
+		default:
+			fmt.Fprintf(&sb, "  '%s[%s]:%s:_files' \\\n", f.dashed(), desc, f.name)
+		}
+	}
+	fmt.Fprintf(&sb, "  '1:command or file:{_files; compadd %s}' \\\n", strings.Join(subcommandNames(), " "))
+	sb.WriteString("  '*:file:_files'\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeFishCompletion(w io.Writer, prog string, fs *flag.FlagSet) error {
+	quote := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# fish completion for %s\n", prog)
+	fmt.Fprintf(&sb, "complete -c %s -n '__fish_use_subcommand' -a '%s'\n", prog, strings.Join(subcommandNames(), " "))
+	for _, f := range completionFlags(fs) {
+		opt := "-l " + f.name
+		if len(f.name) == 1 {
+			opt = "-s " + f.name
+		}
+		fmt.Fprintf(&sb, "complete -c %s %s -d '%s'", prog, opt, quote.Replace(f.usage))
+		switch {
+		case f.isBool:
+		case f.values != nil:
+			fmt.Fprintf(&sb, " -x -a '%s'", strings.Join(f.values, " "))
+		default:
+			sb.WriteString(" -r -F")
+		}
+		sb.WriteString("\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,65 @@
+package jsondiff
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestCompletionCoversFlags checks that every flag of the diff command, as
+// listed by --help, appears in each generated completion script.
+func TestCompletionCoversFlags(t *testing.T) {
+	dir := t.TempDir()
+	help := runCLI(t, dir, "--help")
+	var flags []string
+	for _, m := range regexp.MustCompile(`(?m)^  -(\S+)`).FindAllStringSubmatch(help.stderr, -1) {
+		flags = append(flags, m[1])
+	}
+	if len(flags) < 50 {
+		t.Fatalf("only %d flags in --help:\n%s", len(flags), help.stderr)
+	}
+	defined := make(map[string]bool)
+	for _, f := range flags {
+		defined[f] = true
+	}
+	for name := range completionValues {
+		if !defined[name] {
+			t.Errorf("completion values listed for undefined flag %s", name)
+		}
+	}
+
+	// spelling returns a pattern matching the flag in each shell's script.
+	spelling := map[string]func(name string) string{
+		"bash": func(name string) string {
+			return `[" ]` + regexp.QuoteMeta(completionFlag{name: name}.dashed()) + `[" ]`
+		},
+		"zsh": func(name string) string { return `'` + regexp.QuoteMeta(completionFlag{name: name}.dashed()) + `\[` },
+		"fish": func(name string) string {
+			if len(name) == 1 {
+				return ` -s ` + regexp.QuoteMeta(name) + ` `
+			}
+			return ` -l ` + regexp.QuoteMeta(name) + ` `
+		},
+	}
+	for shell, spell := range spelling {
+		t.Run(shell, func(t *testing.T) {
+			res := runCLI(t, dir, "completion", shell)
+			if res.exit != exitOK {
+				t.Fatalf("exit %d: %s", res.exit, res.stderr)
+			}
+			for _, f := range flags {
+				if !regexp.MustCompile(spell(f)).MatchString(res.stdout) {
+					t.Errorf("flag %s missing", f)
+				}
+			}
+			for _, v := range []string{"fragment", "jsonl", "list", "nfd", "document"} {
+				if !strings.Contains(res.stdout, v) {
+					t.Errorf("value %s missing", v)
+				}
+			}
+		})
+	}
+	if res := runCLI(t, dir, "completion", "tcsh"); res.exit == exitOK {
+		t.Error("unsupported shell accepted")
+	}
+}
@@ -0,0 +1,63 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Config is the optional JSON file passed with --config for settings that
+// are too detailed for command-line flags.
+type Config struct {
+	// Transforms enables string comparison transforms for matching paths.
+	Transforms []TransformRule `json:"transforms"`
+
+	// RedactKeys replaces the key names --auto-redact treats as secrets.
+	RedactKeys []string `json:"redactKeys"`
+
+	// Multisets adds path patterns of arrays compared like --multiset.
+	Multisets []string `json:"multisets"`
+
+	// Links maps path patterns to URLs of related pages, linked from
+	// matching changes.
+	Links []LinkRule `json:"links"`
+
+	// Weights sets how much matching sections count towards the weighted
+	// similarity.
+	Weights []WeightRule `json:"weights"`
+
+	// EnvironmentOrder lists environment names in the order they are
+	// normally compared, such as ["dev", "staging", "prod"]; input labels
+	// naming them the other way round suggest swapped arguments.
+	EnvironmentOrder []string `json:"environmentOrder"`
+
+	// Classes maps class names of the tree and table markup to the classes
+	// emitted instead.
+	Classes map[string]string `json:"classes"`
+}
+
+func loadConfig(filename string) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid config: %v", describeJSONError(data, err))
+	}
+	for _, r := range cfg.Transforms {
+		if r.Path == "" {
+			return nil, fmt.Errorf("invalid config: transform rule without a path")
+		}
+	}
+	if _, err := compileLinkRules(cfg.Links); err != nil {
+		return nil, fmt.Errorf("invalid config: %v", err)
+	}
+	if _, err := compileWeightRules(cfg.Weights); err != nil {
+		return nil, fmt.Errorf("invalid config: %v", err)
+	}
+	if err := validateClasses(cfg.Classes); err != nil {
+		return nil, fmt.Errorf("invalid config: %v", err)
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,31 @@
+package jsondiff
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// jsonPayload returns v encoded as JSON for a copy button: the exact value,
+// unlike the display forms, which may be truncated or formatted. <, > and &
+// are left as they are; escaping them is up to the attribute holding the
+// payload.
+func jsonPayload(v interface{}) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return formatValue(v)
+	}
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
+
+// copyButton renders a button copying the JSON of the leaf value v in a
+// tree, or "" for objects and arrays. It is left out of the tab order so
+// the tree keeps a single tab stop; the "c" key copies the focused item's
+// value instead.
+func (ctx *renderContext) copyButton(v interface{}) string {
+	if isComposite(v) {
+		return ""
+	}
+	return `<button type="button" class="` + ctx.cls("copy-value") + `" tabindex="-1"` + attr("data-copy", jsonPayload(v)) + ` title="Copy JSON value" aria-label="Copy JSON value">&#10697;</button>`
+}
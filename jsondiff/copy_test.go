@@ -0,0 +1,59 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"html"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestJSONPayload(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{"<a href=\"x\">&</a>", `"<a href=\"x\">&</a>"`},
+		{json.Number("1.50"), "1.50"},
+		{1e21, "1e+21"},
+		{nil, "null"},
+		{map[string]interface{}{"b": 1, "a": []interface{}{}}, `{"a":[],"b":1}`},
+	}
+	for _, tt := range tests {
+		if got := jsonPayload(tt.v); got != tt.want {
+			t.Errorf("%#v: %s, want %s", tt.v, got, tt.want)
+		}
+	}
+}
+
+// TestCopyButtonsCLI checks that every copy button of the HTML report holds
+// the exact JSON of its value, even where the display shortens it, and that
+// only leaves of the trees get one.
+func TestCopyButtonsCLI(t *testing.T) {
+	long := strings.Repeat("x", 500)
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"tag": "<b>&amp;</b>", "long": "` + long + `", "obj": {"n": 1}}`,
+		"b.json": `{"tag": "it's \"quoted\"", "long": "` + long + `y", "obj": {"n": 2}}`,
+	})
+	if res := runCLI(t, dir, "-o", "out.html", "a.json", "b.json"); res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	payloads := make(map[string]int)
+	for _, m := range regexp.MustCompile(`data-copy="([^"]*)"`).FindAllStringSubmatch(readFile(t, dir, "out.html"), -1) {
+		var v interface{}
+		payload := html.UnescapeString(m[1])
+		if err := json.Unmarshal([]byte(payload), &v); err != nil {
+			t.Errorf("%s: %v", payload, err)
+		}
+		payloads[payload]++
+	}
+	// Each changed value has a button in its tree and in the table.
+	for _, want := range []string{`"<b>&amp;</b>"`, `"it's \"quoted\""`, `"` + long + `"`, `"` + long + `y"`, "1", "2"} {
+		if payloads[want] != 2 {
+			t.Errorf("%d buttons copy %.40s", payloads[want], want)
+		}
+	}
+	if len(payloads) != 6 {
+		t.Errorf("%d distinct payloads, want 6", len(payloads))
+	}
+}
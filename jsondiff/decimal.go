@@ -0,0 +1,83 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// canonicalDecimal rewrites a JSON number literal so that two literals have
+// the same canonical form exactly when they denote the same decimal value:
+// leading and trailing zeros are dropped into the exponent, so "1.50",
+// "15e-1" and "0.0015E3" all become "15e-1", and "-0" and "+1" (which
+// lenient inputs contain) become "0" and "1". No precision is lost.
+func canonicalDecimal(lit string) string {
+	s := lit
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	exp := 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return lit
+		}
+		s, exp = s[:i], e
+	}
+	intPart, frac, _ := strings.Cut(s, ".")
+	digits := strings.TrimLeft(intPart+frac, "0")
+	exp -= len(frac)
+	if digits == "" {
+		return "0"
+	}
+	trimmed := strings.TrimRight(digits, "0")
+	exp += len(digits) - len(trimmed)
+	if neg {
+		trimmed = "-" + trimmed
+	}
+	if exp == 0 {
+		return trimmed
+	}
+	return fmt.Sprintf("%se%d", trimmed, exp)
+}
+
+// canonicalizeDecimals returns a copy of v with every json.Number replaced
+// by its canonical form, so --decimal-strict compares values textually. The
+// source literal of each rewritten number is recorded in originals by path.
+func canonicalizeDecimals(v interface{}, path string, originals map[string]string) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, vv := range val {
+			out[k] = canonicalizeDecimals(vv, pathKey(path, k), originals)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, vv := range val {
+			out[i] = canonicalizeDecimals(vv, indexKey(path, i), originals)
+		}
+		return out
+	case json.Number:
+		c := canonicalDecimal(string(val))
+		if c != string(val) {
+			originals[path] = string(val)
+		}
+		return json.Number(c)
+	}
+	return v
+}
+
+// restoreDecimalLiterals shows numbers in the table exactly as they were
+// written in the inputs rather than in canonical form.
+func restoreDecimalLiterals(results []DiffResult, originalsA, originalsB map[string]string) {
+	for i := range results {
+		r := &results[i]
+		if lit, ok := originalsA[r.Path]; ok && r.Type != "create" {
+			r.From, r.FromJSON = lit, lit
+		}
+		if lit, ok := originalsB[r.Path]; ok && r.Type != "delete" {
+			r.To, r.ToJSON = lit, lit
+		}
+	}
+}
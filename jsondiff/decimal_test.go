@@ -0,0 +1,103 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCanonicalDecimal(t *testing.T) {
+	tests := map[string]string{
+		"1.50":     "15e-1",
+		"15e-1":    "15e-1",
+		"0.0015E3": "15e-1",
+		"1":        "1",
+		"+1":       "1",
+		"100":      "1e2",
+		"1e2":      "1e2",
+		"-0":       "0",
+		"0.000":    "0",
+		"-2.5e+3":  "-25e2",
+		"007":      "7",
+		"1e":       "1e",
+	}
+	for in, want := range tests {
+		if got := canonicalDecimal(in); got != want {
+			t.Errorf("%s: %s, want %s", in, got, want)
+		}
+	}
+}
+
+// TestDecimalStrictCLI checks that --decimal-strict tells apart numbers
+// float64 cannot, keeps equal decimals written differently equal, and shows
+// the changed numbers as written.
+func TestDecimalStrictCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"price": 1.50, "rate": 0.1, "id": 12345678901234567890, "n": 100, "list": [2.0]}`,
+		"b.json": `{"price": 1.5, "rate": 0.10000000000000001, "id": 12345678901234567891, "n": 1e2, "list": [2.10]}`,
+	})
+	tests := []struct {
+		flags []string
+		want  [][3]string
+	}{
+		{nil, [][3]string{{"list[0]", "2", "2.1"}}},
+		{[]string{"--decimal-strict"}, [][3]string{
+			{"id", "12345678901234567890", "12345678901234567891"},
+			{"list[0]", "2.0", "2.10"},
+			{"rate", "0.1", "0.10000000000000001"},
+		}},
+		// --numeric-strict compares the literals themselves.
+		{[]string{"--numeric-strict"}, [][3]string{
+			{"id", "12345678901234567890", "12345678901234567891"},
+			{"list[0]", "2.0", "2.10"},
+			{"n", "100", "1e2"},
+			{"price", "1.50", "1.5"},
+			{"rate", "0.1", "0.10000000000000001"},
+		}},
+	}
+	for _, tt := range tests {
+		args := append(append([]string{"-f", "json", "-o", "out.json"}, tt.flags...), "a.json", "b.json")
+		if res := runCLI(t, dir, args...); res.exit != exitOK {
+			t.Fatalf("%q: exit %d: %s", tt.flags, res.exit, res.stderr)
+		}
+		var report jsonReport
+		if err := json.Unmarshal([]byte(readFile(t, dir, "out.json")), &report); err != nil {
+			t.Fatal(err)
+		}
+		var got [][3]string
+		for _, s := range report.Sections {
+			for _, c := range s.Changes {
+				got = append(got, [3]string{c.Path, c.From, c.To})
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: changes %q, want %q", tt.flags, got, tt.want)
+		}
+	}
+}
+
+// TestOutOfRangeNumbers checks that numbers beyond float64 are compared by
+// their exact value instead of failing the decoding.
+func TestOutOfRangeNumbers(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"same": 1e400, "sign": -0.5e-400, "big": 1e400}`,
+		"b.json": `{"same": 10E399, "sign": -5e-401, "big": 2e400}`,
+	})
+	res := runCLI(t, dir, "-f", "json", "-o", "-", "a.json", "b.json")
+	if res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	var report jsonReport
+	if err := json.Unmarshal([]byte(res.stdout), &report); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range report.Sections {
+		for _, c := range s.Changes {
+			got = append(got, c.Path)
+		}
+	}
+	if want := []string{"big"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("changes %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,156 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// numericValue returns v as a float64 when it is a JSON number: a float64
+// or json.Number as decoded, or an int or int64 in a document built in Go.
+func numericValue(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := strconv.ParseFloat(string(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// setDelta fills in the numeric delta of an update between two numbers. The
+// percentage is left unset when the old value is zero.
+func setDelta(r *DiffResult, from, to interface{}) {
+	a, okA := numericValue(from)
+	b, okB := numericValue(to)
+	if !okA || !okB {
+		return
+	}
+	d := b - a
+	if d == 0 || !isFinite(d) {
+		// Decimals differing beyond float64 precision (--decimal-strict),
+		// or a difference beyond its range.
+		return
+	}
+	r.Delta = &d
+	if a != 0 {
+		pct := d / math.Abs(a) * 100
+		r.DeltaPercent = &pct
+	}
+}
+
+// DeltaText formats the numeric change for display, e.g. "+5 (+12.5%)", or
+// "+512Mi (+100%)" for a quantity compared with --units.
+func (r DiffResult) DeltaText() string {
+	if r.NonFinite != "" {
+		return r.NonFinite
+	}
+	if r.Delta == nil {
+		return ""
+	}
+	s := formatSigned(*r.Delta)
+	if r.Unit != "" {
+		s = formatQuantity(r.Unit, *r.Delta)
+		if *r.Delta > 0 {
+			s = "+" + s
+		}
+	}
+	if r.DeltaPercent != nil {
+		s += fmt.Sprintf(" (%s%%)", formatSigned(math.Round(*r.DeltaPercent*100)/100))
+	}
+	return s
+}
+
+// DeltaClass is the CSS class for the delta cell: delta-up or delta-down.
+func (r DiffResult) DeltaClass() string {
+	switch {
+	case r.Delta == nil || *r.Delta == 0:
+		return ""
+	case *r.Delta > 0:
+		return "delta-up"
+	default:
+		return "delta-down"
+	}
+}
+
+func formatSigned(f float64) string {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if f > 0 {
+		s = "+" + s
+	}
+	return s
+}
+
+// sortResults orders results in place: "path" sorts by JSON path, "delta"
+// puts the largest absolute numeric changes first and non-numeric changes
+// last (by path), and "document" follows the tree's ordinals, with paths
+// not in the tree last.
+func sortResults(results []DiffResult, by string, ordinals map[string]int) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if by == "document" {
+			oi, okI := ordinals[results[i].Path]
+			oj, okJ := ordinals[results[j].Path]
+			switch {
+			case okI && okJ && oi != oj:
+				return oi < oj
+			case okI != okJ:
+				return okI
+			}
+		}
+		if by == "delta" {
+			di, dj := results[i].Delta, results[j].Delta
+			switch {
+			case di != nil && dj != nil && math.Abs(*di) != math.Abs(*dj):
+				return math.Abs(*di) > math.Abs(*dj)
+			case di != nil && dj == nil:
+				return true
+			case di == nil && dj != nil:
+				return false
+			}
+		}
+		// diff.Diff walks maps in random order, so ties must be broken
+		// down to the ID for the output to be the same on every run.
+		if results[i].Path != results[j].Path {
+			return results[i].Path < results[j].Path
+		}
+		if results[i].Type != results[j].Type {
+			return results[i].Type < results[j].Type
+		}
+		return results[i].ID < results[j].ID
+	})
+}
+
+// Location formats the source lines of the change, e.g. "a.json:87 → b.json:91".
+// Values on the first line past its start also show their byte offset, as
+// every value of a minified input is on line 1.
+func (r DiffResult) Location(labelA, labelB string) string {
+	var parts []string
+	if r.FromLine > 0 {
+		parts = append(parts, sourceLocation(labelA, r.FromLine, r.FromOffset))
+	}
+	if r.ToLine > 0 {
+		parts = append(parts, sourceLocation(labelB, r.ToLine, r.ToOffset))
+	}
+	return strings.Join(parts, " → ")
+}
+
+func sourceLocation(label string, line int, offset int64) string {
+	if line == 1 && offset > 0 {
+		return fmt.Sprintf("%s:1 (offset %d)", label, offset)
+	}
+	return fmt.Sprintf("%s:%d", label, line)
+}
+
+// TypeChanged reports whether an update replaced a value with one of a
+// different JSON type, such as 1 with "1", which look alike in the table.
+func (r DiffResult) TypeChanged() bool {
+	return r.Type == "update" && r.FromType != r.ToType
+}
@@ -0,0 +1,120 @@
+package jsondiff
+
+import (
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDeltaText(t *testing.T) {
+	tests := []struct {
+		from, to    interface{}
+		text, class string
+	}{
+		{8.0, 9.0, "+1 (+12.5%)", "delta-up"},
+		{10.0, 5.0, "-5 (-50%)", "delta-down"},
+		{-4.0, -2.0, "+2 (+50%)", "delta-up"},
+		{0.0, 3.0, "+3", "delta-up"},
+		{3.0, 4.0, "+1 (+33.33%)", "delta-up"},
+		{1.0, 1.0, "", ""},
+		{"1", 2.0, "", ""},
+		{nil, 2.0, "", ""},
+	}
+	for _, tt := range tests {
+		var r DiffResult
+		setDelta(&r, tt.from, tt.to)
+		if got := r.DeltaText(); got != tt.text {
+			t.Errorf("%v to %v: text %q, want %q", tt.from, tt.to, got, tt.text)
+		}
+		if got := r.DeltaClass(); got != tt.class {
+			t.Errorf("%v to %v: class %q, want %q", tt.from, tt.to, got, tt.class)
+		}
+	}
+}
+
+func TestSortResultsByDelta(t *testing.T) {
+	delta := func(f float64) *float64 { return &f }
+	results := []DiffResult{
+		{ID: "1", Path: "b", Delta: delta(1)},
+		{ID: "2", Path: "a"},
+		{ID: "3", Path: "c", Delta: delta(-5)},
+		{ID: "4", Path: "d", Delta: delta(5)},
+		{ID: "5", Path: "e", Delta: delta(0.5)},
+	}
+	sortResults(results, "delta", nil)
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Path)
+	}
+	// Equal magnitudes fall back to the path.
+	if want := []string{"c", "d", "b", "e", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("order %q, want %q", got, want)
+	}
+}
+
+// TestDeltaCSV checks the delta columns of the CSV report and that --sort
+// delta puts the largest numeric changes first.
+func TestSortResultsByDocument(t *testing.T) {
+	ordinals := map[string]int{"": 0, "z": 1, "z.b": 2, "a": 3}
+	results := []DiffResult{
+		{ID: "1", Path: "a"},
+		{ID: "2", Path: "gone"},
+		{ID: "3", Path: "z.b"},
+		{ID: "4", Path: "z"},
+		{ID: "5", Path: "extra"},
+	}
+	sortResults(results, "document", ordinals)
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Path)
+	}
+	// Paths without an ordinal come last, by path.
+	if want := []string{"z", "z.b", "a", "extra", "gone"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("order %q, want %q", got, want)
+	}
+}
+
+func TestDeltaCSV(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"n": {"a": 1, "b": 10, "c": "x", "d": 0}}`,
+		"b.json": `{"n": {"a": 2, "b": 5, "c": "y", "d": 3}}`,
+	})
+	tests := []struct {
+		sort string
+		want [][]string
+	}{
+		{"path", [][]string{
+			{"n.a", "1", "2", "1", "100"},
+			{"n.b", "10", "5", "-5", "-50"},
+			{"n.c", "x", "y", "", ""},
+			{"n.d", "0", "3", "3", ""},
+		}},
+		{"delta", [][]string{
+			{"n.b", "10", "5", "-5", "-50"},
+			{"n.d", "0", "3", "3", ""},
+			{"n.a", "1", "2", "1", "100"},
+			{"n.c", "x", "y", "", ""},
+		}},
+	}
+	for _, tt := range tests {
+		res := runCLI(t, dir, "-f", "csv", "-o", "-", "--sort", tt.sort, "a.json", "b.json")
+		if res.exit != exitOK {
+			t.Fatalf("--sort %s: exit %d: %s", tt.sort, res.exit, res.stderr)
+		}
+		rows, err := csv.NewReader(strings.NewReader(res.stdout)).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"id", "path", "pointer", "type", "from", "to", "delta", "delta_percent"}; !reflect.DeepEqual(rows[0], want) {
+			t.Fatalf("header %q", rows[0])
+		}
+		var got [][]string
+		for _, row := range rows[1:] {
+			got = append(got, []string{row[1], row[4], row[5], row[6], row[7]})
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("--sort %s:\n%q\nwant\n%q", tt.sort, got, tt.want)
+		}
+	}
+}
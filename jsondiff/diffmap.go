@@ -0,0 +1,154 @@
+package jsondiff
+
+// DiffMap indexes change types by path. It is a trie over path
+// segments, so exact, ancestor-of-change and descendant-of-change queries
+// all cost O(path length) regardless of how many changes there are.
+type DiffMap struct {
+	root *diffMapNode
+	size int
+}
+
+type diffMapNode struct {
+	children map[string]*diffMapNode
+	// changeType resolves changes, every change recorded at the node in
+	// the order they were added.
+	changeType   ChangeType
+	changes      []ChangeType
+	hasChange    bool
+	changedBelow int
+}
+
+func newDiffMap() *DiffMap {
+	return &DiffMap{root: &diffMapNode{}}
+}
+
+// Set records the change type for path, replacing any earlier entries.
+func (m *DiffMap) Set(path string, ct ChangeType) {
+	n := m.insert(path)
+	n.changeType, n.changes = ct, []ChangeType{ct}
+}
+
+// Add records another change at path. The diff library can report several
+// changes for one joined path, such as a removal and an addition where
+// array operations interleave; all are kept and the path resolves to
+// resolveChangeTypes of them, whatever order they arrive in.
+func (m *DiffMap) Add(path string, ct ChangeType) {
+	n := m.insert(path)
+	n.changes = append(n.changes, ct)
+	n.changeType = resolveChangeTypes(n.changes)
+}
+
+// resolveChangeTypes returns the single type shown for a path with the
+// given changes: their type when they all agree, otherwise Changed, so a
+// removal and an addition at the same path read as a change of its value.
+func resolveChangeTypes(changes []ChangeType) ChangeType {
+	for _, ct := range changes[1:] {
+		if ct != changes[0] {
+			return Changed
+		}
+	}
+	return changes[0]
+}
+
+// insert returns the node for path, counting it as changed.
+func (m *DiffMap) insert(path string) *diffMapNode {
+	segs := splitPath(path)
+	n := m.root
+	var trail []*diffMapNode
+	for _, seg := range segs {
+		trail = append(trail, n)
+		child := n.children[seg]
+		if child == nil {
+			if n.children == nil {
+				n.children = make(map[string]*diffMapNode)
+			}
+			child = &diffMapNode{}
+			n.children[seg] = child
+		}
+		n = child
+	}
+	if n.hasChange {
+		return n
+	}
+	n.hasChange = true
+	for _, t := range trail {
+		t.changedBelow++
+	}
+	m.size++
+	return n
+}
+
+// Len returns the number of paths with a recorded change.
+func (m *DiffMap) Len() int {
+	if m == nil {
+		return 0
+	}
+	return m.size
+}
+
+func (m *DiffMap) node(path string) *diffMapNode {
+	if m == nil {
+		return nil
+	}
+	n := m.root
+	for _, seg := range splitPath(path) {
+		n = n.children[seg]
+		if n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// Lookup returns the change type recorded exactly at path.
+func (m *DiffMap) Lookup(path string) (ChangeType, bool) {
+	n := m.node(path)
+	if n == nil || !n.hasChange {
+		return "", false
+	}
+	return n.changeType, true
+}
+
+// Changes returns every change recorded exactly at path, in the order they
+// were added.
+func (m *DiffMap) Changes(path string) []ChangeType {
+	n := m.node(path)
+	if n == nil {
+		return nil
+	}
+	return n.changes
+}
+
+// HasChangedDescendant reports whether any change lies strictly below path.
+func (m *DiffMap) HasChangedDescendant(path string) bool {
+	n := m.node(path)
+	return n != nil && n.changedBelow > 0
+}
+
+// ChangedAncestor returns the nearest proper ancestor of path that has a
+// recorded change, if any.
+func (m *DiffMap) ChangedAncestor(path string) (string, ChangeType, bool) {
+	if m == nil {
+		return "", "", false
+	}
+	segs := splitPath(path)
+	n := m.root
+	found := -1
+	var ct ChangeType
+	if n.hasChange && len(segs) > 0 {
+		found, ct = 0, n.changeType
+	}
+	for i := 0; i < len(segs)-1; i++ {
+		n = n.children[segs[i]]
+		if n == nil {
+			break
+		}
+		if n.hasChange {
+			found, ct = i+1, n.changeType
+		}
+	}
+	if found < 0 {
+		return "", "", false
+	}
+	return joinPath(segs[:found]), ct, true
+}
@@ -0,0 +1,198 @@
+package jsondiff
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestDiffMap(t *testing.T) {
+	m := newDiffMap()
+	m.Set("spec.replicas", Changed)
+	m.Add("items[1]", Removed)
+	m.Add("items[1]", Added)
+	m.Add("items.0", Added)
+	m.Add("meta", Removed)
+
+	tests := []struct {
+		path       string
+		ct         ChangeType
+		own, below bool
+		ancestor   string
+	}{
+		{path: "", below: true},
+		{path: "spec", below: true},
+		{path: "spec.replicas", ct: Changed, own: true},
+		{path: "spec.replicas.x", ancestor: "spec.replicas"},
+		{path: "items", below: true},
+		{path: "items[1]", ct: Changed, own: true},
+		{path: "items[0]"},
+		{path: "items.0", ct: Added, own: true},
+		{path: "meta.labels.app", ancestor: "meta"},
+		{path: "other"},
+	}
+	for _, tt := range tests {
+		ct, own := m.Lookup(tt.path)
+		if ct != tt.ct || own != tt.own {
+			t.Errorf("Lookup(%q) = %q, %t, want %q, %t", tt.path, ct, own, tt.ct, tt.own)
+		}
+		if below := m.HasChangedDescendant(tt.path); below != tt.below {
+			t.Errorf("HasChangedDescendant(%q) = %t", tt.path, below)
+		}
+		anc, _, ok := m.ChangedAncestor(tt.path)
+		if anc != tt.ancestor || ok != (tt.ancestor != "") {
+			t.Errorf("ChangedAncestor(%q) = %q, %t, want %q", tt.path, anc, ok, tt.ancestor)
+		}
+	}
+	if got := m.Changes("items[1]"); !reflect.DeepEqual(got, []ChangeType{Removed, Added}) {
+		t.Errorf("Changes(items[1]) = %q", got)
+	}
+	if m.Len() != 4 {
+		t.Errorf("Len() = %d, want 4", m.Len())
+	}
+	m.Set("items[1]", Moved)
+	if ct, _ := m.Lookup("items[1]"); ct != Moved || len(m.Changes("items[1]")) != 1 || m.Len() != 4 {
+		t.Errorf("Set did not replace the changes at items[1]")
+	}
+
+	var empty *DiffMap
+	if _, _, ok := empty.ChangedAncestor("a"); ok || empty.Len() != 0 || empty.HasChangedDescendant("") {
+		t.Error("nil DiffMap reports changes")
+	}
+}
+
+// TestDiffMapAddOrder checks that the type a path resolves to does not
+// depend on the order its changes arrive in, and that the tree item lists
+// them.
+func TestDiffMapAddOrder(t *testing.T) {
+	tests := []struct {
+		changes []ChangeType
+		want    ChangeType
+		attr    string
+	}{
+		{[]ChangeType{Added}, Added, ""},
+		{[]ChangeType{Removed, Removed}, Removed, ` data-changes="removed removed"`},
+		{[]ChangeType{Removed, Added}, Changed, ` data-changes="removed added"`},
+		{[]ChangeType{Added, Removed}, Changed, ` data-changes="added removed"`},
+		{[]ChangeType{Added, Added, Changed}, Changed, ` data-changes="added added changed"`},
+	}
+	for _, tt := range tests {
+		m := newDiffMap()
+		for _, ct := range tt.changes {
+			m.Add("x[0]", ct)
+		}
+		if ct, _ := m.Lookup("x[0]"); ct != tt.want {
+			t.Errorf("%q: resolved to %q, want %q", tt.changes, ct, tt.want)
+		}
+		ctx := renderContext{diffMap: m}
+		if got := ctx.changesAttr("x[0]"); got != tt.attr {
+			t.Errorf("%q: attribute %q, want %q", tt.changes, got, tt.attr)
+		}
+		if m.Len() != 1 {
+			t.Errorf("%q: Len() = %d", tt.changes, m.Len())
+		}
+	}
+}
+
+// treePaths returns the path of every value in v.
+func treePaths(v interface{}) []string {
+	var paths []string
+	var walk func(v interface{}, path string)
+	walk = func(v interface{}, path string) {
+		paths = append(paths, path)
+		switch val := v.(type) {
+		case map[string]interface{}:
+			for k, child := range val {
+				walk(child, pathKey(path, k))
+			}
+		case []interface{}:
+			for i, child := range val {
+				walk(child, indexKey(path, i))
+			}
+		}
+	}
+	walk(v, "")
+	return paths
+}
+
+// TestDiffMapMatchesScan checks the trie's answers against a scan of every
+// changed path, for every value of a document.
+func TestDiffMapMatchesScan(t *testing.T) {
+	a, b := largeDocs(t, 9000, 200)
+	changes, err := collectChanges(diffSeq(context.Background(), a, b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := buildDiffMap(changes, a, b)
+	var changed [][]string
+	for _, c := range changes {
+		changed = append(changed, splitPath(changePath(c, a, b)))
+	}
+	if len(changed) != 200 {
+		t.Fatalf("%d changes, want 200", len(changed))
+	}
+	for _, path := range treePaths(b) {
+		segs := splitPath(path)
+		own, below, ancestor := false, false, -1
+		for _, c := range changed {
+			p := commonPrefix(segs, c)
+			switch {
+			case p == len(segs) && p == len(c):
+				own = true
+			case p == len(segs):
+				below = true
+			case p == len(c):
+				ancestor = max(ancestor, p)
+			}
+		}
+		if _, ok := m.Lookup(path); ok != own {
+			t.Errorf("Lookup(%q) = %t, scan %t", path, ok, own)
+		}
+		if got := m.HasChangedDescendant(path); got != below {
+			t.Errorf("HasChangedDescendant(%q) = %t, scan %t", path, got, below)
+		}
+		anc, _, ok := m.ChangedAncestor(path)
+		if ok != (ancestor >= 0) || ok && anc != joinPath(segs[:ancestor]) {
+			t.Errorf("ChangedAncestor(%q) = %q, %t, scan found depth %d", path, anc, ok, ancestor)
+		}
+	}
+}
+
+// BenchmarkDiffMap builds the index of 5k changes in a 100k-value document
+// and queries it for every value, as rendering does.
+func BenchmarkDiffMap(b *testing.B) {
+	docA, docB := largeDocs(b, 100000, 5000)
+	changes, err := collectChanges(diffSeq(context.Background(), docA, docB))
+	if err != nil {
+		b.Fatal(err)
+	}
+	paths := treePaths(docB)
+	m := buildDiffMap(changes, docA, docB)
+	b.Run("build", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buildDiffMap(changes, docA, docB)
+		}
+	})
+	b.Run("exact", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, p := range paths {
+				getChangeType(m, p)
+			}
+		}
+	})
+	b.Run("descendant", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, p := range paths {
+				m.HasChangedDescendant(p)
+			}
+		}
+	})
+	b.Run("ancestor", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, p := range paths {
+				m.ChangedAncestor(p)
+			}
+		}
+	})
+}
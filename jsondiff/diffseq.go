@@ -0,0 +1,131 @@
+package jsondiff
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/r3labs/diff/v3"
+)
+
+// diffSeq yields the changes between a and b as the walk finds them. When
+// both are objects they are compared one top-level member at a time, so a
+// consumer that stops early skips the members not yet compared. Cancelling
+// ctx aborts the walk, even inside a member, and yields ctx.Err().
+func diffSeq(ctx context.Context, a, b interface{}) iter.Seq2[diff.Change, error] {
+	return func(yield func(diff.Change, error) bool) {
+		opts := []func(*diff.Differ) error{
+			diff.AllowTypeMismatch(true),
+			diff.CustomValueDiffers(&cancelDiffer{ctx: ctx}, &unorderedDiffer{ctx: ctx}),
+		}
+		emit := func(x, y interface{}) bool {
+			changes, err := diff.Diff(x, y, opts...)
+			if err != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					err = ctxErr
+				}
+				yield(diff.Change{}, err)
+				return false
+			}
+			for _, c := range changes {
+				if !yield(c, nil) {
+					return false
+				}
+			}
+			return true
+		}
+
+		ma, okA := a.(map[string]interface{})
+		mb, okB := b.(map[string]interface{})
+		if !okA || !okB {
+			if a == nil && b == nil {
+				// diff.Diff rejects two untyped nils.
+				return
+			}
+			emit(a, b)
+			return
+		}
+		keys := make([]string, 0, len(ma)+len(mb))
+		for k := range ma {
+			keys = append(keys, k)
+		}
+		for k := range mb {
+			if _, ok := ma[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := ctx.Err(); err != nil {
+				yield(diff.Change{}, err)
+				return
+			}
+			// One-member objects keep the library's own add/remove
+			// reporting for keys missing on one side.
+			x, y := map[string]interface{}{}, map[string]interface{}{}
+			if v, ok := ma[k]; ok {
+				x[k] = v
+			}
+			if v, ok := mb[k]; ok {
+				y[k] = v
+			}
+			if !emit(x, y) {
+				return
+			}
+		}
+	}
+}
+
+// collectChanges drains seq into a changelog, stopping at the first error.
+// On error the changes found before it are returned with it.
+func collectChanges(seq iter.Seq2[diff.Change, error]) (diff.Changelog, error) {
+	var changes diff.Changelog
+	for c, err := range seq {
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+// cancelledError reports a comparison stopped by --timeout after finding
+// found changes.
+func cancelledError(timeout time.Duration, found int) error {
+	return fmt.Errorf("Comparison cancelled after %v, %s changes found so far", timeout, groupDigits(found))
+}
+
+// groupDigits formats a non-negative n with comma thousands separators.
+func groupDigits(n int) string {
+	s := strconv.Itoa(n)
+	head := len(s) % 3
+	if head == 0 {
+		head = 3
+	}
+	out := s[:head]
+	for i := head; i < len(s); i += 3 {
+		out += "," + s[i:i+3]
+	}
+	return out
+}
+
+// cancelDiffer is a diff.ValueDiffer that takes over every comparison once
+// its context is done and fails it, unwinding the library's walk.
+type cancelDiffer struct {
+	ctx context.Context
+}
+
+func (d *cancelDiffer) Match(a, b reflect.Value) bool {
+	return d.ctx.Err() != nil
+}
+
+func (d *cancelDiffer) Diff(dt diff.DiffType, df diff.DiffFunc, cl *diff.Changelog, path []string, a, b reflect.Value, parent interface{}) error {
+	return d.ctx.Err()
+}
+
+func (d *cancelDiffer) InsertParentDiffer(func(path []string, a, b reflect.Value, p interface{}) error) {
+}
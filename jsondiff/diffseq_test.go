@@ -0,0 +1,203 @@
+package jsondiff
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// countingContext counts the Err calls the diff walk makes: one per
+// top-level member and one per value compared, so the count measures how
+// much of the documents was walked.
+type countingContext struct {
+	context.Context
+	calls int
+}
+
+func (c *countingContext) Err() error {
+	c.calls++
+	return c.Context.Err()
+}
+
+// wideNodes returns two documents with n top-level members, every one of
+// them changed.
+func wideNodes(t *testing.T, n int) (*Node, *Node) {
+	t.Helper()
+	var a, b strings.Builder
+	a.WriteByte('{')
+	b.WriteByte('{')
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			a.WriteByte(',')
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&a, `"k%04d": {"v": %d, "w": [1, 2, 3]}`, i, i)
+		fmt.Fprintf(&b, `"k%04d": {"v": %d, "w": [1, 2, 3]}`, i, i+1)
+	}
+	a.WriteByte('}')
+	b.WriteByte('}')
+	na, err := ParseNode([]byte(a.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nb, err := ParseNode([]byte(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return na, nb
+}
+
+func TestCompareSeqStopsEarly(t *testing.T) {
+	a, b := wideNodes(t, 1000)
+
+	full := &countingContext{Context: context.Background()}
+	total := 0
+	for _, err := range CompareSeq(full, a, b) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		total++
+	}
+	if total != 1000 || full.calls < 1000 {
+		t.Fatalf("full walk found %d changes in %d steps, want 1000 in at least as many", total, full.calls)
+	}
+
+	early := &countingContext{Context: context.Background()}
+	for nc, err := range CompareSeq(early, a, b) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := joinPath(nc.Path); got != "k0000.v" {
+			t.Errorf("first change at %s, want k0000.v", got)
+		}
+		break
+	}
+	if early.calls*100 > full.calls {
+		t.Errorf("breaking after the first change still walked %d of %d steps", early.calls, full.calls)
+	}
+}
+
+func TestCompareSeqCancel(t *testing.T) {
+	a, b := wideNodes(t, 1000)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	found := 0
+	var last error
+	for _, err := range CompareSeq(ctx, a, b) {
+		if err != nil {
+			last = err
+			continue
+		}
+		found++
+		if found == 3 {
+			cancel()
+		}
+	}
+	if !errors.Is(last, context.Canceled) {
+		t.Errorf("last error %v, want context.Canceled", last)
+	}
+	if found != 3 {
+		t.Errorf("%d changes yielded after cancelling at the third", found)
+	}
+}
+
+func TestCompareSeqMatchesCompare(t *testing.T) {
+	a, b := wideNodes(t, 20)
+	cs, err := Compare(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := 0
+	for nc, err := range CompareSeq(context.Background(), a, b) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i >= len(cs.Changes) || joinPath(nc.Path) != joinPath(cs.Changes[i].Path) || nc.From != cs.Changes[i].From {
+			t.Fatalf("change %d differs from Compare's", i)
+		}
+		i++
+	}
+	if i != len(cs.Changes) {
+		t.Errorf("CompareSeq yielded %d changes, Compare %d", i, len(cs.Changes))
+	}
+}
+
+func TestCompareSeqNil(t *testing.T) {
+	for _, err := range CompareSeq(context.Background(), nil, NodeFromInterface(1.0)) {
+		if err == nil {
+			t.Error("nil document accepted")
+		}
+	}
+}
+
+// keyedLeaf is an array element that counts how often the unordered
+// matcher encodes it, and cancels the walk after the first ten.
+type keyedLeaf struct {
+	keyed  *int
+	cancel context.CancelFunc
+}
+
+func (l keyedLeaf) MarshalJSON() ([]byte, error) {
+	*l.keyed++
+	if *l.keyed == 10 {
+		l.cancel()
+	}
+	return []byte(strconv.Itoa(*l.keyed)), nil
+}
+
+// TestDiffSeqCancelInsideArray cancels the walk while the elements of one
+// long array are keyed, where a walk of long arrays spends nearly all its
+// time, and checks that the rest are not.
+func TestDiffSeqCancelInsideArray(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	keyed := 0
+	long := make([]interface{}, 10000)
+	for i := range long {
+		long[i] = keyedLeaf{keyed: &keyed, cancel: cancel}
+	}
+	a := map[string]interface{}{"items": long}
+	b := map[string]interface{}{"items": []interface{}{"x"}}
+	if _, err := collectChanges(diffSeq(ctx, a, b)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error %v, want context.Canceled", err)
+	}
+	if keyed > 10 {
+		t.Errorf("%d elements keyed, want the walk to stop at the tenth", keyed)
+	}
+}
+
+// TestTimeoutCLI runs a comparison of long arrays with a --timeout far
+// shorter than matching their elements takes.
+func TestTimeoutCLI(t *testing.T) {
+	if testing.Short() {
+		t.Skip("writes and compares large documents")
+	}
+	a, b := largeDocs(t, 90000, 100)
+	files := make(map[string]string)
+	for name, v := range map[string]interface{}{"a.json": a, "b.json": b} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[name] = string(data)
+	}
+	dir := cliDir(t, files)
+
+	res := runCLI(t, dir, "-o", "out.html", "--timeout", "10ms", "a.json", "b.json")
+	if res.exit != exitError || !strings.Contains(res.stderr, "Comparison cancelled after 10ms, ") {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out.html")); !os.IsNotExist(err) {
+		t.Errorf("a cancelled comparison wrote a report: %v", err)
+	}
+	if res := runCLI(t, dir, "-o", "out.html", "--timeout", "1m", "a.json", "b.json"); res.exit != exitOK {
+		t.Fatalf("--timeout 1m: exit %d: %s", res.exit, res.stderr)
+	}
+	wellFormed["html"](t, readFile(t, dir, "out.html"))
+}
@@ -0,0 +1,185 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// Values of --display-dates.
+const (
+	datesISO      = "iso"
+	datesLocal    = "local"
+	datesRelative = "relative"
+)
+
+// localDateLayout is how --display-dates local shows a timestamp.
+const localDateLayout = "2 Jan 2006 15:04:05.999999999 MST"
+
+// displayFormat formats numbers and timestamps for people reading the HTML
+// report, per --display-number-locale and --display-dates. It only changes
+// what the trees and the table show: the comparison, the JSON outputs and
+// the copy buttons all keep the raw values, and every formatted value
+// carries its raw form in a tooltip.
+type displayFormat struct {
+	// numbers formats JSON numbers; nil leaves them as written.
+	numbers *message.Printer
+	dates   string
+	// now is the time relative dates are counted from.
+	now time.Time
+}
+
+// parseDisplayFormat parses the display options, returning nil when
+// neither changes anything.
+func parseDisplayFormat(locale, dates string, now time.Time) (*displayFormat, error) {
+	f := &displayFormat{dates: dates, now: now}
+	switch dates {
+	case datesISO, datesLocal, datesRelative:
+	default:
+		return nil, fmt.Errorf("invalid --display-dates %q: must be iso, local or relative", dates)
+	}
+	if locale != "" {
+		tag, err := language.Parse(locale)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --display-number-locale %q: %v", locale, err)
+		}
+		f.numbers = message.NewPrinter(tag)
+	}
+	if f.numbers == nil && dates == datesISO {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// value returns the formatted form of v, a scalar as decoded from JSON, and
+// whether it differs from the raw one.
+func (f *displayFormat) value(v interface{}) (string, bool) {
+	if f == nil {
+		return "", false
+	}
+	switch val := v.(type) {
+	case float64:
+		return f.number(val, strconv.FormatFloat(val, 'f', -1, 64))
+	case json.Number:
+		n, err := val.Float64()
+		if err != nil {
+			return "", false
+		}
+		return f.number(n, string(val))
+	case string:
+		return f.date(val)
+	}
+	return "", false
+}
+
+// number formats n, written literally as lit, in the number locale. A
+// number a float64 cannot hold exactly, or too large or small to group, is
+// left as written, so formatting never shows digits the input lacks.
+func (f *displayFormat) number(n float64, lit string) (string, bool) {
+	if f.numbers == nil || math.Abs(n) >= 1e15 {
+		return "", false
+	}
+	shortest := strconv.FormatFloat(n, 'f', -1, 64)
+	want, ok := new(big.Rat).SetString(lit)
+	if !ok {
+		return "", false
+	}
+	if got, _ := new(big.Rat).SetString(shortest); got.Cmp(want) != 0 {
+		return "", false
+	}
+	digits := 0
+	if i := strings.IndexByte(shortest, '.'); i >= 0 {
+		digits = len(shortest) - i - 1
+	}
+	if digits > 15 {
+		return "", false
+	}
+	s := f.numbers.Sprint(number.Decimal(n, number.MinFractionDigits(digits), number.MaxFractionDigits(digits)))
+	return s, s != lit
+}
+
+// date formats s per --display-dates if it is an RFC 3339 timestamp.
+func (f *displayFormat) date(s string) (string, bool) {
+	if f.dates == datesISO {
+		return "", false
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return "", false
+	}
+	if f.dates == datesRelative {
+		return relativeTime(t, f.now), true
+	}
+	return t.Local().Format(localDateLayout), true
+}
+
+// relativeTime describes t as a whole number of its largest unit before or
+// after now, such as "3 hours ago" or "in 2 days".
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	past := d >= 0
+	if !past {
+		d = -d
+	}
+	units := []struct {
+		name string
+		size time.Duration
+	}{
+		{"year", 365 * 24 * time.Hour},
+		{"month", 30 * 24 * time.Hour},
+		{"day", 24 * time.Hour},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+		{"second", time.Second},
+	}
+	for _, u := range units {
+		n := int64(d / u.size)
+		if n == 0 {
+			continue
+		}
+		s := fmt.Sprintf("%d %s", n, u.name)
+		if n != 1 {
+			s += "s"
+		}
+		if past {
+			return s + " ago"
+		}
+		return "in " + s
+	}
+	return "now"
+}
+
+// setFormattedValues fills in the formatted forms of the table's old and
+// new values, read from their exact JSON.
+func setFormattedValues(results []DiffResult, f *displayFormat) {
+	if f == nil {
+		return
+	}
+	format := func(raw string) string {
+		if raw == "" {
+			return ""
+		}
+		dec := json.NewDecoder(strings.NewReader(raw))
+		dec.UseNumber()
+		var v interface{}
+		if dec.Decode(&v) != nil {
+			return ""
+		}
+		if s, ok := f.value(v); ok {
+			return s
+		}
+		return ""
+	}
+	for i := range results {
+		r := &results[i]
+		r.FromFormatted, r.ToFormatted = format(r.FromJSON), format(r.ToJSON)
+	}
+}
@@ -0,0 +1,102 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDisplayFormat(t *testing.T) {
+	if f, err := parseDisplayFormat("", datesISO, historyTime); f != nil || err != nil {
+		t.Errorf("defaults: %+v, %v", f, err)
+	}
+	if _, err := parseDisplayFormat("", "unix", historyTime); err == nil || !strings.Contains(err.Error(), "invalid --display-dates") {
+		t.Errorf("bad dates: %v", err)
+	}
+	if _, err := parseDisplayFormat("not a locale!", datesISO, historyTime); err == nil || !strings.Contains(err.Error(), "invalid --display-number-locale") {
+		t.Errorf("bad locale: %v", err)
+	}
+}
+
+func TestDisplayNumbers(t *testing.T) {
+	tests := []struct {
+		locale string
+		v      interface{}
+		want   string
+	}{
+		{"en", 1234567.5, "1,234,567.5"},
+		{"de", 1234567.5, "1.234.567,5"},
+		{"de", json.Number("-1234.25"), "-1.234,25"},
+		{"fr", 12.0, ""},
+		// Left as written: digits float64 lost, and values too large to
+		// group exactly.
+		{"en", json.Number("12345.000000000000000001"), ""},
+		{"en", 1e15, ""},
+		{"en", json.Number("1e400"), ""},
+		{"en", "1234", ""},
+	}
+	for _, tt := range tests {
+		f, err := parseDisplayFormat(tt.locale, datesISO, historyTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := f.value(tt.v)
+		if ok != (tt.want != "") || ok && got != tt.want {
+			t.Errorf("%s %#v: %q, %t, want %q", tt.locale, tt.v, got, ok, tt.want)
+		}
+	}
+	var none *displayFormat
+	if _, ok := none.value(1234.0); ok {
+		t.Error("nil format changed a value")
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	tests := map[time.Duration]string{
+		0:                    "now",
+		-time.Second:         "1 second ago",
+		-90 * time.Minute:    "1 hour ago",
+		-49 * time.Hour:      "2 days ago",
+		3 * 24 * time.Hour:   "in 3 days",
+		400 * 24 * time.Hour: "in 1 year",
+		-45 * 24 * time.Hour: "1 month ago",
+	}
+	for d, want := range tests {
+		if got := relativeTime(historyTime.Add(d), historyTime); got != want {
+			t.Errorf("%v: %q, want %q", d, got, want)
+		}
+	}
+	f, _ := parseDisplayFormat("", datesRelative, historyTime)
+	if got, ok := f.value("2024-03-01T10:30:00Z"); got != "2 hours ago" || !ok {
+		t.Errorf("timestamp: %q, %t", got, ok)
+	}
+	if _, ok := f.value("2024-03-01"); ok {
+		t.Error("a date without a time was formatted")
+	}
+}
+
+// TestDisplayFormatCLI checks that the HTML report shows formatted values
+// with their raw JSON in a tooltip while the JSON report keeps them raw.
+func TestDisplayFormatCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"total": 1234.5}`,
+		"b.json": `{"total": 98765.25}`,
+	})
+	if res := runCLI(t, dir, "--display-number-locale", "de", "-o", "out.html", "a.json", "b.json"); res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	html := readFile(t, dir, "out.html")
+	for _, want := range []string{`<td title="1234.5">1.234,5`, `<td title="98765.25">98.765,25`, `data-copy="98765.25"`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("no %q", want)
+		}
+	}
+	res := runCLI(t, dir, "--display-number-locale", "de", "-f", "json", "-o", "-", "a.json", "b.json")
+	if res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	if strings.Contains(res.stdout, "1.234,5") {
+		t.Error("the JSON report holds a formatted value")
+	}
+}
@@ -0,0 +1,180 @@
+package jsondiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+)
+
+// DriftSide describes one of the two JSON reports compared by diff-of-diffs.
+type DriftSide struct {
+	// File is the report's file name; Original and Modified name the
+	// inputs it compared.
+	File     string        `json:"file"`
+	Original string        `json:"original"`
+	Modified string        `json:"modified"`
+	Inputs   [2]InputStats `json:"inputs"`
+	Total    int           `json:"total"`
+}
+
+// DriftReport groups the changes of two JSON reports, typically of the same
+// comparison on different days, by how they evolved between them.
+type DriftReport struct {
+	Version int       `json:"version"`
+	Before  DriftSide `json:"before"`
+	After   DriftSide `json:"after"`
+	// New changes appear only in the later report, Resolved ones only in
+	// the earlier, and Persisting ones, as in the later report, in both.
+	New        []DiffResult `json:"new"`
+	Resolved   []DiffResult `json:"resolved"`
+	Persisting []DiffResult `json:"persisting"`
+}
+
+// DriftGroup is one group of changes in the HTML diff of diffs.
+type DriftGroup struct {
+	Title, Class string
+	Changes      []DiffResult
+}
+
+// Groups lists the new, resolved and persisting changes in that order.
+func (d DriftReport) Groups() []DriftGroup {
+	return []DriftGroup{
+		{"New", "new", d.New},
+		{"Resolved", "resolved", d.Resolved},
+		{"Persisting", "persisting", d.Persisting},
+	}
+}
+
+// loadJSONReport reads a report written by --format json, refusing
+// documents of another version.
+func loadJSONReport(filename string) (*jsonReport, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var r jsonReport
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&r); err != nil {
+		return nil, fmt.Errorf("not a differ JSON report: %v", describeJSONError(data, err))
+	}
+	if r.Version != jsonReportVersion {
+		return nil, fmt.Errorf("unsupported report version %d, expected %d; regenerate it with --format json", r.Version, jsonReportVersion)
+	}
+	return &r, nil
+}
+
+// changes lists the changes of every section of r in order.
+func (r *jsonReport) changes() []DiffResult {
+	var out []DiffResult
+	for _, sec := range r.Sections {
+		out = append(out, sec.Changes...)
+	}
+	return out
+}
+
+// driftBetween matches the changes of before and after by their stable IDs,
+// which hash a change's path, type and both values, so a change persists
+// only if it is exactly the same in both reports.
+func driftBetween(before, after *jsonReport, beforeFile, afterFile string) DriftReport {
+	side := func(r *jsonReport, file string) DriftSide {
+		return DriftSide{File: file, Original: r.Original, Modified: r.Modified, Inputs: r.Inputs, Total: r.Total}
+	}
+	d := DriftReport{
+		Version:    jsonReportVersion,
+		Before:     side(before, beforeFile),
+		After:      side(after, afterFile),
+		New:        []DiffResult{},
+		Resolved:   []DiffResult{},
+		Persisting: []DiffResult{},
+	}
+	inBefore, inAfter := make(map[string]bool), make(map[string]bool)
+	for _, c := range before.changes() {
+		inBefore[c.ID] = true
+	}
+	for _, c := range after.changes() {
+		inAfter[c.ID] = true
+		if inBefore[c.ID] {
+			d.Persisting = append(d.Persisting, c)
+		} else {
+			d.New = append(d.New, c)
+		}
+	}
+	for _, c := range before.changes() {
+		if !inAfter[c.ID] {
+			d.Resolved = append(d.Resolved, c)
+		}
+	}
+	return d
+}
+
+// runDiffOfDiffs implements the "diff-of-diffs" subcommand: it compares two
+// JSON reports and lists the changes that are new, resolved and persisting.
+func runDiffOfDiffs(args []string) int {
+	fs := flag.NewFlagSet("diff-of-diffs", flag.ContinueOnError)
+	var outputFile, format string
+	fs.StringVar(&outputFile, "o", "", "Output file, or - for stdout (default drift.<format>)")
+	fs.StringVar(&format, "format", "html", "Output format: html or json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: jsondiff diff-of-diffs [-o output] [--format html|json] before.json after.json")
+		fmt.Fprintln(fs.Output(), "Both reports are written by --format json.")
+		fs.PrintDefaults()
+	}
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return flagExit(err)
+	}
+	if len(files) != 2 {
+		fs.Usage()
+		return exitError
+	}
+	if format != "html" && format != "json" {
+		return fatalf("Invalid --format %q: must be html or json", format)
+	}
+	if outputFile == "" {
+		outputFile = "drift." + format
+	}
+
+	var reports [2]*jsonReport
+	for i, filename := range files {
+		r, err := loadJSONReport(filename)
+		if err != nil {
+			return fatalf("Failed to read %s: %v", filename, err)
+		}
+		reports[i] = r
+	}
+	drift := driftBetween(reports[0], reports[1], files[0], files[1])
+
+	w := io.Writer(os.Stdout)
+	var f *os.File
+	if outputFile != "-" {
+		if f, err = os.Create(outputFile); err != nil {
+			return fatalf("Failed to create output file: %v", err)
+		}
+		w = f
+	}
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(drift)
+	} else {
+		var tpl *template.Template
+		if tpl, err = parseReportTemplate(nil, nil, renderContext{}); err == nil {
+			err = tpl.ExecuteTemplate(w, "diff-of-diffs", drift)
+		}
+	}
+	if err == nil && f != nil {
+		err = f.Close()
+	}
+	if err != nil {
+		return fatalf("Failed to write diff of diffs: %v", err)
+	}
+	if f != nil {
+		fmt.Printf("Diff of diffs written to %s\n", outputFile)
+	}
+	return exitOK
+}
@@ -0,0 +1,65 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestDiffOfDiffsCLI compares the reports of two days' comparisons and
+// checks how each change is grouped: a change persists only if its path,
+// type and values are all the same.
+func TestDiffOfDiffsCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"base.json": `{"a": 1, "b": 1, "c": 1, "d": 1}`,
+		"mon.json":  `{"a": 2, "b": 2, "c": 1}`,
+		"tue.json":  `{"a": 2, "b": 3, "c": 5, "d": 1}`,
+		"bad.json":  `{"version": 999, "sections": []}`,
+	})
+	for _, day := range []string{"mon", "tue"} {
+		if res := runCLI(t, dir, "-f", "json", "-o", day+"-report.json", "base.json", day+".json"); res.exit != exitOK {
+			t.Fatalf("%s: exit %d: %s", day, res.exit, res.stderr)
+		}
+	}
+	res := runCLI(t, dir, "diff-of-diffs", "--format", "json", "-o", "-", "mon-report.json", "tue-report.json")
+	if res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	var drift DriftReport
+	if err := json.Unmarshal([]byte(res.stdout), &drift); err != nil {
+		t.Fatal(err)
+	}
+	paths := func(changes []DiffResult) []string {
+		out := []string{}
+		for _, c := range changes {
+			out = append(out, c.Path)
+		}
+		return out
+	}
+	got := [3][]string{paths(drift.New), paths(drift.Resolved), paths(drift.Persisting)}
+	// b changed on both days, but to different values.
+	want := [3][]string{{"b", "c"}, {"b", "d"}, {"a"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("new, resolved, persisting %q, want %q", got, want)
+	}
+	if drift.Before.File != "mon-report.json" || drift.Before.Total != 3 || drift.After.Total != 3 || drift.After.Modified != "tue.json" {
+		t.Errorf("sides %+v, %+v", drift.Before, drift.After)
+	}
+
+	if res := runCLI(t, dir, "diff-of-diffs", "-o", "drift.html", "mon-report.json", "tue-report.json"); res.exit != exitOK {
+		t.Fatalf("html: exit %d: %s", res.exit, res.stderr)
+	}
+	// Like the timeline, the page starts after its define's newline.
+	wellFormed["html"](t, strings.TrimSpace(readFile(t, dir, "drift.html")))
+
+	for file, msg := range map[string]string{
+		"bad.json":  "unsupported report version 999",
+		"base.json": "not a differ JSON report",
+	} {
+		res := runCLI(t, dir, "diff-of-diffs", "-o", "-", file, "tue-report.json")
+		if res.exit != exitError || !strings.Contains(res.stderr, msg) {
+			t.Errorf("%s: exit %d: %s", file, res.exit, res.stderr)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package jsondiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// textEncoding is a Unicode encoding an input may arrive in. Everything but
+// plain UTF-8 is transcoded before parsing.
+type textEncoding struct {
+	name  string
+	width int // bytes per code unit
+	order binary.ByteOrder
+}
+
+var (
+	encodingUTF8    = textEncoding{"UTF-8", 1, nil}
+	encodingUTF16LE = textEncoding{"UTF-16LE", 2, binary.LittleEndian}
+	encodingUTF16BE = textEncoding{"UTF-16BE", 2, binary.BigEndian}
+	encodingUTF32LE = textEncoding{"UTF-32LE", 4, binary.LittleEndian}
+	encodingUTF32BE = textEncoding{"UTF-32BE", 4, binary.BigEndian}
+)
+
+// byteOrderMarks lists the BOMs recognised at the start of an input. The
+// UTF-32LE mark begins with the UTF-16LE one, so it must come first.
+var byteOrderMarks = []struct {
+	bom []byte
+	enc textEncoding
+}{
+	{[]byte("\xef\xbb\xbf"), encodingUTF8},
+	{[]byte("\xff\xfe\x00\x00"), encodingUTF32LE},
+	{[]byte("\x00\x00\xfe\xff"), encodingUTF32BE},
+	{[]byte("\xff\xfe"), encodingUTF16LE},
+	{[]byte("\xfe\xff"), encodingUTF16BE},
+}
+
+// detectEncoding returns the encoding of an input starting with head and the
+// length of its byte order mark. Without a mark, UTF-16 and UTF-32 are
+// recognised by where their NUL bytes fall: JSON is mostly ASCII, whose
+// characters leave all but one byte of each code unit zero. guessed reports
+// such a detection, which may be wrong.
+func detectEncoding(head []byte) (enc textEncoding, bomLen int, guessed bool) {
+	for _, m := range byteOrderMarks {
+		if bytes.HasPrefix(head, m.bom) {
+			return m.enc, len(m.bom), false
+		}
+	}
+	var zeros [4]int
+	units := len(head) / 4 * 4
+	for i, c := range head[:units] {
+		if c == 0 {
+			zeros[i%4]++
+		}
+	}
+	// Each of the four byte positions is seen units/4 times; a position is
+	// mostly zero above half of those, and rarely zero below a tenth, which
+	// leaves room for characters beyond ASCII.
+	mostly := func(n int) bool { return n*2 > units/4 }
+	rarely := func(n int) bool { return n*10 < units/4 }
+	switch {
+	case units == 0:
+	case mostly(zeros[1]) && mostly(zeros[2]) && mostly(zeros[3]) && rarely(zeros[0]):
+		return encodingUTF32LE, 0, true
+	case mostly(zeros[0]) && mostly(zeros[1]) && mostly(zeros[2]) && rarely(zeros[3]):
+		return encodingUTF32BE, 0, true
+	case mostly(zeros[1]) && mostly(zeros[3]) && rarely(zeros[0]+zeros[2]):
+		return encodingUTF16LE, 0, true
+	case mostly(zeros[0]) && mostly(zeros[2]) && rarely(zeros[1]+zeros[3]):
+		return encodingUTF16BE, 0, true
+	}
+	return encodingUTF8, 0, false
+}
+
+// transcodeInput detects the encoding of filename and, unless it is plain
+// UTF-8, writes the document as UTF-8 without a byte order mark to a
+// temporary file, returning its name for the caller to remove. It returns
+// filename itself for plain UTF-8, once checked to be valid, and for files
+// that are not regular, such as pipes, which cannot be read twice. encoding
+// names what was detected, e.g. "UTF-16LE with BOM". A guessed encoding is
+// reported to warn.
+//
+// Files over maxBytes (0 for no limit) are refused before any is read, and
+// known binary formats before their bytes are judged as text. Only inputs
+// to transcode are held in memory; plain UTF-8 is validated as it streams.
+func transcodeInput(filename string, maxBytes int64, warn func(string)) (transcoded, encoding string, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return filename, encodingUTF8.name, nil
+	}
+	if maxBytes > 0 && info.Size() > maxBytes {
+		return "", "", fmt.Errorf("%w: file is %s, limit is %s", errInputTooLarge, formatByteSize(info.Size()), formatByteSize(maxBytes))
+	}
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", "", err
+	}
+	head = head[:n]
+	if err := sniffMagic(head); err != nil {
+		return "", "", err
+	}
+	enc, bomLen, guessed := detectEncoding(head)
+	encoding = enc.name
+	if bomLen > 0 {
+		encoding += " with BOM"
+	}
+	if enc == encodingUTF8 && bomLen == 0 {
+		// Binary content is better described by sniffBinary than by the
+		// first invalid byte, so leave that to readJSON.
+		if sniffBinary(head) != nil {
+			return filename, encoding, nil
+		}
+		if err := validateUTF8(io.MultiReader(bytes.NewReader(head), f)); err != nil {
+			return "", "", err
+		}
+		return filename, encoding, nil
+	}
+	if guessed && warn != nil {
+		warn(fmt.Sprintf("no byte order mark, but its NUL bytes suggest %s; transcoded from that", enc.name))
+	}
+
+	data, err := io.ReadAll(io.MultiReader(bytes.NewReader(head), f))
+	if err != nil {
+		return "", "", err
+	}
+	text, err := toUTF8(data[bomLen:], enc)
+	if err != nil {
+		var bad *encodingError
+		if errors.As(err, &bad) {
+			bad.offset += int64(bomLen)
+		}
+		return "", "", err
+	}
+	tmp, err := os.CreateTemp("", "differ-input-*.json")
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := tmp.Write(text); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", "", err
+	}
+	return tmp.Name(), encoding, nil
+}
+
+// validateUTF8 reads r to the end and returns an *encodingError at the
+// first byte that is not valid UTF-8, holding no more than a buffer of it.
+func validateUTF8(r io.Reader) error {
+	buf := make([]byte, 64<<10)
+	var offset int64
+	carry := 0
+	for {
+		n, err := r.Read(buf[carry:])
+		if err != nil && err != io.EOF {
+			return err
+		}
+		data := buf[:carry+n]
+		i := 0
+		for i < len(data) {
+			if data[i] < utf8.RuneSelf {
+				i++
+				continue
+			}
+			// A rune split across reads is finished on the next one.
+			if err == nil && !utf8.FullRune(data[i:]) {
+				break
+			}
+			r, size := utf8.DecodeRune(data[i:])
+			if r == utf8.RuneError && size == 1 {
+				return &encodingError{encoding: encodingUTF8.name, offset: offset + int64(i), reason: fmt.Sprintf("byte 0x%02x", data[i])}
+			}
+			i += size
+		}
+		offset += int64(i)
+		carry = copy(buf, data[i:])
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// encodingError locates the first byte sequence that is invalid in the
+// input's encoding.
+type encodingError struct {
+	encoding string
+	offset   int64
+	reason   string
+}
+
+func (e *encodingError) Error() string {
+	return fmt.Sprintf("invalid %s at byte offset %d: %s", e.encoding, e.offset, e.reason)
+}
+
+// toUTF8 converts data, without its byte order mark, from enc to UTF-8.
+func toUTF8(data []byte, enc textEncoding) ([]byte, error) {
+	fail := func(offset int, reason string) error {
+		return &encodingError{encoding: enc.name, offset: int64(offset), reason: reason}
+	}
+	if enc.width == 1 {
+		for i := 0; i < len(data); {
+			r, size := utf8.DecodeRune(data[i:])
+			if r == utf8.RuneError && size == 1 {
+				return nil, fail(i, fmt.Sprintf("byte 0x%02x", data[i]))
+			}
+			i += size
+		}
+		return data, nil
+	}
+	if len(data)%enc.width != 0 {
+		return nil, fail(len(data)/enc.width*enc.width, "truncated code unit")
+	}
+	out := make([]byte, 0, len(data)/enc.width*2)
+	for i := 0; i < len(data); i += enc.width {
+		var r rune
+		if enc.width == 4 {
+			r = rune(enc.order.Uint32(data[i:]))
+			if !utf8.ValidRune(r) {
+				return nil, fail(i, fmt.Sprintf("code point U+%X", uint32(r)))
+			}
+		} else {
+			r = rune(enc.order.Uint16(data[i:]))
+			switch {
+			case utf16.IsSurrogate(r) && r < 0xdc00 && i+4 <= len(data):
+				r = utf16.DecodeRune(r, rune(enc.order.Uint16(data[i+2:])))
+				if r == utf8.RuneError {
+					return nil, fail(i, "unpaired surrogate")
+				}
+				i += 2
+			case utf16.IsSurrogate(r):
+				return nil, fail(i, "unpaired surrogate")
+			}
+		}
+		out = utf8.AppendRune(out, r)
+	}
+	return out, nil
+}
@@ -0,0 +1,174 @@
+package jsondiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+const encodingDoc = `{"name": "café", "note": "𝄞"}`
+
+// encodeText returns s in enc, without a byte order mark.
+func encodeText(s string, enc textEncoding) []byte {
+	var buf bytes.Buffer
+	switch enc.width {
+	case 1:
+		buf.WriteString(s)
+	case 2:
+		for _, u := range utf16.Encode([]rune(s)) {
+			binary.Write(&buf, enc.order, u)
+		}
+	case 4:
+		for _, r := range s {
+			binary.Write(&buf, enc.order, uint32(r))
+		}
+	}
+	return buf.Bytes()
+}
+
+// writeInput writes data to a file in a temporary directory.
+func writeInput(t *testing.T, data []byte) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "in.json")
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestTranscodeInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		encoding string
+		guessed  bool
+	}{
+		{"plain UTF-8", encodeText(encodingDoc, encodingUTF8), "UTF-8", false},
+		{"UTF-8 BOM", append([]byte("\xef\xbb\xbf"), encodingDoc...), "UTF-8 with BOM", false},
+		{"UTF-16LE BOM", append([]byte("\xff\xfe"), encodeText(encodingDoc, encodingUTF16LE)...), "UTF-16LE with BOM", false},
+		{"UTF-16BE BOM", append([]byte("\xfe\xff"), encodeText(encodingDoc, encodingUTF16BE)...), "UTF-16BE with BOM", false},
+		{"UTF-32LE BOM", append([]byte("\xff\xfe\x00\x00"), encodeText(encodingDoc, encodingUTF32LE)...), "UTF-32LE with BOM", false},
+		{"UTF-32BE BOM", append([]byte("\x00\x00\xfe\xff"), encodeText(encodingDoc, encodingUTF32BE)...), "UTF-32BE with BOM", false},
+		{"UTF-16LE unmarked", encodeText(encodingDoc, encodingUTF16LE), "UTF-16LE", true},
+		{"UTF-16BE unmarked", encodeText(encodingDoc, encodingUTF16BE), "UTF-16BE", true},
+		{"UTF-32LE unmarked", encodeText(encodingDoc, encodingUTF32LE), "UTF-32LE", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeInput(t, tt.data)
+			var warnings []string
+			got, encoding, err := transcodeInput(file, 0, func(msg string) { warnings = append(warnings, msg) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != file {
+				defer os.Remove(got)
+			}
+			if encoding != tt.encoding {
+				t.Errorf("encoding %q, want %q", encoding, tt.encoding)
+			}
+			if (len(warnings) > 0) != tt.guessed {
+				t.Errorf("warnings %q, guessed %t", warnings, tt.guessed)
+			}
+			if (got == file) != (tt.encoding == "UTF-8") {
+				t.Errorf("transcoded to %s", got)
+			}
+			v, err := readJSONFile(got, defaultInputLimits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := mustDecode(t, encodingDoc); !reflect.DeepEqual(v, want) {
+				t.Errorf("decoded %v, want %v", v, want)
+			}
+		})
+	}
+}
+
+func TestTranscodeInputInvalid(t *testing.T) {
+	// A long valid prefix puts the bad byte past the first read and puts
+	// a multibyte rune across the boundary between two reads.
+	prefix := strings.Repeat("a", 64<<10-1) + "é"
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"UTF-8", []byte(`{"a": "` + "\xff" + `"}`), "invalid UTF-8 at byte offset 7: byte 0xff"},
+		{"UTF-8 truncated rune", []byte(`{"a": "` + "\xc3"), "invalid UTF-8 at byte offset 7: byte 0xc3"},
+		{"UTF-8 after a read", []byte(`"` + prefix + "\xfe" + `"`), "invalid UTF-8 at byte offset 65538: byte 0xfe"},
+		{"UTF-8 with BOM", []byte("\xef\xbb\xbf\"\xc0\""), "invalid UTF-8 at byte offset 4: byte 0xc0"},
+		{"UTF-16LE unpaired surrogate", append([]byte("\xff\xfe\"\x00"), 0x00, 0xdc, '"', 0), "invalid UTF-16LE at byte offset 4: unpaired surrogate"},
+		{"UTF-16BE truncated", []byte("\xfe\xff\x00\"\x00"), "invalid UTF-16BE at byte offset 4: truncated code unit"},
+		{"UTF-32LE out of range", append([]byte("\xff\xfe\x00\x00"), 0x00, 0x00, 0x11, 0x00), "invalid UTF-32LE at byte offset 4: code point U+110000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := transcodeInput(writeInput(t, tt.data), 0, nil)
+			var bad *encodingError
+			if !errors.As(err, &bad) || err.Error() != tt.want {
+				t.Errorf("error %v, want %s", err, tt.want)
+			}
+		})
+	}
+
+	valid := writeInput(t, []byte(`"`+prefix+`"`))
+	if got, _, err := transcodeInput(valid, 0, nil); err != nil || got != valid {
+		t.Errorf("rune split across reads: %s, %v", got, err)
+	}
+}
+
+// TestReadInputBinaryFirst checks that binary files are named as such
+// rather than reported as invalid text in some encoding.
+func TestReadInputBinaryFirst(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"PNG", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "PNG image?"},
+		{"gzip", []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff"), "gzip archive?"},
+		{"zip", []byte("PK\x03\x04\x14\x00\x00\x00\x08\x00"), "zip archive?"},
+		{"control bytes", bytes.Repeat([]byte("\x01\x02\x80\x03"), 64), "non-text bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readInput(inputSource{path: writeInput(t, tt.data)}, execOptions{}, defaultInputLimits, decodeOptions{})
+			if err == nil || !strings.Contains(err.Error(), "appears to be binary") || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %v, want a binary file naming %s", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestTranscodeInputChecksSizeFirst checks that an input over
+// --max-input-size is refused without being read into memory.
+func TestTranscodeInputChecksSizeFirst(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "big.json")
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A sparse file: large on paper, cheap on disk.
+	if err := f.Truncate(300 << 20); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err = readInput(inputSource{path: file}, execOptions{}, inputLimits{maxBytes: 1 << 10}, decodeOptions{})
+	runtime.ReadMemStats(&after)
+	if !errors.Is(err, errInputTooLarge) {
+		t.Fatalf("error %v, want errInputTooLarge", err)
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 1<<20 {
+		t.Errorf("allocated %s refusing the input", formatByteSize(int64(alloc)))
+	}
+}
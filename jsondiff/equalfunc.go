@@ -0,0 +1,110 @@
+package jsondiff
+
+import (
+	"strconv"
+
+	"github.com/r3labs/diff/v3"
+)
+
+// EqualResult is the verdict of an EqualFunc.
+type EqualResult int
+
+const (
+	// Fallthrough leaves the values to the generic comparison.
+	Fallthrough EqualResult = iota
+	// Equal reports no change, whatever the generic comparison would find.
+	Equal
+	// NotEqual reports one change of the whole value.
+	NotEqual
+)
+
+// EqualFunc decides whether the values found at the same path of both
+// documents are equal, for domain-specific equality such as certificates
+// that differ only in encoding. a and b are decoded JSON values.
+type EqualFunc func(a, b interface{}) EqualResult
+
+// CompareOption configures Compare and CompareSeq.
+type CompareOption func(*compareOptions)
+
+type compareOptions struct {
+	hooks []equalHook
+}
+
+type equalHook struct {
+	pattern []string
+	fn      EqualFunc
+}
+
+// WithEqualFunc consults fn before the generic comparison for values at
+// paths matching pattern, when both documents have one. As in --map-as-set,
+// "*" matches any key or index and "[*]" any index. The first matching hook
+// not returning Fallthrough decides; below a value it falls through on,
+// hooks are consulted again.
+func WithEqualFunc(pattern string, fn EqualFunc) CompareOption {
+	return func(o *compareOptions) {
+		o.hooks = append(o.hooks, equalHook{splitPath(pattern), fn})
+	}
+}
+
+// applyEqualHooks runs the hooks over a and b. It returns copies in which
+// every value a hook decided is replaced by null on both sides, so the
+// generic comparison skips it, and the changes for the values a hook found
+// not equal. a and b are not modified.
+func applyEqualHooks(a, b interface{}, hooks []equalHook) (interface{}, interface{}, diff.Changelog) {
+	if len(hooks) == 0 {
+		return a, b, nil
+	}
+	var decided diff.Changelog
+	var walk func(a, b interface{}, raw, typed []string) (interface{}, interface{})
+	walk = func(a, b interface{}, raw, typed []string) (interface{}, interface{}) {
+		if len(typed) > 0 {
+			for _, h := range hooks {
+				if !matchPath(h.pattern, typed) {
+					continue
+				}
+				switch h.fn(a, b) {
+				case Equal:
+					return nil, nil
+				case NotEqual:
+					decided = append(decided, diff.Change{Type: diff.UPDATE, Path: raw, From: a, To: b})
+					return nil, nil
+				}
+			}
+		}
+		switch va := a.(type) {
+		case map[string]interface{}:
+			vb, ok := b.(map[string]interface{})
+			if !ok {
+				return a, b
+			}
+			outA := make(map[string]interface{}, len(va))
+			outB := make(map[string]interface{}, len(vb))
+			for k, v := range va {
+				outA[k] = v
+			}
+			for k, v := range vb {
+				outB[k] = v
+			}
+			for k := range va {
+				if _, ok := vb[k]; ok {
+					outA[k], outB[k] = walk(va[k], vb[k], append(raw[:len(raw):len(raw)], k), append(typed[:len(typed):len(typed)], k))
+				}
+			}
+			return outA, outB
+		case []interface{}:
+			vb, ok := b.([]interface{})
+			if !ok {
+				return a, b
+			}
+			outA := append([]interface{}(nil), va...)
+			outB := append([]interface{}(nil), vb...)
+			for i := 0; i < len(va) && i < len(vb); i++ {
+				outA[i], outB[i] = walk(va[i], vb[i], append(raw[:len(raw):len(raw)], strconv.Itoa(i)), append(typed[:len(typed):len(typed)], indexSegment(i)))
+			}
+			return outA, outB
+		}
+		return a, b
+	}
+	a, b = walk(a, b, []string{}, []string{})
+	return a, b, decided
+}
@@ -0,0 +1,117 @@
+package jsondiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sameWords treats strings differing only in whitespace as equal.
+func sameWords(a, b interface{}) EqualResult {
+	sa, okA := a.(string)
+	sb, okB := b.(string)
+	if !okA || !okB {
+		return Fallthrough
+	}
+	if strings.Join(strings.Fields(sa), " ") == strings.Join(strings.Fields(sb), " ") {
+		return Equal
+	}
+	return NotEqual
+}
+
+func alwaysFallthrough(a, b interface{}) EqualResult { return Fallthrough }
+
+// changedPaths compares a and b with opts and returns the changed paths.
+func changedPaths(t *testing.T, a, b string, opts ...CompareOption) []string {
+	t.Helper()
+	cs, err := Compare(NodeFromInterface(mustDecode(t, a)), NodeFromInterface(mustDecode(t, b)), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{}
+	for _, c := range cs.Changes {
+		paths = append(paths, string(c.Type)+" "+joinPath(c.Path))
+	}
+	return paths
+}
+
+func TestWithEqualFunc(t *testing.T) {
+	const (
+		a = `{"spec": {"certificate": "-----BEGIN\n  abc\n-----END", "name": "x"}, "notes": ["one  two", "three"]}`
+		b = `{"spec": {"certificate": "-----BEGIN abc -----END", "name": "y"}, "notes": ["one two", "four"]}`
+	)
+	tests := []struct {
+		name string
+		opts []CompareOption
+		want []string
+	}{
+		{
+			name: "no hook",
+			want: []string{"changed notes[0]", "changed notes[1]", "changed spec.certificate", "changed spec.name"},
+		},
+		{
+			name: "whitespace-insensitive certificate",
+			opts: []CompareOption{WithEqualFunc("spec.certificate", sameWords)},
+			want: []string{"changed notes[0]", "changed notes[1]", "changed spec.name"},
+		},
+		{
+			name: "array wildcard",
+			opts: []CompareOption{WithEqualFunc("notes[*]", sameWords)},
+			want: []string{"changed notes[1]", "changed spec.certificate", "changed spec.name"},
+		},
+		{
+			name: "always falls through",
+			opts: []CompareOption{WithEqualFunc("*", alwaysFallthrough), WithEqualFunc("spec.*", alwaysFallthrough)},
+			want: []string{"changed notes[0]", "changed notes[1]", "changed spec.certificate", "changed spec.name"},
+		},
+		{
+			name: "falls through to a later hook",
+			opts: []CompareOption{WithEqualFunc("spec.*", alwaysFallthrough), WithEqualFunc("spec.certificate", sameWords)},
+			want: []string{"changed notes[0]", "changed notes[1]", "changed spec.name"},
+		},
+		{
+			name: "whole subtree decided not equal",
+			opts: []CompareOption{WithEqualFunc("spec", func(a, b interface{}) EqualResult { return NotEqual })},
+			want: []string{"changed notes[0]", "changed notes[1]", "changed spec"},
+		},
+		{
+			name: "whole subtree decided equal",
+			opts: []CompareOption{WithEqualFunc("spec", func(a, b interface{}) EqualResult { return Equal })},
+			want: []string{"changed notes[0]", "changed notes[1]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := changedPaths(t, a, b, tt.opts...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithEqualFuncOneSided(t *testing.T) {
+	called := false
+	hook := func(a, b interface{}) EqualResult {
+		called = true
+		return Equal
+	}
+	got := changedPaths(t, `{"a": 1}`, `{"b": 1}`, WithEqualFunc("*", hook))
+	if called {
+		t.Error("hook consulted for a member only one document has")
+	}
+	if want := []string{"removed a", "added b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWithEqualFuncLeavesInputs(t *testing.T) {
+	a := mustDecode(t, `{"s": "x  y", "n": [1]}`)
+	b := mustDecode(t, `{"s": "x y", "n": [2]}`)
+	before := mustDecode(t, `{"s": "x  y", "n": [1]}`)
+	if _, err := Compare(NodeFromInterface(a), NodeFromInterface(b), WithEqualFunc("s", sameWords)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, before) {
+		t.Errorf("input modified: %v", a)
+	}
+}
@@ -0,0 +1,45 @@
+package jsondiff
+
+// EffectiveOption is a diff option changed from its default, listed in the
+// report so readers know what the comparison was allowed to overlook.
+type EffectiveOption struct {
+	Name  string
+	Value string
+	// Masking is set for options that can hide differences, such as
+	// ignores, tolerances, projections and key remapping.
+	Masking bool
+}
+
+// Flag returns the option as typed on the command line, e.g. "-o" or
+// "--trim-space".
+func (o EffectiveOption) Flag() string {
+	if len(o.Name) == 1 {
+		return "-" + o.Name
+	}
+	return "--" + o.Name
+}
+
+// maskingFlags are the flags that can make differing documents compare
+// equal.
+var maskingFlags = map[string]bool{
+	"subset": true, "superset": true, "ignore-value-regex": true, "ignore-values": true,
+	"units": true, "array-granularity": true, "multiset": true, "map-as-set": true,
+	"empty-equals-absent": true, "deep": true, "normalize-unicode": true, "normalize-keys": true,
+	"ignore-case": true, "ignore-key-case": true, "trim-space": true, "collapse-space": true,
+	"include": true, "path-a": true, "path-b": true, "project": true, "project-a": true, "project-b": true,
+	"auto-redact": true, "config": true, "ack": true, "ack-file": true, "key-map": true,
+	"json-schema-aware": true,
+}
+
+// Equivalent reports whether the comparison found no changes at all, in
+// which case the HTML report leads with the verification details and keeps
+// the trees behind a toggle.
+func (c *ReportContext) Equivalent() bool {
+	return c.Total == 0 && len(c.Acknowledged) == 0 && len(c.Metadata) == 0 && c.Page == nil
+}
+
+// SameHash reports whether both inputs hash alike, i.e. are equal apart
+// from formatting and key order before any comparison option applied.
+func (c *ReportContext) SameHash() bool {
+	return c.Inputs[0].SHA256 != "" && c.Inputs[0].SHA256 == c.Inputs[1].SHA256
+}
@@ -0,0 +1,81 @@
+package jsondiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEffectiveOptions(t *testing.T) {
+	o := DefaultOptions()
+	o.Output, o.TrimSpace, o.Quiet = "out.html", true, true
+	o.MapAsSet = stringList{"tags", "labels"}
+	var got []string
+	for _, opt := range effectiveOptions(o) {
+		got = append(got, opt.Flag()+"="+opt.Value+map[bool]string{true: " masking"}[opt.Masking])
+	}
+	// Masking options come first, each group by name.
+	want := []string{"--map-as-set=tags,labels masking", "--trim-space=true masking", "-o=out.html", "--quiet=true"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("options %q, want %q", got, want)
+	}
+	if effectiveOptions(DefaultOptions()) != nil {
+		t.Error("defaults listed as effective")
+	}
+}
+
+// TestMaskingFlagsExist keeps maskingFlags in step with the options.
+func TestMaskingFlagsExist(t *testing.T) {
+	names := make(map[string]bool)
+	typ := reflect.TypeOf(Options{})
+	for i := 0; i < typ.NumField(); i++ {
+		names[typ.Field(i).Tag.Get("json")] = true
+	}
+	for name := range maskingFlags {
+		if !names[name] {
+			t.Errorf("masking flag %q is not an option", name)
+		}
+	}
+}
+
+func TestEquivalentCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json":      `{"name": "svc", "port": 80}`,
+		"same.json":   "{\n  \"port\": 80,\n  \"name\": \"svc\"\n}",
+		"spaces.json": `{"name": " svc ", "port": 80}`,
+		"other.json":  `{"name": "svc", "port": 81}`,
+	})
+	tests := []struct {
+		name  string
+		args  []string
+		want  []string
+		equal bool
+	}{
+		{"reformatted", []string{"same.json"}, []string{
+			"The documents are equivalent",
+			"identical apart from formatting and key order",
+			// -o is listed, but cannot hide differences.
+			`<tr><td>-o</td><td><code>out.html</code></td><td></td></tr>`,
+		}, true},
+		{"masked", []string{"--trim-space", "spaces.json"}, []string{
+			"they compare equal under the options below",
+			`<tr class="masking"><td>--trim-space</td><td><code>true</code></td><td>yes</td></tr>`,
+		}, true},
+		{"changed", []string{"other.json"}, nil, false},
+	}
+	for _, tt := range tests {
+		args := append(append([]string{"-o", "out.html"}, tt.args[:len(tt.args)-1]...), "a.json", tt.args[len(tt.args)-1])
+		if res := runCLI(t, dir, args...); res.exit != exitOK {
+			t.Fatalf("%s: exit %d: %s", tt.name, res.exit, res.stderr)
+		}
+		html := readFile(t, dir, "out.html")
+		if got := strings.Contains(html, `<section class="equivalent"`); got != tt.equal {
+			t.Errorf("%s: equivalent section %t, want %t", tt.name, got, tt.equal)
+		}
+		for _, s := range tt.want {
+			if !strings.Contains(html, s) {
+				t.Errorf("%s: no %q", tt.name, s)
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package jsondiff
+
+import (
+	"net/url"
+	"strings"
+)
+
+// The report template is executed by html/template, which escapes by
+// context on its own. The JSON trees are built as strings instead, for speed
+// on large documents, and trusted as template.HTML, so everything document-
+// or config-controlled interpolated into them goes through one of these,
+// chosen by where it lands:
+//
+//   - escapeHTML for element text,
+//   - attr for an attribute, whose value is always double-quoted,
+//   - safeURL for a URL, before it is passed to attr as an href,
+//   - embeddedJSON for JSON inside a <script> element.
+//
+// Names of elements and attributes, and classes, which cls checks against
+// logicalClasses, are always constants; no such value ever reaches an event
+// handler, a style, or an unquoted attribute.
+
+var htmlEscaper = strings.NewReplacer(
+	`&`, "&amp;",
+	`<`, "&lt;",
+	`>`, "&gt;",
+	`"`, "&quot;",
+	`'`, "&#39;",
+)
+
+// escapeHTML escapes s for element text or a quoted attribute value.
+func escapeHTML(s string) string {
+	return htmlEscaper.Replace(s)
+}
+
+// attr renders the attribute name="value", with a leading space, escaping
+// value. name must be a constant.
+func attr(name, value string) string {
+	return " " + name + `="` + escapeHTML(value) + `"`
+}
+
+// safeURL returns raw normalised if it is an absolute http or https URL, so
+// that no javascript: or data: URL ever becomes a link, and "" otherwise.
+func safeURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
@@ -0,0 +1,165 @@
+package jsondiff
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// treeTag matches one tag of the tree markup: only the elements renderJSON
+// builds, with double-quoted attribute values free of markup.
+var treeTag = regexp.MustCompile(`^<(/?)(div|ul|li|span|button|a|template)((?: [a-z-]+="[^"<>]*")*)>`)
+
+var treeAttr = regexp.MustCompile(` ([a-z-]+)="([^"]*)"`)
+
+// checkTreeMarkup parses html as the tree markup and fails on any element,
+// attribute or text that renderJSON does not build itself.
+func checkTreeMarkup(t *testing.T, html string) {
+	t.Helper()
+	var open []string
+	for i := 0; i < len(html); {
+		if html[i] == '>' {
+			t.Fatalf("bare > at %d in %s", i, html)
+		}
+		if html[i] != '<' {
+			i++
+			continue
+		}
+		m := treeTag.FindStringSubmatch(html[i:])
+		if m == nil {
+			t.Fatalf("unexpected markup at %d: %.60s", i, html[i:])
+		}
+		if m[1] == "/" {
+			if len(open) == 0 || open[len(open)-1] != m[2] {
+				t.Fatalf("</%s> closes %v at %d", m[2], open, i)
+			}
+			open = open[:len(open)-1]
+		} else {
+			open = append(open, m[2])
+		}
+		for _, a := range treeAttr.FindAllStringSubmatch(m[3], -1) {
+			if strings.HasPrefix(a[1], "on") || a[1] == "style" || a[1] == "src" {
+				t.Fatalf("attribute %s at %d", a[1], i)
+			}
+			if a[1] == "href" && !strings.HasPrefix(a[2], "http:") && !strings.HasPrefix(a[2], "https:") {
+				t.Fatalf("link to %q at %d", a[2], i)
+			}
+		}
+		i += len(m[0])
+	}
+	if len(open) > 0 {
+		t.Fatalf("unclosed %v", open)
+	}
+}
+
+// FuzzRenderJSON renders trees of documents built from adversarial keys and
+// values and checks that none of them escapes its text or attribute.
+func FuzzRenderJSON(f *testing.F) {
+	for _, seed := range [][2]string{
+		{`name`, `value`},
+		{`"><script>alert(1)</script>`, `</script><script>alert(1)</script>`},
+		{`javascript:alert(1)`, `javascript:alert(1)`},
+		{`' onmouseover='x`, `" onclick="x`},
+		{`<!--`, `]]><img src=x onerror=alert(1)>`},
+		{`a&amp;b`, "  \x00"},
+		{`https://example.com/"x`, `<a href="javascript:x">`},
+	} {
+		f.Add(seed[0], seed[1])
+	}
+	links, err := compileLinkRules([]LinkRule{{Path: `(?P<p>.*)`, URL: `{{.p}}`}})
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Fuzz(func(t *testing.T, key, value string) {
+		a := map[string]interface{}{
+			key:    value,
+			"list": []interface{}{value, 1.0},
+			"obj":  map[string]interface{}{key: value, "n": nil},
+		}
+		b := map[string]interface{}{
+			key:     value + "!",
+			"list":  []interface{}{value, key},
+			"other": map[string]interface{}{value: key},
+		}
+		changes, err := collectChanges(diffSeq(context.Background(), a, b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		base := renderContext{diffMap: buildDiffMap(changes, a, b), showGhosts: true, links: links, editable: true}
+		base.ordinals = nodeOrdinals(a, b, base)
+		base.nodeIDs = nodeIDs(b, base)
+		for _, side := range []Side{SideA, SideB} {
+			ctx := base
+			ctx.side, ctx.other = side, b
+			doc := interface{}(a)
+			if side == SideB {
+				ctx.other, doc = a, b
+			}
+			checkTreeMarkup(t, string(renderJSON(doc, "", &ctx)))
+			ctx.lazyDepth = 1
+			checkTreeMarkup(t, string(renderJSON(doc, "", &ctx)))
+		}
+		js, err := embeddedJSON(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.ContainsAny(string(js), "<>") {
+			t.Errorf("embedded JSON contains markup: %s", js)
+		}
+	})
+}
+
+// TestReportNoInjectedScript renders the whole HTML report of a hostile
+// document and checks it has no script element a benign one lacks.
+func TestReportNoInjectedScript(t *testing.T) {
+	count := func(r *Report) int {
+		var buf bytes.Buffer
+		if err := renderers["html"].Render(&buf, r); err != nil {
+			t.Fatal(err)
+		}
+		return strings.Count(strings.ToLower(buf.String()), "<script")
+	}
+	benign := count(reportFor(t, `{"a": "x", "b": [1]}`, `{"a": "y", "b": [2]}`))
+	hostile := count(reportFor(t,
+		`{"</script><script>alert(1)</script>": "<script>x</script>", "b": ["<img src=x onerror=alert(1)>"]}`,
+		`{"</script><script>alert(1)</script>": "</SCRIPT><SCRIPT>y", "b": ["javascript:alert(1)"]}`))
+	if hostile != benign {
+		t.Errorf("%d script elements in the hostile report, %d in a benign one", hostile, benign)
+	}
+}
+
+// modifiedJSON extracts the document --editable embeds in a report.
+var modifiedJSON = regexp.MustCompile(`(?s)<script type="application/json" id="modified-json">(.*?)</script>`)
+
+// TestEditableRoundTrip checks that the Modified document an --editable
+// report embeds for export parses back to the document compared, whatever
+// markup or line separators its strings hold.
+func TestEditableRoundTrip(t *testing.T) {
+	const b = `{
+  "</script><script>alert(1)</script>": "<!-- x -->",
+  "amp": "a & b <c>",
+  "seps": "line\u2028para\u2029end",
+  "unicode": "café 𝄞",
+  "numbers": [0, -1.5, 1e21, 0.1],
+  "nested": {"empty": {}, "list": [], "null": null, "t": true}
+}`
+	dir := cliDir(t, map[string]string{"a.json": `{"amp": "x"}`, "b.json": b})
+	if res := runCLI(t, dir, "--editable", "-o", "out.html", "a.json", "b.json"); res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	m := modifiedJSON.FindStringSubmatch(readFile(t, dir, "out.html"))
+	if m == nil {
+		t.Fatal("no embedded document in the report")
+	}
+	var got interface{}
+	if err := json.Unmarshal([]byte(m[1]), &got); err != nil {
+		t.Fatalf("embedded document does not parse: %v\n%s", err, m[1])
+	}
+	if want := mustDecode(t, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("embedded document %v, want %v", got, want)
+	}
+}
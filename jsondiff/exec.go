@@ -0,0 +1,126 @@
+package jsondiff
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// execOptions controls how --exec-a and --exec-b commands are run.
+type execOptions struct {
+	shell   bool
+	timeout time.Duration
+}
+
+// runInputCommand runs command and saves its stdout to a temporary file, so
+// the output is read through the same limits and position tracking as any
+// other input. The caller removes the returned file.
+func runInputCommand(command string, opts execOptions) (string, error) {
+	var argv []string
+	if opts.shell {
+		argv = []string{"sh", "-c", command}
+	} else {
+		var err error
+		if argv, err = splitCommand(command); err != nil {
+			return "", err
+		}
+		if len(argv) == 0 {
+			return "", errors.New("empty command")
+		}
+	}
+
+	ctx := context.Background()
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+
+	out, err := os.CreateTemp("", "differ-exec-*.json")
+	if err != nil {
+		return "", err
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Stdout, cmd.Stderr = out, &stderr
+	runErr := cmd.Run()
+	info, statErr := out.Stat()
+	out.Close()
+
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		err = fmt.Errorf("timed out after %s", opts.timeout)
+	case runErr != nil:
+		err = runErr
+	case statErr != nil:
+		err = statErr
+	case info.Size() == 0:
+		err = errors.New("produced no output")
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%v: %s", err, msg)
+		}
+		return "", err
+	}
+	return out.Name(), nil
+}
+
+// splitCommand splits s into arguments on unquoted whitespace. Single
+// quotes keep their contents literally; inside double quotes a backslash
+// escapes the next character. Use --shell for anything more elaborate.
+func splitCommand(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == '\\':
+			escaped, inArg = true, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in command %q", s)
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,155 @@
+package jsondiff
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestHelperProcess is the fake command the exec tests run: it is the test
+// binary itself, doing what its arguments after "--" ask.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("DIFFER_HELPER_PROCESS") != "1" {
+		return
+	}
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	switch args[1] {
+	case "json":
+		fmt.Println(`{"spec":{"replicas":3}}`)
+	case "silent":
+	case "fail":
+		fmt.Fprintln(os.Stderr, "deployment not found")
+		os.Exit(1)
+	case "sleep":
+		time.Sleep(10 * time.Second)
+	}
+	os.Exit(0)
+}
+
+// helperCommand returns the command line running TestHelperProcess with mode.
+func helperCommand(t *testing.T, mode string) string {
+	t.Setenv("DIFFER_HELPER_PROCESS", "1")
+	return fmt.Sprintf("'%s' -test.run=TestHelperProcess -- %s", os.Args[0], mode)
+}
+
+// emptyTempDir points os.TempDir at a fresh directory and returns a check
+// that nothing was left in it.
+func emptyTempDir(t *testing.T) func() {
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+	return func() {
+		t.Helper()
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range entries {
+			t.Errorf("temporary file %s left behind", e.Name())
+		}
+	}
+}
+
+func TestRunInputCommand(t *testing.T) {
+	tests := []struct {
+		mode    string
+		timeout time.Duration
+		wantErr string
+	}{
+		{mode: "json"},
+		{mode: "silent", wantErr: "produced no output"},
+		{mode: "fail", wantErr: "deployment not found"},
+		{mode: "sleep", timeout: 100 * time.Millisecond, wantErr: "timed out after 100ms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			checkTemp := emptyTempDir(t)
+			name, err := runInputCommand(helperCommand(t, tt.mode), execOptions{timeout: tt.timeout})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				checkTemp()
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(name)
+			os.Remove(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSpace(string(data)); got != `{"spec":{"replicas":3}}` {
+				t.Errorf("output = %s", got)
+			}
+		})
+	}
+}
+
+func TestRunInputCommandShell(t *testing.T) {
+	checkTemp := emptyTempDir(t)
+	name, err := runInputCommand(helperCommand(t, "json")+" | tr 3 4", execOptions{shell: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(name)
+	os.Remove(name)
+	if got := strings.TrimSpace(string(data)); got != `{"spec":{"replicas":4}}` {
+		t.Errorf("output = %s", got)
+	}
+	checkTemp()
+}
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{`kubectl get deploy foo -o json`, []string{"kubectl", "get", "deploy", "foo", "-o", "json"}},
+		{`jq '.a b' "x \"y\""`, []string{"jq", ".a b", `x "y"`}},
+		{`echo a\ b ''`, []string{"echo", "a b", ""}},
+	}
+	for _, tt := range tests {
+		got, err := splitCommand(tt.in)
+		if err != nil {
+			t.Errorf("splitCommand(%q): %v", tt.in, err)
+			continue
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+			t.Errorf("splitCommand(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if _, err := splitCommand(`echo 'open`); err == nil {
+		t.Error("unterminated quote accepted")
+	}
+}
+
+// TestReadInputCleansUp checks that an input failing after its command ran
+// returns an error with no temporary file left behind.
+func TestReadInputCleansUp(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		subpath string
+		wantErr string
+	}{
+		{"missing subpath", "json", "spec.missing", "Path spec.missing not found"},
+		{"failing command", "fail", "", "failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkTemp := emptyTempDir(t)
+			src := inputSource{command: helperCommand(t, tt.mode), subpath: tt.subpath}
+			_, err := readInput(src, execOptions{}, defaultInputLimits, decodeOptions{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+			}
+			checkTemp()
+		})
+	}
+}
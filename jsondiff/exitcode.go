@@ -0,0 +1,95 @@
+package jsondiff
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Process exit codes. Usage and I/O errors exit with exitError, which Run
+// returns to main.
+const (
+	exitOK              = 0
+	exitError           = 1
+	exitChangesFound    = 2
+	exitSchemaViolation = 3
+)
+
+// maxExitCode is the highest status --exit-code-map may assign; shells
+// reserve the codes above it.
+const maxExitCode = 125
+
+// exitCodeMap is a parsed --exit-code-map, giving the exit status for runs
+// in which changes of each --fail-on kind remain.
+type exitCodeMap map[string]int
+
+// parseExitCodeMap parses a comma-separated list of kind=code pairs such as
+// "added=0,removed=3". Codes run from 0 to maxExitCode, except exitError,
+// which is kept for failures of differ itself.
+func parseExitCodeMap(list string) (exitCodeMap, error) {
+	if list == "" {
+		return nil, nil
+	}
+	m := make(exitCodeMap)
+	for _, pair := range strings.Split(list, ",") {
+		kind, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --exit-code-map entry %q: must be kind=code", pair)
+		}
+		kind = strings.TrimSpace(kind)
+		if !slices.Contains(failOnKinds, kind) {
+			return nil, fmt.Errorf("invalid --exit-code-map kind %q: must be one of %s", kind, strings.Join(failOnKinds, ", "))
+		}
+		if _, dup := m[kind]; dup {
+			return nil, fmt.Errorf("invalid --exit-code-map: %s is mapped twice", kind)
+		}
+		code, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || code < 0 || code > maxExitCode {
+			return nil, fmt.Errorf("invalid --exit-code-map code %q for %s: must be an integer from 0 to %d", value, kind, maxExitCode)
+		}
+		if code == exitError {
+			return nil, fmt.Errorf("invalid --exit-code-map code %d for %s: %d is reserved for errors", code, kind, exitError)
+		}
+		m[kind] = code
+	}
+	return m, nil
+}
+
+// exitCause is one reason for a non-zero exit status.
+type exitCause struct {
+	code   int
+	reason string
+}
+
+// exitCauses lists the codes m assigns to the kinds present among results
+// and, for reordered, reorders, in failOnKinds order.
+func (m exitCodeMap) exitCauses(results []DiffResult, reorders []KeyReorder) []exitCause {
+	var causes []exitCause
+	for _, kind := range failOnKinds {
+		code, ok := m[kind]
+		if !ok {
+			continue
+		}
+		n := countFailOn(results, map[string]bool{kind: true})
+		if kind == kindReordered {
+			n = len(reorders)
+		}
+		if n > 0 {
+			causes = append(causes, exitCause{code, fmt.Sprintf("%d change(s) of kind %s map to status %d with --exit-code-map", n, kind, code)})
+		}
+	}
+	return causes
+}
+
+// chooseExitCode returns the highest code among causes, the first of equal
+// ones, with its reason, or exitOK and "" when there are none.
+func chooseExitCode(causes []exitCause) (int, string) {
+	best := exitCause{code: exitOK}
+	for _, c := range causes {
+		if c.code > best.code {
+			best = c
+		}
+	}
+	return best.code, best.reason
+}
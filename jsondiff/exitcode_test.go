@@ -0,0 +1,72 @@
+package jsondiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseExitCodeMap(t *testing.T) {
+	m, err := parseExitCodeMap(" added = 0, removed=3,changed=125")
+	if want := (exitCodeMap{"added": 0, "removed": 3, "changed": 125}); err != nil || !reflect.DeepEqual(m, want) {
+		t.Errorf("got %v, %v, want %v", m, err, want)
+	}
+	if m, err := parseExitCodeMap(""); m != nil || err != nil {
+		t.Errorf("empty: %v, %v", m, err)
+	}
+	tests := map[string]string{
+		"added":           "must be kind=code",
+		"grown=3":         `invalid --exit-code-map kind "grown"`,
+		"added=3,added=4": "added is mapped twice",
+		"added=x":         `invalid --exit-code-map code "x" for added`,
+		"added=126":       "must be an integer from 0 to 125",
+		"removed=-1":      "must be an integer from 0 to 125",
+		"changed=1":       "1 is reserved for errors",
+	}
+	for list, want := range tests {
+		if _, err := parseExitCodeMap(list); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: error %v, want %q", list, err, want)
+		}
+	}
+}
+
+func TestExitCauses(t *testing.T) {
+	m := exitCodeMap{"added": 3, "removed": 3, "changed": 0, "reordered": 5}
+	results := []DiffResult{{Path: "a", Type: "create"}, {Path: "b", Type: "delete"}, {Path: "c", Type: "update"}}
+	causes := m.exitCauses(results, nil)
+	code, reason := chooseExitCode(causes)
+	// Of equal codes the first kind, in --fail-on order, gives the reason.
+	if code != 3 || reason != "1 change(s) of kind added map to status 3 with --exit-code-map" || len(causes) != 3 {
+		t.Errorf("%d, %q from %+v", code, reason, causes)
+	}
+	if code, _ := chooseExitCode(m.exitCauses(results, []KeyReorder{{Path: "x"}})); code != 5 {
+		t.Errorf("with a reorder: %d, want 5", code)
+	}
+	if code, reason := chooseExitCode(nil); code != exitOK || reason != "" {
+		t.Errorf("no causes: %d, %q", code, reason)
+	}
+}
+
+func TestExitCodeMapCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"a": 1, "gone": 1}`,
+		"b.json": `{"a": 1, "new": 1}`,
+	})
+	tests := []struct {
+		list string
+		exit int
+	}{
+		{"added=3,removed=4", 4},
+		{"added=0,removed=0", exitOK},
+		{"changed=9", exitOK},
+	}
+	for _, tt := range tests {
+		res := runCLI(t, dir, "--exit-code-map", tt.list, "-o", "out.html", "a.json", "b.json")
+		if res.exit != tt.exit {
+			t.Errorf("%s: exit %d, want %d: %s", tt.list, res.exit, tt.exit, res.stderr)
+		}
+	}
+	if res := runCLI(t, dir, "--exit-code-map", "added=1", "-o", "out.html", "a.json", "b.json"); res.exit != exitError || !strings.Contains(res.stderr, "reserved for errors") {
+		t.Errorf("reserved code: exit %d: %s", res.exit, res.stderr)
+	}
+}
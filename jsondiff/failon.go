@@ -0,0 +1,94 @@
+package jsondiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// failOnKinds are the values accepted by --fail-on: change types, the null
+// kinds refining "changed", and reordered objects found by
+// --detect-key-reorder.
+var failOnKinds = []string{string(Added), string(Removed), string(Changed), kindNulled, kindUnNulled, kindReordered}
+
+// parseFailOn parses the comma-separated --fail-on list.
+func parseFailOn(list string) (map[string]bool, error) {
+	if list == "" {
+		return nil, nil
+	}
+	set := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		known := false
+		for _, k := range failOnKinds {
+			known = known || k == name
+		}
+		if !known {
+			return nil, fmt.Errorf("invalid --fail-on %q: must be one of %s", name, strings.Join(failOnKinds, ", "))
+		}
+		set[name] = true
+	}
+	return set, nil
+}
+
+// countFailOn counts the results whose change type or kind is in set.
+func countFailOn(results []DiffResult, set map[string]bool) int {
+	n := 0
+	for _, r := range results {
+		if set[string(r.ChangeType())] || (r.Kind != "" && set[r.Kind]) {
+			n++
+		}
+	}
+	return n
+}
+
+// gatePatterns are the --fail-on-path patterns, in the syntax of matchPath.
+// A change gates the run, failing it with exitChangesFound, when it is at a
+// matching path or replaces a container a matching path may lie in; changes
+// elsewhere are still reported but never fail the run on their account.
+type gatePatterns [][]string
+
+// parseGatePatterns parses the --fail-on-path values, each a
+// comma-separated list of path patterns.
+func parseGatePatterns(values []string) (gatePatterns, error) {
+	var g gatePatterns
+	for _, v := range values {
+		for _, p := range strings.Split(v, ",") {
+			p = strings.TrimSpace(p)
+			if p == "" {
+				return nil, fmt.Errorf("invalid --fail-on-path %q: empty pattern", v)
+			}
+			g = append(g, splitPath(p))
+		}
+	}
+	return g, nil
+}
+
+// gates reports whether r is at a path matching a pattern, or adds, removes
+// or replaces an object or array below which a matching path may lie.
+func (g gatePatterns) gates(r DiffResult) bool {
+	path := splitPath(r.Path)
+	container := func(t string) bool { return t == "object" || t == "array" }
+	for _, p := range g {
+		if matchPath(p, path) {
+			return true
+		}
+		if (container(r.FromType) || container(r.ToType)) && matchPathPrefix(p, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// markGating sets Gating on the results g gates and returns their number.
+// Only the results passed in can gate, so changes a filter dropped or an
+// acknowledgement moved aside never fail the run.
+func markGating(results []DiffResult, g gatePatterns) int {
+	n := 0
+	for i := range results {
+		if g.gates(results[i]) {
+			results[i].Gating = true
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,88 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestGatePatterns(t *testing.T) {
+	g, err := parseGatePatterns([]string{"spec.replicas, **.image", "ports[*].port", "security.**"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		r    DiffResult
+		want bool
+	}{
+		{DiffResult{Path: "spec.replicas", FromType: "number", ToType: "number"}, true},
+		{DiffResult{Path: "spec.template.containers[0].image", FromType: "string", ToType: "string"}, true},
+		{DiffResult{Path: "ports[2].port", ToType: "number"}, true},
+		{DiffResult{Path: "ports[2].name", ToType: "string"}, false},
+		// An added element may hold a gated path.
+		{DiffResult{Path: "ports[2]", ToType: "object"}, true},
+		{DiffResult{Path: "spec", FromType: "object", ToType: "string"}, true},
+		{DiffResult{Path: "spec.labels", FromType: "string", ToType: "string"}, false},
+		{DiffResult{Path: "status", FromType: "object"}, true},
+		{DiffResult{Path: "security.tls.cert", FromType: "string", ToType: "string"}, true},
+		{DiffResult{Path: "security", FromType: "object"}, true},
+	}
+	for _, tt := range tests {
+		if got := g.gates(tt.r); got != tt.want {
+			t.Errorf("%s (%s to %s): gates %t", tt.r.Path, tt.r.FromType, tt.r.ToType, got)
+		}
+	}
+	if _, err := parseGatePatterns([]string{"a,"}); err == nil || !strings.Contains(err.Error(), "empty pattern") {
+		t.Errorf("empty pattern: %v", err)
+	}
+}
+
+func TestFailOnPathCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"spec": {"replicas": 2, "labels": "x"}, "ports": [{"port": 80}]}`,
+		"b.json": `{"spec": {"replicas": 2, "labels": "y"}, "ports": [{"port": 80}, {"port": 443}]}`,
+	})
+	tests := []struct {
+		patterns string
+		exit     int
+		gating   []string
+	}{
+		{"spec.replicas", exitOK, nil},
+		{"ports[*].port", exitChangesFound, []string{"ports[1]"}},
+		{"spec.labels,spec.replicas", exitChangesFound, []string{"spec.labels"}},
+	}
+	for _, tt := range tests {
+		res := runCLI(t, dir, "--fail-on-path", tt.patterns, "-f", "json", "-o", "-", "a.json", "b.json")
+		if res.exit != tt.exit {
+			t.Errorf("%s: exit %d, want %d: %s", tt.patterns, res.exit, tt.exit, res.stderr)
+		}
+		var report jsonReport
+		if err := json.Unmarshal([]byte(res.stdout), &report); err != nil {
+			t.Fatal(err)
+		}
+		var gating []string
+		for _, s := range report.Sections {
+			for _, c := range s.Changes {
+				if c.Gating {
+					gating = append(gating, c.Path)
+				}
+			}
+		}
+		if strings.Join(gating, " ") != strings.Join(tt.gating, " ") {
+			t.Errorf("%s: gating %q, want %q", tt.patterns, gating, tt.gating)
+		}
+	}
+
+	if res := runCLI(t, dir, "--fail-on-path", "ports.**", "-o", "out.html", "a.json", "b.json"); res.exit != exitChangesFound {
+		t.Errorf("html: exit %d: %s", res.exit, res.stderr)
+	}
+	if n := strings.Count(readFile(t, dir, "out.html"), `title="Matches --fail-on-path; fails the run">gating</span>`); n != 1 {
+		t.Errorf("%d gating badges, want 1", n)
+	}
+
+	// A change the filters drop cannot gate.
+	res := runCLI(t, dir, "--fail-on-path", "spec.labels", "--ignore-value-regex", "^[xy]$", "-o", "out.html", "a.json", "b.json")
+	if res.exit != exitOK {
+		t.Errorf("suppressed change: exit %d: %s", res.exit, res.stderr)
+	}
+}
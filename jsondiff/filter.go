@@ -0,0 +1,136 @@
+package jsondiff
+
+import (
+	"sort"
+
+	"github.com/r3labs/diff/v3"
+)
+
+// Suppression counts the changes a filtering mechanism removed from the
+// report. Changes lists them when --show-suppressed is given.
+type Suppression struct {
+	Reason  string       `json:"reason"`
+	Count   int          `json:"count"`
+	Changes []DiffResult `json:"changes,omitempty"`
+}
+
+// suppressionLog records the changes filters drop, by reason.
+type suppressionLog struct {
+	changes map[string][]diff.Change
+}
+
+func newSuppressionLog() *suppressionLog {
+	return &suppressionLog{changes: make(map[string][]diff.Change)}
+}
+
+// add records c as dropped for reason.
+func (s *suppressionLog) add(reason string, c diff.Change) {
+	s.changes[reason] = append(s.changes[reason], c)
+}
+
+// note lists reason even if nothing is dropped for it, so the report shows
+// that a mode was active.
+func (s *suppressionLog) note(reason string) {
+	if _, ok := s.changes[reason]; !ok {
+		s.changes[reason] = nil
+	}
+}
+
+// redact masks the values of the dropped changes, as red.changes does for
+// the reported ones, so --show-suppressed shows no secret either.
+func (s *suppressionLog) redact(red *redactor, a, b interface{}) {
+	for _, changes := range s.changes {
+		red.changes(changes, a, b)
+	}
+}
+
+// list returns the reasons with their counts, sorted by reason. With
+// detailed set each entry also lists its changes, resolved against the
+// compared documents a and b and sorted by path.
+func (s *suppressionLog) list(detailed bool, a, b interface{}) []Suppression {
+	out := make([]Suppression, 0, len(s.changes))
+	for reason, changes := range s.changes {
+		sup := Suppression{Reason: reason, Count: len(changes)}
+		if detailed && len(changes) > 0 {
+			sup.Changes = buildDiffTable(changes, a, b)
+			sortResults(sup.Changes, "path", nil)
+		}
+		out = append(out, sup)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Reason < out[j].Reason })
+	return out
+}
+
+const reasonEmptyEqualsAbsent = "empty equals absent"
+
+// filterEmptyEqualsAbsent drops creates and deletes whose value is an empty
+// array or object. With deep set, containers holding only empty containers
+// count as empty too.
+func filterEmptyEqualsAbsent(changes []diff.Change, deep bool, suppressed *suppressionLog) []diff.Change {
+	kept := changes[:0:0]
+	for _, c := range changes {
+		var v interface{}
+		switch c.Type {
+		case diff.CREATE:
+			v = c.To
+		case diff.DELETE:
+			v = c.From
+		default:
+			kept = append(kept, c)
+			continue
+		}
+		if isEmptyContainer(v, deep) {
+			suppressed.add(reasonEmptyEqualsAbsent, c)
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+const (
+	reasonSubset   = "subset: additions ignored"
+	reasonSuperset = "superset: removals ignored"
+)
+
+// filterChangeType drops every change of type t, counting them under
+// reason. The reason is listed even when nothing was dropped, so the report
+// shows that the mode was active.
+func filterChangeType(changes []diff.Change, t, reason string, suppressed *suppressionLog) []diff.Change {
+	suppressed.note(reason)
+	kept := changes[:0:0]
+	for _, c := range changes {
+		if c.Type == t {
+			suppressed.add(reason, c)
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+func isEmptyContainer(v interface{}, deep bool) bool {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if !deep {
+			return len(val) == 0
+		}
+		for _, vv := range val {
+			if !isEmptyContainer(vv, true) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		if !deep {
+			return len(val) == 0
+		}
+		for _, vv := range val {
+			if !isEmptyContainer(vv, true) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
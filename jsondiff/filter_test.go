@@ -0,0 +1,175 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// TestTransformsRecordSuppressed checks that the comparison transforms,
+// which hide differences before diffing rather than drop changes after it,
+// still list what they hid under --show-suppressed.
+func TestTransformsRecordSuppressed(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		flags    []string
+		reason   string
+		from, to string
+	}{
+		{"ignore-case", `{"s": "Hello"}`, `{"s": "hello"}`, []string{"--ignore-case"}, "equal after ignore-case", "Hello", "hello"},
+		{"trim-space", `{"s": " x "}`, `{"s": "x"}`, []string{"--trim-space"}, "equal after trim-space", " x ", "x"},
+		{"collapse-space", `{"s": "a  b"}`, `{"s": "a b"}`, []string{"--collapse-space"}, "equal after collapse-space", "a  b", "a b"},
+		{"normalize-unicode", `{"s": "caf\u00e9"}`, `{"s": "cafe\u0301"}`, []string{"--normalize-unicode", "nfc"}, "equal after normalize-unicode nfc", "caf\u00e9", "cafe\u0301"},
+		{"first transform to equate", `{"s": " Hi "}`, `{"s": "hi"}`, []string{"--trim-space", "--ignore-case"}, "equal after ignore-case", " Hi ", "hi"},
+		{"ignore-key-case", `{"UserId": 1}`, `{"userId": 1}`, []string{"--ignore-key-case"}, reasonIgnoreKeyCase, "UserId", "userId"},
+		{"map-as-set", `{"tags": {"x": 1}}`, `{"tags": {"x": 2}}`, []string{"--map-as-set", "tags"}, reasonMapAsSet, "1", "2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := cliDir(t, map[string]string{"a.json": tt.a, "b.json": tt.b})
+			args := append([]string{"--show-suppressed", "-f", "json", "-o", "out.json"}, tt.flags...)
+			res := runCLI(t, dir, append(args, "a.json", "b.json")...)
+			if res.exit != exitOK {
+				t.Fatalf("exit %d: %s", res.exit, res.stderr)
+			}
+			var report jsonReport
+			if err := json.Unmarshal([]byte(readFile(t, dir, "out.json")), &report); err != nil {
+				t.Fatal(err)
+			}
+			if report.Total != 0 {
+				t.Errorf("%d changes reported, want none", report.Total)
+			}
+			if len(report.Suppressed) != 1 {
+				t.Fatalf("suppressed %+v, want one reason", report.Suppressed)
+			}
+			sup := report.Suppressed[0]
+			if sup.Reason != tt.reason || sup.Count != 1 || len(sup.Changes) != 1 {
+				t.Fatalf("suppressed %+v, want one change for %q", sup, tt.reason)
+			}
+			if c := sup.Changes[0]; c.From != tt.from || c.To != tt.to {
+				t.Errorf("suppressed %q -> %q, want %q -> %q", c.From, c.To, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestIsEmptyContainer(t *testing.T) {
+	tests := []struct {
+		v             interface{}
+		shallow, deep bool
+	}{
+		{map[string]interface{}{}, true, true},
+		{[]interface{}{}, true, true},
+		{map[string]interface{}{"a": []interface{}{}, "b": map[string]interface{}{"c": []interface{}{}}}, false, true},
+		{[]interface{}{map[string]interface{}{}}, false, true},
+		{[]interface{}{nil}, false, false},
+		{map[string]interface{}{"a": ""}, false, false},
+		{"", false, false},
+		{nil, false, false},
+	}
+	for _, tt := range tests {
+		if got := isEmptyContainer(tt.v, false); got != tt.shallow {
+			t.Errorf("%#v: %t, want %t", tt.v, got, tt.shallow)
+		}
+		if got := isEmptyContainer(tt.v, true); got != tt.deep {
+			t.Errorf("%#v deep: %t, want %t", tt.v, got, tt.deep)
+		}
+	}
+}
+
+// TestEmptyEqualsAbsent checks which added and removed members
+// --empty-equals-absent and --deep suppress, and that changes between
+// values present on both sides are kept.
+func TestEmptyEqualsAbsent(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"tags": [], "nested": {"x": {}}, "meta": {}, "list": [], "n": 1}`,
+		"b.json": `{"labels": {}, "extra": {"y": [[]]}, "meta": {"k": []}, "list": {}, "n": 2, "z": [0]}`,
+	})
+	tests := []struct {
+		flags      []string
+		paths      []string
+		suppressed int
+	}{
+		{nil, []string{"extra", "labels", "list", "meta.k", "n", "nested", "tags", "z"}, 0},
+		{[]string{"--empty-equals-absent"}, []string{"extra", "list", "n", "nested", "z"}, 3},
+		{[]string{"--empty-equals-absent", "--deep"}, []string{"list", "n", "z"}, 5},
+	}
+	for _, tt := range tests {
+		args := append(append([]string{"--show-suppressed", "-f", "json", "-o", "out.json"}, tt.flags...), "a.json", "b.json")
+		if res := runCLI(t, dir, args...); res.exit != exitOK {
+			t.Fatalf("%q: exit %d: %s", tt.flags, res.exit, res.stderr)
+		}
+		var report jsonReport
+		if err := json.Unmarshal([]byte(readFile(t, dir, "out.json")), &report); err != nil {
+			t.Fatal(err)
+		}
+		var paths []string
+		for _, s := range report.Sections {
+			for _, c := range s.Changes {
+				paths = append(paths, c.Path)
+			}
+		}
+		if !reflect.DeepEqual(paths, tt.paths) {
+			t.Errorf("%q: changes %q, want %q", tt.flags, paths, tt.paths)
+		}
+		count := 0
+		for _, s := range report.Suppressed {
+			if s.Reason == reasonEmptyEqualsAbsent {
+				count = s.Count
+			}
+		}
+		if count != tt.suppressed {
+			t.Errorf("%q: %d suppressed, want %d", tt.flags, count, tt.suppressed)
+		}
+	}
+}
+
+// TestSubsetSuperset checks which changes --subset and --superset drop, that
+// the dropped ones are counted even when there are none, and that what
+// remains still gates the exit status.
+func TestSubsetSuperset(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"keep": 1, "gone": 2, "chg": 1, "list": [1]}`,
+		"b.json": `{"keep": 1, "new": 3, "chg": 2, "list": [1, 2]}`,
+	})
+	tests := []struct {
+		flags      []string
+		paths      []string
+		reason     string
+		suppressed int
+	}{
+		{[]string{"--subset"}, []string{"chg", "gone"}, reasonSubset, 2},
+		{[]string{"--superset"}, []string{"chg", "list[1]", "new"}, reasonSuperset, 1},
+	}
+	for _, tt := range tests {
+		for _, files := range [][]string{{"a.json", "b.json"}, {"a.json", "a.json"}} {
+			args := append(append([]string{"--fail-on", "changed", "-f", "json", "-o", "out.json"}, tt.flags...), files...)
+			res := runCLI(t, dir, args...)
+			var report jsonReport
+			if err := json.Unmarshal([]byte(readFile(t, dir, "out.json")), &report); err != nil {
+				t.Fatal(err)
+			}
+			var paths []string
+			for _, s := range report.Sections {
+				for _, c := range s.Changes {
+					paths = append(paths, c.Path)
+				}
+			}
+			wantPaths, wantCount, wantExit := tt.paths, tt.suppressed, exitChangesFound
+			if files[1] == "a.json" {
+				wantPaths, wantCount, wantExit = nil, 0, exitOK
+			}
+			if res.exit != wantExit {
+				t.Errorf("%q %q: exit %d, want %d: %s", tt.flags, files, res.exit, wantExit, res.stderr)
+			}
+			if !reflect.DeepEqual(paths, wantPaths) {
+				t.Errorf("%q %q: changes %q, want %q", tt.flags, files, paths, wantPaths)
+			}
+			want := []Suppression{{Reason: tt.reason, Count: wantCount}}
+			if !reflect.DeepEqual(report.Suppressed, want) {
+				t.Errorf("%q %q: suppressed %+v, want %+v", tt.flags, files, report.Suppressed, want)
+			}
+		}
+	}
+}
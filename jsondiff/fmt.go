@@ -0,0 +1,177 @@
+package jsondiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// runFmt implements the "fmt" subcommand: it rewrites JSON documents in a
+// canonical, deterministic layout.
+func runFmt(args []string) int {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	var outputFile, keyOrder string
+	var check bool
+	fs.StringVar(&outputFile, "o", "", "Output file (default stdout)")
+	fs.StringVar(&keyOrder, "key-order", "sorted", "Object key order: sorted or original")
+	fs.BoolVar(&check, "check", false, "List files that are not canonical and exit 1 instead of writing output")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: jsondiff fmt [--check] [--key-order sorted|original] [-o output.json] input.json")
+		fs.PrintDefaults()
+	}
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return flagExit(err)
+	}
+
+	if keyOrder != "sorted" && keyOrder != "original" {
+		return fatalf("Invalid --key-order %q: must be sorted or original", keyOrder)
+	}
+	if len(files) == 0 || (!check && len(files) != 1) {
+		fs.Usage()
+		return exitError
+	}
+
+	if check {
+		clean := true
+		for _, filename := range files {
+			data, err := os.ReadFile(filename)
+			if err != nil {
+				return fatalf("Failed to read file %s: %v", filename, err)
+			}
+			formatted, err := canonicalJSON(data, keyOrder == "sorted")
+			if err != nil {
+				return fatalf("Invalid JSON in %s: %v", filename, err)
+			}
+			if !bytes.Equal(data, formatted) {
+				fmt.Println(filename)
+				clean = false
+			}
+		}
+		if !clean {
+			return exitError
+		}
+		return exitOK
+	}
+
+	filename := files[0]
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fatalf("Failed to read file %s: %v", filename, err)
+	}
+	formatted, err := canonicalJSON(data, keyOrder == "sorted")
+	if err != nil {
+		return fatalf("Invalid JSON in %s: %v", filename, err)
+	}
+
+	if outputFile == "" {
+		os.Stdout.Write(formatted)
+		return exitOK
+	}
+	if err := os.WriteFile(outputFile, formatted, 0o644); err != nil {
+		return fatalf("Failed to write output file: %v", err)
+	}
+	return exitOK
+}
+
+// canonicalJSON re-encodes data with two-space indentation, numbers kept as
+// their literal json.Number text and a trailing newline. When sortKeys is
+// false object members keep their original order.
+func canonicalJSON(data []byte, sortKeys bool) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var buf bytes.Buffer
+	if sortKeys {
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		if err := expectEOF(dec); err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		// encoding/json writes map keys in sorted order.
+		if err := enc.Encode(v); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	if err := writeOrdered(&buf, dec, ""); err != nil {
+		return nil, err
+	}
+	if err := expectEOF(dec); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func expectEOF(dec *json.Decoder) error {
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("unexpected data after top-level value at offset %d", dec.InputOffset())
+	}
+	return nil
+}
+
+// writeOrdered copies the next value from dec to buf token by token, so that
+// object members are written in the order they appear in the source.
+func writeOrdered(buf *bytes.Buffer, dec *json.Decoder, indent string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		closing := byte('}')
+		if t == '[' {
+			closing = ']'
+		}
+		buf.WriteByte(byte(t))
+		inner := indent + "  "
+		first := true
+		for dec.More() {
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			buf.WriteString("\n" + inner)
+			if t == '{' {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				writeJSONScalar(buf, keyTok)
+				buf.WriteString(": ")
+			}
+			if err := writeOrdered(buf, dec, inner); err != nil {
+				return err
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+		if !first {
+			buf.WriteString("\n" + indent)
+		}
+		buf.WriteByte(closing)
+	default:
+		writeJSONScalar(buf, tok)
+	}
+	return nil
+}
+
+func writeJSONScalar(buf *bytes.Buffer, tok json.Token) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(tok)
+	// Encode always terminates the value with a newline.
+	buf.Truncate(buf.Len() - 1)
+}
@@ -0,0 +1,105 @@
+package jsondiff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	in := `{"b": [1.50, 1e3, -0], "a": {"z": "<&>", "y": {}}, "c": []}`
+	tests := []struct {
+		name     string
+		sortKeys bool
+		want     string
+	}{
+		{"sorted", true, `{
+  "a": {
+    "y": {},
+    "z": "<&>"
+  },
+  "b": [
+    1.50,
+    1e3,
+    -0
+  ],
+  "c": []
+}
+`},
+		{"original", false, `{
+  "b": [
+    1.50,
+    1e3,
+    -0
+  ],
+  "a": {
+    "z": "<&>",
+    "y": {}
+  },
+  "c": []
+}
+`},
+	}
+	for _, tt := range tests {
+		got, err := canonicalJSON([]byte(in), tt.sortKeys)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+		// Formatting is idempotent.
+		again, err := canonicalJSON(got, tt.sortKeys)
+		if err != nil || string(again) != string(got) {
+			t.Errorf("%s: reformatting gave\n%s\n%v", tt.name, again, err)
+		}
+	}
+
+	for _, scalar := range []string{`"s"`, `12.0`, `null`, `true`} {
+		for _, sortKeys := range []bool{true, false} {
+			if got, err := canonicalJSON([]byte(" "+scalar+" "), sortKeys); string(got) != scalar+"\n" || err != nil {
+				t.Errorf("%s, sorted %t: %q, %v", scalar, sortKeys, got, err)
+			}
+		}
+	}
+	for _, bad := range []string{`{"a": 1} {}`, `[1,]`, ``} {
+		for _, sortKeys := range []bool{true, false} {
+			if _, err := canonicalJSON([]byte(bad), sortKeys); err == nil {
+				t.Errorf("%q, sorted %t: no error", bad, sortKeys)
+			}
+		}
+	}
+}
+
+// TestFmtCLI writes a formatted file with -o and checks that --check passes
+// it and lists only the files that still need formatting.
+func TestFmtCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"messy.json": `{"n":1,"name":"a"}`,
+		"order.json": "{\n  \"name\": \"a\",\n  \"n\": 1\n}\n",
+	})
+	if res := runCLI(t, dir, "fmt", "-o", "clean.json", "messy.json"); res.exit != exitOK || res.stdout != "" {
+		t.Fatalf("exit %d, stdout %q: %s", res.exit, res.stdout, res.stderr)
+	}
+	if got, want := readFile(t, dir, "clean.json"), "{\n  \"n\": 1,\n  \"name\": \"a\"\n}\n"; got != want {
+		t.Errorf("clean.json: %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		args   []string
+		exit   int
+		stdout string
+	}{
+		{[]string{"--check", "clean.json"}, exitOK, ""},
+		{[]string{"--check", "clean.json", "messy.json", "order.json"}, exitError, "messy.json\norder.json\n"},
+		{[]string{"--check", "--key-order", "original", "order.json"}, exitOK, ""},
+		{[]string{"--key-order", "original", "messy.json"}, exitOK, "{\n  \"n\": 1,\n  \"name\": \"a\"\n}\n"},
+		{[]string{"--key-order", "reverse", "messy.json"}, exitError, ""},
+		{[]string{"clean.json", "messy.json"}, exitError, ""},
+	}
+	for _, tt := range tests {
+		res := runCLI(t, dir, append([]string{"fmt"}, tt.args...)...)
+		if res.exit != tt.exit || res.stdout != tt.stdout {
+			t.Errorf("%s: exit %d, stdout %q, want %d, %q", strings.Join(tt.args, " "), res.exit, res.stdout, tt.exit, tt.stdout)
+		}
+	}
+}
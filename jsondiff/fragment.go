@@ -0,0 +1,182 @@
+package jsondiff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Fragments embed parts of the report in another page. Their markup has no
+// <html>, <head> or <body> and relies only on these class names, which are
+// kept stable:
+//
+//	differ-tree        wrapper of one pane, with data-side="a" or "b"
+//	json-object, json-array, json-list
+//	                   containers and their member lists (role="tree"/"group")
+//	json-key           one member or element (role="treeitem"), plus one of
+//	                   added, removed, changed or unchanged, and
+//	                   contains-changes / ghost where they apply
+//	key, json-string, json-number, json-bool, json-null
+//	                   member names and scalar values
+//	toggle, change-marker, sr-only, approx, remapped
+//	                   expand control, textual change marker, screen-reader
+//	                   text and annotations
+//	differ-table       wrapper of the diff table; inside it diff-section,
+//	                   change-id, location, delta-up, delta-down, badge
+//
+// The styles and keyboard script they need are written separately by
+// RenderFragmentCSS and RenderFragmentScript. The "classes" section of
+// --config maps these names to a design system's own classes.
+
+// RenderTreeHTML writes the tree of one side of the report.
+func (r *Report) RenderTreeHTML(side Side, w io.Writer) error {
+	doc := r.A
+	if side == SideB {
+		doc = r.B
+	}
+	tpl, err := parseReportTemplate(r.A, r.B, r.render)
+	if err != nil {
+		return err
+	}
+	return tpl.ExecuteTemplate(w, "tree", map[string]interface{}{"Side": side, "Doc": doc})
+}
+
+// ErrSubtreeNotFound is returned by RenderSubtreeHTML for a pointer naming
+// no value on the requested side.
+var ErrSubtreeNotFound = errors.New("no value at pointer")
+
+// RenderSubtreeHTML writes the tree markup of the value at pointer, an RFC
+// 6901 JSON Pointer, on one side of the report. The markup is what the full
+// tree renders for that value, in full even with --lazy-depth, so a page can
+// fetch subtrees as they are expanded instead of loading the whole tree up
+// front.
+func (r *Report) RenderSubtreeHTML(pointer string, side Side, w io.Writer) error {
+	ctx := r.render
+	ctx.lazyDepth = 0
+	ctx.side, ctx.other = side, r.B
+	doc := r.A
+	if side == SideB {
+		ctx.remapped, ctx.other = nil, r.A
+		doc = r.B
+	}
+	v, path, ok := resolvePointer(doc, pointer, &ctx)
+	if !ok {
+		return fmt.Errorf("%w %q", ErrSubtreeNotFound, pointer)
+	}
+	_, err := io.WriteString(w, string(renderJSON(v, path, &ctx)))
+	return err
+}
+
+// resolvePointer returns the value at pointer in doc and its path as the
+// tree renders it.
+func resolvePointer(doc interface{}, pointer string, ctx *renderContext) (interface{}, string, bool) {
+	if pointer == "" {
+		return doc, "", true
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, "", false
+	}
+	v, path := doc, ""
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		switch val := v.(type) {
+		case map[string]interface{}:
+			child, ok := val[token]
+			if !ok {
+				return nil, "", false
+			}
+			v, path = child, pathKey(path, ctx.segment(path, token))
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(val) || strconv.Itoa(i) != token {
+				return nil, "", false
+			}
+			v, path = val[i], indexKey(path, i)
+		default:
+			return nil, "", false
+		}
+	}
+	return v, path, true
+}
+
+// RenderTableHTML writes the diff table: the section index, one section per
+// top-level key and the acknowledged changes.
+func (r *Report) RenderTableHTML(w io.Writer) error {
+	tpl, err := parseReportTemplate(r.A, r.B, r.render)
+	if err != nil {
+		return err
+	}
+	io.WriteString(w, `<div class="`+r.render.cls("differ-table")+`">`)
+	if err := tpl.ExecuteTemplate(w, "diff-table", r.htmlData()); err != nil {
+		return templateDataError(err)
+	}
+	_, err = io.WriteString(w, "</div>\n")
+	return err
+}
+
+// RenderFragmentCSS writes the stylesheet for the tree and table fragments.
+func (r *Report) RenderFragmentCSS(w io.Writer) error {
+	tpl, err := parseReportTemplate(r.A, r.B, r.render)
+	if err != nil {
+		return err
+	}
+	if err := tpl.ExecuteTemplate(w, "tree-styles", nil); err != nil {
+		return err
+	}
+	return tpl.ExecuteTemplate(w, "table-styles", nil)
+}
+
+// RenderFragmentScript writes the script adding keyboard and pointer
+// expand/collapse to embedded trees.
+func (r *Report) RenderFragmentScript(w io.Writer) error {
+	tpl, err := parseReportTemplate(r.A, r.B, r.render)
+	if err != nil {
+		return err
+	}
+	return tpl.ExecuteTemplate(w, "tree-script", nil)
+}
+
+// Asset is an extra file a renderer ships next to its output, named by
+// replacing the output's extension with Ext.
+type Asset struct {
+	Ext  string
+	Data []byte
+}
+
+// AssetRenderer is implemented by renderers whose output needs companion
+// files, such as a stylesheet.
+type AssetRenderer interface {
+	Renderer
+	Assets(r *Report) ([]Asset, error)
+}
+
+// fragmentRenderer writes both trees and the table as embeddable markup,
+// with the CSS and script as separate assets.
+type fragmentRenderer struct{}
+
+func (fragmentRenderer) Name() string             { return "fragment" }
+func (fragmentRenderer) DefaultExtension() string { return "html" }
+
+func (fragmentRenderer) Render(w io.Writer, r *Report) error {
+	if err := r.RenderTreeHTML(SideA, w); err != nil {
+		return err
+	}
+	if err := r.RenderTreeHTML(SideB, w); err != nil {
+		return err
+	}
+	return r.RenderTableHTML(w)
+}
+
+func (fragmentRenderer) Assets(r *Report) ([]Asset, error) {
+	var css, js bytes.Buffer
+	if err := r.RenderFragmentCSS(&css); err != nil {
+		return nil, err
+	}
+	if err := r.RenderFragmentScript(&js); err != nil {
+		return nil, err
+	}
+	return []Asset{{Ext: ".css", Data: css.Bytes()}, {Ext: ".js", Data: js.Bytes()}}, nil
+}
@@ -0,0 +1,49 @@
+package jsondiff
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestFragmentGolden pins the markup of the fragment renderer, whose class
+// names and structure embedding pages rely on.
+func TestFragmentGolden(t *testing.T) {
+	r := fixtureReport(t)
+	parts := []struct {
+		name   string
+		render func(*bytes.Buffer) error
+	}{
+		{"fragment-tree-a.html", func(w *bytes.Buffer) error { return r.RenderTreeHTML(SideA, w) }},
+		{"fragment-tree-b.html", func(w *bytes.Buffer) error { return r.RenderTreeHTML(SideB, w) }},
+		{"fragment-table.html", func(w *bytes.Buffer) error { return r.RenderTableHTML(w) }},
+	}
+	var all bytes.Buffer
+	for _, p := range parts {
+		var buf bytes.Buffer
+		if err := p.render(&buf); err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, p.name, buf.String())
+		all.Write(buf.Bytes())
+	}
+
+	var out bytes.Buffer
+	if err := (fragmentRenderer{}).Render(&out, r); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != all.String() {
+		t.Error("-f fragment output is not the two trees followed by the table")
+	}
+	assets, err := (fragmentRenderer{}).Assets(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 2 || assets[0].Ext != ".css" || assets[1].Ext != ".js" {
+		t.Fatalf("assets %v, want a stylesheet and a script", assets)
+	}
+	for _, class := range []string{".json-key", ".json-list", ".toggle", ".change-marker"} {
+		if !bytes.Contains(assets[0].Data, []byte(class)) {
+			t.Errorf("stylesheet does not style %s", class)
+		}
+	}
+}
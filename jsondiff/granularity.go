@@ -0,0 +1,179 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/r3labs/diff/v3"
+)
+
+// Values of --array-granularity.
+const (
+	granularityElements = "elements"
+	granularityWhole    = "whole"
+)
+
+// kindArrayChanged is the Kind of the single change reported for an array
+// compared whole.
+const kindArrayChanged = "array-changed"
+
+// arrayGranularity decides which arrays are compared whole rather than
+// element by element: those matching the first rule whose pattern matches,
+// otherwise all arrays when mode is whole.
+type arrayGranularity struct {
+	mode  string
+	rules []granularityRule
+}
+
+type granularityRule struct {
+	pattern []string
+	mode    string
+}
+
+// parseArrayGranularity parses --array-granularity values: a bare mode sets
+// the default and pattern=mode sets it for arrays at matching paths.
+func parseArrayGranularity(values []string) (arrayGranularity, error) {
+	g := arrayGranularity{mode: granularityElements}
+	for _, v := range values {
+		pattern, mode, hasPattern := strings.Cut(v, "=")
+		if !hasPattern {
+			mode = v
+		}
+		if mode != granularityElements && mode != granularityWhole {
+			return g, fmt.Errorf("invalid --array-granularity %q: must be whole, elements or path-pattern=whole|elements", v)
+		}
+		if hasPattern {
+			g.rules = append(g.rules, granularityRule{splitPath(pattern), mode})
+		} else {
+			g.mode = mode
+		}
+	}
+	return g, nil
+}
+
+func (g arrayGranularity) whole(path []string) bool {
+	for _, r := range g.rules {
+		if matchPath(r.pattern, path) {
+			return r.mode == granularityWhole
+		}
+	}
+	return g.mode == granularityWhole
+}
+
+// active reports whether any array may be compared whole.
+func (g arrayGranularity) active() bool {
+	if g.mode == granularityWhole {
+		return true
+	}
+	for _, r := range g.rules {
+		if r.mode == granularityWhole {
+			return true
+		}
+	}
+	return false
+}
+
+// collapseArrays replaces the changes inside every array of a and b that is
+// compared whole with one update of the array, made when the canonical
+// serializations of the two sides differ (so reordering counts even though
+// element matching ignores order). It runs on the matcher's output, after
+// every comparison transform, and returns the paths of the replaced arrays.
+func (g arrayGranularity) collapseArrays(changes diff.Changelog, a, b interface{}) (diff.Changelog, map[string]bool) {
+	whole := make(map[string]bool)
+	var added diff.Changelog
+	walkArrayPairs(a, b, func(va, vb []interface{}, raw, typed []string) bool {
+		if !g.whole(typed) {
+			return false
+		}
+		ja, _ := json.Marshal(va)
+		jb, _ := json.Marshal(vb)
+		if string(ja) != string(jb) {
+			whole[joinPath(typed)] = true
+			added = append(added, diff.Change{Type: diff.UPDATE, Path: raw, From: va, To: vb})
+		}
+		return true
+	})
+	return replaceArrayChanges(changes, a, b, whole, added), whole
+}
+
+// walkArrayPairs calls visit for every array present at the same path of a
+// and b, with the path as diff.Change segments (raw) and bracketed (typed).
+// The elements of an array are walked pairwise by index unless visit
+// returns true.
+func walkArrayPairs(a, b interface{}, visit func(va, vb []interface{}, raw, typed []string) bool) {
+	var walk func(a, b interface{}, raw, typed []string)
+	walk = func(a, b interface{}, raw, typed []string) {
+		switch va := a.(type) {
+		case map[string]interface{}:
+			vb, ok := b.(map[string]interface{})
+			if !ok {
+				return
+			}
+			for _, k := range sortedKeys(va) {
+				if vv, ok := vb[k]; ok {
+					walk(va[k], vv, append(raw[:len(raw):len(raw)], k), append(typed[:len(typed):len(typed)], k))
+				}
+			}
+		case []interface{}:
+			vb, ok := b.([]interface{})
+			if !ok || visit(va, vb, raw, typed) {
+				return
+			}
+			for i := 0; i < len(va) && i < len(vb); i++ {
+				walk(va[i], vb[i], append(raw[:len(raw):len(raw)], strconv.Itoa(i)), append(typed[:len(typed):len(typed)], indexSegment(i)))
+			}
+		}
+	}
+	walk(a, b, []string{}, []string{})
+}
+
+// replaceArrayChanges drops the changes at or below the arrays in replaced
+// and appends added, sorted by path, in their place.
+func replaceArrayChanges(changes diff.Changelog, a, b interface{}, replaced map[string]bool, added diff.Changelog) diff.Changelog {
+	kept := changes[:0:0]
+	for _, c := range changes {
+		segs := typedPath(c.Path, a, b)
+		inside := false
+		for n := len(segs); n >= 0 && !inside; n-- {
+			inside = replaced[joinPath(segs[:n])]
+		}
+		if !inside {
+			kept = append(kept, c)
+		}
+	}
+	sort.SliceStable(added, func(i, j int) bool {
+		return strings.Join(added[i].Path, "\x00") < strings.Join(added[j].Path, "\x00")
+	})
+	return append(kept, added...)
+}
+
+// markWholeArrays labels the results for arrays compared whole with their
+// item counts.
+func markWholeArrays(results []DiffResult, whole map[string]bool, a, b interface{}) {
+	for i := range results {
+		r := &results[i]
+		if !whole[r.Path] {
+			continue
+		}
+		va, _ := lookupPath(a, splitPath(r.Path))
+		vb, _ := lookupPath(b, splitPath(r.Path))
+		ra, _ := va.([]interface{})
+		rb, _ := vb.([]interface{})
+		r.Kind = kindArrayChanged
+		r.From = fmt.Sprintf("%s (%d items)", compactJSON(va), len(ra))
+		r.To = fmt.Sprintf("%s (%d items)", compactJSON(vb), len(rb))
+		r.FromJSON, r.ToJSON = jsonPayload(va), jsonPayload(vb)
+	}
+}
+
+// compactJSON renders v on one line for the From and To columns.
+func compactJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return formatValue(v)
+	}
+	return string(data)
+}
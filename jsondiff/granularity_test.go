@@ -0,0 +1,79 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseArrayGranularity(t *testing.T) {
+	g, err := parseArrayGranularity([]string{"whole", "items[*].tags=elements", "rows=whole"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]bool{
+		"items[0].tags": false,
+		"rows":          true,
+		"other":         true,
+	}
+	for path, want := range tests {
+		if got := g.whole(splitPath(path)); got != want {
+			t.Errorf("%s: whole %t, want %t", path, got, want)
+		}
+	}
+	if !g.active() {
+		t.Error("whole default: not active")
+	}
+	if g, _ := parseArrayGranularity([]string{"tags=elements"}); g.active() {
+		t.Error("only element rules: active")
+	}
+	if _, err := parseArrayGranularity([]string{"tags=rows"}); err == nil || !strings.Contains(err.Error(), `invalid --array-granularity "tags=rows"`) {
+		t.Errorf("bad mode: %v", err)
+	}
+}
+
+// TestArrayGranularityCLI checks that an array compared whole is reported
+// as one change with its item counts, reordering included, while other
+// arrays keep their element changes.
+func TestArrayGranularityCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"tags": ["a", "b"], "ports": [{"p": 1}], "same": [1, 2]}`,
+		"b.json": `{"tags": ["b", "a", "c"], "ports": [{"p": 2}], "same": [1, 2]}`,
+	})
+	tests := []struct {
+		flags []string
+		want  [][3]string
+	}{
+		{[]string{"--array-granularity", "whole"}, [][3]string{
+			{"ports", `[{"p":1}] (1 items)`, `[{"p":2}] (1 items)`},
+			{"tags", `["a","b"] (2 items)`, `["b","a","c"] (3 items)`},
+		}},
+		{[]string{"--array-granularity", "tags=whole"}, [][3]string{
+			{"ports[0].p", "1", "2"},
+			{"tags", `["a","b"] (2 items)`, `["b","a","c"] (3 items)`},
+		}},
+	}
+	for _, tt := range tests {
+		res := runCLI(t, dir, append(append([]string{"-f", "json", "-o", "-"}, tt.flags...), "a.json", "b.json")...)
+		if res.exit != exitOK {
+			t.Fatalf("%q: exit %d: %s", tt.flags, res.exit, res.stderr)
+		}
+		var report jsonReport
+		if err := json.Unmarshal([]byte(res.stdout), &report); err != nil {
+			t.Fatal(err)
+		}
+		var got [][3]string
+		for _, s := range report.Sections {
+			for _, c := range s.Changes {
+				got = append(got, [3]string{c.Path, c.From, c.To})
+				if c.Path == "tags" && c.Kind != kindArrayChanged {
+					t.Errorf("%q: tags kind %q", tt.flags, c.Kind)
+				}
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: changes %q, want %q", tt.flags, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package jsondiff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// defaultGraphMaxNodes is the default of --graph-max-nodes.
+const defaultGraphMaxNodes = 500
+
+// dotColors are the fill and border colors of changed nodes, matching the
+// tree's.
+var dotColors = map[ChangeType][2]string{
+	Added:   {"#d4edda", "#28a745"},
+	Removed: {"#f8d7da", "#dc3545"},
+	Changed: {"#fff3cd", "#ffc107"},
+	Moved:   {"#dbedff", "#0366d6"},
+}
+
+// dotRenderer writes a Graphviz digraph of where the documents differ:
+// the changed values, colored by change type, and the containers holding
+// them, linked by containment. Unchanged members are not drawn but counted
+// in their container's label, so `dot -Tsvg` gives a map of the changes.
+type dotRenderer struct{}
+
+func (dotRenderer) Name() string             { return "dot" }
+func (dotRenderer) DefaultExtension() string { return "dot" }
+
+func (dotRenderer) Render(w io.Writer, r *Report) error {
+	g := &dotGraph{w: bufio.NewWriter(w), m: r.render.diffMap, maxNodes: r.MaxNodes}
+	fmt.Fprintln(g.w, "digraph differ {")
+	fmt.Fprintln(g.w, `  node [shape=box, fontname="Helvetica"];`)
+	g.visit("", r.LabelA+" → "+r.LabelB, nil, r.A, r.B)
+	fmt.Fprintln(g.w, "}")
+	return g.w.Flush()
+}
+
+// dotGraph is the state of one dot rendering.
+type dotGraph struct {
+	w *bufio.Writer
+	m *DiffMap
+	// maxNodes caps the nodes drawn; past it, each container gets one
+	// marker counting the changed members left out.
+	maxNodes int
+	nodes    int
+}
+
+// visit draws the value at path, labelled label and linked from the node
+// parent ("" for the root), and below it the members that are changed or
+// hold changes.
+func (g *dotGraph) visit(parent, label string, path []string, a, b interface{}) {
+	p := joinPath(path)
+	var changed []string
+	unchanged := 0
+	children := make(map[string][2]interface{})
+	if g.m.HasChangedDescendant(p) {
+		eachMergedChild(a, b, func(seg string, ca, cb interface{}) {
+			cp := joinPath(append(path[:len(path):len(path)], seg))
+			_, own := g.m.Lookup(cp)
+			if !own && !g.m.HasChangedDescendant(cp) {
+				unchanged++
+				return
+			}
+			changed = append(changed, seg)
+			children[seg] = [2]interface{}{ca, cb}
+		})
+	}
+	if unchanged > 0 {
+		label += fmt.Sprintf("\n(%d unchanged)", unchanged)
+	}
+	var attrs string
+	if p != "" {
+		attrs = ", tooltip=" + dotQuote(p)
+	}
+	if ct, ok := g.m.Lookup(p); ok {
+		c := dotColors[ct]
+		attrs += fmt.Sprintf(", style=filled, fillcolor=%q, color=%q", c[0], c[1])
+	}
+	id := g.node(label, attrs)
+	if parent != "" {
+		fmt.Fprintf(g.w, "  %s -> %s;\n", parent, id)
+	}
+	for i, seg := range changed {
+		if g.nodes >= g.maxNodes {
+			more := g.node(fmt.Sprintf("%d more (--graph-max-nodes)", len(changed)-i), ", shape=note, style=dashed")
+			fmt.Fprintf(g.w, "  %s -> %s [style=dashed];\n", id, more)
+			return
+		}
+		v := children[seg]
+		g.visit(id, seg, append(path[:len(path):len(path)], seg), v[0], v[1])
+	}
+}
+
+// node writes a node with the given label and further attributes, each
+// preceded by ", ", and returns its id.
+func (g *dotGraph) node(label, attrs string) string {
+	id := "n" + strconv.Itoa(g.nodes)
+	g.nodes++
+	fmt.Fprintf(g.w, "  %s [label=%s%s];\n", id, dotQuote(label), attrs)
+	return id
+}
+
+// eachMergedChild calls fn for every member of a and b in path order,
+// pairing those with the same key or index. A container of the other kind
+// on one side contributes nothing.
+func eachMergedChild(a, b interface{}, fn func(seg string, ca, cb interface{})) {
+	ma, _ := a.(map[string]interface{})
+	mb, _ := b.(map[string]interface{})
+	if ma != nil || mb != nil {
+		for _, k := range mergedKeys(ma, mb) {
+			fn(k, ma[k], mb[k])
+		}
+		return
+	}
+	la, _ := a.([]interface{})
+	lb, _ := b.([]interface{})
+	for i := 0; i < max(len(la), len(lb)); i++ {
+		var ca, cb interface{}
+		if i < len(la) {
+			ca = la[i]
+		}
+		if i < len(lb) {
+			cb = lb[i]
+		}
+		fn(indexSegment(i), ca, cb)
+	}
+}
+
+// dotEscaper escapes a string for a double-quoted Graphviz ID. Backslashes
+// are doubled so that no key is read as an escape such as \N or \l, and
+// line breaks become \n.
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// dotQuote returns s as a double-quoted Graphviz ID.
+func dotQuote(s string) string {
+	return `"` + dotEscaper.Replace(s) + `"`
+}
@@ -0,0 +1,105 @@
+package jsondiff
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// renderDot renders r with the dot renderer.
+func renderDot(t *testing.T, r *Report) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := (dotRenderer{}).Render(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+// dotStatement matches the node and edge lines the dot renderer writes;
+// a quoted ID ends at the first unescaped double quote.
+var dotStatement = regexp.MustCompile(`^  (n\d+ \[label="(?:[^"\\\n]|\\.)*"(?:, \w+=(?:"(?:[^"\\\n]|\\.)*"|\w+))*\]|n\d+ -> n\d+(?: \[style=dashed\])?);$`)
+
+// checkDotSyntax fails unless every line of out between the braces is a
+// well-formed node or edge statement.
+func checkDotSyntax(t *testing.T, out string) {
+	t.Helper()
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) < 3 || lines[0] != "digraph differ {" || lines[len(lines)-1] != "}" {
+		t.Fatalf("not a digraph:\n%s", out)
+	}
+	for _, line := range lines[2 : len(lines)-1] {
+		if !dotStatement.MatchString(line) {
+			t.Errorf("malformed statement %q", line)
+		}
+	}
+}
+
+func TestDotGolden(t *testing.T) {
+	out := renderDot(t, fixtureReport(t))
+	checkDotSyntax(t, out)
+	checkGolden(t, "graph-fixture.dot", out)
+}
+
+func TestDotQuote(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{`plain`, `"plain"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`\N`, `"\\N"`},
+		{`a\`, `"a\\"`},
+		{"two\nlines", `"two\nlines"`},
+		{"crlf\r\nend\r", `"crlf\nend\n"`},
+		{`{a -> b; <c>}`, `"{a -> b; <c>}"`},
+	}
+	for _, tt := range tests {
+		if got := dotQuote(tt.in); got != tt.want {
+			t.Errorf("dotQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDotLabelEscaping(t *testing.T) {
+	r := reportFor(t,
+		`{"say \"hi\"": {"\\N": 1, "keep": 0}, "two\nlines": [1], "a -> b; }": {"<x>": "\\"}}`,
+		`{"say \"hi\"": {"\\N": 2, "keep": 0}, "two\nlines": [2], "a -> b; }": {"<x>": "\\\\"}}`)
+	r.LabelA, r.LabelB = `old "a".json`, `new\b.json`
+	out := renderDot(t, r)
+	checkDotSyntax(t, out)
+	checkGolden(t, "graph-escaping.dot", out)
+}
+
+func TestDotMaxNodes(t *testing.T) {
+	const docA = `{"a": 1, "b": 1, "c": 1, "d": {"x": 1, "y": 1}, "e": 1}`
+	const docB = `{"a": 2, "b": 2, "c": 2, "d": {"x": 2, "y": 2}, "e": 1}`
+	tests := []struct {
+		maxNodes int
+		// nodes counts the drawn nodes including markers.
+		nodes   int
+		markers []string
+	}{
+		{maxNodes: 100, nodes: 7},
+		{maxNodes: 3, nodes: 4, markers: []string{"2 more"}},
+		{maxNodes: 5, nodes: 6, markers: []string{"2 more"}},
+		{maxNodes: 1, nodes: 2, markers: []string{"4 more"}},
+	}
+	for _, tt := range tests {
+		r := reportFor(t, docA, docB)
+		r.MaxNodes = tt.maxNodes
+		out := renderDot(t, r)
+		checkDotSyntax(t, out)
+		if nodes := strings.Count(out, "[label="); nodes != tt.nodes {
+			t.Errorf("max %d: %d nodes, want %d", tt.maxNodes, nodes, tt.nodes)
+		}
+		var markers []string
+		for _, m := range regexp.MustCompile(`label="(\d+ more) \(--graph-max-nodes\)"`).FindAllStringSubmatch(out, -1) {
+			markers = append(markers, m[1])
+		}
+		if strings.Join(markers, ",") != strings.Join(tt.markers, ",") {
+			t.Errorf("max %d: markers %q, want %q", tt.maxNodes, markers, tt.markers)
+		}
+		if tt.maxNodes == 3 {
+			checkGolden(t, "graph-truncated.dot", out)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package jsondiff
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// HeatMarker is one change on the overview strip beside the trees.
+type HeatMarker struct {
+	// Ordinal is the data-ordinal of the Modified tree item the marker
+	// scrolls to.
+	Ordinal int
+	Type    ChangeType
+	Path    string
+	// Position is the item's place in the tree, from 0 (top) to 1.
+	Position float64
+}
+
+// Top is the marker's CSS top offset as a percentage.
+func (m HeatMarker) Top() string {
+	return fmt.Sprintf("%.2f%%", m.Position*100)
+}
+
+// nodeOrdinals numbers the nodes of the merged tree of a and b in document
+// order: object members by key, then array elements by index. This is the
+// Modified tree with ghosts of removed members where they would appear, so
+// removed paths get ordinals too. Paths are in the DiffMap's path space.
+func nodeOrdinals(a, b interface{}, base renderContext) map[string]int {
+	ordinals := make(map[string]int)
+	ctxA, ctxB := base, base
+	ctxA.side, ctxB.side = SideA, SideB
+	var walk func(path string, a, b interface{})
+	walk = func(path string, a, b interface{}) {
+		ordinals[path] = len(ordinals)
+		members := make(map[string][2]interface{})
+		objA, _ := a.(map[string]interface{})
+		for k, v := range objA {
+			p := pathKey(path, ctxA.segment(path, k))
+			m := members[p]
+			m[0] = v
+			members[p] = m
+		}
+		objB, _ := b.(map[string]interface{})
+		for k, v := range objB {
+			p := pathKey(path, ctxB.segment(path, k))
+			m := members[p]
+			m[1] = v
+			members[p] = m
+		}
+		keys := make([]string, 0, len(members))
+		for p := range members {
+			keys = append(keys, p)
+		}
+		sort.Strings(keys)
+		for _, p := range keys {
+			walk(p, members[p][0], members[p][1])
+		}
+
+		arrA, _ := a.([]interface{})
+		arrB, _ := b.([]interface{})
+		for i := 0; i < max(len(arrA), len(arrB)); i++ {
+			var x, y interface{}
+			if i < len(arrA) {
+				x = arrA[i]
+			}
+			if i < len(arrB) {
+				y = arrB[i]
+			}
+			walk(indexKey(path, i), x, y)
+		}
+	}
+	walk("", a, b)
+	return ordinals
+}
+
+// buildHeatMap places a marker for each result at the ordinal of its path,
+// or of its nearest numbered ancestor, ordered top to bottom. Without ghosts
+// a removed member has no item in the Modified tree, so its marker scrolls
+// to its parent's while keeping the place the ghost would have.
+func buildHeatMap(results []DiffResult, ordinals map[string]int, ghosts bool) []HeatMarker {
+	if len(ordinals) == 0 {
+		return nil
+	}
+	markers := make([]HeatMarker, 0, len(results))
+	for _, r := range results {
+		segs := splitPath(r.Path)
+		ord, ok := ordinals[r.Path]
+		for n := len(segs) - 1; !ok && n >= 0; n-- {
+			ord, ok = ordinals[joinPath(segs[:n])]
+		}
+		target := ord
+		if r.ChangeType() == Removed && !ghosts && len(segs) > 0 {
+			target = ordinals[joinPath(segs[:len(segs)-1])]
+		}
+		markers = append(markers, HeatMarker{
+			Ordinal:  target,
+			Type:     r.ChangeType(),
+			Path:     r.Path,
+			Position: float64(ord) / float64(len(ordinals)),
+		})
+	}
+	sort.SliceStable(markers, func(i, j int) bool {
+		if markers[i].Position != markers[j].Position {
+			return markers[i].Position < markers[j].Position
+		}
+		return markers[i].Path < markers[j].Path
+	})
+	return markers
+}
+
+// nodeIDs returns the ids of the Modified tree's items by path. Top-level
+// members of b keep their table-of-contents anchor; every other item,
+// ghosts included, is named after its ordinal, so ids never collide.
+func nodeIDs(b interface{}, ctx renderContext) map[string]string {
+	ids := make(map[string]string, len(ctx.ordinals))
+	for path, ord := range ctx.ordinals {
+		if path != "" {
+			ids[path] = fmt.Sprintf("node-%d", ord)
+		}
+	}
+	ctx.side = SideB
+	if obj, ok := b.(map[string]interface{}); ok {
+		for k := range obj {
+			p := pathKey("", ctx.segment("", k))
+			ids[p] = treeAnchor(SideB, p)
+		}
+	}
+	return ids
+}
+
+// setTargets sets the Target of each result to the id of its tree item.
+func setTargets(results []DiffResult, ids map[string]string) {
+	for i := range results {
+		results[i].Target = ids[results[i].Path]
+	}
+}
+
+// ordinalAttr returns the data-ordinal attribute of the Modified tree item
+// at path, which heat-map markers scroll to.
+func (ctx *renderContext) ordinalAttr(path string) string {
+	if ctx.side != SideB {
+		return ""
+	}
+	if ord, ok := ctx.ordinals[path]; ok {
+		return attr("data-ordinal", strconv.Itoa(ord))
+	}
+	return ""
+}
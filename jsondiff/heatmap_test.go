@@ -0,0 +1,112 @@
+package jsondiff
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestNodeOrdinals(t *testing.T) {
+	a := map[string]interface{}{"b": 1, "old": []interface{}{1, 2}}
+	b := map[string]interface{}{"a": map[string]interface{}{"x": 1}, "b": 2, "old": []interface{}{1}}
+	got := nodeOrdinals(a, b, renderContext{})
+	// Removed members and elements are numbered where their ghosts appear.
+	want := map[string]int{"": 0, "a": 1, "a.x": 2, "b": 3, "old": 4, "old[0]": 5, "old[1]": 6}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ordinals %v, want %v", got, want)
+	}
+}
+
+func TestBuildHeatMap(t *testing.T) {
+	ordinals := map[string]int{"": 0, "a": 1, "a.x": 2, "b": 3}
+	results := []DiffResult{
+		{Path: "b", Type: "update"},
+		{Path: "a.x.deep", Type: "create"},
+		{Path: "a.x", Type: "delete"},
+	}
+	for _, ghosts := range []bool{false, true} {
+		var got []string
+		for _, m := range buildHeatMap(results, ordinals, ghosts) {
+			got = append(got, fmt.Sprintf("%s %s %d", m.Path, m.Top(), m.Ordinal))
+		}
+		// a.x.deep has no ordinal of its own and takes that of a.x. The
+		// removed a.x keeps its place but, without its ghost, scrolls to a.
+		want := []string{"a.x 50.00% 1", "a.x.deep 50.00% 2", "b 75.00% 3"}
+		if ghosts {
+			want[0] = "a.x 50.00% 2"
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ghosts %t: markers %q, want %q", ghosts, got, want)
+		}
+	}
+	if buildHeatMap(results, nil, false) != nil {
+		t.Error("markers without ordinals")
+	}
+}
+
+// TestHeatMapCLI checks that every marker of the HTML report points at an
+// item of the Modified tree, with and without ghosts.
+func TestHeatMapCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"name": "svc", "spec": {"replicas": 2, "old": true}, "ports": [80]}`,
+		"b.json": `{"name": "svc", "spec": {"replicas": 3}, "ports": [80, 443], "new": 1}`,
+	})
+	marker := regexp.MustCompile(`<a href="#" class="\w+" style="top: [\d.]+%" title="\w+: ([^"]+)" data-change="\w+" data-ordinal="(\d+)">`)
+	for _, flags := range [][]string{nil, {"--show-ghosts"}} {
+		res := runCLI(t, dir, append(append([]string{"-o", "out.html"}, flags...), "a.json", "b.json")...)
+		if res.exit != exitOK {
+			t.Fatalf("%q: exit %d: %s", flags, res.exit, res.stderr)
+		}
+		html := readFile(t, dir, "out.html")
+		var paths []string
+		for _, m := range marker.FindAllStringSubmatch(html, -1) {
+			paths = append(paths, m[1])
+			if !regexp.MustCompile(`<li[^>]* data-ordinal="` + m[2] + `"`).MatchString(html) {
+				t.Errorf("%q: %s: no tree item has data-ordinal %s", flags, m[1], m[2])
+			}
+		}
+		if want := []string{"new", "ports[1]", "spec.old", "spec.replicas"}; !reflect.DeepEqual(paths, want) {
+			t.Errorf("%q: markers %q, want %q", flags, paths, want)
+		}
+	}
+}
+
+// TestSortDocumentCLI checks that --sort document lists the rows in tree
+// order and that each row's data-target names an item of the Modified tree.
+func TestSortDocumentCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"zeta": {"b": 1, "a": 1}, "alpha": [0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 1], "old": 1}`,
+		"b.json": `{"zeta": {"b": 2, "a": 2}, "alpha": [0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 2], "new": 1}`,
+	})
+	res := runCLI(t, dir, "--sort", "document", "--show-ghosts", "-o", "out.html", "a.json", "b.json")
+	if res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	html := readFile(t, dir, "out.html")
+	var targets []string
+	for _, m := range regexp.MustCompile(`<tr [^>]*data-target="([^"]+)"`).FindAllStringSubmatch(html, -1) {
+		targets = append(targets, m[1])
+		if !strings.Contains(html, ` id="`+m[1]+`"`) {
+			t.Errorf("no element has id %s", m[1])
+		}
+	}
+	if len(targets) != 6 {
+		t.Fatalf("%d rows with targets, want 6", len(targets))
+	}
+
+	res = runCLI(t, dir, "--sort", "document", "-f", "csv", "-o", "-", "a.json", "b.json")
+	if res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	var paths []string
+	for _, line := range strings.Split(strings.TrimSpace(res.stdout), "\n")[1:] {
+		paths = append(paths, strings.Split(line, ",")[1])
+	}
+	// By path, alpha[10] would come before alpha[2].
+	want := []string{"alpha[2]", "alpha[10]", "new", "old", "zeta.a", "zeta.b"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("order %q, want %q", paths, want)
+	}
+}
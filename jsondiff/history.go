@@ -0,0 +1,192 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// historyIndex is the page listing every report written to an output
+// directory.
+const historyIndex = "index.html"
+
+// historySuffix names the sidecar written next to each report in an output
+// directory. The index is rebuilt from the sidecars, never from the reports.
+const historySuffix = ".summary.json"
+
+// historyEntry is the sidecar of one report: the --summary-out document plus
+// the report it describes.
+type historyEntry struct {
+	Report  string    `json:"report"`
+	Created time.Time `json:"created"`
+	summaryFile
+}
+
+func (e historyEntry) Added() int   { return e.Counts[Added] }
+func (e historyEntry) Removed() int { return e.Counts[Removed] }
+func (e historyEntry) Changed() int { return e.Counts[Changed] }
+
+// isOutputDir reports whether -o names a directory: one ending in a path
+// separator, or an existing directory.
+func isOutputDir(name string) bool {
+	if strings.HasSuffix(name, "/") || strings.HasSuffix(name, string(os.PathSeparator)) {
+		return true
+	}
+	fi, err := os.Stat(name)
+	return err == nil && fi.IsDir()
+}
+
+// sanitizeLabel reduces an input label to characters safe in a file name on
+// any platform, e.g. "cfg/a b.json#spec" -> "cfg-a-b.json-spec". Letters
+// lose their accents first, so "café" becomes "cafe" rather than "caf".
+func sanitizeLabel(label string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range norm.NFKD.String(label) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if r < 0x80 && (r == '.' || r == '_' || r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			sb.WriteRune(r)
+			dash = false
+		} else if !dash {
+			sb.WriteByte('-')
+			dash = true
+		}
+	}
+	s := strings.Trim(sb.String(), "-.")
+	if len(s) > 60 {
+		s = strings.TrimRight(s[:60], "-.")
+	}
+	if s == "" {
+		return "input"
+	}
+	return s
+}
+
+// maxReportSuffix bounds the numbered suffixes historyReportName and
+// renderOutputName try before giving up on a name.
+const maxReportSuffix = 1000
+
+// historyReportName creates dir if needed and returns a new report file in
+// it named <labelA>_vs_<labelB>_<timestamp>.<ext>, adding -2, -3, ... when a
+// report of that name already exists.
+func historyReportName(dir, labelA, labelB, ext, compress string, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	base := fmt.Sprintf("%s_vs_%s_%s", sanitizeLabel(labelA), sanitizeLabel(labelB), now.Format("20060102-150405"))
+	suffix := "." + ext
+	if compress == "gzip" {
+		suffix += ".gz"
+	}
+	for n := 1; n <= maxReportSuffix; n++ {
+		stem := base
+		if n > 1 {
+			stem = fmt.Sprintf("%s-%d", base, n)
+		}
+		name := filepath.Join(dir, stem+suffix)
+		free, err := historyNameFree(name)
+		if err != nil {
+			return "", err
+		}
+		if free {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("%d reports named %s%s already exist in %s", maxReportSuffix, base, suffix, dir)
+}
+
+// historyNameFree reports whether neither report nor its sidecar exists.
+// Errors other than not existing, such as permission denied, are returned
+// rather than taken as the name being in use.
+func historyNameFree(report string) (bool, error) {
+	for _, name := range []string{report, historySidecar(report)} {
+		_, err := os.Stat(name)
+		if err == nil {
+			return false, nil
+		}
+		if !os.IsNotExist(err) {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// historySidecar returns the sidecar file name of a report.
+func historySidecar(report string) string {
+	report = strings.TrimSuffix(report, ".gz")
+	return strings.TrimSuffix(report, filepath.Ext(report)) + historySuffix
+}
+
+// writeHistory records report in dir and rebuilds the directory's index.
+func writeHistory(dir, report string, created time.Time, s summaryFile, r *Report) error {
+	entry := historyEntry{Report: filepath.Base(report), Created: created, summaryFile: s}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(historySidecar(report), append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return rebuildHistoryIndex(dir, r)
+}
+
+// rebuildHistoryIndex rewrites dir's index from all sidecars in it, newest
+// first. Sidecars that cannot be read are skipped with a warning, and the
+// index is replaced only once the new one is complete.
+func rebuildHistoryIndex(dir string, r *Report) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+historySuffix))
+	if err != nil {
+		return err
+	}
+	var entries []historyEntry
+	for _, f := range files {
+		var e historyEntry
+		data, err := os.ReadFile(f)
+		if err == nil {
+			err = json.Unmarshal(data, &e)
+		}
+		if err == nil && e.Report == "" {
+			err = fmt.Errorf("no report named")
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s in %s: %v\n", filepath.Base(f), historyIndex, err)
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].Created.Equal(entries[j].Created) {
+			return entries[i].Created.After(entries[j].Created)
+		}
+		return entries[i].Report > entries[j].Report
+	})
+
+	index := filepath.Join(dir, historyIndex)
+	tmp := index + ".tmp"
+	tpl, err := parseReportTemplate(r.A, r.B, r.render)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = tpl.ExecuteTemplate(f, "history", map[string]interface{}{"Reports": entries})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, index)
+}
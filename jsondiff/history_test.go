@@ -0,0 +1,80 @@
+package jsondiff
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var historyTime = time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+func TestHistoryReportName(t *testing.T) {
+	dir := t.TempDir()
+	touch := func(name string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	next := func() string {
+		t.Helper()
+		name, err := historyReportName(dir, "a.json", "b.json", "html", "", historyTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return filepath.Base(name)
+	}
+
+	const base = "a.json_vs_b.json_20240301-123000"
+	if got := next(); got != base+".html" {
+		t.Errorf("first report %s", got)
+	}
+	touch(base + ".html")
+	if got := next(); got != base+"-2.html" {
+		t.Errorf("after a report %s", got)
+	}
+	// A sidecar left without its report still takes the name.
+	touch(base + "-2" + historySuffix)
+	if got := next(); got != base+"-3.html" {
+		t.Errorf("after a sidecar %s", got)
+	}
+}
+
+func TestHistoryReportNameStatError(t *testing.T) {
+	// A name too long for the file system fails Stat with ENAMETOOLONG,
+	// which must not be taken as the name being in use.
+	done := make(chan error, 1)
+	go func() {
+		_, err := historyReportName(t.TempDir(), "a", "b", strings.Repeat("x", 300), "", historyTime)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("no error for a name the file system refuses")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("historyReportName did not return")
+	}
+}
+
+func TestHistoryReportNameGivesUp(t *testing.T) {
+	dir := t.TempDir()
+	const base = "a_vs_b_20240301-123000"
+	for n := 1; n <= maxReportSuffix; n++ {
+		name := base + ".json"
+		if n > 1 {
+			name = fmt.Sprintf("%s-%d.json", base, n)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := historyReportName(dir, "a", "b", "json", "", historyTime)
+	if err == nil || !strings.Contains(err.Error(), "already exist") {
+		t.Errorf("error %v, want the suffixes used up", err)
+	}
+}
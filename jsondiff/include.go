@@ -0,0 +1,127 @@
+package jsondiff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/r3labs/diff/v3"
+)
+
+const reasonOutsideInclude = "outside --include"
+
+// includeScope restricts the comparison to the values at and below paths
+// matching one of the --include patterns. Everything else is out of scope:
+// its changes are dropped before any other filter runs, so ignores only
+// subtract from what is included.
+type includeScope struct {
+	// patterns lists the patterns as given, for the report header.
+	patterns []string
+	compiled [][]string
+}
+
+// parseIncludeScope parses the --include values, each a comma-separated
+// list of path patterns in the syntax of matchPath.
+func parseIncludeScope(values []string) (includeScope, error) {
+	var s includeScope
+	for _, v := range values {
+		for _, p := range strings.Split(v, ",") {
+			p = strings.TrimSpace(p)
+			if p == "" {
+				return includeScope{}, fmt.Errorf("invalid --include %q: empty pattern", v)
+			}
+			s.patterns = append(s.patterns, p)
+			s.compiled = append(s.compiled, splitPath(p))
+		}
+	}
+	return s, nil
+}
+
+func (s includeScope) active() bool {
+	return len(s.compiled) > 0
+}
+
+// contains reports whether path is in scope: whether it or one of its
+// ancestors matches a pattern.
+func (s includeScope) contains(path []string) bool {
+	for n := len(path); n >= 0; n-- {
+		for _, p := range s.compiled {
+			if matchPath(p, path[:n]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reaches reports whether anything below path may be in scope.
+func (s includeScope) reaches(path []string) bool {
+	for _, p := range s.compiled {
+		if matchPathPrefix(p, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterInclude keeps the changes in scope. A change to a value that is
+// only partly in scope, such as an added object of which one member is
+// included, is narrowed to the in-scope values it holds; a replaced value
+// is narrowed to the removal and addition of those.
+func filterInclude(changes []diff.Change, scope includeScope, a, b interface{}, suppressed *suppressionLog) []diff.Change {
+	kept := changes[:0:0]
+	for _, c := range changes {
+		path := typedPath(c.Path, a, b)
+		if scope.contains(path) {
+			kept = append(kept, c)
+			continue
+		}
+		var narrowed []diff.Change
+		if scope.reaches(path) {
+			if c.Type != diff.CREATE {
+				narrowed = scope.narrow(narrowed, diff.DELETE, c.Path, path, c.From)
+			}
+			if c.Type != diff.DELETE {
+				narrowed = scope.narrow(narrowed, diff.CREATE, c.Path, path, c.To)
+			}
+		}
+		if len(narrowed) == 0 {
+			suppressed.add(reasonOutsideInclude, c)
+			continue
+		}
+		kept = append(kept, narrowed...)
+	}
+	return kept
+}
+
+// narrow appends to out a change of type t for every value within v that is
+// in scope while its parent is not. raw and path both locate v, raw in the
+// untyped form of diff.Change paths.
+func (s includeScope) narrow(out []diff.Change, t string, raw, path []string, v interface{}) []diff.Change {
+	if s.contains(path) {
+		c := diff.Change{Type: t, Path: raw}
+		if t == diff.DELETE {
+			c.From = v
+		} else {
+			c.To = v
+		}
+		return append(out, c)
+	}
+	if !s.reaches(path) {
+		return out
+	}
+	child := func(rawSeg, seg string, cv interface{}) {
+		out = s.narrow(out, t, append(raw[:len(raw):len(raw)], rawSeg), append(path[:len(path):len(path)], seg), cv)
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for _, k := range sortedKeys(val) {
+			child(k, k, val[k])
+		}
+	case []interface{}:
+		for i, cv := range val {
+			child(strconv.Itoa(i), indexSegment(i), cv)
+		}
+	}
+	return out
+}
@@ -0,0 +1,91 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIncludeScope(t *testing.T) {
+	s, err := parseIncludeScope([]string{"spec.replicas, items[*].name", "meta"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"spec.replicas", "items[*].name", "meta"}; !reflect.DeepEqual(s.patterns, want) {
+		t.Errorf("patterns %q, want %q", s.patterns, want)
+	}
+	tests := []struct {
+		path              string
+		contains, reaches bool
+	}{
+		{"spec.replicas", true, true},
+		{"spec.replicas.x", true, true},
+		{"spec", false, true},
+		{"", false, true},
+		{"spec.image", false, false},
+		{"items[3].name", true, true},
+		{"items[3]", false, true},
+		{"items[3].id", false, false},
+		{"meta.labels.app", true, true},
+	}
+	for _, tt := range tests {
+		path := splitPath(tt.path)
+		if got := s.contains(path); got != tt.contains {
+			t.Errorf("contains(%s) = %t", tt.path, got)
+		}
+		// reaches is only asked of paths out of scope.
+		if got := s.reaches(path); !tt.contains && got != tt.reaches {
+			t.Errorf("reaches(%s) = %t", tt.path, got)
+		}
+	}
+	if _, err := parseIncludeScope([]string{"a,,b"}); err == nil || !strings.Contains(err.Error(), "empty pattern") {
+		t.Errorf("empty pattern: %v", err)
+	}
+}
+
+// TestIncludeCLI checks that only changes in scope are reported, that an
+// added value partly in scope is narrowed to its in-scope members, and that
+// --fail-on sees only the scope.
+func TestIncludeCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"spec": {"replicas": 2, "image": "a"}, "status": {"ready": 1}, "items": [{"id": 1, "name": "x"}]}`,
+		"b.json": `{"spec": {"replicas": 3, "image": "b"}, "status": {"ready": 2}, "items": [{"id": 2, "name": "y"}], "new": {"labels": {"app": "web"}, "other": 1}}`,
+	})
+	res := runCLI(t, dir, "--include", "spec.replicas,items[*].name", "--include", "new.labels", "--show-suppressed", "-f", "json", "-o", "-", "a.json", "b.json")
+	if res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	var report jsonReport
+	if err := json.Unmarshal([]byte(res.stdout), &report); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range report.Sections {
+		for _, c := range s.Changes {
+			got = append(got, c.Type+" "+c.Path)
+		}
+	}
+	if want := []string{"update items[0].name", "create new.labels", "update spec.replicas"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("changes %q, want %q", got, want)
+	}
+	if want := []string{"spec.replicas", "items[*].name", "new.labels"}; !reflect.DeepEqual(report.Include, want) {
+		t.Errorf("include %q, want %q", report.Include, want)
+	}
+	if len(report.Suppressed) != 1 || report.Suppressed[0].Reason != reasonOutsideInclude || report.Suppressed[0].Count != 3 {
+		t.Errorf("suppressed %+v", report.Suppressed)
+	}
+
+	for _, tt := range []struct {
+		include string
+		exit    int
+	}{{"status", exitChangesFound}, {"items[*].missing", exitOK}} {
+		res := runCLI(t, dir, "--include", tt.include, "--fail-on", "changed", "-o", "out.html", "a.json", "b.json")
+		if res.exit != tt.exit {
+			t.Errorf("--include %s: exit %d, want %d: %s", tt.include, res.exit, tt.exit, res.stderr)
+		}
+	}
+	if html := readFile(t, dir, "out.html"); !strings.Contains(html, "The included paths are equivalent") {
+		t.Error("out-of-scope changes were reported")
+	}
+}
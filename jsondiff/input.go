@@ -0,0 +1,612 @@
+package jsondiff
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// parseJSON decodes a single JSON document, annotating syntax and type
+// errors with the line and column at which they occurred.
+func parseJSON(data []byte) (interface{}, error) {
+	var parsed interface{}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, describeJSONError(data, err)
+	}
+	return parsed, nil
+}
+
+// describeJSONError rewrites encoding/json errors that carry a byte offset
+// into "line L, column C: ..." form.
+func describeJSONError(data []byte, err error) error {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return err
+	}
+	line, col := lineColumn(data, offset)
+	lo, hi := max(offset-excerptRadius-1, 0), min(offset+excerptRadius, int64(len(data)))
+	return fmt.Errorf("line %d, column %d: %v%s", line, col, err, excerpt(data[lo:hi], offset-lo, lo > 0, hi < int64(len(data))))
+}
+
+// excerptRadius is how many bytes of context an error excerpt shows on each
+// side of the offending byte, so a minified single-line input does not
+// print megabytes.
+const excerptRadius = 120
+
+// excerpt renders the line around the byte just before offset in window
+// with a caret under it, e.g.
+//
+//	…"name": "x", "size": 12,}
+//	                        ^
+//
+// It stops at line breaks; cutLeft and cutRight report that the window was
+// cut from a longer input, which is marked with an ellipsis.
+func excerpt(window []byte, offset int64, cutLeft, cutRight bool) string {
+	at := int(max(offset-1, 0))
+	if at > len(window) {
+		at = len(window)
+	}
+	start, end := 0, len(window)
+	if i := bytes.LastIndexByte(window[:at], '\n'); i >= 0 {
+		start, cutLeft = i+1, false
+	}
+	if i := bytes.IndexByte(window[at:], '\n'); i >= 0 {
+		end, cutRight = at+i, false
+	}
+	// Drop partial UTF-8 sequences the window cut through.
+	for start < at && !utf8.RuneStart(window[start]) {
+		start++
+	}
+	text := bytes.TrimRight(window[start:end], "\r")
+	for n := 0; cutRight && n < utf8.UTFMax-1 && len(text) > at-start; n++ {
+		if r, size := utf8.DecodeLastRune(text); r != utf8.RuneError || size != 1 {
+			break
+		}
+		text = text[:len(text)-1]
+	}
+	clean := func(b []byte) string {
+		return strings.Map(func(r rune) rune {
+			if r < ' ' || r == utf8.RuneError {
+				return ' '
+			}
+			return r
+		}, string(b))
+	}
+	prefix := ""
+	if cutLeft {
+		prefix = "…"
+	}
+	line := prefix + clean(text)
+	if cutRight {
+		line += "…"
+	}
+	caret := utf8.RuneCountInString(prefix) + utf8.RuneCountInString(clean(window[start:min(at, start+len(text))]))
+	return "\n  " + line + "\n  " + strings.Repeat(" ", caret) + "^"
+}
+
+// excerptInFile reads the window around offset from filename and renders it
+// with excerpt.
+func excerptInFile(filename string, offset int64) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	lo, hi := max(offset-excerptRadius-1, 0), min(offset+excerptRadius, info.Size())
+	if hi < lo {
+		return "", nil
+	}
+	window := make([]byte, hi-lo)
+	if _, err := f.ReadAt(window, lo); err != nil && err != io.EOF {
+		return "", err
+	}
+	return excerpt(window, offset-lo, lo > 0, hi < info.Size()), nil
+}
+
+// lineColumn converts a byte offset into 1-based line and column numbers.
+// encoding/json reports the offset just past the offending byte, so the
+// column points at that byte.
+func lineColumn(data []byte, offset int64) (line, col int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	prefix := data[:offset]
+	line = bytes.Count(prefix, []byte("\n")) + 1
+	col = len(prefix) - bytes.LastIndexByte(prefix, '\n') - 1
+	if col < 1 {
+		col = 1
+	}
+	return line, col
+}
+
+// inputLimits bounds the resources a single input document may consume.
+type inputLimits struct {
+	maxBytes       int64
+	maxDepth       int
+	maxArrayLength int
+}
+
+var defaultInputLimits = inputLimits{
+	maxBytes:       512 << 20,
+	maxDepth:       1000,
+	maxArrayLength: 10000000,
+}
+
+// errInputTooLarge is returned when an input exceeds inputLimits.maxBytes.
+var errInputTooLarge = errors.New("input exceeds --max-input-size")
+
+// readJSONFile streams a JSON document from filename, enforcing limits
+// before and while reading so oversized inputs fail without being held in
+// memory. Errors carry the line and column where decoding stopped.
+func readJSONFile(filename string, limits inputLimits) (interface{}, error) {
+	v, _, err := readJSON(filename, limits, decodeOptions{})
+	return v, err
+}
+
+// decodeOptions controls what readJSON keeps beyond the document itself.
+type decodeOptions struct {
+	// positions records the source position of every value, keyed by path.
+	positions bool
+	// exactNumbers keeps numbers as their json.Number literals instead of
+	// converting them to float64.
+	exactNumbers bool
+	// warn, when set, is told about numbers that float64 cannot represent
+	// exactly.
+	warn func(string)
+	// inputFormat is one of inputFormats; the stream formats decode every
+	// top-level value and wrap them in an array.
+	inputFormat string
+}
+
+// Input formats accepted by --input-format.
+const (
+	inputJSON    = "json"
+	inputJSONSeq = "json-seq" // RFC 7464: values prefixed by a record separator
+	inputConcat  = "concat"   // values back to back or separated by whitespace
+)
+
+func parseInputFormat(name string) error {
+	switch name {
+	case inputJSON, inputJSONSeq, inputConcat:
+		return nil
+	}
+	return fmt.Errorf("invalid --input-format %q: must be json, json-seq or concat", name)
+}
+
+// recordSeparator starts every value of an RFC 7464 JSON text sequence.
+const recordSeparator = 0x1E
+
+// rsReader turns record separators into spaces, which encoding/json skips,
+// keeping offsets and line numbers intact.
+type rsReader struct{ r io.Reader }
+
+func (s rsReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	for i := range p[:n] {
+		if p[i] == recordSeparator {
+			p[i] = ' '
+		}
+	}
+	return n, err
+}
+
+func readJSON(filename string, limits inputLimits, opts decodeOptions) (interface{}, map[string]Position, error) {
+	trackPositions := opts.positions
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	if info, err := f.Stat(); err == nil && info.Mode().IsRegular() && limits.maxBytes > 0 && info.Size() > limits.maxBytes {
+		return nil, nil, fmt.Errorf("%w: file is %s, limit is %s", errInputTooLarge, formatByteSize(info.Size()), formatByteSize(limits.maxBytes))
+	}
+
+	// Refuse binary files up front with a useful message rather than the
+	// decoder's complaint about their first byte.
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, nil, err
+	}
+	if err := sniffBinary(head[:n]); err != nil {
+		return nil, nil, err
+	}
+	var r io.Reader = io.MultiReader(bytes.NewReader(head[:n]), f)
+	if limits.maxBytes > 0 {
+		r = &limitedReader{r: r, remaining: limits.maxBytes, limit: limits.maxBytes}
+	}
+	if opts.inputFormat == inputJSONSeq {
+		r = rsReader{r}
+	}
+	// Positions are resolved against the document in memory, so tracking
+	// them costs a copy of the input instead of a second read of it.
+	var src *sourceIndex
+	if trackPositions {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		src = newSourceIndex(data)
+		r = bytes.NewReader(data)
+	}
+	d := &streamDecoder{dec: json.NewDecoder(r), limits: limits, exactNumbers: opts.exactNumbers}
+	d.dec.UseNumber()
+	if trackPositions {
+		d.offsets = make(map[string]int64)
+	}
+	decode := d.decode
+	if opts.inputFormat == inputJSONSeq || opts.inputFormat == inputConcat {
+		decode = d.decodeSequence
+	}
+	v, offset, err := decode()
+	if err != nil {
+		if errors.Is(err, errInputTooLarge) {
+			return nil, nil, err
+		}
+		if line, col, lerr := lineColumnInFile(filename, offset); lerr == nil {
+			context, _ := excerptInFile(filename, offset)
+			return nil, nil, fmt.Errorf("line %d, column %d: %v%s", line, col, err, context)
+		}
+		return nil, nil, err
+	}
+	if d.lossy > 0 && opts.warn != nil {
+		opts.warn(fmt.Sprintf("%d number(s) such as %s are not exactly representable as float64 and are compared approximately; use --decimal-strict to compare them exactly", d.lossy, d.lossyExample))
+	}
+	if !trackPositions {
+		return v, nil, nil
+	}
+	return v, resolvePositions(src, d.offsets), nil
+}
+
+// limitedReader fails with errInputTooLarge once more than limit bytes have
+// been read, rather than silently truncating like io.LimitReader.
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	limit     int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, fmt.Errorf("%w: limit is %s", errInputTooLarge, formatByteSize(l.limit))
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, fmt.Errorf("%w: limit is %s", errInputTooLarge, formatByteSize(l.limit))
+	}
+	return n, err
+}
+
+// streamDecoder builds a document from a json.Decoder token stream,
+// enforcing the nesting and array length limits as it goes.
+type streamDecoder struct {
+	dec    *json.Decoder
+	limits inputLimits
+
+	// offsets, when non-nil, receives for every path the input offset
+	// preceding its value (before any whitespace or separator).
+	offsets map[string]int64
+
+	// exactNumbers keeps json.Number values; otherwise they become float64
+	// and lossy counts the literals that do not survive the conversion.
+	exactNumbers bool
+	lossy        int
+	lossyExample string
+}
+
+// decode reads one document and requires it to be the whole input. On
+// failure it also returns the input offset at which decoding stopped.
+func (d *streamDecoder) decode() (interface{}, int64, error) {
+	v, err := d.value("", 0)
+	if err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, syntaxErr.Offset, err
+		}
+		return nil, d.dec.InputOffset(), err
+	}
+	if _, err := d.dec.Token(); err != io.EOF {
+		if err == nil {
+			err = errors.New("unexpected data after top-level value")
+		}
+		return nil, d.dec.InputOffset(), err
+	}
+	return v, 0, nil
+}
+
+// decodeSequence reads top-level values until the end of the input and
+// returns them as an array, so two streams compare element by element.
+func (d *streamDecoder) decodeSequence() (interface{}, int64, error) {
+	values := make([]interface{}, 0)
+	if d.offsets != nil {
+		d.offsets[""] = 0
+	}
+	for d.dec.More() {
+		if d.limits.maxArrayLength > 0 && len(values) >= d.limits.maxArrayLength {
+			return nil, d.dec.InputOffset(), fmt.Errorf("more than %d values (see --max-array-length)", d.limits.maxArrayLength)
+		}
+		start := d.dec.InputOffset()
+		v, err := d.value(indexKey("", len(values)), 1)
+		if err != nil {
+			offset := d.dec.InputOffset()
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				offset = syntaxErr.Offset
+			}
+			return nil, offset, fmt.Errorf("value %d after offset %d: %w", len(values)+1, start, err)
+		}
+		values = append(values, v)
+	}
+	// More also stops before a stray '}' or ']'.
+	if _, err := d.dec.Token(); err != io.EOF {
+		offset := d.dec.InputOffset()
+		return nil, offset, fmt.Errorf("unexpected data at offset %d after value %d", offset, len(values))
+	}
+	return values, 0, nil
+}
+
+func (d *streamDecoder) value(path string, depth int) (interface{}, error) {
+	if d.offsets != nil {
+		d.offsets[path] = d.dec.InputOffset()
+	}
+	tok, err := d.dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if n, ok := tok.(json.Number); ok {
+		return d.number(n)
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+	if d.limits.maxDepth > 0 && depth >= d.limits.maxDepth {
+		return nil, fmt.Errorf("nesting deeper than %d levels (see --max-depth)", d.limits.maxDepth)
+	}
+
+	switch delim {
+	case '{':
+		obj := make(map[string]interface{})
+		for d.dec.More() {
+			keyTok, err := d.dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := keyTok.(string)
+			v, err := d.value(pathKey(path, key), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			obj[key] = v
+		}
+		if _, err := d.dec.Token(); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	case '[':
+		arr := make([]interface{}, 0)
+		for d.dec.More() {
+			if d.limits.maxArrayLength > 0 && len(arr) >= d.limits.maxArrayLength {
+				return nil, fmt.Errorf("array longer than %d elements (see --max-array-length)", d.limits.maxArrayLength)
+			}
+			v, err := d.value(indexKey(path, len(arr)), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		if _, err := d.dec.Token(); err != nil {
+			return nil, err
+		}
+		return arr, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", delim)
+}
+
+// number converts a number token to float64 unless exactNumbers is set,
+// noting literals whose value the conversion changes. Numbers float64
+// cannot hold stay json.Number.
+func (d *streamDecoder) number(n json.Number) (interface{}, error) {
+	if d.exactNumbers {
+		return n, nil
+	}
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil {
+		// Beyond the range of float64: keep the exact value, in canonical
+		// form so that equal values written differently compare equal.
+		return json.Number(canonicalDecimal(string(n))), nil
+	}
+	if canonicalDecimal(strconv.FormatFloat(f, 'g', -1, 64)) != canonicalDecimal(string(n)) {
+		if d.lossy == 0 {
+			d.lossyExample = string(n)
+		}
+		d.lossy++
+	}
+	return f, nil
+}
+
+// sourceIndex maps byte offsets of a document held in memory to source
+// positions.
+type sourceIndex struct {
+	data       []byte
+	lineStarts []int64
+}
+
+func newSourceIndex(data []byte) *sourceIndex {
+	s := &sourceIndex{data: data}
+	for i, b := range data {
+		if b == '\n' {
+			s.lineStarts = append(s.lineStarts, int64(i+1))
+		}
+	}
+	return s
+}
+
+// position returns the position of the first byte at or after off that is
+// not whitespace or a separator, which is where a value recorded at the
+// decoder's offset before its token starts.
+func (s *sourceIndex) position(off int64) Position {
+	for off < int64(len(s.data)) && isSeparatorOrSpace(s.data[off]) {
+		off++
+	}
+	line := sort.Search(len(s.lineStarts), func(i int) bool { return s.lineStarts[i] > off })
+	lineStart := int64(0)
+	if line > 0 {
+		lineStart = s.lineStarts[line-1]
+	}
+	return Position{Line: line + 1, Column: int(off-lineStart) + 1, Offset: off}
+}
+
+// resolvePositions converts the offsets recorded by streamDecoder into
+// positions in src.
+func resolvePositions(src *sourceIndex, offsets map[string]int64) map[string]Position {
+	positions := make(map[string]Position, len(offsets))
+	for p, off := range offsets {
+		positions[p] = src.position(off)
+	}
+	return positions
+}
+
+func isSeparatorOrSpace(b byte) bool {
+	switch b {
+	case ' ', '\t', '\r', '\n', ',', ':', recordSeparator:
+		return true
+	}
+	return false
+}
+
+// lineColumnInFile converts a byte offset into line and column numbers by
+// rescanning the file, so the document never has to be held in memory.
+func lineColumnInFile(filename string, offset int64) (line, col int, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	line, col = 1, 1
+	br := bufio.NewReader(io.LimitReader(f, offset))
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		if b == '\n' {
+			line, col = line+1, 1
+		} else {
+			col++
+		}
+	}
+	// encoding/json reports the offset just past the offending byte.
+	if col > 1 {
+		col--
+	}
+	return line, col, nil
+}
+
+// parseByteSize parses sizes such as "512MB", "1GiB", "4096" or "10k".
+func parseByteSize(s string) (int64, error) {
+	t := strings.ToUpper(strings.TrimSpace(s))
+	multipliers := []struct {
+		suffix string
+		mult   int64
+	}{
+		{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+		{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30},
+		{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"B", 1},
+	}
+	mult := int64(1)
+	for _, m := range multipliers {
+		if strings.HasSuffix(t, m.suffix) {
+			t, mult = strings.TrimSpace(strings.TrimSuffix(t, m.suffix)), m.mult
+			break
+		}
+	}
+	n, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * mult, nil
+}
+
+func formatByteSize(n int64) string {
+	switch {
+	case n >= 1<<30 && n%(1<<30) == 0:
+		return fmt.Sprintf("%dGB", n>>30)
+	case n >= 1<<20 && n%(1<<20) == 0:
+		return fmt.Sprintf("%dMB", n>>20)
+	case n >= 1<<10 && n%(1<<10) == 0:
+		return fmt.Sprintf("%dKB", n>>10)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+// annotateLines sets the source line and byte offset of each change's old
+// and new value.
+// pathInA translates a compared path to its path in file1.
+func annotateLines(results []DiffResult, positionsA, positionsB map[string]Position, pathInA func(string) string) {
+	for i := range results {
+		r := &results[i]
+		if r.Type != "create" {
+			if pos, ok := positionsA[pathInA(r.Path)]; ok {
+				r.FromLine, r.FromOffset = pos.Line, pos.Offset
+			}
+		}
+		if r.Type != "delete" {
+			if pos, ok := positionsB[r.Path]; ok {
+				r.ToLine, r.ToOffset = pos.Line, pos.Offset
+			}
+		}
+	}
+}
+
+// rebasePositions keeps the positions at or below root, with root stripped
+// from their paths, so they line up with a subtree extracted by --path-a or
+// --path-b.
+func rebasePositions(positions map[string]Position, root string) map[string]Position {
+	if positions == nil {
+		return nil
+	}
+	out := make(map[string]Position)
+	for p, pos := range positions {
+		switch {
+		case p == root:
+			out[""] = pos
+		case strings.HasPrefix(p, root+"."):
+			out[p[len(root)+1:]] = pos
+		case strings.HasPrefix(p, root+"["):
+			out[p[len(root):]] = pos
+		}
+	}
+	return out
+}
@@ -0,0 +1,374 @@
+package jsondiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+const positionsDoc = `{
+  "name": "svc",
+  "ports": [80,
+    443],
+  "spec": {
+    "multi": [
+      1,
+      2
+    ]
+  }
+}
+`
+
+func TestReadJSONPositions(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "a.json")
+	if err := os.WriteFile(filename, []byte(positionsDoc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, positions, err := readJSON(filename, defaultInputLimits, decodeOptions{positions: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Position{
+		"":              {Line: 1, Column: 1, Offset: 0},
+		"name":          {Line: 2, Column: 11, Offset: 12},
+		"ports":         {Line: 3, Column: 12, Offset: 30},
+		"ports[0]":      {Line: 3, Column: 13, Offset: 31},
+		"ports[1]":      {Line: 4, Column: 5, Offset: 39},
+		"spec":          {Line: 5, Column: 11, Offset: 55},
+		"spec.multi":    {Line: 6, Column: 14, Offset: 70},
+		"spec.multi[1]": {Line: 8, Column: 7, Offset: 87},
+	}
+	for path, w := range want {
+		if got := positions[path]; got != w {
+			t.Errorf("position of %q = %+v, want %+v", path, got, w)
+		}
+	}
+
+	// The Node parser shares the position helper, so both agree.
+	root, err := ParseNode([]byte(positionsDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	walkNodes(root, func(n *Node) {
+		if got, w := n.Pos, positions[joinPath(n.Path())]; got != w {
+			t.Errorf("ParseNode position of %q = %+v, readJSON has %+v", joinPath(n.Path()), got, w)
+		}
+	})
+}
+
+func TestReadJSONWithoutPositions(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "a.json")
+	if err := os.WriteFile(filename, []byte(positionsDoc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, positions, err := readJSON(filename, defaultInputLimits, decodeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if positions != nil {
+		t.Errorf("positions recorded without being asked for: %d", len(positions))
+	}
+}
+
+func walkNodes(n *Node, fn func(*Node)) {
+	fn(n)
+	for _, c := range n.Children {
+		walkNodes(c, fn)
+	}
+}
+
+// TestReadJSONLimitBoundaries checks each limit at its value, which is
+// allowed, and one past it, which is refused.
+func TestReadJSONLimitBoundaries(t *testing.T) {
+	nested := func(depth int) string { return strings.Repeat("[", depth) + strings.Repeat("]", depth) }
+	array := func(n int) string { return "[" + strings.TrimSuffix(strings.Repeat("0,", n), ",") + "]" }
+	tests := []struct {
+		name   string
+		doc    string
+		limits inputLimits
+		want   string
+	}{
+		{"size at limit", `"abcdef"`, inputLimits{maxBytes: 8}, ""},
+		{"size over limit", `"abcdefg"`, inputLimits{maxBytes: 8}, "input exceeds --max-input-size: file is 9 bytes, limit is 8 bytes"},
+		{"depth at limit", nested(4), inputLimits{maxDepth: 4}, ""},
+		{"depth over limit", nested(5), inputLimits{maxDepth: 4}, "nesting deeper than 4 levels"},
+		{"array at limit", array(5), inputLimits{maxArrayLength: 5}, ""},
+		{"array over limit", array(6), inputLimits{maxArrayLength: 5}, "array longer than 5 elements"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readJSONFile(writeInput(t, []byte(tt.doc)), tt.limits)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("error %v at the limit", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestLimitedReaderBoundary checks the limit on streams, whose size is not
+// known up front.
+func TestLimitedReaderBoundary(t *testing.T) {
+	for _, tt := range []struct {
+		size int
+		fail bool
+	}{{7, false}, {8, false}, {9, true}} {
+		r := &limitedReader{r: strings.NewReader(strings.Repeat("x", tt.size)), remaining: 8, limit: 8}
+		data, err := io.ReadAll(r)
+		if fail := errors.Is(err, errInputTooLarge); fail != tt.fail {
+			t.Errorf("%d bytes: error %v", tt.size, err)
+		}
+		if len(data) > 9 {
+			t.Errorf("%d bytes: read %d past the limit", tt.size, len(data))
+		}
+	}
+}
+
+// endlessString is a JSON string that never ends.
+type endlessString struct{ started bool }
+
+func (r *endlessString) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'a'
+	}
+	if !r.started && len(p) > 0 {
+		p[0], r.started = '"', true
+	}
+	return len(p), nil
+}
+
+// TestDecodeStreamBoundedMemory checks that a stream is refused once it
+// passes the limit, holding no more than about the limit in memory.
+func TestDecodeStreamBoundedMemory(t *testing.T) {
+	const limit = 1 << 20
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	d := &streamDecoder{
+		dec:    json.NewDecoder(&limitedReader{r: &endlessString{}, remaining: limit, limit: limit}),
+		limits: inputLimits{maxBytes: limit},
+	}
+	_, _, err := d.decode()
+	runtime.ReadMemStats(&after)
+	if !errors.Is(err, errInputTooLarge) {
+		t.Fatalf("error %v, want errInputTooLarge", err)
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 8*limit {
+		t.Errorf("allocated %s for a %s limit", formatByteSize(int64(alloc)), formatByteSize(limit))
+	}
+}
+
+// minifiedDoc returns a single-line object of about size bytes whose
+// values hold multibyte runes, with extra spliced in halfway.
+func minifiedDoc(size int, extra string) []byte {
+	var b strings.Builder
+	b.WriteString("{")
+	for i := 0; b.Len() < size; i++ {
+		if extra != "" && b.Len() >= size/2 {
+			b.WriteString(extra)
+			extra = ""
+		}
+		fmt.Fprintf(&b, `"k%07d":"café 日本 𝄞 %d",`, i, i)
+	}
+	b.WriteString(`"last":"end"}`)
+	return []byte(b.String())
+}
+
+// TestParseErrorExcerptSingleLine checks the excerpt of an error in the
+// middle of a multi-megabyte single-line input: a window of the line
+// around the error, whole runes only, with the caret under the error.
+func TestParseErrorExcerptSingleLine(t *testing.T) {
+	const bad = `"bad":,`
+	data := minifiedDoc(4<<20, bad)
+	at := bytes.Index(data, []byte(bad)) + len(`"bad":`)
+
+	_, fromFile := readJSONFile(writeInput(t, data), defaultInputLimits)
+	_, fromMemory := parseJSON(data)
+	for _, err := range []error{fromFile, fromMemory} {
+		if err == nil {
+			t.Fatal("no error")
+		}
+		msg := err.Error()
+		if want := fmt.Sprintf("line 1, column %d: ", at+1); !strings.HasPrefix(msg, want) {
+			t.Errorf("error %.80s, want it to start %q", msg, want)
+		}
+		lines := strings.Split(msg, "\n")
+		if len(lines) != 3 {
+			t.Fatalf("error has %d lines, want the message, excerpt and caret", len(lines))
+		}
+		line, caret := []rune(strings.TrimPrefix(lines[1], "  ")), strings.TrimPrefix(lines[2], "  ")
+		if !utf8.ValidString(lines[1]) || strings.ContainsRune(lines[1], utf8.RuneError) {
+			t.Errorf("excerpt splits a rune: %q", lines[1])
+		}
+		if len(line) > 2*excerptRadius+2 || line[0] != '…' || line[len(line)-1] != '…' {
+			t.Errorf("excerpt is not a window of the line: %q", lines[1])
+		}
+		if i := len(caret) - 1; caret[i] != '^' || line[i] != ',' {
+			t.Errorf("caret under %q, want it under the comma:\n%s\n%s", line[i], lines[1], lines[2])
+		}
+	}
+}
+
+// TestReadJSONPositionsSingleLine checks the offsets recorded for values of
+// a multi-megabyte single-line input, which line numbers cannot tell apart.
+func TestReadJSONPositionsSingleLine(t *testing.T) {
+	data := minifiedDoc(4<<20, "")
+	_, positions, err := readJSON(writeInput(t, data), defaultInputLimits, decodeOptions{positions: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := int64(bytes.LastIndex(data, []byte(`"end"`)))
+	if got, want := positions["last"], (Position{Line: 1, Column: int(last) + 1, Offset: last}); got != want {
+		t.Errorf("position of last %+v, want %+v", got, want)
+	}
+	if got := sourceLocation("a.json", 1, last); got != fmt.Sprintf("a.json:1 (offset %d)", last) {
+		t.Errorf("location %q", got)
+	}
+	if got := sourceLocation("a.json", 3, 40); got != "a.json:3" {
+		t.Errorf("location %q", got)
+	}
+}
+
+func TestRebasePositions(t *testing.T) {
+	positions := map[string]Position{
+		"spec":             {Line: 2},
+		"spec.items":       {Line: 3},
+		"spec.items[0]":    {Line: 4},
+		"spec.items[0].id": {Line: 5},
+		"specs":            {Line: 9},
+		"other":            {Line: 10},
+	}
+	got := rebasePositions(positions, "spec.items")
+	want := map[string]Position{"": {Line: 3}, "[0]": {Line: 4}, "[0].id": {Line: 5}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := rebasePositions(nil, "spec"); got != nil {
+		t.Errorf("nil positions: %v", got)
+	}
+}
+
+// TestSubtreeCLI compares subtrees at different paths of the two files and
+// checks that changes are reported relative to them, with the line numbers
+// of the files they came from.
+func TestSubtreeCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": "{\n  \"envs\": {\n    \"prod\": {\n      \"replicas\": 3,\n      \"image\": \"web\"\n    }\n  }\n}",
+		"b.json": "{\"spec\": {\"template\": [\n  {\"image\": \"web\",\n   \"replicas\": 2}\n]}}",
+	})
+	res := runCLI(t, dir, "-f", "json", "-o", "-", "--path-a", "envs.prod", "--path-b", "spec.template[0]", "a.json", "b.json")
+	if res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	var report jsonReport
+	if err := json.Unmarshal([]byte(res.stdout), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Total != 1 || report.Sections[0].Changes[0].Path != "replicas" {
+		t.Fatalf("changes %+v", report.Sections)
+	}
+	if report.Inputs[0].Label != "a.json#envs.prod" || report.Inputs[1].Label != "b.json#spec.template[0]" {
+		t.Errorf("labels %q, %q", report.Inputs[0].Label, report.Inputs[1].Label)
+	}
+
+	res = runCLI(t, dir, "-o", "out.html", "--line-numbers", "--path-a", "envs.prod", "--path-b", "spec.template[0]", "a.json", "b.json")
+	if res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	html := readFile(t, dir, "out.html")
+	for id, line := range map[string]string{"tree-a-replicas": "4", "tree-b-replicas": "3"} {
+		item := regexp.MustCompile(`<li id="` + id + `"[^>]*>`).FindString(html)
+		if !strings.Contains(item, ` data-line="`+line+`"`) {
+			t.Errorf("%s: %q, want line %s", id, item, line)
+		}
+	}
+
+	res = runCLI(t, dir, "-o", "out.html", "--path-a", "envs.dev", "a.json", "b.json")
+	if res.exit != exitError || !strings.Contains(res.stderr, "Path envs.dev not found in a.json") {
+		t.Errorf("missing path: exit %d: %s", res.exit, res.stderr)
+	}
+}
+
+func TestReadJSONSequences(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name, format, data string
+		want               interface{}
+		// err is a substring of the error, or "" for none.
+		err string
+	}{
+		{"json-seq", inputJSONSeq, "\x1e{\"a\": 1}\n\x1e[2]\n\x1e\"s\"\n", []interface{}{map[string]interface{}{"a": 1.0}, []interface{}{2.0}, "s"}, ""},
+		{"concat", inputConcat, `1 2{"a":null}[]"x"`, []interface{}{1.0, 2.0, map[string]interface{}{"a": nil}, []interface{}{}, "x"}, ""},
+		{"empty stream", inputConcat, " \n", []interface{}{}, ""},
+		{"stray bracket", inputConcat, `{"a": 1} ]`, nil, "unexpected data at offset 9 after value 1"},
+		{"bad value", inputJSONSeq, "\x1e1\n\x1e{\"a\" 1}\n", nil, "value 2 after offset"},
+		{"too many values", inputConcat, "1 2 3 4 5 6", nil, "more than 5 values"},
+		{"plain json", inputJSON, `1 2`, nil, "unexpected data after top-level value"},
+	}
+	limits := defaultInputLimits
+	limits.maxArrayLength = 5
+	for _, tt := range tests {
+		filename := filepath.Join(dir, tt.name)
+		if err := os.WriteFile(filename, []byte(tt.data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, _, err := readJSON(filename, limits, decodeOptions{inputFormat: tt.format})
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		} else if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Positions count the record separators as characters.
+	filename := filepath.Join(dir, "positions")
+	if err := os.WriteFile(filename, []byte("\x1e{\"a\": 1}\n\x1e{\"a\": 2}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, positions, err := readJSON(filename, defaultInputLimits, decodeOptions{inputFormat: inputJSONSeq, positions: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := positions["[1].a"], (Position{Line: 2, Column: 8, Offset: 17}); got != want {
+		t.Errorf("position of [1].a = %+v, want %+v", got, want)
+	}
+}
+
+// TestSequenceCLI compares two JSON text sequences element by element.
+func TestSequenceCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.seq": "\x1e{\"id\": 1, \"v\": \"x\"}\n\x1e{\"id\": 2, \"v\": \"y\"}\n",
+		"b.seq": "\x1e{\"id\": 1, \"v\": \"x\"}\n\x1e{\"id\": 2, \"v\": \"z\"}\n\x1e{\"id\": 3}\n",
+	})
+	res := runCLI(t, dir, "--input-format", "json-seq", "-f", "jsonl", "-o", "-", "a.seq", "b.seq")
+	if res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	want := `{"path":"[1].v","pointer":"/1/v","status":"changed","a":"y","b":"z"}` + "\n" +
+		`{"path":"[2].id","pointer":"/2/id","status":"added","b":3}` + "\n"
+	if res.stdout != want {
+		t.Errorf("got\n%swant\n%s", res.stdout, want)
+	}
+	if res := runCLI(t, dir, "-o", "out.html", "a.seq", "b.seq"); res.exit != exitError {
+		t.Errorf("sequences read as JSON: exit %d", res.exit)
+	}
+}
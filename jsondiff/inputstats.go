@@ -0,0 +1,95 @@
+package jsondiff
+
+import "fmt"
+
+// InputStats describes the shape of one input document, shown side by side
+// in the report header so that gross structural differences stand out.
+type InputStats struct {
+	Label string `json:"label"`
+	Bytes int64  `json:"bytes"`
+	// Encoding names the detected encoding, such as "UTF-8" or "UTF-16LE
+	// with BOM"; anything else is transcoded to UTF-8 before parsing.
+	Encoding string `json:"encoding"`
+	// SHA256 hashes the document re-encoded with sorted keys; only the
+	// JSON report sets it.
+	SHA256 string `json:"sha256,omitempty"`
+	// TopLevel counts the members of a root object or the elements of a
+	// root array.
+	TopLevel int `json:"topLevel"`
+	// Leaves counts the scalar values, including empty objects and arrays.
+	// How long parsing took is left to the --verbose log, so that the same
+	// inputs always render the same report.
+	Leaves   int `json:"leaves"`
+	MaxDepth int `json:"maxDepth"`
+}
+
+// documentStats walks v once, collecting the structural counts of
+// InputStats.
+func documentStats(v interface{}) InputStats {
+	var s InputStats
+	switch val := v.(type) {
+	case map[string]interface{}:
+		s.TopLevel = len(val)
+	case []interface{}:
+		s.TopLevel = len(val)
+	}
+	var walk func(v interface{}, depth int)
+	walk = func(v interface{}, depth int) {
+		s.MaxDepth = max(s.MaxDepth, depth)
+		switch val := v.(type) {
+		case map[string]interface{}:
+			if len(val) == 0 {
+				s.Leaves++
+			}
+			for _, child := range val {
+				walk(child, depth+1)
+			}
+		case []interface{}:
+			if len(val) == 0 {
+				s.Leaves++
+			}
+			for _, child := range val {
+				walk(child, depth+1)
+			}
+		default:
+			s.Leaves++
+		}
+	}
+	walk(v, 0)
+	return s
+}
+
+// ByteSize formats Bytes for display, e.g. "1.5 MiB".
+func (s InputStats) ByteSize() string {
+	return formatByteSize(s.Bytes)
+}
+
+// sizeMismatchRatio is how many times larger one input may be than the
+// other before the report warns that the wrong file may have been passed.
+const sizeMismatchRatio = 10
+
+// sizeMismatch returns a warning when the inputs differ in byte size or leaf
+// count by more than sizeMismatchRatio, or "" when they are comparable.
+func sizeMismatch(stats [2]InputStats) string {
+	a, b := stats[0], stats[1]
+	switch {
+	case exceedsRatio(int64(a.Leaves), int64(b.Leaves)):
+		return fmt.Sprintf("%s has %d leaves but %s has %d; check that the right files were compared", a.Label, a.Leaves, b.Label, b.Leaves)
+	case exceedsRatio(a.Bytes, b.Bytes):
+		return fmt.Sprintf("%s is %s but %s is %s; check that the right files were compared", a.Label, a.ByteSize(), b.Label, b.ByteSize())
+	}
+	return ""
+}
+
+func exceedsRatio(x, y int64) bool {
+	if x > y {
+		x, y = y, x
+	}
+	return y > sizeMismatchRatio*max(x, 1)
+}
+
+// SizeWarning returns the warning shown when the inputs differ greatly in
+// size, or "".
+func (c *ReportContext) SizeWarning() string {
+	return sizeMismatch(c.InputStats)
+}
@@ -0,0 +1,77 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDocumentStats(t *testing.T) {
+	tests := []struct {
+		doc                        string
+		topLevel, leaves, maxDepth int
+	}{
+		{`{"a": 1, "b": {"c": [1, 2, {}]}, "d": []}`, 3, 5, 3},
+		{`[[], [[1]]]`, 2, 2, 3},
+		{`{}`, 0, 1, 0},
+		{`"s"`, 0, 1, 0},
+	}
+	for _, tt := range tests {
+		var v interface{}
+		if err := json.Unmarshal([]byte(tt.doc), &v); err != nil {
+			t.Fatal(err)
+		}
+		s := documentStats(v)
+		if s.TopLevel != tt.topLevel || s.Leaves != tt.leaves || s.MaxDepth != tt.maxDepth {
+			t.Errorf("%s: top level %d, leaves %d, depth %d", tt.doc, s.TopLevel, s.Leaves, s.MaxDepth)
+		}
+	}
+}
+
+func TestSizeMismatch(t *testing.T) {
+	tests := []struct {
+		a, b InputStats
+		want string
+	}{
+		{InputStats{Label: "a", Bytes: 100, Leaves: 10}, InputStats{Label: "b", Bytes: 1000, Leaves: 100}, ""},
+		{InputStats{Label: "a", Bytes: 100, Leaves: 10}, InputStats{Label: "b", Bytes: 150, Leaves: 101}, "a has 10 leaves but b has 101"},
+		{InputStats{Label: "a", Bytes: 2048, Leaves: 1}, InputStats{Label: "b", Bytes: 100, Leaves: 1}, "a is 2KB but b is 100 bytes"},
+		{InputStats{Label: "a", Leaves: 0}, InputStats{Label: "b", Leaves: 10}, ""},
+		{InputStats{Label: "a", Leaves: 0}, InputStats{Label: "b", Leaves: 11}, "a has 0 leaves"},
+	}
+	for _, tt := range tests {
+		got := sizeMismatch([2]InputStats{tt.a, tt.b})
+		if tt.want == "" && got != "" || !strings.Contains(got, tt.want) {
+			t.Errorf("%+v, %+v: %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestInputStatsCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"a": 1}`,
+		"b.json": `{"a": [1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11]}`,
+	})
+	res := runCLI(t, dir, "-f", "json", "-o", "-", "a.json", "b.json")
+	if res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	var report jsonReport
+	if err := json.Unmarshal([]byte(res.stdout), &report); err != nil {
+		t.Fatal(err)
+	}
+	a, b := report.Inputs[0], report.Inputs[1]
+	if a.Label != "a.json" || a.Bytes != 8 || a.Encoding != "UTF-8" || a.Leaves != 1 || b.Leaves != 11 || b.MaxDepth != 2 {
+		t.Errorf("inputs %+v, %+v", a, b)
+	}
+	if len(a.SHA256) != 64 || a.SHA256 == b.SHA256 {
+		t.Errorf("hashes %q, %q", a.SHA256, b.SHA256)
+	}
+
+	if res := runCLI(t, dir, "-o", "out.html", "a.json", "b.json"); res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	if html := readFile(t, dir, "out.html"); !strings.Contains(html, "a.json has 1 leaves but b.json has 11") {
+		t.Error("no size warning")
+	}
+}
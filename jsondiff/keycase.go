@@ -0,0 +1,157 @@
+package jsondiff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/r3labs/diff/v3"
+)
+
+// keyFolds records the object members of document A that --ignore-key-case
+// paired with a differently cased member of document B. Both maps are keyed
+// by the compared path of the parent object joined with a key: toB maps A's
+// key to the B casing used for comparison, toA maps it back.
+type keyFolds struct {
+	toB map[string]string
+	toA map[string]string
+}
+
+// foldKeyCase returns a copy of a in which object keys that match a key of
+// the corresponding object in b only case-insensitively are renamed to b's
+// casing. Keys that exist in both casings within one object are a genuine
+// conflict: they are left alone and reported through warn.
+func foldKeyCase(a, b interface{}, warn func(string)) (interface{}, *keyFolds) {
+	f := &keyFolds{toB: make(map[string]string), toA: make(map[string]string)}
+	return f.fold(a, b, "", warn), f
+}
+
+func (f *keyFolds) fold(a, b interface{}, path string, warn func(string)) interface{} {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, _ := b.(map[string]interface{})
+		conflictsA := caseConflicts(av)
+		conflictsB := caseConflicts(bv)
+		for _, lower := range sortedStrings(conflictsA) {
+			warn(fmt.Sprintf("--ignore-key-case: %s in %s has keys differing only in case: %s", describePath(path), "file1", strings.Join(conflictsA[lower], ", ")))
+		}
+		for _, lower := range sortedStrings(conflictsB) {
+			warn(fmt.Sprintf("--ignore-key-case: %s in %s has keys differing only in case: %s", describePath(path), "file2", strings.Join(conflictsB[lower], ", ")))
+		}
+		byLower := make(map[string]string, len(bv))
+		for k := range bv {
+			byLower[strings.ToLower(k)] = k
+		}
+
+		out := make(map[string]interface{}, len(av))
+		for _, k := range sortedKeys(av) {
+			key := k
+			lower := strings.ToLower(k)
+			if _, exact := bv[k]; !exact && conflictsA[lower] == nil && conflictsB[lower] == nil {
+				if kb, ok := byLower[lower]; ok {
+					key = kb
+					f.toB[pathKey(path, k)] = kb
+					f.toA[pathKey(path, kb)] = k
+				}
+			}
+			out[key] = f.fold(av[k], bv[key], pathKey(path, key), warn)
+		}
+		return out
+	case []interface{}:
+		bv, _ := b.([]interface{})
+		out := make([]interface{}, len(av))
+		for i, v := range av {
+			var counterpart interface{}
+			if i < len(bv) {
+				counterpart = bv[i]
+			}
+			out[i] = f.fold(v, counterpart, indexKey(path, i), warn)
+		}
+		return out
+	}
+	return a
+}
+
+const reasonIgnoreKeyCase = "equal after ignore-key-case"
+
+// suppress records in suppressed each member of A paired with a differently
+// cased member of B, which would otherwise be removed and added: From is
+// A's key and To is B's.
+func (f *keyFolds) suppress(suppressed *suppressionLog) {
+	for compared, keyA := range f.toA {
+		segs := splitPath(compared)
+		suppressed.add(reasonIgnoreKeyCase, diff.Change{Type: diff.UPDATE, Path: segs, From: keyA, To: segs[len(segs)-1]})
+	}
+}
+
+// caseConflicts returns, by lowercased key, the keys of m that differ only
+// in case.
+func caseConflicts(m map[string]interface{}) map[string][]string {
+	byLower := make(map[string][]string)
+	for _, k := range sortedKeys(m) {
+		lower := strings.ToLower(k)
+		byLower[lower] = append(byLower[lower], k)
+	}
+	for lower, keys := range byLower {
+		if len(keys) < 2 {
+			delete(byLower, lower)
+		}
+	}
+	return byLower
+}
+
+func sortedStrings(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func describePath(path string) string {
+	if path == "" {
+		return "the root object"
+	}
+	return path
+}
+
+// segment returns the compared key for A's member key of the object at
+// parent.
+func (f *keyFolds) segment(parent, key string) string {
+	if f != nil {
+		if kb, ok := f.toB[pathKey(parent, key)]; ok {
+			return kb
+		}
+	}
+	return key
+}
+
+// pairedInA reports whether B's member key of the object at parent was
+// paired with a differently cased member of A.
+func (f *keyFolds) pairedInA(parent, key string) bool {
+	if f == nil {
+		return false
+	}
+	_, ok := f.toA[pathKey(parent, key)]
+	return ok
+}
+
+// originalPath translates a compared path back to the keys of document A.
+func (f *keyFolds) originalPath(path string) string {
+	if f == nil || len(f.toA) == 0 {
+		return path
+	}
+	var compared, original []string
+	for _, seg := range splitPath(path) {
+		key := seg
+		if !isIndexSegment(seg) {
+			if ka, ok := f.toA[pathKey(joinPath(compared), seg)]; ok {
+				key = ka
+			}
+		}
+		compared = append(compared, seg)
+		original = append(original, key)
+	}
+	return joinPath(original)
+}
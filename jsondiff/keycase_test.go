@@ -0,0 +1,109 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFoldKeyCase(t *testing.T) {
+	a := map[string]interface{}{
+		"UserId": 1.0,
+		"Spec":   map[string]interface{}{"Replicas": 2.0, "image": "web"},
+		"items":  []interface{}{map[string]interface{}{"Name": "x"}},
+		"Dup":    map[string]interface{}{"Key": 1.0, "key": 2.0},
+		"only":   true,
+	}
+	b := map[string]interface{}{
+		"userId": 1.0,
+		"spec":   map[string]interface{}{"replicas": 3.0, "Image": "web"},
+		"items":  []interface{}{map[string]interface{}{"name": "x"}},
+		"dup":    map[string]interface{}{"KEY": 1.0},
+		"ONLY":   true,
+		"only":   false,
+	}
+	var warnings []string
+	folded, f := foldKeyCase(a, b, func(msg string) { warnings = append(warnings, msg) })
+
+	want := map[string]interface{}{
+		"userId": 1.0,
+		"spec":   map[string]interface{}{"replicas": 2.0, "Image": "web"},
+		"items":  []interface{}{map[string]interface{}{"name": "x"}},
+		// Keys differing only in case within one object are left alone.
+		"dup":  map[string]interface{}{"Key": 1.0, "key": 2.0},
+		"only": true,
+	}
+	if !reflect.DeepEqual(folded, want) {
+		t.Errorf("folded %v, want %v", folded, want)
+	}
+	if _, changed := a["userId"]; changed {
+		t.Error("a was modified")
+	}
+	wantWarnings := []string{
+		"--ignore-key-case: the root object in file2 has keys differing only in case: ONLY, only",
+		"--ignore-key-case: dup in file1 has keys differing only in case: Key, key",
+	}
+	if !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Errorf("warnings %q, want %q", warnings, wantWarnings)
+	}
+
+	if got := f.segment("spec", "Replicas"); got != "replicas" {
+		t.Errorf("segment %q", got)
+	}
+	if got := f.segment("spec", "other"); got != "other" {
+		t.Errorf("unpaired segment %q", got)
+	}
+	if !f.pairedInA("items[0]", "name") || f.pairedInA("", "only") {
+		t.Error("pairedInA")
+	}
+	for compared, original := range map[string]string{
+		"spec.replicas":  "Spec.Replicas",
+		"items[0].name":  "items[0].Name",
+		"dup.Key":        "Dup.Key",
+		"userId":         "UserId",
+		"missing.userId": "missing.userId",
+	} {
+		if got := f.originalPath(compared); got != original {
+			t.Errorf("originalPath(%s) = %s, want %s", compared, got, original)
+		}
+	}
+	var none *keyFolds
+	if none.segment("", "A") != "A" || none.pairedInA("", "A") || none.originalPath("A.b") != "A.b" {
+		t.Error("nil keyFolds changed a key")
+	}
+}
+
+// TestIgnoreKeyCaseCLI checks that paired keys are compared as one member,
+// reported under the compared path along with A's own.
+func TestIgnoreKeyCaseCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"Spec": {"Replicas": 2, "Image": "web"}}`,
+		"b.json": `{"spec": {"replicas": 3, "image": "web"}}`,
+	})
+	changes := func(flags ...string) []DiffResult {
+		t.Helper()
+		args := append(append([]string{"-f", "json", "-o", "out.json"}, flags...), "a.json", "b.json")
+		if res := runCLI(t, dir, args...); res.exit != exitOK {
+			t.Fatalf("%q: exit %d: %s", flags, res.exit, res.stderr)
+		}
+		var report jsonReport
+		if err := json.Unmarshal([]byte(readFile(t, dir, "out.json")), &report); err != nil {
+			t.Fatal(err)
+		}
+		var all []DiffResult
+		for _, s := range report.Sections {
+			all = append(all, s.Changes...)
+		}
+		return all
+	}
+	if got := changes(); len(got) != 2 || got[0].Path != "Spec" || got[1].Path != "spec" {
+		t.Errorf("without the flag: %+v", got)
+	}
+	got := changes("--ignore-key-case")
+	if len(got) != 1 {
+		t.Fatalf("changes %+v, want one", got)
+	}
+	if c := got[0]; c.Path != "spec.replicas" || c.FoldedFrom != "Spec.Replicas" || c.From != "2" || c.To != "3" {
+		t.Errorf("change %+v", c)
+	}
+}
@@ -0,0 +1,133 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+)
+
+// KeyMapping renames the key at From to the final segment of To.
+type KeyMapping struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	from []string
+	to   []string
+}
+
+// RemappedKey records a single key rename applied to document A.
+type RemappedKey struct {
+	From string
+	To   string
+}
+
+func loadKeyMap(filename string) ([]KeyMapping, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var mappings []KeyMapping
+	if err := json.Unmarshal(data, &mappings); err != nil {
+		return nil, fmt.Errorf("invalid key map: %v", err)
+	}
+
+	for i := range mappings {
+		m := &mappings[i]
+		m.from = splitPath(m.From)
+		m.to = splitPath(m.To)
+		if len(m.from) == 0 || len(m.from) != len(m.to) {
+			return nil, fmt.Errorf("key map entry %q -> %q: paths must have the same depth", m.From, m.To)
+		}
+		if joinPath(m.from[:len(m.from)-1]) != joinPath(m.to[:len(m.to)-1]) {
+			return nil, fmt.Errorf("key map entry %q -> %q: only the last segment may differ", m.From, m.To)
+		}
+		if m.to[len(m.to)-1] == "*" {
+			return nil, fmt.Errorf("key map entry %q -> %q: target key cannot be a wildcard", m.From, m.To)
+		}
+	}
+	return mappings, nil
+}
+
+// applyKeyMap returns a copy of v with keys renamed according to mappings,
+// and the renames that were applied, keyed by the new path. v itself is
+// not modified. Mapping paths are written against the whole file, so when
+// v is the part of it at root (see --path-a), they are matched against
+// root followed by paths in v; the renames are keyed by paths in v.
+func applyKeyMap(v interface{}, root []string, mappings []KeyMapping) (interface{}, map[string]RemappedKey) {
+	applied := make(map[string]RemappedKey)
+	return remapKeys(v, root, len(root), mappings, applied), applied
+}
+
+// remapKeys renames the keys of v, found at path in the file. The first
+// rootLen segments of path lead to the compared document.
+func remapKeys(v interface{}, path []string, rootLen int, mappings []KeyMapping, applied map[string]RemappedKey) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for _, k := range sortedKeys(val) {
+			p := append(path[:len(path):len(path)], k)
+			for _, m := range mappings {
+				if !matchPath(m.from, p) {
+					continue
+				}
+				newKey := m.to[len(m.to)-1]
+				if newKey == k {
+					break
+				}
+				// Keys are renamed in sorted order, so a key sorting after
+				// k still holds its name.
+				_, taken := out[newKey]
+				if _, pending := val[newKey]; taken || pending && newKey > k {
+					log.Printf("Key map: not renaming %s, %q already exists", joinPath(p[rootLen:]), newKey)
+					break
+				}
+				np := append(path[:len(path):len(path)], newKey)
+				applied[joinPath(np[rootLen:])] = RemappedKey{
+					From: joinPath(p[rootLen:]),
+					To:   joinPath(np[rootLen:]),
+				}
+				p = np
+				break
+			}
+			out[p[len(p)-1]] = remapKeys(val[k], p, rootLen, mappings, applied)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, vv := range val {
+			out[i] = remapKeys(vv, append(path[:len(path):len(path)], indexSegment(i)), rootLen, mappings, applied)
+		}
+		return out
+	}
+	return v
+}
+
+func sortedRemaps(m map[string]RemappedKey) []RemappedKey {
+	out := make([]RemappedKey, 0, len(m))
+	for _, rk := range m {
+		out = append(out, rk)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].To < out[j].To })
+	return out
+}
+
+// unmappedPath translates a path in the remapped document A back to the
+// path it had in the file as read.
+func unmappedPath(path string, remapped map[string]RemappedKey) string {
+	if len(remapped) == 0 {
+		return path
+	}
+	var mapped, original []string
+	for _, seg := range splitPath(path) {
+		mapped = append(mapped, seg)
+		if rk, ok := remapped[joinPath(mapped)]; ok {
+			from := splitPath(rk.From)
+			seg = from[len(from)-1]
+		}
+		original = append(original, seg)
+	}
+	return joinPath(original)
+}
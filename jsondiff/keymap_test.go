@@ -0,0 +1,89 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// loadTestKeyMap writes entries to a key map file and loads it.
+func loadTestKeyMap(t *testing.T, entries string) []KeyMapping {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "map.json")
+	if err := os.WriteFile(filename, []byte(entries), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mappings, err := loadKeyMap(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mappings
+}
+
+func mustDecode(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestApplyKeyMapUnderSubpath(t *testing.T) {
+	mappings := loadTestKeyMap(t, `[
+		{"from": "customer.address.zip", "to": "customer.address.postalCode"},
+		{"from": "orders[*].items.*.sku", "to": "orders[*].items.*.code"}
+	]`)
+	tests := []struct {
+		name     string
+		root     []string
+		doc      string
+		want     string
+		wantFrom map[string]string
+	}{
+		{
+			name:     "whole file",
+			doc:      `{"customer":{"address":{"zip":"1000"}}}`,
+			want:     `{"customer":{"address":{"postalCode":"1000"}}}`,
+			wantFrom: map[string]string{"customer.address.postalCode": "customer.address.zip"},
+		},
+		{
+			name:     "object subpath",
+			root:     []string{"customer"},
+			doc:      `{"address":{"zip":"1000"}}`,
+			want:     `{"address":{"postalCode":"1000"}}`,
+			wantFrom: map[string]string{"address.postalCode": "address.zip"},
+		},
+		{
+			name:     "array element subpath",
+			root:     []string{"orders", "[2]"},
+			doc:      `{"items":[{"sku":"a"}]}`,
+			want:     `{"items":[{"code":"a"}]}`,
+			wantFrom: map[string]string{"items[0].code": "items[0].sku"},
+		},
+		{
+			name:     "mapping outside the subpath",
+			root:     []string{"supplier"},
+			doc:      `{"address":{"zip":"1000"}}`,
+			want:     `{"address":{"zip":"1000"}}`,
+			wantFrom: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, applied := applyKeyMap(mustDecode(t, tt.doc), tt.root, mappings)
+			if want := mustDecode(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+			gotFrom := make(map[string]string)
+			for to, rk := range applied {
+				gotFrom[to] = rk.From
+			}
+			if !reflect.DeepEqual(gotFrom, tt.wantFrom) {
+				t.Errorf("renames %v, want %v", gotFrom, tt.wantFrom)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package jsondiff
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+// lazySubtree is a container left out of a pane by --lazy-depth: a
+// placeholder stands in the tree and its markup is written once, in a
+// <template> after the tree, for the tree script to swap in when the
+// container is first expanded.
+type lazySubtree struct {
+	id             string
+	path           string
+	v, counterpart interface{}
+	// levelOffset is the renderContext's at the placeholder.
+	levelOffset int
+}
+
+// deferred reports whether the container v at path is rendered lazily: it
+// lies exactly lazyDepth levels deep and neither it, its ancestors nor its
+// descendants changed, so every change stays in the initial page.
+func (ctx *renderContext) deferred(path string, v interface{}) bool {
+	if ctx.lazy == nil || ctx.diffMap == nil || !hasChildren(v) || len(splitPath(path)) != ctx.lazyDepth {
+		return false
+	}
+	if _, ok := ctx.diffMap.Lookup(path); ok || ctx.diffMap.HasChangedDescendant(path) {
+		return false
+	}
+	_, _, ok := ctx.diffMap.ChangedAncestor(path)
+	return !ok
+}
+
+// lazyPlaceholder records v as deferred and returns the collapsed container
+// standing in for it.
+func (ctx *renderContext) lazyPlaceholder(v, counterpart interface{}, path string) template.HTML {
+	id := fmt.Sprintf("lazy-%s-%d", ctx.side, len(*ctx.lazy))
+	*ctx.lazy = append(*ctx.lazy, lazySubtree{id: id, path: path, v: v, counterpart: counterpart, levelOffset: ctx.levelOffset})
+	class, open, close := "json-object", "{", "}"
+	if _, ok := v.([]interface{}); ok {
+		class, open, close = "json-array", "[", "]"
+	}
+	return template.HTML(`<div class="` + ctx.cls(class) + `"` + attr("data-lazy", id) + `>` + open + `&hellip;` + close + `</div>`)
+}
+
+// renderLazyTree renders the whole document doc of a pane, deferring the
+// containers chosen by deferred and appending their markup as templates.
+// Each template holds exactly what the eager render puts in place of the
+// placeholder.
+func renderLazyTree(doc interface{}, ctx *renderContext) template.HTML {
+	var subtrees []lazySubtree
+	pane := *ctx
+	pane.lazy = &subtrees
+	var sb strings.Builder
+	sb.WriteString(string(renderJSON(doc, "", &pane)))
+	eager := *ctx
+	eager.lazyDepth = 0
+	for _, s := range subtrees {
+		eager.levelOffset = s.levelOffset
+		sb.WriteString(`<template` + attr("id", s.id) + `>`)
+		sb.WriteString(string(renderNode(s.v, s.counterpart, s.path, &eager)))
+		sb.WriteString(`</template>`)
+	}
+	return template.HTML(sb.String())
+}
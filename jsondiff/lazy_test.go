@@ -0,0 +1,125 @@
+package jsondiff
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const (
+	lazyDocA = `{"meta": {"labels": {"app": "web", "tier": "front"}, "owners": ["x", "y"]}, "spec": {"replicas": 2, "ports": [{"port": 80}, {"port": 443}]}, "list": [[1, 2], [3]]}`
+	lazyDocB = `{"meta": {"labels": {"app": "web", "tier": "front"}, "owners": ["x", "y"]}, "spec": {"replicas": 3, "ports": [{"port": 80}, {"port": 443}]}, "list": [[1, 2], [3]]}`
+)
+
+var lazyTemplate = regexp.MustCompile(`<template id="([^"]+)">(.*?)</template>`)
+
+// expandLazy does what the tree script does on expanding every deferred
+// item: it swaps each placeholder for its template and marks the item
+// expanded.
+func expandLazy(t *testing.T, html string) string {
+	t.Helper()
+	templates := lazyTemplate.FindAllStringSubmatch(html, -1)
+	html = lazyTemplate.ReplaceAllString(html, "")
+	for _, m := range templates {
+		placeholder := regexp.MustCompile(`<div class="json-(object|array)" data-lazy="` + m[1] + `">[^<]*</div>`)
+		loc := placeholder.FindStringIndex(html)
+		if loc == nil {
+			t.Fatalf("no placeholder for template %s", m[1])
+		}
+		item := strings.LastIndex(html[:loc[0]], `aria-expanded="false"`)
+		if item < 0 {
+			t.Fatalf("placeholder %s is not in a collapsed item", m[1])
+		}
+		html = html[:item] + `aria-expanded="true"` + html[item+len(`aria-expanded="false"`):loc[0]] + m[2] + html[loc[1]:]
+	}
+	return html
+}
+
+// pointerOf returns the JSON Pointer of a tree path of plain keys.
+func pointerOf(path string) string {
+	var sb strings.Builder
+	for _, seg := range splitPath(path) {
+		sb.WriteString("/" + strings.Trim(seg, "[]"))
+	}
+	return sb.String()
+}
+
+func TestLazyTreeMatchesEager(t *testing.T) {
+	r := reportFor(t, lazyDocA, lazyDocB)
+	for _, side := range []Side{SideA, SideB} {
+		var eager bytes.Buffer
+		if err := r.RenderTreeHTML(side, &eager); err != nil {
+			t.Fatal(err)
+		}
+		for depth, want := range map[int][]string{1: {"list", "meta"}, 2: {"list[0]", "list[1]", "meta.labels", "meta.owners", "spec.ports"}} {
+			lr := *r
+			lr.render.lazyDepth = depth
+			var lazy bytes.Buffer
+			if err := lr.RenderTreeHTML(side, &lazy); err != nil {
+				t.Fatal(err)
+			}
+			templates := lazyTemplate.FindAllStringSubmatch(lazy.String(), -1)
+			if len(templates) != len(want) {
+				t.Fatalf("side %s depth %d: %d subtrees deferred, want %d", side, depth, len(templates), len(want))
+			}
+			if got := expandLazy(t, lazy.String()); got != eager.String() {
+				t.Errorf("side %s depth %d: expanded lazy tree differs from the eager one\nlazy:  %s\neager: %s", side, depth, got, eager.String())
+			}
+		}
+	}
+}
+
+func TestLazyTreeKeepsChanges(t *testing.T) {
+	r := reportFor(t, lazyDocA, lazyDocB)
+	r.render.lazyDepth = 1
+	var buf bytes.Buffer
+	if err := r.RenderTreeHTML(SideB, &buf); err != nil {
+		t.Fatal(err)
+	}
+	tree := lazyTemplate.ReplaceAllString(buf.String(), "")
+	if !strings.Contains(tree, `data-change="changed"`) {
+		t.Error("the changed replicas member was deferred")
+	}
+	if strings.Contains(tree, `"front"`) {
+		t.Error("the unchanged meta members were rendered up front")
+	}
+}
+
+func TestRenderSubtreeHTML(t *testing.T) {
+	r := reportFor(t, lazyDocA, lazyDocB)
+	var eager bytes.Buffer
+	if err := r.RenderTreeHTML(SideB, &eager); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"meta", "meta.labels", "meta.owners", "spec", "spec.ports[1]", "list[0]", "spec.replicas"} {
+		var sub bytes.Buffer
+		if err := r.RenderSubtreeHTML(pointerOf(path), SideB, &sub); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if sub.Len() == 0 || !strings.Contains(eager.String(), sub.String()) {
+			t.Errorf("%s: subtree markup is not part of the full tree:\n%s", path, sub.String())
+		}
+	}
+
+	// With --lazy-depth, the subtree is what the deferred template holds.
+	r.render.lazyDepth = 1
+	var lazy bytes.Buffer
+	if err := r.RenderTreeHTML(SideB, &lazy); err != nil {
+		t.Fatal(err)
+	}
+	var sub bytes.Buffer
+	if err := r.RenderSubtreeHTML("/meta", SideB, &sub); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(lazy.String(), `">`+sub.String()+`</template>`) {
+		t.Errorf("subtree of /meta is not the deferred template:\n%s", sub.String())
+	}
+
+	for _, pointer := range []string{"/nope", "/list/2", "/list/01", "meta"} {
+		if err := r.RenderSubtreeHTML(pointer, SideA, &sub); !errors.Is(err, ErrSubtreeNotFound) {
+			t.Errorf("%q: error %v, want ErrSubtreeNotFound", pointer, err)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package jsondiff
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// LeafEntry is one leaf of the two documents merged, as listed by
+// --json-include-unchanged and the jsonl format. Leaves are scalars and
+// empty objects and arrays, as counted by documentStats, so every leaf of
+// either document appears exactly once.
+type LeafEntry struct {
+	Path    string `json:"path"`
+	Pointer string `json:"pointer"`
+	// Status is the change type the report gives the leaf, unchanged
+	// included; a leaf below an added, removed or changed container takes
+	// the container's.
+	Status ChangeType `json:"status"`
+	// A and B are the leaf's values; a side is absent where only the other
+	// document has the leaf.
+	A json.RawMessage `json:"a,omitempty"`
+	B json.RawMessage `json:"b,omitempty"`
+}
+
+// walkMergedLeaves calls fn for every leaf of a and b in path order,
+// pairing the leaves found at the same path. Where one document has a
+// container and the other a leaf or a container of the other kind, each
+// side is walked on its own.
+func walkMergedLeaves(a, b interface{}, hasA, hasB bool, path []string, fn func(path []string, a, b interface{}, hasA, hasB bool) error) error {
+	leafA, leafB := !hasA || !hasChildren(a), !hasB || !hasChildren(b)
+	if leafA && leafB {
+		return fn(path, a, b, hasA, hasB)
+	}
+	child := func(seg string, ca, cb interface{}, okA, okB bool) error {
+		return walkMergedLeaves(ca, cb, okA, okB, append(path[:len(path):len(path)], seg), fn)
+	}
+	ma, objA := a.(map[string]interface{})
+	mb, objB := b.(map[string]interface{})
+	la, arrA := a.([]interface{})
+	lb, arrB := b.([]interface{})
+	switch {
+	case (!hasA || objA && !leafA) && (!hasB || objB && !leafB):
+		for _, k := range mergedKeys(ma, mb) {
+			va, okA := ma[k]
+			vb, okB := mb[k]
+			if err := child(k, va, vb, okA, okB); err != nil {
+				return err
+			}
+		}
+		return nil
+	case (!hasA || arrA && !leafA) && (!hasB || arrB && !leafB):
+		for i := 0; i < max(len(la), len(lb)); i++ {
+			var va, vb interface{}
+			if i < len(la) {
+				va = la[i]
+			}
+			if i < len(lb) {
+				vb = lb[i]
+			}
+			if err := child(indexSegment(i), va, vb, i < len(la), i < len(lb)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walkMergedLeaves(a, nil, true, false, path, fn); err != nil {
+		return err
+	}
+	return walkMergedLeaves(nil, b, false, true, path, fn)
+}
+
+// leafPointer returns the JSON Pointer of a path built by walkMergedLeaves,
+// whose array segments are bracketed.
+func leafPointer(path []string) string {
+	var sb strings.Builder
+	for _, seg := range path {
+		sb.WriteByte('/')
+		if i, ok := parseIndexSegment(seg); ok {
+			sb.WriteString(strconv.Itoa(i))
+			continue
+		}
+		sb.WriteString(pointerEscaper.Replace(seg))
+	}
+	return sb.String()
+}
+
+// leafStatus returns the change type the report gives the leaf at path.
+func leafStatus(m *DiffMap, path string) ChangeType {
+	if ct, ok := m.Lookup(path); ok {
+		return ct
+	}
+	if _, ct, ok := m.ChangedAncestor(path); ok {
+		return ct
+	}
+	return Unchanged
+}
+
+// writeLeaves calls emit with the entry of every leaf of the report's
+// documents, skipping unchanged ones unless all is set.
+func (r *Report) writeLeaves(all bool, emit func(LeafEntry) error) error {
+	m := r.render.diffMap
+	a, b := r.A, r.B
+	if r.render.folds != nil || r.render.keySegment != nil {
+		sideA, sideB := r.render, r.render
+		sideA.side, sideB.side = SideA, SideB
+		a, b = sideA.comparedKeys(a, ""), sideB.comparedKeys(b, "")
+	}
+	return walkMergedLeaves(a, b, true, true, nil, func(path []string, a, b interface{}, hasA, hasB bool) error {
+		p := joinPath(path)
+		e := LeafEntry{Path: p, Pointer: leafPointer(path), Status: leafStatus(m, p)}
+		if e.Status == Unchanged && !all {
+			return nil
+		}
+		var err error
+		if hasA {
+			if e.A, err = json.Marshal(a); err != nil {
+				return err
+			}
+		}
+		if hasB {
+			if e.B, err = json.Marshal(b); err != nil {
+				return err
+			}
+		}
+		return emit(e)
+	})
+}
+
+// comparedKeys returns a copy of v, the document on ctx's side, whose object
+// keys are the path segments the comparison used for them, so that members
+// paired by --ignore-key-case or a key transform share one path.
+func (ctx *renderContext) comparedKeys(v interface{}, path string) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		// As in the comparison, the first of two keys normalized alike
+		// wins.
+		for _, k := range sortedKeys(val) {
+			seg := ctx.segment(path, k)
+			if _, dup := out[seg]; !dup {
+				out[seg] = ctx.comparedKeys(val[k], pathKey(path, seg))
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, vv := range val {
+			out[i] = ctx.comparedKeys(vv, indexKey(path, i))
+		}
+		return out
+	}
+	return v
+}
+
+// leafEntries lists every leaf, for the JSON report.
+func (r *Report) leafEntries() ([]LeafEntry, error) {
+	entries := []LeafEntry{}
+	err := r.writeLeaves(true, func(e LeafEntry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	return entries, err
+}
+
+// jsonlRenderer streams one LeafEntry per line, as JSON Lines, without
+// holding the entries in memory: the changed leaves, or with
+// --json-include-unchanged every leaf.
+type jsonlRenderer struct{}
+
+func (jsonlRenderer) Name() string             { return "jsonl" }
+func (jsonlRenderer) DefaultExtension() string { return "jsonl" }
+
+func (jsonlRenderer) Render(w io.Writer, r *Report) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	err := r.writeLeaves(r.AllLeaves, func(e LeafEntry) error { return enc.Encode(e) })
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
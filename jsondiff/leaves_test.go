@@ -0,0 +1,103 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWalkMergedLeaves(t *testing.T) {
+	a := map[string]interface{}{"s": "x", "obj": map[string]interface{}{"k": 1.0}, "list": []interface{}{1.0, 2.0}, "kind": []interface{}{}}
+	b := map[string]interface{}{"s": "y", "obj": map[string]interface{}{}, "list": []interface{}{1.0}, "kind": map[string]interface{}{"n": nil}}
+	var got []string
+	err := walkMergedLeaves(a, b, true, true, nil, func(path []string, va, vb interface{}, hasA, hasB bool) error {
+		got = append(got, joinPath(path)+" "+leafPointer(path)+" "+map[bool]string{true: "a"}[hasA]+map[bool]string{true: "b"}[hasB])
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Where the two sides hold different kinds of value, A's leaves come
+	// first.
+	want := []string{
+		"kind /kind a",
+		"kind.n /kind/n b",
+		"list[0] /list/0 ab",
+		"list[1] /list/1 a",
+		"obj.k /obj/k a",
+		"obj /obj b",
+		"s /s ab",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("leaves\n%q\nwant\n%q", got, want)
+	}
+}
+
+// TestLeavesCLI checks the jsonl lines of the changed leaves and, with
+// --json-include-unchanged, of every leaf, including members paired by
+// --ignore-key-case or a key normalization.
+func TestLeavesCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"Spec": {"Replicas": 2, "Image": "web"}, "caf\u00e9": 1, "tags": ["a"]}`,
+		"b.json": `{"spec": {"replicas": 3, "image": "web"}, "cafe\u0301": 2, "tags": ["a", "b"]}`,
+	})
+	// The key as written in each file, in NFC and NFD.
+	const nfc, nfd = "caf\u00e9", "cafe\u0301"
+	tests := []struct {
+		name  string
+		flags []string
+		want  []string
+	}{
+		{"changed", []string{"--ignore-key-case", "--normalize-unicode", "nfc", "--normalize-keys"}, []string{
+			`{"path":"` + nfc + `","pointer":"/` + nfc + `","status":"changed","a":1,"b":2}`,
+			`{"path":"spec.replicas","pointer":"/spec/replicas","status":"changed","a":2,"b":3}`,
+			`{"path":"tags[1]","pointer":"/tags/1","status":"added","b":"b"}`,
+		}},
+		{"all", []string{"--ignore-key-case", "--normalize-unicode", "nfc", "--normalize-keys", "--json-include-unchanged"}, []string{
+			`{"path":"` + nfc + `","pointer":"/` + nfc + `","status":"changed","a":1,"b":2}`,
+			`{"path":"spec.image","pointer":"/spec/image","status":"unchanged","a":"web","b":"web"}`,
+			`{"path":"spec.replicas","pointer":"/spec/replicas","status":"changed","a":2,"b":3}`,
+			`{"path":"tags[0]","pointer":"/tags/0","status":"unchanged","a":"a","b":"a"}`,
+			`{"path":"tags[1]","pointer":"/tags/1","status":"added","b":"b"}`,
+		}},
+		{"keys as written", nil, []string{
+			`{"path":"Spec.Image","pointer":"/Spec/Image","status":"removed","a":"web"}`,
+			`{"path":"Spec.Replicas","pointer":"/Spec/Replicas","status":"removed","a":2}`,
+			`{"path":"` + nfd + `","pointer":"/` + nfd + `","status":"added","b":2}`,
+			`{"path":"` + nfc + `","pointer":"/` + nfc + `","status":"removed","a":1}`,
+			`{"path":"spec.image","pointer":"/spec/image","status":"added","b":"web"}`,
+			`{"path":"spec.replicas","pointer":"/spec/replicas","status":"added","b":3}`,
+			`{"path":"tags[1]","pointer":"/tags/1","status":"added","b":"b"}`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append(append([]string{"-f", "jsonl", "-o", "-"}, tt.flags...), "a.json", "b.json")
+			res := runCLI(t, dir, args...)
+			if res.exit != exitOK {
+				t.Fatalf("exit %d: %s", res.exit, res.stderr)
+			}
+			got := strings.Split(strings.TrimSuffix(res.stdout, "\n"), "\n")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+
+	// The JSON report lists the same leaves.
+	if res := runCLI(t, dir, "-f", "json", "-o", "out.json", "--ignore-key-case", "--normalize-unicode", "nfc", "--normalize-keys", "--json-include-unchanged", "a.json", "b.json"); res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	var report jsonReport
+	if err := json.Unmarshal([]byte(readFile(t, dir, "out.json")), &report); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, l := range report.Leaves {
+		paths = append(paths, l.Path)
+	}
+	if want := []string{nfc, "spec.image", "spec.replicas", "tags[0]", "tags[1]"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("report leaves %q, want %q", paths, want)
+	}
+}
@@ -0,0 +1,63 @@
+package jsondiff
+
+import "sort"
+
+// changeTypeOrder is the order change types appear in the legend; types not
+// listed follow in name order.
+var changeTypeOrder = []ChangeType{Added, Removed, Changed, Moved}
+
+// LegendEntry is one change type present in a diff, with the number of
+// changes of that type.
+type LegendEntry struct {
+	Type  ChangeType
+	Count int
+}
+
+// resultChangeType maps a diff.Change type, or "move", to the ChangeType
+// used for highlighting.
+func resultChangeType(t string) ChangeType {
+	switch t {
+	case "create":
+		return Added
+	case "delete":
+		return Removed
+	case "update":
+		return Changed
+	case "move":
+		return Moved
+	}
+	return Unchanged
+}
+
+// ChangeType is the highlighting class of the change.
+func (r DiffResult) ChangeType() ChangeType {
+	return resultChangeType(r.Type)
+}
+
+// buildLegend counts results by change type, leaving out absent types.
+func buildLegend(results []DiffResult) []LegendEntry {
+	counts := make(map[ChangeType]int)
+	for _, r := range results {
+		counts[r.ChangeType()]++
+	}
+	rank := make(map[ChangeType]int, len(changeTypeOrder))
+	for i, ct := range changeTypeOrder {
+		rank[ct] = i
+	}
+	legend := make([]LegendEntry, 0, len(counts))
+	for ct, n := range counts {
+		legend = append(legend, LegendEntry{Type: ct, Count: n})
+	}
+	sort.Slice(legend, func(i, j int) bool {
+		ri, okI := rank[legend[i].Type]
+		rj, okJ := rank[legend[j].Type]
+		switch {
+		case okI && okJ:
+			return ri < rj
+		case okI != okJ:
+			return okI
+		}
+		return legend[i].Type < legend[j].Type
+	})
+	return legend
+}
@@ -0,0 +1,56 @@
+package jsondiff
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestBuildLegend(t *testing.T) {
+	var results []DiffResult
+	for _, typ := range []string{"update", "move", "create", "update", "nonsense", "update", "create"} {
+		results = append(results, DiffResult{Type: typ})
+	}
+	want := []LegendEntry{{Added, 2}, {Changed, 3}, {Moved, 1}, {Unchanged, 1}}
+	if got := buildLegend(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("legend %+v, want %+v", got, want)
+	}
+	if got := buildLegend(nil); len(got) != 0 {
+		t.Errorf("no results: %+v", got)
+	}
+}
+
+// TestLegendCLI checks the legend's toggle buttons and the rules hiding
+// each type, and that a report without changes has no legend.
+func TestLegendCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"n": 1, "gone": "x", "old": {"deep": [1, 2, 3]}}`,
+		"b.json": `{"n": 2, "new": 1, "new2": 2, "moved": {"deep": [1, 2, 3]}}`,
+	})
+	if res := runCLI(t, dir, "--detect-moves", "-o", "out.html", "a.json", "b.json"); res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	html := readFile(t, dir, "out.html")
+	button := regexp.MustCompile(`data-toggle-change="(\w+)" aria-pressed="true">(\w+ \(\d+\))</button>`)
+	var got []string
+	for _, m := range button.FindAllStringSubmatch(html, -1) {
+		got = append(got, m[1]+": "+m[2])
+	}
+	want := []string{"added: added (2)", "removed: removed (1)", "changed: changed (1)", "moved: moved (1)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buttons %q, want %q", got, want)
+	}
+	for _, ct := range []string{"added", "removed", "changed", "moved"} {
+		if !strings.Contains(html, `.hide-`+ct+` [data-change="`+ct+`"]`) {
+			t.Errorf("no rule hiding %s changes", ct)
+		}
+	}
+
+	if res := runCLI(t, dir, "-o", "same.html", "a.json", "a.json"); res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	if strings.Contains(readFile(t, dir, "same.html"), `<div class="legend"`) {
+		t.Error("legend shown without changes")
+	}
+}
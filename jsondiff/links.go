@@ -0,0 +1,129 @@
+package jsondiff
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"text/template"
+)
+
+// LinkRule maps paths to pages in other systems, such as a service catalog.
+// Path is a regular expression matched against the whole change path; the
+// values of its named groups are available to the URL template, e.g.
+//
+//	{"path": "services\\.(?P<svc>[^.]+)(\\..*)?", "url": "https://catalog/svc/{{.svc}}"}
+type LinkRule struct {
+	Path string `json:"path"`
+	URL  string `json:"url"`
+}
+
+type linkRule struct {
+	re  *regexp.Regexp
+	url *template.Template
+	// literals counts the characters the pattern matches literally; the
+	// matching rule with the most is the most specific.
+	literals int
+}
+
+// linkRules are the compiled "links" of the config.
+type linkRules []linkRule
+
+func compileLinkRules(rules []LinkRule) (linkRules, error) {
+	var out linkRules
+	for _, r := range rules {
+		re, err := regexp.Compile(`^(?:` + r.Path + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid link path %q: %v", r.Path, err)
+		}
+		tpl, err := template.New(r.Path).Option("missingkey=error").Parse(r.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid link url %q: %v", r.URL, err)
+		}
+		// Every group the template uses must exist in the pattern.
+		groups := make(map[string]string)
+		for _, name := range re.SubexpNames() {
+			if name != "" {
+				groups[name] = "x"
+			}
+		}
+		if err := tpl.Execute(&strings.Builder{}, groups); err != nil {
+			return nil, fmt.Errorf("invalid link url %q: %v", r.URL, err)
+		}
+		parsed, _ := syntax.Parse(r.Path, syntax.Perl)
+		out = append(out, linkRule{re: re, url: tpl, literals: literalCount(parsed)})
+	}
+	return out, nil
+}
+
+// literalCount counts the literal characters re matches.
+func literalCount(re *syntax.Regexp) int {
+	if re == nil {
+		return 0
+	}
+	n := 0
+	if re.Op == syntax.OpLiteral {
+		n = len(re.Rune)
+	}
+	for _, sub := range re.Sub {
+		n += literalCount(sub)
+	}
+	return n
+}
+
+// link returns the URL for path from the most specific matching rule, the
+// first of equally specific ones, or "" when none applies. Captured values
+// are escaped as URL path segments. A rule whose template needs a group
+// that did not take part in the match is skipped, as is a result that is
+// not an http or https URL.
+func (rules linkRules) link(path string) string {
+	best, bestLiterals := "", -1
+	for _, r := range rules {
+		if r.literals <= bestLiterals {
+			continue
+		}
+		m := r.re.FindStringSubmatchIndex(path)
+		if m == nil {
+			continue
+		}
+		groups := make(map[string]string)
+		for i, name := range r.re.SubexpNames() {
+			if name != "" && m[2*i] >= 0 {
+				groups[name] = url.PathEscape(path[m[2*i]:m[2*i+1]])
+			}
+		}
+		var sb strings.Builder
+		if err := r.url.Execute(&sb, groups); err != nil {
+			continue
+		}
+		u := safeURL(sb.String())
+		if u == "" {
+			continue
+		}
+		best, bestLiterals = u, r.literals
+	}
+	return best
+}
+
+// setLinks sets the Link of every result whose path a rule matches.
+func setLinks(results []DiffResult, rules linkRules) {
+	if len(rules) == 0 {
+		return
+	}
+	for i := range results {
+		results[i].Link = rules.link(results[i].Path)
+	}
+}
+
+// linkIcon renders an external link for a changed tree item at path.
+func (ctx *renderContext) linkIcon(path string, ct ChangeType) string {
+	if len(ctx.links) == 0 || ct == Unchanged {
+		return ""
+	}
+	u := ctx.links.link(path)
+	if u == "" {
+		return ""
+	}
+	return `<a class="` + ctx.cls("ext-link") + `"` + attr("href", u) + ` target="_blank" rel="noopener"` + attr("title", "Open "+u) + `>&#8599;</a>`
+}
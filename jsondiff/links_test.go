@@ -0,0 +1,83 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLinkRules(t *testing.T) {
+	rules, err := compileLinkRules([]LinkRule{
+		{Path: `services\.(?P<svc>[^.]+)(\..*)?`, URL: "https://catalog/svc/{{.svc}}"},
+		{Path: `services\.db(\..*)?`, URL: "https://catalog/databases"},
+		{Path: `.*`, URL: "https://wiki/all"},
+		{Path: `(?P<name>owners\..*)|(?P<other>x)`, URL: "https://people/{{.name}}"},
+		{Path: `script`, URL: "javascript:alert(1)"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"services.web.replicas": "https://catalog/svc/web",
+		// The rule with more literal characters wins.
+		"services.db.size": "https://catalog/databases",
+		"services.a b":     "https://catalog/svc/a%20b",
+		"owners.ops":       "https://people/owners.ops",
+		// The name group took no part in matching x, so the next rule
+		// applies; javascript: URLs never do.
+		"x":      "https://wiki/all",
+		"script": "https://wiki/all",
+	}
+	for path, want := range tests {
+		if got := rules.link(path); got != want {
+			t.Errorf("%s: %q, want %q", path, got, want)
+		}
+	}
+
+	for _, bad := range []LinkRule{
+		{Path: "(", URL: "https://x"},
+		{Path: "a", URL: "{{.a"},
+		{Path: "(?P<a>.*)", URL: "https://x/{{.b}}"},
+	} {
+		if _, err := compileLinkRules([]LinkRule{bad}); err == nil || !strings.Contains(err.Error(), "invalid link") {
+			t.Errorf("%+v: %v", bad, err)
+		}
+	}
+}
+
+func TestLinksCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json":      `{"services": {"web": {"replicas": 2}}, "other": 1}`,
+		"b.json":      `{"services": {"web": {"replicas": 3}}, "other": 2}`,
+		"config.json": `{"links": [{"path": "services\\.(?P<svc>[^.]+)(\\..*)?", "url": "https://catalog/svc/{{.svc}}"}]}`,
+		"bad.json":    `{"links": [{"path": "(", "url": "https://x"}]}`,
+	})
+	res := runCLI(t, dir, "--config", "config.json", "-f", "json", "-o", "-", "a.json", "b.json")
+	if res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	var report jsonReport
+	if err := json.Unmarshal([]byte(res.stdout), &report); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, s := range report.Sections {
+		for _, c := range s.Changes {
+			got[c.Path] = c.Link
+		}
+	}
+	if want := map[string]string{"services.web.replicas": "https://catalog/svc/web", "other": ""}; !reflect.DeepEqual(got, want) {
+		t.Errorf("links %q, want %q", got, want)
+	}
+
+	if res := runCLI(t, dir, "--config", "config.json", "-o", "out.html", "a.json", "b.json"); res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	if html := readFile(t, dir, "out.html"); !strings.Contains(html, `href="https://catalog/svc/web" target="_blank" rel="noopener"`) {
+		t.Error("no link on the changed item")
+	}
+	if res := runCLI(t, dir, "--config", "bad.json", "-o", "out.html", "a.json", "b.json"); res.exit != exitError || !strings.Contains(res.stderr, `invalid config: invalid link path "("`) {
+		t.Errorf("bad rule: exit %d: %s", res.exit, res.stderr)
+	}
+}
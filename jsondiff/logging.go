@@ -0,0 +1,41 @@
+package jsondiff
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// logger receives the timed phase diagnostics enabled by --verbose. It
+// discards everything by default; library users can install their own
+// handler with SetLogger.
+var logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// SetLogger replaces the logger used for diagnostics.
+func SetLogger(l *slog.Logger) {
+	logger = l
+}
+
+// newStderrLogger returns the --verbose logger. Diagnostics always go to
+// stderr so they never mix with a report written to stdout.
+func newStderrLogger(format string) (*slog.Logger, error) {
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(os.Stderr, nil)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, nil)), nil
+	}
+	return nil, fmt.Errorf("invalid --log-format %q: must be text or json", format)
+}
+
+// phase starts timing a named step of the pipeline. Calling the returned
+// function logs the step with its duration and any extra attributes.
+func phase(name string, attrs ...any) func(extra ...any) {
+	start := time.Now()
+	return func(extra ...any) {
+		args := append(append([]any{}, attrs...), extra...)
+		logger.Info(name, append(args, "duration", time.Since(start))...)
+	}
+}
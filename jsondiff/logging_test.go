@@ -0,0 +1,89 @@
+package jsondiff
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPhase(t *testing.T) {
+	var buf bytes.Buffer
+	defer SetLogger(logger)
+	SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	done := phase("parse", "input", "a.json")
+	done("bytes", 12)
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+	if entry["msg"] != "parse" || entry["input"] != "a.json" || entry["bytes"] != 12.0 {
+		t.Errorf("entry %v", entry)
+	}
+	if d, ok := entry["duration"].(float64); !ok || d < 0 {
+		t.Errorf("duration %v", entry["duration"])
+	}
+}
+
+// TestVerboseCLI checks that --verbose logs every phase to stderr, in
+// either format, and leaves a report written to stdout intact.
+func TestVerboseCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{"a.json": `{"n": 1}`, "b.json": `{"n": 2}`})
+
+	res := runCLI(t, dir, "--verbose", "--log-format", "json", "-f", "json", "-o", "-", "a.json", "b.json")
+	if res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	wellFormed["json"](t, res.stdout)
+	var phases []string
+	sc := bufio.NewScanner(strings.NewReader(res.stderr))
+	for sc.Scan() {
+		var entry struct {
+			Level    string `json:"level"`
+			Msg      string `json:"msg"`
+			Format   string `json:"format"`
+			Duration *int64 `json:"duration"`
+		}
+		if err := json.Unmarshal(sc.Bytes(), &entry); err != nil {
+			t.Fatalf("stderr line %q: %v", sc.Text(), err)
+		}
+		if entry.Level != "INFO" || entry.Duration == nil {
+			t.Errorf("entry %s", sc.Text())
+		}
+		if entry.Msg == "render" && entry.Format != "json" {
+			t.Errorf("render entry %s", sc.Text())
+		}
+		phases = append(phases, entry.Msg)
+	}
+	if got := strings.Join(phases, " "); got != "parse parse diff index render" {
+		t.Errorf("phases %q", got)
+	}
+
+	res = runCLI(t, dir, "-v", "-o", "out.html", "a.json", "b.json")
+	if res.exit != exitOK || !strings.Contains(res.stderr, "level=INFO msg=diff changes=1 duration=") {
+		t.Errorf("text logs: exit %d: %s", res.exit, res.stderr)
+	}
+	if res := runCLI(t, dir, "-o", "out.html", "a.json", "b.json"); res.stderr != "" {
+		t.Errorf("logs without --verbose: %s", res.stderr)
+	}
+	if res := runCLI(t, dir, "-v", "--log-format", "xml", "a.json", "b.json"); res.exit != exitError || !strings.Contains(res.stderr, `invalid --log-format "xml"`) {
+		t.Errorf("--log-format xml: exit %d: %s", res.exit, res.stderr)
+	}
+}
+
+func TestProfileCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{"a.json": `{"n": 1}`, "b.json": `{"n": 2}`})
+	if res := runCLI(t, dir, "--profile", "cpu.pprof", "-o", "out.html", "a.json", "b.json"); res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	// A CPU profile is a gzipped protocol buffer.
+	data, err := os.ReadFile(filepath.Join(dir, "cpu.pprof"))
+	if err != nil || !bytes.HasPrefix(data, []byte("\x1f\x8b")) {
+		t.Errorf("profile %d bytes, %v", len(data), err)
+	}
+}
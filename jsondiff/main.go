@@ -0,0 +1,1503 @@
+package jsondiff
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/r3labs/diff/v3"
+)
+
+type ChangeType string
+
+const (
+	Added     ChangeType = "added"
+	Removed   ChangeType = "removed"
+	Changed   ChangeType = "changed"
+	Unchanged ChangeType = "unchanged"
+)
+
+// Side identifies which input document a pane of the report shows.
+type Side string
+
+const (
+	SideA Side = "a"
+	SideB Side = "b"
+)
+
+type DiffResult struct {
+	ID   string `json:"id"`
+	Path string `json:"path"`
+	// Pointer is Path as an RFC 6901 JSON Pointer, which stays unambiguous
+	// for keys containing dots, slashes or brackets.
+	Pointer string `json:"pointer"`
+	Type    string `json:"type"`
+	// Kind refines Type: set-added and set-removed for members of objects
+	// compared with --map-as-set, nulled and un-nulled for updates to or
+	// from null, moved-path for a move found by --detect-moves,
+	// paired-element for elements paired by --array-match-threshold.
+	Kind string `json:"kind,omitempty"`
+	// MovedFrom is the old path of a moved-path or paired-element result,
+	// whose Path is the new one, and of a change inside a paired element.
+	MovedFrom string `json:"movedFrom,omitempty"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	// FromJSON and ToJSON are the exact JSON of the old and new value,
+	// copied by the table's copy buttons; empty for the missing side.
+	FromJSON string `json:"-"`
+	ToJSON   string `json:"-"`
+	// FromFormatted and ToFormatted are the old and new value as
+	// --display-number-locale and --display-dates show them in the table,
+	// when that differs from From and To.
+	FromFormatted string `json:"-"`
+	ToFormatted   string `json:"-"`
+
+	// JSON types of the old and new value; empty for the missing side of
+	// an addition or removal.
+	FromType string `json:"fromType,omitempty"`
+	ToType   string `json:"toType,omitempty"`
+
+	// Set when both sides of an update are numbers, or quantities compared
+	// with --units, in which case Unit is duration (seconds) or bytes.
+	Delta        *float64 `json:"delta,omitempty"`
+	DeltaPercent *float64 `json:"deltaPercent,omitempty"`
+	Unit         string   `json:"unit,omitempty"`
+	// NonFinite replaces the delta of a --units comparison in which either
+	// side spells NaN or an infinity, e.g. "NaN (non-standard) → 512Mi".
+	NonFinite string `json:"nonFinite,omitempty"`
+
+	// Source lines and byte offsets of the old and new value, when known.
+	// Offsets locate values in minified single-line inputs.
+	FromLine   int   `json:"fromLine,omitempty"`
+	ToLine     int   `json:"toLine,omitempty"`
+	FromOffset int64 `json:"fromOffset,omitempty"`
+	ToOffset   int64 `json:"toOffset,omitempty"`
+
+	// Target is the id of the Modified tree item for Path, which the HTML
+	// table row names in data-target.
+	Target string `json:"-"`
+
+	// FoldedFrom is the path in file1 when --ignore-key-case paired a
+	// differently cased key on the way to this change.
+	FoldedFrom string `json:"foldedFrom,omitempty"`
+
+	// Link is the URL the config's "links" give for Path.
+	Link string `json:"link,omitempty"`
+
+	// Gating is set when the change matches --fail-on-path and so fails
+	// the run.
+	Gating bool `json:"gating,omitempty"`
+
+	// Populated only when a JSON Schema is supplied with --schema.
+	SchemaTitle       string   `json:"schemaTitle,omitempty"`
+	SchemaDescription string   `json:"schemaDescription,omitempty"`
+	SchemaErrors      []string `json:"schemaErrors,omitempty"`
+}
+
+// runDiff compares two documents; it is the diff subcommand and also runs
+// when jsondiff is invoked without one. When complete is non-nil it is handed
+// the diff flags instead, so completion scripts cover them.
+func runDiff(args []string, complete func(fs *flag.FlagSet) int) int {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: jsondiff [diff] [flags] file1.json file2.json")
+		fmt.Fprintln(fs.Output(), "       jsondiff [diff] [flags] --exec-a CMD --exec-b CMD (either replaces its file)")
+		fmt.Fprintln(fs.Output(), "       producer | jsondiff [diff] [flags] --stdin-pair")
+		fmt.Fprintln(fs.Output(), "       jsondiff help [command]")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+
+	var opts Options
+	opts.RegisterFlags(fs)
+	var printTemplateAPI bool
+	var optionsJSON string
+	fs.BoolVar(&printTemplateAPI, "template-api-version", false, "Print the template API version custom templates are executed with, and exit")
+	fs.StringVar(&optionsJSON, "options-json", "", "Read all options from this JSON file (- for stdin), an object keyed by flag name (output for -o); flags given as well override it")
+	// Completion scripts are generated from the flags defined above.
+	if complete != nil {
+		return complete(fs)
+	}
+
+	start := time.Now()
+	args, err := parseInterspersed(fs, args)
+	if err != nil {
+		return flagExit(err)
+	}
+	if optionsJSON == "-" && opts.StdinPair {
+		return fatalf("--options-json - cannot be combined with --stdin-pair, which also reads stdin")
+	}
+	if optionsJSON != "" {
+		if err := opts.applyOptionsJSON(optionsJSON, fs); err != nil {
+			return fatalf("Failed to read --options-json %s: %v", optionsJSON, err)
+		}
+	}
+	execOpts := execOptions{shell: opts.Shell, timeout: opts.ExecTimeout.Duration()}
+	options := effectiveOptions(opts)
+
+	if printTemplateAPI {
+		fmt.Println(templateAPIVersion)
+		return exitOK
+	}
+	if opts.Format == "list" {
+		for _, name := range rendererNames() {
+			fmt.Printf("%s\t.%s\n", name, renderers[name].DefaultExtension())
+		}
+		return exitOK
+	}
+
+	// Each --exec-a/--exec-b command replaces one positional file.
+	inputs := []inputSource{{command: opts.ExecA, subpath: opts.PathA}, {command: opts.ExecB, subpath: opts.PathB}}
+	for i := range inputs {
+		if inputs[i].command == "" && len(args) > 0 {
+			inputs[i].path, args = args[0], args[1:]
+		}
+	}
+	for i, expr := range []string{opts.ProjectA, opts.ProjectB} {
+		flagName := []string{"project-a", "project-b"}[i]
+		if expr == "" {
+			expr, flagName = opts.Project, "project"
+		}
+		if expr == "" {
+			continue
+		}
+		p, err := parseProjection(expr)
+		if err != nil {
+			return fatalf("Invalid --%s: %v", flagName, err)
+		}
+		inputs[i].projection = p
+	}
+	if err := opts.Validate(); err != nil {
+		return fatal(err)
+	}
+	if opts.StdinPair {
+		if len(args) != 0 || inputs[0].path != "" || inputs[1].path != "" {
+			return fatalf("--stdin-pair reads both documents from stdin and takes no files")
+		}
+	} else if len(args) != 0 || (inputs[0].command == "" && inputs[0].path == "") || (inputs[1].command == "" && inputs[1].path == "") {
+		fs.SetOutput(os.Stdout)
+		fs.Usage()
+		return exitError
+	}
+
+	if opts.Verbose {
+		l, err := newStderrLogger(opts.LogFormat)
+		if err != nil {
+			return fatal(err)
+		}
+		SetLogger(l)
+	}
+	// Validate has checked every value, so parsing them again cannot fail.
+	renderer := renderers[opts.Format]
+	normForm, normalize, _ := parseNormalizationForm(opts.NormalizeUnicode)
+	granularity, _ := parseArrayGranularity(opts.ArrayGranularity)
+	unitRules, _ := parseUnitRules(opts.Units)
+	failOn, _ := parseFailOn(opts.FailOn)
+	gates, _ := parseGatePatterns(opts.FailOnPath)
+	exitCodes, _ := parseExitCodeMap(opts.ExitCodeMap)
+	valuePatterns, _ := parseValuePatterns(opts.IgnoreValues, opts.IgnoreValueRegex)
+	include, _ := parseIncludeScope(opts.Include)
+	if opts.ExpandAll {
+		opts.ArrayContext = 0
+	}
+	writeReport := !opts.StatsOnly && (opts.Output != "" || !opts.SummaryOnly)
+	output := outputOptions{minify: opts.MinifyHTML}
+	output.compress, _ = parseCompression(opts.Compress, opts.Output)
+	limits := defaultInputLimits
+	limits.maxDepth, limits.maxArrayLength = opts.MaxDepth, opts.MaxArrayLength
+	limits.maxBytes, _ = parseByteSize(opts.MaxInputSize)
+	if opts.StdinPair {
+		pair, err := readStdinPair(os.Stdin, limits)
+		if err != nil {
+			return fatalf("Failed to read --stdin-pair input: %v", err)
+		}
+		defer pair.remove()
+		inputs[0], inputs[1] = pair.inputs[0], pair.inputs[1]
+		inputs[0].subpath, inputs[1].subpath = opts.PathA, opts.PathB
+	}
+	if opts.Swap {
+		inputs[0], inputs[1] = inputs[1], inputs[0]
+	}
+	if tpl, _ := parseOutputTemplate(opts.Output); tpl != nil {
+		name, err := renderOutputName(tpl, newOutputNameFields(inputs[0], inputs[1], start))
+		if err != nil {
+			return fatalf("Failed to name the output: %v", err)
+		}
+		opts.Output = name
+	}
+
+	var historyDir string
+	if opts.Output != "" && opts.Output != "-" && isOutputDir(opts.Output) {
+		historyDir = opts.Output
+		name, err := historyReportName(historyDir, inputs[0].label(), inputs[1].label(), renderer.DefaultExtension(), output.compress, start)
+		if err != nil {
+			return fatalf("Failed to prepare output directory %s: %v", historyDir, err)
+		}
+		opts.Output = name
+	}
+	if opts.Output == "" {
+		opts.Output = "diff." + renderer.DefaultExtension()
+		if output.compress == "gzip" {
+			opts.Output += ".gz"
+		}
+	}
+
+	var cfg Config
+	if opts.Config != "" {
+		loaded, err := loadConfig(opts.Config)
+		if err != nil {
+			return fatalf("Failed to load config %s: %v", opts.Config, err)
+		}
+		cfg = *loaded
+	}
+	// loadConfig has checked the link rules.
+	links, _ := compileLinkRules(cfg.Links)
+	weights, _ := compileWeightRules(cfg.Weights)
+
+	transforms := comparisonTransforms{rules: cfg.Transforms}
+	if normalize {
+		transforms.global = append(transforms.global, unicodeTransform(normForm))
+		if opts.NormalizeKeys {
+			transforms.key = normForm.String
+		}
+	}
+	if opts.TrimSpace {
+		transforms.global = append(transforms.global, trimSpaceTransform)
+	}
+	if opts.CollapseSpace {
+		transforms.global = append(transforms.global, collapseSpaceTransform)
+	}
+	if opts.IgnoreCase {
+		transforms.global = append(transforms.global, ignoreCaseTransform)
+	}
+
+	file1, file2 := inputs[0].name(), inputs[1].name()
+	decode := decodeOptions{positions: opts.LineNumbers || opts.DetectKeyReorder, exactNumbers: opts.DecimalStrict || opts.NumericStrict, inputFormat: opts.InputFormat}
+	doc1, err := readInput(inputs[0], execOpts, limits, decode)
+	if err != nil {
+		return fatal(err)
+	}
+	doc2, err := readInput(inputs[1], execOpts, limits, decode)
+	if err != nil {
+		return fatal(err)
+	}
+	json1, positions1 := doc1.value, doc1.positions
+	json2, positions2 := doc2.value, doc2.positions
+	inputStats := [2]InputStats{doc1.stats, doc2.stats}
+	if msg := sizeMismatch(inputStats); msg != "" {
+		fmt.Fprintln(os.Stderr, "Warning: "+msg)
+	}
+	var reorders []KeyReorder
+	if opts.DetectKeyReorder {
+		// Member order comes from the source offsets, so this runs before
+		// the key map renames members of file1.
+		if inputs[0].projection != nil || inputs[1].projection != nil {
+			fmt.Fprintln(os.Stderr, "Warning: --detect-key-reorder cannot see the member order of projected inputs")
+		}
+		reorders = detectKeyReorders(json1, json2, positions1, positions2)
+		if !opts.LineNumbers {
+			positions1, positions2 = nil, nil
+		}
+	}
+	var summaryInputs [2]summaryInput
+	if opts.SummaryOut != "" || !opts.StatsOnly && (opts.Bundle != "" || historyDir != "" || opts.Format == "html" || opts.Format == "json") {
+		// Hash the documents as read, before the key map renames members
+		// of file1.
+		summaryInputs[0] = summaryInput{Label: inputs[0].label(), SHA256: documentHash(json1)}
+		summaryInputs[1] = summaryInput{Label: inputs[1].label(), SHA256: documentHash(json2)}
+	}
+
+	var remapped map[string]RemappedKey
+	if opts.KeyMap != "" {
+		mappings, err := loadKeyMap(opts.KeyMap)
+		if err != nil {
+			return fatalf("Failed to load key map %s: %v", opts.KeyMap, err)
+		}
+		json1, remapped = applyKeyMap(json1, doc1.root, mappings)
+	}
+
+	// Comparison runs on transformed copies so the report keeps the original bytes.
+	cmp1, cmp2 := json1, json2
+	warn := func(msg string) { fmt.Fprintln(os.Stderr, "Warning: "+msg) }
+	// The transforms hide differences rather than drop changes, so they
+	// record what they hid here for --show-suppressed.
+	suppressed := newSuppressionLog()
+	var folds *keyFolds
+	if opts.IgnoreKeyCase {
+		cmp1, folds = foldKeyCase(json1, json2, warn)
+		folds.suppress(suppressed)
+	}
+	var approx map[string]bool
+	if !transforms.empty() {
+		originals1, originals2 := make(map[string]string), make(map[string]string)
+		cmp1 = transforms.apply(cmp1, originals1, warn)
+		cmp2 = transforms.apply(json2, originals2, warn)
+		approx = approxEqualPaths(cmp1, cmp2, originals1, originals2)
+		transforms.suppress(approx, cmp1, originals1, originals2, suppressed)
+	}
+	sets := parseSetPatterns(opts.MapAsSet)
+	if len(sets) > 0 {
+		sets.suppressValues(cmp1, cmp2, nil, suppressed)
+		cmp1, cmp2 = sets.collapseSets(cmp1, nil), sets.collapseSets(cmp2, nil)
+	}
+	var literals1, literals2 map[string]string
+	if opts.DecimalStrict {
+		literals1, literals2 = make(map[string]string), make(map[string]string)
+		cmp1 = canonicalizeDecimals(cmp1, "", literals1)
+		cmp2 = canonicalizeDecimals(cmp2, "", literals2)
+	}
+
+	stopProfile := func() {}
+	if opts.Profile != "" {
+		pf, err := os.Create(opts.Profile)
+		if err != nil {
+			return fatalf("Failed to create profile: %v", err)
+		}
+		if err := pprof.StartCPUProfile(pf); err != nil {
+			return fatalf("Failed to start profile: %v", err)
+		}
+		stopProfile = func() {
+			pprof.StopCPUProfile()
+			pf.Close()
+		}
+	}
+
+	ctx := context.Background()
+	if opts.Timeout.Duration() > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout.Duration())
+		defer cancel()
+	}
+
+	done := phase("diff")
+	changes, err := collectChanges(diffSeq(ctx, cmp1, cmp2))
+	if err != nil {
+		if ctx.Err() != nil {
+			return fatal(cancelledError(opts.Timeout.Duration(), len(changes)))
+		}
+		return fatalf("Failed to diff: %v", err)
+	}
+	var wholeArrays map[string]bool
+	if granularity.active() {
+		changes, wholeArrays = granularity.collapseArrays(changes, cmp1, cmp2)
+	}
+	var multisetCounts map[string][]multisetCount
+	if bags := parseSetPatterns(append(opts.Multiset, cfg.Multisets...)); len(bags) > 0 {
+		changes, multisetCounts = bags.collapseMultisets(changes, cmp1, cmp2)
+	}
+	done("changes", len(changes))
+
+	if include.active() {
+		changes = filterInclude(changes, include, cmp1, cmp2, suppressed)
+	}
+	if opts.SchemaAware {
+		changes = filterSchemaComments(changes, cmp1, cmp2, suppressed)
+	}
+	if opts.EmptyEqualsAbsent {
+		changes = filterEmptyEqualsAbsent(changes, opts.Deep, suppressed)
+	}
+	if len(valuePatterns) > 0 {
+		changes = filterValuePatterns(changes, valuePatterns, suppressed)
+	}
+	var quantities unitQuantities
+	if len(unitRules) > 0 {
+		if approx == nil {
+			approx = make(map[string]bool)
+		}
+		changes, quantities = filterUnits(changes, unitRules, cmp1, cmp2, approx, suppressed)
+	}
+	if opts.Subset {
+		changes = filterChangeType(changes, diff.CREATE, reasonSubset, suppressed)
+	}
+	if opts.Superset {
+		changes = filterChangeType(changes, diff.DELETE, reasonSuperset, suppressed)
+	}
+
+	// Masking happens after diffing so secrets are still compared, and
+	// before the table is built so no output ever sees them.
+	shownA, shownB := json1, json2
+	var red *redactor
+	var redactions []Redaction
+	if opts.AutoRedact {
+		red = newRedactor(cfg.RedactKeys)
+		red.changes(changes, cmp1, cmp2)
+		suppressed.redact(red, cmp1, cmp2)
+		shownA, shownB = red.document(json1), red.document(json2)
+		redactions = red.list()
+	}
+
+	done = phase("index")
+	diffMap := buildDiffMap(changes, cmp1, cmp2)
+	var diffTable []DiffResult
+	if opts.StatsOnly {
+		diffTable = buildStatsTable(changes, cmp1, cmp2)
+	} else {
+		diffTable = buildDiffTable(changes, cmp1, cmp2)
+	}
+	setUnitDeltas(diffTable, quantities)
+	markWholeArrays(diffTable, wholeArrays, cmp1, cmp2)
+	markMultisets(diffTable, multisetCounts)
+	done("paths", diffMap.Len())
+	if opts.DecimalStrict {
+		restoreDecimalLiterals(diffTable, literals1, literals2)
+	}
+	sets.markSetChanges(diffTable)
+	if folds != nil {
+		for i := range diffTable {
+			if orig := folds.originalPath(diffTable[i].Path); orig != diffTable[i].Path {
+				diffTable[i].FoldedFrom = orig
+			}
+		}
+	}
+	if opts.LineNumbers {
+		annotateLines(diffTable, positions1, positions2, func(p string) string {
+			return unmappedPath(folds.originalPath(p), remapped)
+		})
+	}
+	if opts.ArrayMatchThreshold > 0 {
+		diffTable, err = pairSimilarElements(ctx, diffTable, cmp1, cmp2, diffMap, opts.ArrayMatchThreshold)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fatal(cancelledError(opts.Timeout.Duration(), len(changes)))
+			}
+			return fatalf("Failed to diff paired elements: %v", err)
+		}
+	}
+	if opts.DetectMoves {
+		diffTable = detectMoves(diffTable, cmp1, cmp2, diffMap)
+	}
+
+	if opts.Schema != "" {
+		schema, err := loadSchema(opts.Schema)
+		if err != nil {
+			return fatalf("Failed to load schema %s: %v", opts.Schema, err)
+		}
+		annotateSchema(diffTable, schema, json1, json2)
+		if red != nil {
+			red.schemaErrors(diffTable)
+		}
+	}
+
+	setLinks(diffTable, links)
+	display, _ := parseDisplayFormat(opts.NumberLocale, opts.DisplayDates, time.Now())
+	setFormattedValues(diffTable, display)
+
+	acks, err := parseAcks(opts.Ack, opts.AckFile)
+	if err != nil {
+		return fatalf("Failed to read acks file %s: %v", opts.AckFile, err)
+	}
+	active, acknowledged, unknownAcks := splitAcknowledged(diffTable, acks)
+	for _, id := range unknownAcks {
+		fmt.Fprintf(os.Stderr, "Warning: acknowledged change %s not found in this diff\n", id)
+	}
+	var metadata []DiffResult
+	var schemaBanner string
+	if opts.SchemaAware {
+		active, metadata = splitMetadata(active)
+		if schemaBanner = schemaMismatch(json1, json2); schemaBanner != "" {
+			fmt.Fprintln(os.Stderr, "Warning: "+schemaBanner)
+		}
+	}
+	invalidChanges := countSchemaInvalid(active)
+	gating := markGating(active, gates)
+	var swapWarning string
+	if !opts.NoSwapWarning {
+		counts := summarize(active, 0)
+		swapWarning = swapSuspicion(swapEvidence{
+			added:    counts.Added,
+			removed:  counts.Removed,
+			modTimes: [2]time.Time{inputs[0].modTime(os.Stat), inputs[1].modTime(os.Stat)},
+			labels:   [2]string{inputs[0].label(), inputs[1].label()},
+			order:    cfg.EnvironmentOrder,
+		})
+		if swapWarning != "" {
+			fmt.Fprintln(os.Stderr, "Warning: "+swapWarning)
+		}
+	}
+
+	aggs := computeAggregates(opts.Aggregate, json1, json2, func(msg string) {
+		fmt.Fprintln(os.Stderr, "Warning: "+msg)
+	})
+
+	arrayStats := buildArrayStats(active)
+	renderCtx := renderContext{
+		diffMap:      diffMap,
+		remapped:     remapped,
+		approx:       approx,
+		showGhosts:   opts.ShowGhosts,
+		arrayContext: opts.ArrayContext,
+		lazyDepth:    opts.LazyDepth,
+		arrayStats:   arrayStats,
+		wholeArrays:  wholeArrays,
+		multisets:    multisetCounts,
+		reordered:    reorderSet(reorders),
+		links:        links,
+		display:      display,
+		sets:         sets,
+		editable:     opts.Editable,
+		keySegment:   transforms.key,
+		folds:        folds,
+		positions:    [2]map[string]Position{positions1, positions2},
+		classes:      cfg.Classes,
+		templateDir:  opts.TemplateDir,
+	}
+	if !opts.StatsOnly {
+		renderCtx.ordinals = nodeOrdinals(shownA, shownB, renderCtx)
+		renderCtx.nodeIDs = nodeIDs(shownB, renderCtx)
+		setTargets(active, renderCtx.nodeIDs)
+		setTargets(acknowledged, renderCtx.nodeIDs)
+		setTargets(metadata, renderCtx.nodeIDs)
+	}
+	sortResults(active, opts.Sort, renderCtx.ordinals)
+	sortResults(acknowledged, opts.Sort, renderCtx.ordinals)
+	sections := buildSections(active, opts.CollapseThreshold)
+	var scores similarityScores
+	if !opts.StatsOnly || opts.SummaryOut != "" {
+		// --stats-only prints only the plain similarity unless a summary
+		// file asks for the weighted one.
+		scores = scoreSimilarity(cmp1, cmp2, diffMap, weights)
+	}
+	report := &Report{
+		Original:     file1,
+		Modified:     file2,
+		LabelA:       inputs[0].label(),
+		LabelB:       inputs[1].label(),
+		ProjectionA:  inputs[0].projection.String(),
+		ProjectionB:  inputs[1].projection.String(),
+		Include:      include.patterns,
+		SwapWarning:  swapWarning,
+		SchemaBanner: schemaBanner,
+		AllLeaves:    opts.IncludeUnchanged,
+		MaxNodes:     opts.GraphMaxNodes,
+		A:            shownA,
+		B:            shownB,
+		Total:        len(active),
+		Legend:       buildLegend(active),
+		Arrays:       sortedArrayStats(arrayStats),
+		TOC:          buildTOC(shownA, shownB, renderCtx),
+		HeatMap:      buildHeatMap(active, renderCtx.ordinals, opts.ShowGhosts),
+		Sections:     sections,
+		Acknowledged: acknowledged,
+		Metadata:     metadata,
+		Aggregates:   aggs,
+		Suppressed:   suppressed.list(opts.ShowSuppressed, cmp1, cmp2),
+		Redacted:     redactions,
+		Remapped:     sortedRemaps(remapped),
+		Reordered:    reorders,
+		InputStats:   inputStats,
+		Schema:       opts.Schema != "",
+		Editable:     opts.Editable,
+		Inputs:       summaryInputs,
+		Similarity:   similarity(cmp1, cmp2, diffMap),
+		Scores:       scores,
+		Options:      options,
+		render:       renderCtx,
+	}
+
+	// Everything above computes the diff; only rendering remains.
+	if ctx.Err() != nil {
+		return fatal(cancelledError(opts.Timeout.Duration(), len(changes)))
+	}
+	var written string
+	done = phase("render", "format", opts.Format, "output", opts.Output)
+	switch {
+	case !writeReport:
+	case opts.Paginate > 0:
+		files, err := writePaginatedHTML(opts.Output, report, opts.Paginate)
+		if err != nil {
+			return fatalf("Failed to write report: %v", err)
+		}
+		written = fmt.Sprintf("Diff written to %s (%d pages)", opts.Output, len(files)-1)
+	default:
+		if err := writeReportFile(opts.Output, renderer, report, output); err != nil {
+			return fatalf("Failed to write report: %v", err)
+		}
+		written = "Diff written to " + opts.Output
+	}
+	if writeReport {
+		done()
+	}
+	stopProfile()
+
+	if opts.EmitChangedA != "" {
+		if err := emitChanged(opts.EmitChangedA, shownA, diffMap, folds.segment, opts.EmitArrayMode); err != nil {
+			return fatalf("Failed to write %s: %v", opts.EmitChangedA, err)
+		}
+	}
+	if opts.EmitChangedB != "" {
+		identity := func(_, key string) string { return key }
+		if err := emitChanged(opts.EmitChangedB, shownB, diffMap, identity, opts.EmitArrayMode); err != nil {
+			return fatalf("Failed to write %s: %v", opts.EmitChangedB, err)
+		}
+	}
+
+	failing := countFailOn(active, failOn)
+	if failOn[kindReordered] {
+		failing += len(reorders)
+	}
+	var causes []exitCause
+	if gating > 0 {
+		causes = append(causes, exitCause{exitChangesFound, fmt.Sprintf("%d change(s) match --fail-on-path %s", gating, strings.Join(opts.FailOnPath, ","))})
+	}
+	if invalidChanges > 0 {
+		causes = append(causes, exitCause{exitSchemaViolation, fmt.Sprintf("%d change(s) make %s invalid against %s", invalidChanges, file2, opts.Schema)})
+	}
+	if failing > 0 {
+		causes = append(causes, exitCause{exitChangesFound, fmt.Sprintf("%d change(s) match --fail-on %s", failing, opts.FailOn)})
+	}
+	causes = append(causes, exitCodes.exitCauses(active, reorders)...)
+	exitCode, exitReason := chooseExitCode(causes)
+	summary := newSummaryFile(summaryInputs, active, sections, report.Similarity, report.Scores, exitCode, exitReason, time.Since(start))
+	summary.Include = include.patterns
+	if len(gates) > 0 {
+		summary.Gating = &gating
+	}
+	if opts.SummaryOut != "" {
+		if err := writeSummaryFile(opts.SummaryOut, summary); err != nil {
+			return fatalf("Failed to write %s: %v", opts.SummaryOut, err)
+		}
+	}
+	if opts.ValidateOutput {
+		checks := map[string]interface{}{}
+		if writeReport && opts.Format == "json" {
+			checks["json"] = report.jsonReport()
+		}
+		if opts.SummaryOut != "" {
+			checks["summary"] = summary
+		}
+		for _, name := range outputSchemaNames {
+			if v, ok := checks[name]; ok {
+				if err := validateOutput(name, v); err != nil {
+					return fatalf("--validate-output: %v", err)
+				}
+			}
+		}
+	}
+	if historyDir != "" {
+		if err := writeHistory(historyDir, opts.Output, start, summary, report); err != nil {
+			return fatalf("Failed to update %s: %v", filepath.Join(historyDir, historyIndex), err)
+		}
+	}
+	if opts.Bundle != "" {
+		if err := writeBundle(opts.Bundle, report, summary); err != nil {
+			return fatalf("Failed to write bundle %s: %v", opts.Bundle, err)
+		}
+	}
+
+	switch {
+	case opts.Quiet:
+	case opts.SummaryOnly || opts.StatsOnly:
+		s := summarize(active, report.Similarity)
+		s.Include = include.patterns
+		if len(gates) > 0 {
+			s.Gating = &gating
+		}
+		fmt.Println(s)
+	case written != "" && opts.Output != "-":
+		fmt.Println(written)
+	}
+
+	for _, c := range causes {
+		if c.code != exitOK {
+			fmt.Fprintln(os.Stderr, c.reason)
+		}
+	}
+	return exitCode
+}
+
+// writeHTMLReport renders the side-by-side trees and the diff table through
+// template.html. base holds the rendering state shared by both panes and
+// data supplies everything except the two documents.
+func writeHTMLReport(w io.Writer, json1, json2 interface{}, base renderContext, data *ReportContext) error {
+	tpl, err := parseReportTemplate(json1, json2, base)
+	if err != nil {
+		return err
+	}
+
+	// renderJSON walks object keys in sorted order itself, so the documents
+	// are passed through as decoded.
+	data.Original = json1
+	data.Modified = json2
+	if data.Editable {
+		blob, err := embeddedJSON(json2)
+		if err != nil {
+			return err
+		}
+		data.ModifiedJSON = blob
+	}
+	if err := tpl.ExecuteTemplate(w, "template.html", data); err != nil {
+		return templateDataError(err)
+	}
+	return nil
+}
+
+// embeddedJSON encodes v for a <script type="application/json"> element.
+// encoding/json escapes <, > and & (and U+2028/U+2029), so no "</script>"
+// or "<!--" in a value can end the element early, and JSON.parse restores
+// the values exactly.
+func embeddedJSON(v interface{}) (template.JS, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return template.JS(data), nil
+}
+
+// reportTemplate is the report template, built in so reports render the
+// same whatever the working directory.
+//
+//go:embed template.html
+var reportTemplate string
+
+// parseReportTemplate parses the report template with renderJSON bound to
+// the two documents. The *.html partials in base.templateDir, set by
+// --template-dir, are parsed after it: a file defining e.g.
+// {{define "diff-table"}} replaces that partial of the default report.
+func parseReportTemplate(json1, json2 interface{}, base renderContext) (*template.Template, error) {
+	t, err := template.New("template.html").Funcs(template.FuncMap{
+		"class": base.cls,
+		// v0 templates call renderJSON without a side; it is then inferred
+		// from the document passed.
+		"renderJSON": func(v interface{}, path string, sides ...Side) template.HTML {
+			side := SideA
+			switch {
+			case len(sides) > 0:
+				side = sides[0]
+			case sameDocument(v, json2):
+				side = SideB
+			}
+			ctx := base
+			ctx.side = side
+			ctx.other = json2
+			if side == SideB {
+				ctx.remapped = nil
+				ctx.other = json1
+			}
+			return renderJSON(v, path, &ctx)
+		},
+	}).Parse(reportTemplate)
+	if err != nil {
+		return nil, err
+	}
+	if base.templateDir == "" {
+		return t, nil
+	}
+	partials, err := filepath.Glob(filepath.Join(base.templateDir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	if len(partials) == 0 {
+		return nil, fmt.Errorf("--template-dir %s holds no *.html partials", base.templateDir)
+	}
+	if err := checkTemplateAPI(partials); err != nil {
+		return nil, err
+	}
+	return t.ParseFiles(partials...)
+}
+
+// inputSource is one side of the comparison: a file, or a command whose
+// stdout is the document.
+type inputSource struct {
+	path    string
+	command string
+	// subpath, when set, selects the subtree that is compared.
+	subpath string
+	// projection, when set, is applied after subpath.
+	projection *projection
+	// display, when set, names the input instead of path, which is then a
+	// temporary file.
+	display string
+}
+
+// name identifies the input in messages and reports.
+func (s inputSource) name() string {
+	name := s.path
+	if s.command != "" {
+		name = s.command
+	}
+	if s.display != "" {
+		name = s.display
+	}
+	return s.withSubpath(name)
+}
+
+// label is the short name shown on panes and source locations.
+func (s inputSource) label() string {
+	if s.display != "" {
+		return s.withSubpath(s.display)
+	}
+	if s.command != "" {
+		return s.withSubpath(s.command)
+	}
+	return s.withSubpath(filepath.Base(s.path))
+}
+
+func (s inputSource) withSubpath(name string) string {
+	if s.subpath == "" {
+		return name
+	}
+	return name + "#" + s.subpath
+}
+
+// inputDocument is one input as read by readInput.
+type inputDocument struct {
+	value interface{}
+	// positions holds the source positions of value's members by path.
+	positions map[string]Position
+	// stats describes the whole file as parsed.
+	stats InputStats
+	// root is the path of value in the file, as selected by --path-a or
+	// --path-b, with array indices bracketed; nil for the whole file.
+	root []string
+}
+
+// readInput reads and decodes one input. Any temporary file made on the way
+// is removed before it returns, error or not.
+func readInput(src inputSource, opts execOptions, limits inputLimits, decode decodeOptions) (inputDocument, error) {
+	filename := src.path
+	if src.command != "" {
+		done := phase("exec", "command", src.command)
+		var err error
+		if filename, err = runInputCommand(src.command, opts); err != nil {
+			return inputDocument{}, fmt.Errorf("Command %q failed: %w", src.command, err)
+		}
+		defer os.Remove(filename)
+		done()
+	}
+
+	done := phase("parse", "input", src.name())
+	decode.warn = func(msg string) {
+		fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", src.name(), msg)
+	}
+	source := filename
+	filename, encoding, err := transcodeInput(source, limits.maxBytes, decode.warn)
+	if err != nil {
+		return inputDocument{}, fmt.Errorf("Failed to read %s: %w", src.name(), err)
+	}
+	if filename != source {
+		defer os.Remove(filename)
+	}
+	parsed, positions, err := readJSON(filename, limits, decode)
+	if err != nil {
+		return inputDocument{}, fmt.Errorf("Failed to read %s: %w", src.name(), err)
+	}
+	stats := documentStats(parsed)
+	stats.Label = src.label()
+	stats.Encoding = encoding
+	if info, err := os.Stat(source); err == nil {
+		stats.Bytes = info.Size()
+		done("bytes", info.Size())
+	} else {
+		done()
+	}
+	var root []string
+	if src.subpath != "" {
+		segs := splitPath(src.subpath)
+		root = typedPath(segs, parsed, nil)
+		var ok bool
+		if parsed, ok = lookupPath(parsed, segs); !ok {
+			return inputDocument{}, fmt.Errorf("Path %s not found in %s", src.subpath, inputSource{path: src.path, command: src.command, display: src.display}.name())
+		}
+		positions = rebasePositions(positions, joinPath(root))
+	}
+	if src.projection != nil {
+		if parsed, err = src.projection.Apply(parsed); err != nil {
+			return inputDocument{}, fmt.Errorf("Failed to apply projection %s to %s: %w", src.projection.source, src.name(), err)
+		}
+		// The projected document has no source lines of its own.
+		positions = nil
+	}
+	return inputDocument{value: parsed, positions: positions, stats: stats, root: root}, nil
+}
+
+// changePath returns the path of c with array elements bracketed, judged
+// against the compared documents a and b.
+func changePath(c diff.Change, a, b interface{}) string {
+	return joinPath(typedPath(c.Path, a, b))
+}
+
+func buildDiffMap(changes []diff.Change, a, b interface{}) *DiffMap {
+	m := newDiffMap()
+	for _, c := range changes {
+		m.Add(changePath(c, a, b), resultChangeType(c.Type))
+	}
+	return m
+}
+
+func buildDiffTable(changes []diff.Change, a, b interface{}) []DiffResult {
+	results := make([]DiffResult, 0, len(changes))
+	for _, c := range changes {
+		path := changePath(c, a, b)
+		r := DiffResult{
+			ID:      changeID(path, c.Type, c.From, c.To),
+			Path:    path,
+			Pointer: jsonPointer(c.Path, a, b),
+			Type:    c.Type,
+		}
+		if c.Type != "create" {
+			r.From, r.FromType, r.FromJSON = formatValue(c.From), jsonTypeName(c.From), jsonPayload(c.From)
+		}
+		if c.Type != "delete" {
+			r.To, r.ToType, r.ToJSON = formatValue(c.To), jsonTypeName(c.To), jsonPayload(c.To)
+		}
+		if c.Type == "update" {
+			setDelta(&r, c.From, c.To)
+			r.Kind = nullKind(c.From, c.To)
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// buildStatsTable builds the results for --stats-only, which counts them
+// and so needs only the path, type, kind and JSON types of each change: no
+// values are formatted or encoded, and results get no ID.
+func buildStatsTable(changes []diff.Change, a, b interface{}) []DiffResult {
+	results := make([]DiffResult, 0, len(changes))
+	for _, c := range changes {
+		r := DiffResult{Path: changePath(c, a, b), Type: c.Type}
+		if c.Type != "create" {
+			r.FromType = jsonTypeName(c.From)
+		}
+		if c.Type != "delete" {
+			r.ToType = jsonTypeName(c.To)
+		}
+		if c.Type == "update" {
+			r.Kind = nullKind(c.From, c.To)
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// renderContext carries the per-pane state renderJSON needs beyond the value itself.
+type renderContext struct {
+	diffMap  *DiffMap
+	remapped map[string]RemappedKey
+
+	// approx holds paths whose values differ only in ways a comparison
+	// transform ignored; they are marked with "≈".
+	approx map[string]bool
+
+	// positions holds the source positions of documents A and B by path.
+	positions [2]map[string]Position
+
+	// keySegment, when set, maps an object key to the path segment used for
+	// change lookups (e.g. its Unicode-normalized form).
+	keySegment func(string) string
+
+	// folds holds the members of A paired case-insensitively with B.
+	folds *keyFolds
+
+	// editable makes changed leaves of the Modified pane editable, and
+	// gives its items data-path attributes locating them in the document.
+	editable bool
+
+	// sets holds the --map-as-set patterns; matching objects show only
+	// their keys.
+	sets setPatterns
+
+	// arrayStats holds the element counts shown next to changed arrays.
+	arrayStats map[string]ArrayStats
+
+	// ordinals numbers the Modified tree's items for the heat map.
+	ordinals map[string]int
+	// nodeIDs holds the ids of the Modified tree's items.
+	nodeIDs map[string]string
+
+	// wholeArrays holds the arrays compared whole by --array-granularity;
+	// they start collapsed.
+	wholeArrays map[string]bool
+	// multisets holds the value count changes of arrays compared as
+	// multisets, shown under the array.
+	multisets map[string][]multisetCount
+	// links are the config's link rules, used for changed items.
+	links linkRules
+	// display formats numbers and timestamps for reading, per
+	// --display-number-locale and --display-dates.
+	display *displayFormat
+
+	// reordered holds the objects reported by --detect-key-reorder.
+	reordered map[string]KeyReorder
+
+	// arrayContext, when positive, limits arrays containing changes to the
+	// changed elements and this many neighbours on each side.
+	arrayContext int
+	// levelOffset is added to the aria-level of tree items: one for each
+	// folded gap of array elements they are rendered in.
+	levelOffset int
+
+	// lazyDepth, when positive, defers the unchanged containers that many
+	// levels deep (see deferred); lazy collects them while a pane renders.
+	lazyDepth int
+	lazy      *[]lazySubtree
+
+	// classes maps logical class names to the classes emitted for them,
+	// from the config's "classes" section.
+	classes classMap
+	// templateDir holds the --template-dir partials, if any.
+	templateDir string
+
+	// When showGhosts is set, members present only in other (the document
+	// shown in the opposite pane) are rendered as ghosts at their position.
+	showGhosts bool
+	side       Side
+	other      interface{}
+	// ghost is set while a ghost's value renders; its lists and items take
+	// no part in the tree, so keyboard navigation skips them.
+	ghost bool
+}
+
+// segment returns the path segment for the member key of the object at
+// parent.
+func (ctx *renderContext) segment(parent, key string) string {
+	if ctx.side != SideB {
+		key = ctx.folds.segment(parent, key)
+	}
+	if ctx.keySegment != nil {
+		return ctx.keySegment(key)
+	}
+	return key
+}
+
+// pairedElsewhere reports whether key, a member only the other document
+// has, was paired case-insensitively with a member of own and so must not
+// be shown as a ghost.
+func (ctx *renderContext) pairedElsewhere(parent, key string, own map[string]interface{}) bool {
+	if ctx.folds == nil {
+		return false
+	}
+	if ctx.side == SideB {
+		_, ok := own[ctx.folds.segment(parent, key)]
+		return ok && ctx.folds.segment(parent, key) != key
+	}
+	return ctx.folds.pairedInA(parent, key)
+}
+
+// lineAttr returns the data-line attribute for the value at path, if its
+// source position is known.
+func (ctx *renderContext) lineAttr(path string) string {
+	positions := ctx.positions[0]
+	if ctx.side == SideB {
+		positions = ctx.positions[1]
+	} else {
+		path = unmappedPath(ctx.folds.originalPath(path), ctx.remapped)
+	}
+	if pos, ok := positions[path]; ok {
+		return attr("data-line", strconv.Itoa(pos.Line))
+	}
+	return ""
+}
+
+func (ctx *renderContext) approxMarker(path string) string {
+	if ctx.approx[path] {
+		return `<span class="` + ctx.cls("approx") + `" title="equal after comparison transforms">&#8776;</span>`
+	}
+	return ""
+}
+
+func renderJSON(v interface{}, path string, ctx *renderContext) template.HTML {
+	if path == "" && ctx.lazyDepth > 0 && ctx.lazy == nil {
+		return renderLazyTree(v, ctx)
+	}
+	var counterpart interface{}
+	if ctx.showGhosts {
+		counterpart, _ = lookupPath(ctx.other, splitPath(path))
+	}
+	return renderNode(v, counterpart, path, ctx)
+}
+
+// renderNode renders v, using counterpart (the value at the same path in the
+// other document, or nil) to place ghosts of members that exist only there.
+func renderNode(v, counterpart interface{}, path string, ctx *renderContext) template.HTML {
+	if ctx.deferred(path, v) {
+		return ctx.lazyPlaceholder(v, counterpart, path)
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		var sb strings.Builder
+		sb.WriteString(`<div class="` + ctx.cls("json-object") + `">{`)
+		sb.WriteString(ctx.reorderBadge(path))
+		sb.WriteString(ctx.listOpen(path))
+		other, _ := counterpart.(map[string]interface{})
+		keys := sortedKeys(val)
+		first := path == ""
+		asSet := ctx.sets.matches(splitPath(path))
+		for _, k := range mergedKeys(val, other) {
+			p := pathKey(path, ctx.segment(path, k))
+			vv, own := val[k]
+			if !own {
+				if ctx.pairedElsewhere(path, k, val) {
+					continue
+				}
+				sb.WriteString(renderGhost(other[k], p, ctx.keyHTML(k)+": ", ctx))
+				continue
+			}
+			id := ""
+			if path == "" {
+				id = treeAnchor(ctx.side, p)
+			}
+			if asSet {
+				sb.WriteString(ctx.itemOpen(p, nil, first, id))
+				first = false
+				sb.WriteString(ctx.keyHTML(k))
+				if k != keys[len(keys)-1] {
+					sb.WriteString(",")
+				}
+				sb.WriteString("</li>")
+				continue
+			}
+			sb.WriteString(ctx.itemOpen(p, vv, first, id))
+			first = false
+			sb.WriteString(ctx.keyHTML(k))
+			if rk, ok := ctx.remapped[p]; ok {
+				sb.WriteString(`<span class="` + ctx.cls("remapped") + `"` + attr("title", "renamed from "+rk.From) + `>&#8644;</span>`)
+			}
+			sb.WriteString(": ")
+			sb.WriteString(string(renderNode(vv, other[k], p, ctx)))
+			sb.WriteString(ctx.copyButton(vv))
+			sb.WriteString(ctx.approxMarker(p))
+			if k != keys[len(keys)-1] {
+				sb.WriteString(",")
+			}
+			sb.WriteString("</li>")
+		}
+		sb.WriteString("</ul>}")
+		sb.WriteString("</div>")
+		return template.HTML(sb.String())
+
+	case []interface{}:
+		var sb strings.Builder
+		sb.WriteString(`<div class="` + ctx.cls("json-array") + `">[`)
+		if s, ok := ctx.arrayStats[path]; ok {
+			sb.WriteString(`<span class="` + ctx.cls("array-stats") + `">` + escapeHTML(s.String()) + `</span>`)
+		}
+		sb.WriteString(ctx.multisetSummary(path))
+		sb.WriteString(ctx.listOpen(path))
+		other, _ := counterpart.([]interface{})
+		item := func(i int, first bool) {
+			p := indexKey(path, i)
+			var cp interface{}
+			if i < len(other) {
+				cp = other[i]
+			}
+			sb.WriteString(ctx.itemOpen(p, val[i], first, ""))
+			sb.WriteString(string(renderNode(val[i], cp, p, ctx)))
+			sb.WriteString(ctx.copyButton(val[i]))
+			sb.WriteString(ctx.approxMarker(p))
+			if i < len(val)-1 {
+				sb.WriteString(",")
+			}
+			sb.WriteString("</li>")
+		}
+		shown := ctx.arrayWindow(path, len(val))
+		first := path == ""
+		for i := 0; i < len(val); first = false {
+			if shown == nil || shown[i] {
+				item(i, first)
+				i++
+				continue
+			}
+			// Unchanged elements outside the context window are folded
+			// into one expandable gap item.
+			end := i
+			for end < len(val) && !shown[end] {
+				end++
+			}
+			sb.WriteString(ctx.gapOpen(path, end-i, first))
+			ctx.levelOffset++
+			for ; i < end; i++ {
+				item(i, false)
+			}
+			ctx.levelOffset--
+			sb.WriteString("</ul></div></li>")
+		}
+		for i := len(val); i < len(other); i++ {
+			sb.WriteString(renderGhost(other[i], indexKey(path, i), "", ctx))
+		}
+		sb.WriteString("</ul>]")
+		sb.WriteString("</div>")
+		return template.HTML(sb.String())
+
+	case string:
+		if attr := ctx.editAttr(path, "string"); attr != "" {
+			return template.HTML(`<span class="` + ctx.cls("json-string") + `">"<span` + attr + `>` + escapeHTML(val) + `</span>"</span>`)
+		}
+		if shown, ok := ctx.display.value(val); ok {
+			return template.HTML(`<span class="` + ctx.cls("json-string") + `"` + attr("title", jsonPayload(val)) + `>"` + escapeHTML(shown) + `"</span>`)
+		}
+		return template.HTML(`<span class="` + ctx.cls("json-string") + `">"` + escapeHTML(val) + `"</span>`)
+
+	case float64:
+		return ctx.numberHTML(val, fmt.Sprintf("%v", val), path)
+
+	case json.Number:
+		return ctx.numberHTML(val, string(val), path)
+
+	case bool:
+		return template.HTML(`<span class="` + ctx.cls("json-bool") + `"` + ctx.editAttr(path, "json") + `>` + strconv.FormatBool(val) + `</span>`)
+
+	case nil:
+		return template.HTML(`<span class="` + ctx.cls("json-null") + `"` + ctx.editAttr(path, "json") + `>null</span>`)
+
+	default:
+		return template.HTML(escapeHTML(fmt.Sprintf("%v", val)))
+	}
+}
+
+// numberHTML renders the number v, written as raw. A number formatted for
+// display keeps its JSON in a tooltip; an editable one is always shown raw,
+// since its text is what the export reads back.
+func (ctx *renderContext) numberHTML(v interface{}, raw, path string) template.HTML {
+	if edit := ctx.editAttr(path, "json"); edit != "" {
+		return template.HTML(`<span class="` + ctx.cls("json-number") + `"` + edit + `>` + escapeHTML(raw) + `</span>`)
+	}
+	if shown, ok := ctx.display.value(v); ok {
+		return template.HTML(`<span class="` + ctx.cls("json-number") + `"` + attr("title", jsonPayload(v)) + `>` + escapeHTML(shown) + `</span>`)
+	}
+	return template.HTML(`<span class="` + ctx.cls("json-number") + `">` + escapeHTML(raw) + `</span>`)
+}
+
+// renderGhost renders a member that exists only in the other document. On
+// the Modified side such a member was removed; on the Original side it is
+// one that will be added.
+func renderGhost(v interface{}, path, label string, ctx *renderContext) string {
+	changeType := Added
+	if ctx.side == SideB {
+		changeType = Removed
+	}
+	plain := &renderContext{display: ctx.display, classes: ctx.classes, ghost: true}
+	id := ""
+	if ghostID := ctx.nodeIDs[path]; ghostID != "" && ctx.side == SideB {
+		id = attr("id", ghostID)
+	}
+	return fmt.Sprintf(`<li%s class="%s" aria-hidden="true"%s%s>`, id, ctx.cls("json-key ghost "+string(changeType)), dataChange(changeType), ctx.ordinalAttr(path)) +
+		ctx.changeMarker(changeType) + label + string(renderNode(v, nil, path, plain)) + "</li>"
+}
+
+// mergedKeys returns the sorted union of the keys of own and other.
+func mergedKeys(own, other map[string]interface{}) []string {
+	if len(other) == 0 {
+		return sortedKeys(own)
+	}
+	merged := make(map[string]interface{}, len(own)+len(other))
+	for k := range other {
+		merged[k] = nil
+	}
+	for k := range own {
+		merged[k] = nil
+	}
+	return sortedKeys(merged)
+}
+
+// listOpen opens the list of members of the container at path. The root
+// container's list is the ARIA tree itself; nested ones are groups.
+func (ctx *renderContext) listOpen(path string) string {
+	if ctx.ghost {
+		return `<ul class="` + ctx.cls("json-list") + `">`
+	}
+	if path == "" {
+		return `<ul class="` + ctx.cls("json-list") + `" role="tree" aria-label="JSON document">`
+	}
+	return `<ul class="` + ctx.cls("json-list") + `" role="group">`
+}
+
+// itemOpen opens the tree item for the value v at path. Non-empty
+// containers are expandable and get a toggle; first marks the item that
+// receives keyboard focus when tabbing into the tree. A non-empty id is
+// set as the item's id, for links from the table of contents.
+func (ctx *renderContext) itemOpen(path string, v interface{}, first bool, id string) string {
+	tabindex := -1
+	if first {
+		tabindex = 0
+	}
+	ct := ChangeType(getChangeType(ctx.diffMap, path))
+	var sb strings.Builder
+	sb.WriteString(`<li`)
+	if id == "" && ctx.side == SideB {
+		id = ctx.nodeIDs[path]
+	}
+	if id != "" {
+		sb.WriteString(attr("id", id))
+	}
+	if ctx.editable && ctx.side == SideB {
+		sb.WriteString(attr("data-path", pathJSON(path)))
+	}
+	sb.WriteString(attr("class", ctx.cls("json-key "+ctx.nodeClass(path))))
+	if !ctx.ghost {
+		sb.WriteString(fmt.Sprintf(` role="treeitem" aria-level="%d" tabindex="%d"`, len(splitPath(path))+ctx.levelOffset, tabindex))
+	}
+	sb.WriteString(ctx.lineAttr(path) + dataChange(ct) + ctx.changesAttr(path) + ctx.ordinalAttr(path))
+	if hasChildren(v) {
+		expanded := !ctx.wholeArrays[path] && !ctx.deferred(path, v)
+		sb.WriteString(fmt.Sprintf(` aria-expanded="%t"><span class="`+ctx.cls("toggle")+`" aria-hidden="true"></span>`, expanded))
+	} else {
+		sb.WriteString(`>`)
+	}
+	sb.WriteString(ctx.changeMarker(ct))
+	sb.WriteString(ctx.linkIcon(path, ct))
+	return sb.String()
+}
+
+// arrayWindow returns which elements of the array at path to show when only
+// changed elements and arrayContext unchanged neighbours on each side are
+// wanted, or nil to show them all. Windows of nearby changes merge.
+func (ctx *renderContext) arrayWindow(path string, n int) []bool {
+	if ctx.arrayContext <= 0 || !ctx.diffMap.HasChangedDescendant(path) {
+		return nil
+	}
+	shown := make([]bool, n)
+	hidden := n
+	for i := 0; i < n; i++ {
+		p := indexKey(path, i)
+		if _, changed := ctx.diffMap.Lookup(p); !changed && !ctx.diffMap.HasChangedDescendant(p) {
+			continue
+		}
+		for j := max(0, i-ctx.arrayContext); j <= min(n-1, i+ctx.arrayContext); j++ {
+			if !shown[j] {
+				shown[j] = true
+				hidden--
+			}
+		}
+	}
+	if hidden == 0 {
+		return nil
+	}
+	return shown
+}
+
+// gapOpen opens a collapsed tree item standing for count unchanged array
+// elements, which are rendered inside it and shown when it is expanded.
+func (ctx *renderContext) gapOpen(path string, count int, first bool) string {
+	tabindex := -1
+	if first {
+		tabindex = 0
+	}
+	noun := "items"
+	if count == 1 {
+		noun = "item"
+	}
+	return fmt.Sprintf(`<li class="%s" role="treeitem" aria-level="%d" tabindex="%d" aria-expanded="false">`+
+		`<span class="%s" aria-hidden="true"></span><span class="%s">&hellip; %d unchanged %s &hellip;</span>`+
+		`<div class="%s"><ul class="%s" role="group">`,
+		ctx.cls("json-key array-gap"), len(splitPath(path))+1+ctx.levelOffset, tabindex, ctx.cls("toggle"), ctx.cls("gap-label"), count, noun,
+		ctx.cls("json-array"), ctx.cls("json-list"))
+}
+
+// editAttr returns the attributes making the changed leaf at path editable
+// in the Modified pane, or nothing. kind tells the export script whether
+// the edited text is a string or JSON to parse.
+func (ctx *renderContext) editAttr(path, kind string) string {
+	if !ctx.editable || ctx.side != SideB || path == "" {
+		return ""
+	}
+	if _, changed := ctx.diffMap.Lookup(path); !changed {
+		return ""
+	}
+	return ` contenteditable="true"` + attr("data-edit", kind)
+}
+
+// pathJSON encodes path as a JSON array of keys and indices, which the
+// export script can follow without parsing the dotted form.
+func pathJSON(path string) string {
+	segs := splitPath(path)
+	out := make([]interface{}, len(segs))
+	for i, seg := range segs {
+		if n, ok := parseIndexSegment(seg); ok {
+			out[i] = n
+		} else {
+			out[i] = seg
+		}
+	}
+	data, _ := json.Marshal(out)
+	return string(data)
+}
+
+// dataChange returns the data-change attribute the legend toggles use to
+// hide changes of one type, or nothing for unchanged values.
+func dataChange(ct ChangeType) string {
+	if ct == Unchanged {
+		return ""
+	}
+	return attr("data-change", string(ct))
+}
+
+// changesAttr lists in data-changes the changes behind a path whose type
+// was resolved from more than one, e.g. data-changes="removed added".
+func (ctx *renderContext) changesAttr(path string) string {
+	changes := ctx.diffMap.Changes(path)
+	if len(changes) < 2 {
+		return ""
+	}
+	names := make([]string, len(changes))
+	for i, ct := range changes {
+		names[i] = string(ct)
+	}
+	return attr("data-changes", strings.Join(names, " "))
+}
+
+func hasChildren(v interface{}) bool {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return len(val) > 0
+	case []interface{}:
+		return len(val) > 0
+	}
+	return false
+}
+
+// changeMarker returns a textual marker for ct, so a change is not conveyed
+// by color alone. Screen readers get the change type spelled out.
+func (ctx *renderContext) changeMarker(ct ChangeType) string {
+	var symbol string
+	switch ct {
+	case Added:
+		symbol = "+"
+	case Removed:
+		symbol = "&minus;"
+	case Changed:
+		symbol = "~"
+	case Moved:
+		symbol = "&rArr;"
+	default:
+		return ""
+	}
+	return `<span class="` + ctx.cls("change-marker") + `" aria-hidden="true">` + symbol + `</span><span class="` + ctx.cls("sr-only") + `">` + string(ct) + ` </span>`
+}
+
+// nodeClass returns the CSS classes for the list item at path: its change
+// type, plus contains-changes when an unchanged container has changes below.
+func (ctx *renderContext) nodeClass(path string) string {
+	ct := getChangeType(ctx.diffMap, path)
+	if ct == string(Unchanged) && ctx.diffMap.HasChangedDescendant(path) {
+		return ct + " contains-changes"
+	}
+	return ct
+}
+
+func getChangeType(diffMap *DiffMap, path string) string {
+	if v, ok := diffMap.Lookup(path); ok {
+		return string(v)
+	}
+	return string(Unchanged)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,438 @@
+package jsondiff
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMain runs the command line instead of the tests when runCLI starts
+// the test binary as jsondiff.
+func TestMain(m *testing.M) {
+	if os.Getenv("JSONDIFF_TEST_CLI") == "1" {
+		os.Exit(Run(os.Args[1:]))
+	}
+	os.Exit(m.Run())
+}
+
+// cliResult is the outcome of one runCLI.
+type cliResult struct {
+	stdout, stderr string
+	exit           int
+}
+
+// runCLI runs jsondiff with args in dir.
+func runCLI(t *testing.T, dir string, args ...string) cliResult {
+	t.Helper()
+	return runCLIWith(t, exec.Command(os.Args[0], args...), dir)
+}
+
+// runCLIWith is runCLI for a command whose stdin or environment the caller
+// has set up.
+func runCLIWith(t *testing.T, cmd *exec.Cmd, dir string) cliResult {
+	t.Helper()
+	cmd.Dir = dir
+	cmd.Env = append(append(os.Environ(), "JSONDIFF_TEST_CLI=1", "SOURCE_DATE_EPOCH="), cmd.Env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatal(err)
+	}
+	return cliResult{stdout.String(), stderr.String(), cmd.ProcessState.ExitCode()}
+}
+
+// cliDir returns a temporary directory holding the given files.
+func cliDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// readFile returns the contents of the file name in dir.
+func readFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// largeDocs returns two documents of roughly nodes values each, a list of
+// records that differ in exactly changes of them.
+func largeDocs(t testing.TB, nodes, changes int) (interface{}, interface{}) {
+	t.Helper()
+	// Each record is 9 values: itself, id, name, tags and its two
+	// elements, attrs, n and s.
+	records := nodes / 9
+	a := make([]interface{}, records)
+	b := make([]interface{}, records)
+	record := func(i, n int) interface{} {
+		return map[string]interface{}{
+			"id":    float64(i),
+			"name":  fmt.Sprintf("item-%d", i),
+			"tags":  []interface{}{"x", "y"},
+			"attrs": map[string]interface{}{"n": float64(n), "s": "v"},
+		}
+	}
+	step := records / changes
+	for i := range a {
+		a[i] = record(i, i)
+		if i%step == 0 && i/step < changes {
+			b[i] = record(i, -i-1)
+		} else {
+			b[i] = record(i, i)
+		}
+	}
+	return map[string]interface{}{"items": a}, map[string]interface{}{"items": b}
+}
+
+// sortJSON is the deep copy made of both documents before rendering them
+// until renderJSON's own key order was relied on; it is kept here to show
+// that dropping it changes nothing but the cost.
+func sortJSON(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		sorted := make(map[string]interface{}, len(val))
+		for _, k := range sortedKeys(val) {
+			sorted[k] = sortJSON(val[k])
+		}
+		return sorted
+	case []interface{}:
+		for i := range val {
+			val[i] = sortJSON(val[i])
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+func TestRenderWithoutSortJSON(t *testing.T) {
+	a, b := largeDocs(t, 9000, 50)
+	changes, err := collectChanges(diffSeq(context.Background(), a, b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := renderContext{diffMap: buildDiffMap(changes, a, b), side: SideB, other: a}
+	direct := renderJSON(b, "", &ctx)
+	if copied := renderJSON(sortJSON(b), "", &ctx); copied != direct {
+		t.Error("rendering the sortJSON copy differs from rendering the document as decoded")
+	}
+
+	const doc = `{"z": {"b": [3, {"y": 1, "x": 2}], "a": 1.50}, "m": null}`
+	got, err := canonicalJSON([]byte(doc), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v interface{}
+	dec := json.NewDecoder(strings.NewReader(doc))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	var want bytes.Buffer
+	enc := json.NewEncoder(&want)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(sortJSON(v)); err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want.String() {
+		t.Errorf("canonicalJSON = %s, want %s", got, want.String())
+	}
+}
+
+// BenchmarkRenderTree renders the Modified tree of a 100k-value document
+// with and without the sortJSON copy.
+func BenchmarkRenderTree(b *testing.B) {
+	docA, docB := largeDocs(b, 100000, 5000)
+	changes, err := collectChanges(diffSeq(context.Background(), docA, docB))
+	if err != nil {
+		b.Fatal(err)
+	}
+	ctx := renderContext{diffMap: buildDiffMap(changes, docA, docB), side: SideB, other: docA}
+	b.Run("sortJSON", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			renderJSON(sortJSON(docB), "", &ctx)
+		}
+	})
+	b.Run("decoded", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			renderJSON(docB, "", &ctx)
+		}
+	})
+}
+
+// pipelineFiles writes the documents of largeDocs to a temporary
+// directory and returns their paths.
+func pipelineFiles(tb testing.TB, nodes, changes int) (string, string) {
+	tb.Helper()
+	dir := tb.TempDir()
+	docA, docB := largeDocs(tb, nodes, changes)
+	var files []string
+	for i, doc := range []interface{}{docA, docB} {
+		data, err := json.Marshal(doc)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		file := filepath.Join(dir, fmt.Sprintf("%c.json", 'a'+i))
+		if err := os.WriteFile(file, data, 0o644); err != nil {
+			tb.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	return files[0], files[1]
+}
+
+// pipelineArgs returns the arguments of the whole command, writing the
+// HTML report, and of --stats-only, over the same large pair of files.
+func pipelineArgs(tb testing.TB) (full, statsOnly []string) {
+	a, b := pipelineFiles(tb, 100000, 1000)
+	out := filepath.Join(filepath.Dir(a), "out.html")
+	return []string{"--quiet", "-o", out, a, b}, []string{"--quiet", "--stats-only", a, b}
+}
+
+func BenchmarkStatsOnly(b *testing.B) {
+	full, statsOnly := pipelineArgs(b)
+	run := func(args []string) func(*testing.B) {
+		return func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				runDiff(args, nil)
+			}
+		}
+	}
+	b.Run("full", run(full))
+	b.Run("stats-only", run(statsOnly))
+}
+
+// BenchmarkStatsOnlySpeedup reports how many times cheaper --stats-only
+// makes a comparison than writing the HTML report, as the speedup metric.
+// Skipping the table values and all rendering is meant to keep it above 5;
+// timing is left to benchmark runs so that a loaded machine cannot fail
+// the tests.
+func BenchmarkStatsOnlySpeedup(b *testing.B) {
+	full, statsOnly := pipelineArgs(b)
+	var fullTime, statsTime time.Duration
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		start := time.Now()
+		runDiff(full, nil)
+		fullTime += time.Since(start)
+		start = time.Now()
+		runDiff(statsOnly, nil)
+		statsTime += time.Since(start)
+	}
+	b.ReportMetric(float64(fullTime)/float64(statsTime), "speedup")
+}
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+var goldenDir = filepath.Join("testdata", "golden")
+
+// checkGolden compares got with the golden file name in testdata/golden,
+// rewriting the file instead when the tests run with -update.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	file := filepath.Join(goldenDir, name)
+	if *update {
+		if err := os.MkdirAll(goldenDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("%v (run the tests with -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s (run the tests with -update to accept it):\n%s", file, got)
+	}
+}
+
+// TestOutputDeterministic runs the whole pipeline 20 times on a large
+// document and checks that the HTML and JSON reports never change, whatever
+// order Go iterates maps in.
+func TestOutputDeterministic(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the command line 40 times")
+	}
+	a, b := largeDocs(t, 20000, 500)
+	a.(map[string]interface{})["gone"] = map[string]interface{}{"x": 1.0, "y": []interface{}{"a", "a", "b"}}
+	b.(map[string]interface{})["new"] = map[string]interface{}{"y": []interface{}{"a", "b", "b"}, "x": 1.0}
+	files := make(map[string]string)
+	for name, v := range map[string]interface{}{"a.json": a, "b.json": b} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[name] = string(data)
+	}
+	dir := cliDir(t, files)
+	for _, format := range []string{"html", "json"} {
+		var first string
+		for i := 0; i < 20; i++ {
+			res := runCLI(t, dir, "-f", format, "-o", "out", "--show-ghosts", "--detect-moves", "--line-numbers", "a.json", "b.json")
+			if res.exit != exitOK {
+				t.Fatalf("exit %d: %s", res.exit, res.stderr)
+			}
+			out := readFile(t, dir, "out")
+			if i == 0 {
+				first = out
+			} else if out != first {
+				t.Fatalf("%s output of run %d differs from the first", format, i+1)
+			}
+		}
+	}
+}
+
+// TestArrayWindow checks which elements --array-context shows around the
+// changes in a 20-element array, drawn as # for shown and . for folded.
+func TestArrayWindow(t *testing.T) {
+	tests := []struct {
+		name    string
+		changed []int
+		context int
+		want    string
+	}{
+		{"one change", []int{10}, 2, "........#####......."},
+		{"overlapping windows merge", []int{5, 7}, 1, "....#####..........."},
+		{"adjacent windows merge", []int{5, 8}, 1, "....######.........."},
+		{"windows one apart stay separate", []int{5, 9}, 1, "....###.###........."},
+		{"window cut at the start", []int{0}, 2, "###................."},
+		{"window cut at the end", []int{19}, 2, ".................###"},
+		{"changes side by side", []int{3, 4}, 1, "..####.............."},
+		{"change inside an element", []int{-12}, 1, "...........###......"},
+		{"windows covering everything", []int{3, 10, 16}, 3, ""},
+		{"no context", []int{5}, 0, ""},
+		{"no change", nil, 2, ""},
+	}
+	for _, tt := range tests {
+		a, b := make([]interface{}, 20), make([]interface{}, 20)
+		for i := range a {
+			a[i] = map[string]interface{}{"v": float64(i)}
+			b[i] = map[string]interface{}{"v": float64(i)}
+		}
+		for _, i := range tt.changed {
+			if i < 0 {
+				// A negative index changes a value nested in the element.
+				b[-i] = map[string]interface{}{"v": float64(-i), "w": true}
+				continue
+			}
+			b[i] = "changed"
+		}
+		docA, docB := map[string]interface{}{"list": a}, map[string]interface{}{"list": b}
+		changes, err := collectChanges(diffSeq(context.Background(), docA, docB))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx := renderContext{diffMap: buildDiffMap(changes, docA, docB), arrayContext: tt.context}
+		var got strings.Builder
+		if shown := ctx.arrayWindow("list", len(a)); shown != nil {
+			for _, s := range shown {
+				got.WriteString(map[bool]string{true: "#", false: "."}[s])
+			}
+		}
+		if got.String() != tt.want {
+			t.Errorf("%s:\n got %q\nwant %q", tt.name, got.String(), tt.want)
+		}
+	}
+}
+
+// TestArrayContextGaps checks the folded runs the HTML report shows between
+// two windows one element apart.
+func TestArrayContextGaps(t *testing.T) {
+	var a, b []string
+	for i := 0; i < 20; i++ {
+		a = append(a, fmt.Sprint(i))
+		if i == 5 || i == 9 {
+			b = append(b, `"x"`)
+		} else {
+			b = append(b, fmt.Sprint(i))
+		}
+	}
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"list": [` + strings.Join(a, ", ") + `]}`,
+		"b.json": `{"list": [` + strings.Join(b, ", ") + `]}`,
+	})
+	if res := runCLI(t, dir, "-o", "out.html", "--array-context", "1", "a.json", "b.json"); res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	out := readFile(t, dir, "out.html")
+	// Each pane folds elements 0-3, 7 and 11-19.
+	for label, want := range map[string]int{"4 unchanged items": 2, "1 unchanged item ": 2, "9 unchanged items": 2} {
+		if got := strings.Count(out, "&hellip; "+label); got != want {
+			t.Errorf("%q shown %d times, want %d", label, got, want)
+		}
+	}
+	if got := strings.Count(out, "unchanged item"); got != 6 {
+		t.Errorf("%d folded runs, want 6", got)
+	}
+}
+
+// TestShowGhosts checks that each pane shows the members only the other
+// document has, marked as the change they stand for and kept out of the
+// ARIA tree, and that they are left out without --show-ghosts.
+func TestShowGhosts(t *testing.T) {
+	a := map[string]interface{}{"keep": 1.0, "gone": map[string]interface{}{"x": 1.0}, "list": []interface{}{1.0}}
+	b := map[string]interface{}{"keep": 1.0, "new": true, "list": []interface{}{1.0, 2.0}}
+	changes, err := collectChanges(diffSeq(context.Background(), a, b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ghost := regexp.MustCompile(`<li class="json-key ghost (\w+)" aria-hidden="true"[^>]*>(?:<span class="(?:change-marker|sr-only)"[^>]*>[^<]*</span>)*(?:<span class="key">"(\w+)"</span>: )?`)
+	tests := []struct {
+		side Side
+		doc  interface{}
+		want []string
+	}{
+		{SideA, a, []string{"added list[1]", "added new"}},
+		{SideB, b, []string{"removed gone"}},
+	}
+	for _, tt := range tests {
+		ctx := renderContext{diffMap: buildDiffMap(changes, a, b), side: tt.side, other: b}
+		if tt.side == SideB {
+			ctx.other = a
+		}
+		if html := string(renderJSON(tt.doc, "", &ctx)); strings.Contains(html, "ghost") {
+			t.Errorf("side %s: ghosts without showGhosts", tt.side)
+		}
+
+		ctx.showGhosts = true
+		html := string(renderJSON(tt.doc, "", &ctx))
+		var got []string
+		for _, m := range ghost.FindAllStringSubmatch(html, -1) {
+			name := m[2]
+			if name == "" {
+				name = "list[1]"
+			}
+			got = append(got, m[1]+" "+name)
+		}
+		if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+			t.Errorf("side %s: ghosts %q, want %q", tt.side, got, tt.want)
+		}
+		checkTreeMarkup(t, html)
+	}
+}
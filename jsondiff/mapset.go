@@ -0,0 +1,109 @@
+package jsondiff
+
+import (
+	"reflect"
+
+	"github.com/r3labs/diff/v3"
+)
+
+const reasonMapAsSet = "map-as-set: member values ignored"
+
+// setPatterns holds the --map-as-set path patterns, split into segments.
+type setPatterns [][]string
+
+func parseSetPatterns(patterns []string) setPatterns {
+	out := make(setPatterns, len(patterns))
+	for i, p := range patterns {
+		out[i] = splitPath(p)
+	}
+	return out
+}
+
+// matches reports whether the object at path is compared as a set of keys.
+func (s setPatterns) matches(path []string) bool {
+	for _, p := range s {
+		if matchPath(p, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// collapseSets returns a copy of v in which every object at a path matching
+// s has its values replaced by true, so only its key set is compared. v
+// itself is not modified.
+func (s setPatterns) collapseSets(v interface{}, path []string) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		asSet := s.matches(path)
+		for k, vv := range val {
+			if asSet {
+				out[k] = true
+				continue
+			}
+			out[k] = s.collapseSets(vv, append(path[:len(path):len(path)], k))
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, vv := range val {
+			out[i] = s.collapseSets(vv, append(path[:len(path):len(path)], indexSegment(i)))
+		}
+		return out
+	}
+	return v
+}
+
+// suppressValues records in suppressed each member of a set object whose
+// value differs between a and b, as collapseSets hides those differences.
+// a and b are the documents before collapseSets.
+func (s setPatterns) suppressValues(a, b interface{}, path []string, suppressed *suppressionLog) {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			return
+		}
+		asSet := s.matches(path)
+		for k, va := range av {
+			vb, ok := bv[k]
+			if !ok {
+				continue
+			}
+			p := append(path[:len(path):len(path)], k)
+			if !asSet {
+				s.suppressValues(va, vb, p, suppressed)
+			} else if !reflect.DeepEqual(va, vb) {
+				suppressed.add(reasonMapAsSet, diff.Change{Type: diff.UPDATE, Path: p, From: va, To: vb})
+			}
+		}
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok {
+			return
+		}
+		for i := 0; i < len(av) && i < len(bv); i++ {
+			s.suppressValues(av[i], bv[i], append(path[:len(path):len(path)], indexSegment(i)), suppressed)
+		}
+	}
+}
+
+// markSetChanges classifies additions and removals of members of set
+// objects as set-added and set-removed, showing the key as the value.
+func (s setPatterns) markSetChanges(results []DiffResult) {
+	for i := range results {
+		r := &results[i]
+		segs := splitPath(r.Path)
+		if len(segs) == 0 || !s.matches(segs[:len(segs)-1]) {
+			continue
+		}
+		key := segs[len(segs)-1]
+		switch r.Type {
+		case "create":
+			r.Kind, r.To, r.ToJSON = "set-added", key, jsonPayload(key)
+		case "delete":
+			r.Kind, r.From, r.FromJSON = "set-removed", key, jsonPayload(key)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestCollapseSets(t *testing.T) {
+	s := parseSetPatterns([]string{"hosts[*].labels"})
+	doc := map[string]interface{}{
+		"labels": map[string]interface{}{"a": 1},
+		"hosts": []interface{}{
+			map[string]interface{}{"labels": map[string]interface{}{"x": 1, "y": map[string]interface{}{"z": 2}}},
+		},
+	}
+	got := s.collapseSets(doc, nil)
+	want := map[string]interface{}{
+		"labels": map[string]interface{}{"a": 1},
+		"hosts": []interface{}{
+			map[string]interface{}{"labels": map[string]interface{}{"x": true, "y": true}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("collapsed %v, want %v", got, want)
+	}
+	if labels := doc["hosts"].([]interface{})[0].(map[string]interface{})["labels"].(map[string]interface{}); labels["x"] != 1 {
+		t.Error("the document was modified")
+	}
+}
+
+// TestMapAsSetCLI checks that members of a set object are reported as added
+// or removed keys while their changed values are only counted.
+func TestMapAsSetCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"tags": {"web": 1, "old": 2, "db": {"v": 1}}, "meta": {"web": 1}}`,
+		"b.json": `{"tags": {"web": 5, "new": 2, "db": {"v": 2}}, "meta": {"web": 2}}`,
+	})
+	res := runCLI(t, dir, "--map-as-set", "tags", "--show-suppressed", "-f", "json", "-o", "-", "a.json", "b.json")
+	if res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	var report jsonReport
+	if err := json.Unmarshal([]byte(res.stdout), &report); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, s := range report.Sections {
+		for _, c := range s.Changes {
+			got[c.Path] = fmt.Sprintf("%s %v %v", c.Kind, c.From, c.To)
+		}
+	}
+	want := map[string]string{
+		"tags.new": "set-added  new",
+		"tags.old": "set-removed old ",
+		"meta.web": " 1 2",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changes %q, want %q", got, want)
+	}
+	if len(report.Suppressed) != 1 || report.Suppressed[0].Reason != reasonMapAsSet {
+		t.Fatalf("suppressed %+v", report.Suppressed)
+	}
+	var hidden []string
+	for _, c := range report.Suppressed[0].Changes {
+		hidden = append(hidden, c.Path)
+	}
+	if want := []string{"tags.db", "tags.web"}; report.Suppressed[0].Count != 2 || !reflect.DeepEqual(hidden, want) {
+		t.Errorf("suppressed %d %q, want %q", report.Suppressed[0].Count, hidden, want)
+	}
+}
@@ -0,0 +1,114 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// Moved marks both ends of a value that --detect-moves found removed at one
+// path and added, deeply equal, at another.
+const Moved ChangeType = "moved"
+
+// kindMovedPath is the Kind of a result pairing a removal with an addition.
+const kindMovedPath = "moved-path"
+
+// detectMoves pairs each removal in results with an addition of a deeply
+// equal value elsewhere and replaces the two with one "move" result at the
+// new path, whose MovedFrom is the old one. Values are matched by their
+// canonical JSON in a single pass over results. A value removed more than
+// once is ambiguous and left alone; a value removed once and added at
+// several paths goes to the addition sharing the longest common ancestor
+// with the removal, unless two tie. Both ends are marked Moved in m.
+func detectMoves(results []DiffResult, a, b interface{}, m *DiffMap) []DiffResult {
+	type group struct {
+		removed, added []int
+	}
+	groups := make(map[string]*group)
+	var keys []string
+	for i, r := range results {
+		var v interface{}
+		var ok bool
+		switch {
+		case r.Type == "delete" && r.Kind == "":
+			v, ok = lookupPath(a, splitPath(r.Path))
+		case r.Type == "create" && r.Kind == "":
+			v, ok = lookupPath(b, splitPath(r.Path))
+		}
+		if !ok {
+			continue
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			continue
+		}
+		g := groups[string(data)]
+		if g == nil {
+			g = &group{}
+			groups[string(data)] = g
+			keys = append(keys, string(data))
+		}
+		if r.Type == "delete" {
+			g.removed = append(g.removed, i)
+		} else {
+			g.added = append(g.added, i)
+		}
+	}
+
+	// moveTo maps the index of a paired addition to its removal.
+	moveTo := make(map[int]int)
+	dropped := make(map[int]bool)
+	sort.Strings(keys)
+	for _, k := range keys {
+		g := groups[k]
+		if len(g.removed) != 1 || len(g.added) == 0 {
+			continue
+		}
+		from := splitPath(results[g.removed[0]].Path)
+		best, bestDepth, tie := -1, -1, false
+		for _, i := range g.added {
+			d := commonPrefix(from, splitPath(results[i].Path))
+			switch {
+			case d > bestDepth:
+				best, bestDepth, tie = i, d, false
+			case d == bestDepth:
+				tie = true
+			}
+		}
+		if tie {
+			continue
+		}
+		moveTo[best] = g.removed[0]
+		dropped[g.removed[0]] = true
+	}
+	if len(moveTo) == 0 {
+		return results
+	}
+
+	out := make([]DiffResult, 0, len(results)-len(moveTo))
+	for i, r := range results {
+		if dropped[i] {
+			continue
+		}
+		if j, ok := moveTo[i]; ok {
+			old := results[j]
+			v, _ := lookupPath(b, splitPath(r.Path))
+			r.ID = changeID(r.Path, "move", old.Path, v)
+			r.Type, r.Kind, r.MovedFrom = "move", kindMovedPath, old.Path
+			r.From, r.FromType, r.FromLine, r.FromOffset = r.To, old.FromType, old.FromLine, old.FromOffset
+			r.FromJSON = old.FromJSON
+			m.Set(old.Path, Moved)
+			m.Set(r.Path, Moved)
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// commonPrefix returns the number of leading segments a and b share.
+func commonPrefix(a, b []string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
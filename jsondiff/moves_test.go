@@ -0,0 +1,86 @@
+package jsondiff
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestDetectMoves(t *testing.T) {
+	tests := []struct {
+		name, a, b string
+		// want lists the results as "type path" or "move from>to".
+		want []string
+	}{
+		{"renamed key", `{"old": {"x": [1, 2]}, "k": 1}`, `{"new": {"x": [1, 2]}, "k": 1}`,
+			[]string{"move old>new"}},
+		{"into an array", `{"src": {"big": [1, 2, 3]}, "list": [1]}`, `{"list": [1, {"big": [1, 2, 3]}]}`,
+			[]string{"move src>list[1]"}},
+		{"removed twice", `{"a": 1, "b": 1}`, `{"c": 1}`,
+			[]string{"create c", "delete a", "delete b"}},
+		{"closest addition", `{"p": {"x": "v"}}`, `{"p": {"y": "v"}, "q": "v"}`,
+			[]string{"create q", "move p.x>p.y"}},
+		{"tied additions", `{"x": "v"}`, `{"y": "v", "z": "v"}`,
+			[]string{"create y", "create z", "delete x"}},
+		{"different values", `{"x": 1}`, `{"y": 2}`,
+			[]string{"create y", "delete x"}},
+	}
+	for _, tt := range tests {
+		var a, b interface{}
+		if err := json.Unmarshal([]byte(tt.a), &a); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal([]byte(tt.b), &b); err != nil {
+			t.Fatal(err)
+		}
+		changes, err := collectChanges(diffSeq(context.Background(), a, b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := buildDiffMap(changes, a, b)
+		results := detectMoves(buildDiffTable(changes, a, b), a, b, m)
+		var got []string
+		for _, r := range results {
+			if r.Type == "move" {
+				got = append(got, "move "+r.MovedFrom+">"+r.Path)
+				for _, p := range []string{r.MovedFrom, r.Path} {
+					if ct, _ := m.Lookup(p); ct != Moved {
+						t.Errorf("%s: %s is %s in the diff map", tt.name, p, ct)
+					}
+				}
+				continue
+			}
+			got = append(got, r.Type+" "+r.Path)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestMovesArrayStatsCLI checks that a move counts as an added element of
+// the array it lands in and a removed one of the array it leaves.
+func TestMovesArrayStatsCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"src": {"big": [1, 2, 3]}, "list": [1], "from": [2, {"k": "v"}]}`,
+		"b.json": `{"list": [1, {"big": [1, 2, 3]}], "from": [2], "to": {"k": "v"}}`,
+	})
+	res := runCLI(t, dir, "--detect-moves", "-f", "json", "-o", "-", "a.json", "b.json")
+	if res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	var report jsonReport
+	if err := json.Unmarshal([]byte(res.stdout), &report); err != nil {
+		t.Fatal(err)
+	}
+	want := []ArrayStats{
+		{Path: "from", Removed: 1},
+		{Path: "list", Added: 1},
+	}
+	if !reflect.DeepEqual(report.Arrays, want) {
+		t.Errorf("arrays %+v, want %+v", report.Arrays, want)
+	}
+}
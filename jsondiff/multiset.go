@@ -0,0 +1,114 @@
+package jsondiff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/r3labs/diff/v3"
+)
+
+// kindMultiset is the Kind of a change to how often a value occurs in an
+// array compared as a multiset.
+const kindMultiset = "multiset"
+
+// multisetCount is how often one value occurs in a multiset array on each
+// side. Value is the element's JSON encoding, so 1 and "1" stay apart.
+type multisetCount struct {
+	Value    string
+	From, To int
+}
+
+func (c multisetCount) String() string {
+	return fmt.Sprintf("%s: %d→%d", c.Value, c.From, c.To)
+}
+
+// collapseMultisets replaces the changes inside arrays at paths matching s
+// whose elements are all primitives (strings, numbers, booleans, null) with
+// one update per value occurring a different number of times on each side,
+// so ["a","a","b"] against ["a","b","b"] reads "a": 2→1 and "b": 1→2
+// rather than as index changes. It returns the counts of each such array.
+func (s setPatterns) collapseMultisets(changes diff.Changelog, a, b interface{}) (diff.Changelog, map[string][]multisetCount) {
+	counts := make(map[string][]multisetCount)
+	replaced := make(map[string]bool)
+	var added diff.Changelog
+	walkArrayPairs(a, b, func(va, vb []interface{}, raw, typed []string) bool {
+		if !s.matches(typed) || !allPrimitive(va) || !allPrimitive(vb) {
+			return false
+		}
+		path := joinPath(typed)
+		replaced[path] = true
+		for _, c := range countValues(va, vb) {
+			if c.From == c.To {
+				continue
+			}
+			counts[path] = append(counts[path], c)
+			added = append(added, diff.Change{
+				Type: diff.UPDATE,
+				Path: raw,
+				From: fmt.Sprintf("%s: %d", c.Value, c.From),
+				To:   fmt.Sprintf("%s: %d", c.Value, c.To),
+			})
+		}
+		return true
+	})
+	return replaceArrayChanges(changes, a, b, replaced, added), counts
+}
+
+// countValues returns the occurrences of every value of a and b, ordered by
+// value.
+func countValues(a, b []interface{}) []multisetCount {
+	byValue := make(map[string]*multisetCount)
+	count := func(elems []interface{}, side func(*multisetCount) *int) {
+		for _, e := range elems {
+			value := jsonPayload(e)
+			c := byValue[value]
+			if c == nil {
+				c = &multisetCount{Value: value}
+				byValue[c.Value] = c
+			}
+			*side(c)++
+		}
+	}
+	count(a, func(c *multisetCount) *int { return &c.From })
+	count(b, func(c *multisetCount) *int { return &c.To })
+	out := make([]multisetCount, 0, len(byValue))
+	for _, c := range byValue {
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Value < out[j].Value })
+	return out
+}
+
+func allPrimitive(elems []interface{}) bool {
+	for _, e := range elems {
+		switch e.(type) {
+		case map[string]interface{}, []interface{}:
+			return false
+		}
+	}
+	return true
+}
+
+// markMultisets labels the changes made by collapseMultisets.
+func markMultisets(results []DiffResult, counts map[string][]multisetCount) {
+	for i := range results {
+		if _, ok := counts[results[i].Path]; ok {
+			results[i].Kind = kindMultiset
+		}
+	}
+}
+
+// multisetSummary renders the count changes of the multiset array at path
+// under its tree node, e.g. "a": 2→1, "b": 1→2.
+func (ctx *renderContext) multisetSummary(path string) string {
+	counts := ctx.multisets[path]
+	if len(counts) == 0 {
+		return ""
+	}
+	parts := make([]string, len(counts))
+	for i, c := range counts {
+		parts[i] = c.String()
+	}
+	return `<span class="` + ctx.cls("count-diff") + `" title="Value counts, compared as a multiset">` + escapeHTML(strings.Join(parts, ", ")) + `</span>`
+}
@@ -0,0 +1,124 @@
+package jsondiff
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCountValues(t *testing.T) {
+	got := countValues(
+		[]interface{}{"a", "a", "b", 1.0, "1", nil, true},
+		[]interface{}{"a", "b", "b", "1", "1", nil, false})
+	want := []multisetCount{
+		{`"1"`, 1, 2}, {`"a"`, 2, 1}, {`"b"`, 1, 2},
+		{"1", 1, 0}, {"false", 0, 1}, {"null", 1, 1}, {"true", 1, 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("countValues = %v, want %v", got, want)
+	}
+	if got := countValues(nil, []interface{}{}); len(got) != 0 {
+		t.Errorf("counts of two empty arrays: %v", got)
+	}
+}
+
+// multisetRows compares a and b with the arrays at patterns compared as
+// multisets and returns each change as "kind path from -> to".
+func multisetRows(t *testing.T, a, b string, patterns ...string) ([]string, map[string][]multisetCount) {
+	t.Helper()
+	va, vb := mustDecode(t, a), mustDecode(t, b)
+	changes, err := collectChanges(diffSeq(context.Background(), va, vb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, counts := parseSetPatterns(patterns).collapseMultisets(changes, va, vb)
+	results := buildDiffTable(changes, va, vb)
+	markMultisets(results, counts)
+	var rows []string
+	for _, r := range results {
+		rows = append(rows, r.Kind+" "+r.Path+" "+r.From+" -> "+r.To)
+	}
+	return rows, counts
+}
+
+func TestCollapseMultisets(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		patterns []string
+		want     []string
+	}{
+		{
+			name:     "duplicates",
+			a:        `{"tags": ["a", "a", "b"]}`,
+			b:        `{"tags": ["b", "a", "b"]}`,
+			patterns: []string{"tags"},
+			want:     []string{`multiset tags "a": 2 -> "a": 1`, `multiset tags "b": 1 -> "b": 2`},
+		},
+		{
+			name:     "emptied",
+			a:        `{"tags": ["a", "a"]}`,
+			b:        `{"tags": []}`,
+			patterns: []string{"tags"},
+			want:     []string{`multiset tags "a": 2 -> "a": 0`},
+		},
+		{
+			name:     "both empty",
+			a:        `{"tags": [], "n": 1}`,
+			b:        `{"tags": [], "n": 2}`,
+			patterns: []string{"tags"},
+			want:     []string{" n 1 -> 2"},
+		},
+		{
+			name:     "mixed primitives",
+			a:        `{"v": [1, "1", true, null]}`,
+			b:        `{"v": ["1", "1", false, null]}`,
+			patterns: []string{"v"},
+			want:     []string{`multiset v "1": 1 -> "1": 2`, "multiset v 1: 1 -> 1: 0", "multiset v false: 0 -> false: 1", "multiset v true: 1 -> true: 0"},
+		},
+		{
+			name:     "reordered only",
+			a:        `{"tags": ["a", "b", "a"]}`,
+			b:        `{"tags": ["a", "a", "b"]}`,
+			patterns: []string{"tags"},
+			want:     nil,
+		},
+		{
+			name:     "objects are not primitives",
+			a:        `{"v": [{"x": 1}, "a"]}`,
+			b:        `{"v": [{"x": 2}, "a"]}`,
+			patterns: []string{"v"},
+			want:     []string{" v[0].x 1 -> 2"},
+		},
+		{
+			name:     "unmatched path",
+			a:        `{"tags": ["a"], "other": ["a", "a"]}`,
+			b:        `{"tags": ["b"], "other": ["a"]}`,
+			patterns: []string{"tags"},
+			want:     []string{" other[1] a -> ", `multiset tags "a": 1 -> "a": 0`, `multiset tags "b": 0 -> "b": 1`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := multisetRows(t, tt.a, tt.b, tt.patterns...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changes %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultisetSummary(t *testing.T) {
+	_, counts := multisetRows(t, `{"tags": ["a", "a", "b"]}`, `{"tags": ["a", "b", "b", "<c>"]}`, "tags")
+	ctx := renderContext{multisets: counts}
+	got := ctx.multisetSummary("tags")
+	for _, want := range []string{`class="count-diff"`, `&quot;a&quot;: 2→1`, `&quot;b&quot;: 1→2`, `&quot;&lt;c&gt;&quot;: 0→1`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("summary %s lacks %s", got, want)
+		}
+	}
+	if ctx.multisetSummary("other") != "" {
+		t.Error("summary rendered for an array not compared as a multiset")
+	}
+}
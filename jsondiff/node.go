@@ -0,0 +1,340 @@
+package jsondiff
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"sort"
+	"strconv"
+
+	"github.com/r3labs/diff/v3"
+)
+
+// Kind is the JSON type of a Node.
+type Kind int
+
+const (
+	KindNull Kind = iota
+	KindBool
+	KindNumber
+	KindString
+	KindArray
+	KindObject
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindBool:
+		return "boolean"
+	case KindNumber:
+		return "number"
+	case KindString:
+		return "string"
+	case KindArray:
+		return "array"
+	case KindObject:
+		return "object"
+	}
+	return "null"
+}
+
+// Position locates a value in its source document. Line and Column are
+// 1-based; Offset is the 0-based byte offset of the value's first byte.
+type Position struct {
+	Line   int
+	Column int
+	Offset int64
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Node is a typed JSON value that keeps object members in source order and
+// remembers where it was decoded from.
+type Node struct {
+	Kind Kind
+	// Key is the member name when the parent is an object.
+	Key string
+	// Index is the element index when the parent is an array, otherwise -1.
+	Index  int
+	Parent *Node
+	// Children holds object members in source order, or array elements.
+	Children []*Node
+	// Value holds the scalar value: bool, float64, string or nil.
+	Value interface{}
+	// Literal is the number exactly as written in the source.
+	Literal string
+	Pos     Position
+}
+
+// ParseNode decodes data into a Node tree, recording each value's position.
+func ParseNode(data []byte) (*Node, error) {
+	p := &nodeParser{src: newSourceIndex(data), dec: json.NewDecoder(bytes.NewReader(data))}
+	p.dec.UseNumber()
+	n, err := p.parse(nil, "", -1)
+	if err != nil {
+		return nil, describeJSONError(data, err)
+	}
+	if _, err := p.dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after top-level value at offset %d", p.dec.InputOffset())
+	}
+	return n, nil
+}
+
+type nodeParser struct {
+	src *sourceIndex
+	dec *json.Decoder
+}
+
+// position returns the position of the next token.
+func (p *nodeParser) position() Position {
+	return p.src.position(p.dec.InputOffset())
+}
+
+func (p *nodeParser) parse(parent *Node, key string, index int) (*Node, error) {
+	n := &Node{Parent: parent, Key: key, Index: index, Pos: p.position()}
+	tok, err := p.dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '{' {
+			n.Kind = KindObject
+			for p.dec.More() {
+				keyTok, err := p.dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				child, err := p.parse(n, keyTok.(string), -1)
+				if err != nil {
+					return nil, err
+				}
+				n.Children = append(n.Children, child)
+			}
+		} else {
+			n.Kind = KindArray
+			for i := 0; p.dec.More(); i++ {
+				child, err := p.parse(n, "", i)
+				if err != nil {
+					return nil, err
+				}
+				n.Children = append(n.Children, child)
+			}
+		}
+		if _, err := p.dec.Token(); err != nil {
+			return nil, err
+		}
+	case json.Number:
+		f, err := t.Float64()
+		if err != nil {
+			return nil, err
+		}
+		n.Kind, n.Value, n.Literal = KindNumber, f, string(t)
+	case string:
+		n.Kind, n.Value = KindString, t
+	case bool:
+		n.Kind, n.Value = KindBool, t
+	case nil:
+		n.Kind = KindNull
+	}
+	return n, nil
+}
+
+// Child returns the member or element addressed by seg, or nil.
+func (n *Node) Child(seg string) *Node {
+	switch n.Kind {
+	case KindObject:
+		// Later duplicates win, matching encoding/json.
+		for i := len(n.Children) - 1; i >= 0; i-- {
+			if n.Children[i].Key == seg {
+				return n.Children[i]
+			}
+		}
+	case KindArray:
+		i, ok := parseIndexSegment(seg)
+		if !ok {
+			// Unbracketed indices, as in diff.Change paths.
+			idx, err := strconv.Atoi(seg)
+			i, ok = idx, err == nil
+		}
+		if ok && i >= 0 && i < len(n.Children) {
+			return n.Children[i]
+		}
+	}
+	return nil
+}
+
+// Lookup returns the node at path, or nil if there is none.
+func (n *Node) Lookup(path []string) *Node {
+	for _, seg := range path {
+		if n = n.Child(seg); n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// Path returns the segments leading from the root to n, with array indices
+// bracketed as in splitPath.
+func (n *Node) Path() []string {
+	var segs []string
+	for cur := n; cur.Parent != nil; cur = cur.Parent {
+		if cur.Parent.Kind == KindArray {
+			segs = append(segs, indexSegment(cur.Index))
+		} else {
+			segs = append(segs, cur.Key)
+		}
+	}
+	for i, j := 0, len(segs)-1; i < j; i, j = i+1, j-1 {
+		segs[i], segs[j] = segs[j], segs[i]
+	}
+	return segs
+}
+
+// Interface converts the tree into the map[string]interface{} /
+// []interface{} representation used by the rest of the pipeline.
+func (n *Node) Interface() interface{} {
+	switch n.Kind {
+	case KindObject:
+		m := make(map[string]interface{}, len(n.Children))
+		for _, c := range n.Children {
+			m[c.Key] = c.Interface()
+		}
+		return m
+	case KindArray:
+		a := make([]interface{}, len(n.Children))
+		for i, c := range n.Children {
+			a[i] = c.Interface()
+		}
+		return a
+	}
+	return n.Value
+}
+
+// NodeFromInterface builds a Node tree from a decoded document. Object
+// members are ordered by key and positions are left zero.
+func NodeFromInterface(v interface{}) *Node {
+	return nodeFromInterface(v, nil, "", -1)
+}
+
+func nodeFromInterface(v interface{}, parent *Node, key string, index int) *Node {
+	n := &Node{Parent: parent, Key: key, Index: index}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		n.Kind = KindObject
+		for _, k := range sortedKeys(val) {
+			n.Children = append(n.Children, nodeFromInterface(val[k], n, k, -1))
+		}
+	case []interface{}:
+		n.Kind = KindArray
+		for i, vv := range val {
+			n.Children = append(n.Children, nodeFromInterface(vv, n, "", i))
+		}
+	case float64:
+		n.Kind, n.Value, n.Literal = KindNumber, val, strconv.FormatFloat(val, 'g', -1, 64)
+	case json.Number:
+		f, _ := val.Float64()
+		n.Kind, n.Value, n.Literal = KindNumber, f, string(val)
+	case string:
+		n.Kind, n.Value = KindString, val
+	case bool:
+		n.Kind, n.Value = KindBool, val
+	}
+	return n
+}
+
+// NodeChange is a single difference between two Node trees. From is nil for
+// additions and To is nil for removals.
+type NodeChange struct {
+	Type ChangeType
+	Path []string
+	From *Node
+	To   *Node
+}
+
+// ChangeSet is the result of comparing two Node trees.
+type ChangeSet struct {
+	A, B    *Node
+	Changes []NodeChange
+}
+
+// Compare diffs two Node trees and returns the changes with references to
+// the nodes involved on each side.
+// Neither tree is modified, so a and b may share nodes or be the same tree.
+func Compare(a, b *Node, opts ...CompareOption) (*ChangeSet, error) {
+	if a == nil || b == nil {
+		return nil, errors.New("compare: nil document")
+	}
+	cs := &ChangeSet{A: a, B: b}
+	for nc, err := range CompareSeq(context.Background(), a, b, opts...) {
+		if err != nil {
+			return nil, err
+		}
+		cs.Changes = append(cs.Changes, nc)
+	}
+	sort.SliceStable(cs.Changes, func(i, j int) bool {
+		return joinPath(cs.Changes[i].Path) < joinPath(cs.Changes[j].Path)
+	})
+	return cs, nil
+}
+
+// CompareSeq is the streaming form of Compare: it yields changes as the
+// comparison finds them, without holding them all, and stops comparing when
+// the loop body breaks or ctx is cancelled. Changes come grouped by
+// top-level key but are otherwise unordered. A failure, including
+// cancellation, is yielded as the last element with a non-nil error.
+func CompareSeq(ctx context.Context, a, b *Node, opts ...CompareOption) iter.Seq2[NodeChange, error] {
+	return func(yield func(NodeChange, error) bool) {
+		if a == nil || b == nil {
+			yield(NodeChange{}, errors.New("compare: nil document"))
+			return
+		}
+		var o compareOptions
+		for _, opt := range opts {
+			opt(&o)
+		}
+		va, vb, decided := applyEqualHooks(a.Interface(), b.Interface(), o.hooks)
+		changes := func(yield func(diff.Change, error) bool) {
+			for _, c := range decided {
+				if !yield(c, nil) {
+					return
+				}
+			}
+			diffSeq(ctx, va, vb)(yield)
+		}
+		for c, err := range changes {
+			if err != nil {
+				yield(NodeChange{}, err)
+				return
+			}
+			nc := NodeChange{Path: typedPath(c.Path, va, vb)}
+			switch c.Type {
+			case diff.CREATE:
+				nc.Type = Added
+			case diff.DELETE:
+				nc.Type = Removed
+			default:
+				nc.Type = Changed
+			}
+			if nc.Type != Added {
+				nc.From = a.Lookup(nc.Path)
+			}
+			if nc.Type != Removed {
+				nc.To = b.Lookup(nc.Path)
+			}
+			if !yield(nc, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package jsondiff
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNode(t *testing.T) {
+	root, err := ParseNode([]byte(`{"z": 1, "a": [true, null, "s"], "n": 1.50}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Kind != KindObject {
+		t.Fatalf("root kind = %v", root.Kind)
+	}
+	var keys []string
+	for _, c := range root.Children {
+		keys = append(keys, c.Key)
+	}
+	if got := strings.Join(keys, ","); got != "z,a,n" {
+		t.Errorf("members in order %s, want source order z,a,n", got)
+	}
+	arr := root.Child("a")
+	var kinds []string
+	for _, c := range arr.Children {
+		kinds = append(kinds, c.Kind.String())
+	}
+	if got := strings.Join(kinds, ","); got != "boolean,null,string" {
+		t.Errorf("element kinds %s", got)
+	}
+	if n := root.Child("n"); n.Literal != "1.50" || n.Value != 1.5 {
+		t.Errorf("number literal %q value %v", n.Literal, n.Value)
+	}
+	if s := root.Lookup([]string{"a", "[2]"}); s == nil || s.Value != "s" || s.Pos.Column != 28 {
+		t.Errorf("a[2] = %+v", s)
+	}
+	if got := joinPath(arr.Children[1].Path()); got != "a[1]" {
+		t.Errorf("Path() = %s", got)
+	}
+}
+
+func TestParseNodeErrors(t *testing.T) {
+	for _, in := range []string{`{"a": }`, `{"a": 1} 2`, `[1,`} {
+		if _, err := ParseNode([]byte(in)); err == nil {
+			t.Errorf("ParseNode(%s) accepted", in)
+		}
+	}
+	_, err := ParseNode([]byte("{\n  \"a\": x\n}"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("error %v does not name line 2", err)
+	}
+}
+
+func TestNodeInterfaceRoundTrip(t *testing.T) {
+	const doc = `{"a": [1, {"b": null}], "c": "x", "d": false}`
+	var want interface{}
+	if err := json.Unmarshal([]byte(doc), &want); err != nil {
+		t.Fatal(err)
+	}
+	root, err := ParseNode([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := root.Interface(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseNode(...).Interface() = %v, want %v", got, want)
+	}
+	if got := NodeFromInterface(want).Interface(); !reflect.DeepEqual(got, want) {
+		t.Errorf("NodeFromInterface(...).Interface() = %v, want %v", got, want)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	a, err := ParseNode([]byte(`{"keep": 1, "edit": "x", "gone": [1], "list": [1, 2]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ParseNode([]byte(`{"keep": 1, "edit": "y", "new": true, "list": [1, 3]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, err := Compare(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range cs.Changes {
+		got = append(got, string(c.Type)+" "+joinPath(c.Path))
+		if c.Type != Added && c.From != a.Lookup(c.Path) {
+			t.Errorf("%s: From is not the node of a", joinPath(c.Path))
+		}
+		if c.Type != Removed && c.To != b.Lookup(c.Path) {
+			t.Errorf("%s: To is not the node of b", joinPath(c.Path))
+		}
+	}
+	want := []string{"changed edit", "removed gone", "changed list[1]", "added new"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changes %q, want %q", got, want)
+	}
+
+	if cs, err := Compare(a, a); err != nil || len(cs.Changes) != 0 {
+		t.Errorf("comparing a tree with itself: %v, %v", cs, err)
+	}
+	if _, err := Compare(a, nil); err == nil {
+		t.Error("nil document accepted")
+	}
+}
+
+// aliasedDocs returns two documents built around one shared object, which
+// appears twice in a and twice in b. Each call builds fresh values, so a
+// second call gives a snapshot to compare the first against.
+func aliasedDocs() (a, b interface{}) {
+	shared := map[string]interface{}{
+		"Name":   " Web  App ",
+		"price":  json.Number("1.50"),
+		"tags":   []interface{}{"b", "a"},
+		"labels": map[string]interface{}{"tier": "front"},
+	}
+	a = map[string]interface{}{"x": shared, "list": []interface{}{shared, shared}}
+	b = map[string]interface{}{"x": shared, "y": shared, "list": []interface{}{shared}}
+	return a, b
+}
+
+func TestCompareLeavesAliasedInputs(t *testing.T) {
+	a, b := aliasedDocs()
+	wantA, wantB := aliasedDocs()
+	na, nb := NodeFromInterface(a), NodeFromInterface(b)
+	hook := WithEqualFunc("*.labels", func(a, b interface{}) EqualResult { return Equal })
+	cs, err := Compare(na, nb, hook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range cs.Changes {
+		got = append(got, string(c.Type)+" "+joinPath(c.Path))
+	}
+	if want := []string{"removed list[1]", "added y"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("changes %q, want %q", got, want)
+	}
+	for _, n := range []*Node{na, nb} {
+		if cs, err := Compare(n, n, hook); err != nil || len(cs.Changes) != 0 {
+			t.Errorf("comparing a tree with itself: %v, %v", cs, err)
+		}
+	}
+	if !reflect.DeepEqual(a, wantA) || !reflect.DeepEqual(b, wantB) {
+		t.Errorf("inputs modified:\na: %v\nb: %v", a, b)
+	}
+	if !reflect.DeepEqual(na.Interface(), NodeFromInterface(wantA).Interface()) || !reflect.DeepEqual(nb.Interface(), NodeFromInterface(wantB).Interface()) {
+		t.Error("trees modified by Compare")
+	}
+}
+
+// TestTransformsLeaveAliasedInputs runs every transform of the comparison
+// pipeline over documents sharing values and checks that each returns a
+// transformed copy without touching what it was given.
+func TestTransformsLeaveAliasedInputs(t *testing.T) {
+	mappings := loadTestKeyMap(t, `[{"from": "x.Name", "to": "x.title"}]`)
+	transforms := comparisonTransforms{
+		global: []stringTransform{trimSpaceTransform, collapseSpaceTransform, ignoreCaseTransform},
+		key:    strings.ToLower,
+	}
+	warn := func(string) {}
+	tests := []struct {
+		name string
+		run  func(a, b interface{}) interface{}
+	}{
+		{"key map", func(a, b interface{}) interface{} {
+			out, _ := applyKeyMap(a, nil, mappings)
+			return out
+		}},
+		{"key case", func(a, b interface{}) interface{} {
+			out, _ := foldKeyCase(a, map[string]interface{}{"x": map[string]interface{}{"NAME": ""}}, warn)
+			return out
+		}},
+		{"string transforms", func(a, b interface{}) interface{} {
+			return transforms.apply(a, make(map[string]string), warn)
+		}},
+		{"map as set", func(a, b interface{}) interface{} {
+			return parseSetPatterns([]string{"*.labels", "list[*]"}).collapseSets(a, nil)
+		}},
+		{"decimals", func(a, b interface{}) interface{} {
+			return canonicalizeDecimals(a, "", make(map[string]string))
+		}},
+		{"diff", func(a, b interface{}) interface{} {
+			changes, err := collectChanges(diffSeq(context.Background(), a, b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			buildDiffTable(changes, a, b)
+			return nil
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := aliasedDocs()
+			wantA, wantB := aliasedDocs()
+			out := tt.run(a, b)
+			if !reflect.DeepEqual(a, wantA) || !reflect.DeepEqual(b, wantB) {
+				t.Errorf("inputs modified:\na: %v\nb: %v", a, b)
+			}
+			if out != nil && reflect.DeepEqual(out, wantA) {
+				t.Errorf("transform changed nothing: %v", out)
+			}
+		})
+	}
+}
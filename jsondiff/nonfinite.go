@@ -0,0 +1,52 @@
+package jsondiff
+
+import (
+	"math"
+	"strings"
+)
+
+// Strings are never compared as numbers, whatever they look like: "1e5",
+// "NaN" and "Infinity" are strings like any other unless a coercion such as
+// --units applies to their path. Standard JSON has no NaN or infinities, so
+// they can only come from such a coercion, whose parser accepts them.
+
+// nonFinite is a NaN or an infinity spelled in a string that a coercion
+// parsed. It stands in for the float64, which would be unequal to itself as
+// NaN and cannot be encoded as JSON, so a change involving one is always
+// reported and never gets a delta.
+type nonFinite string
+
+const (
+	notANumber  nonFinite = "NaN"
+	posInfinity nonFinite = "Infinity"
+	negInfinity nonFinite = "-Infinity"
+)
+
+func (n nonFinite) String() string {
+	return string(n) + " (non-standard)"
+}
+
+// parseNonFinite recognises the spellings of NaN and the infinities that
+// strconv.ParseFloat accepts, such as "nan", "+Inf" and "-infinity", in any
+// case.
+func parseNonFinite(s string) (nonFinite, bool) {
+	sign := ""
+	if strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-") {
+		sign, s = s[:1], s[1:]
+	}
+	switch strings.ToLower(s) {
+	case "nan":
+		return notANumber, true
+	case "inf", "infinity":
+		if sign == "-" {
+			return negInfinity, true
+		}
+		return posInfinity, true
+	}
+	return "", false
+}
+
+// isFinite reports whether f is neither NaN nor infinite.
+func isFinite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
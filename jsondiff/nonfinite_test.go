@@ -0,0 +1,100 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseNonFinite(t *testing.T) {
+	tests := []struct {
+		s    string
+		want nonFinite
+		ok   bool
+	}{
+		{"NaN", notANumber, true},
+		{"nan", notANumber, true},
+		{"-NaN", notANumber, true},
+		{"Inf", posInfinity, true},
+		{"+infinity", posInfinity, true},
+		{"-Infinity", negInfinity, true},
+		{"-INF", negInfinity, true},
+		{"", "", false},
+		{"Infinite", "", false},
+		{"nano", "", false},
+		{"1e400", "", false},
+		{"--inf", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := parseNonFinite(tt.s)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%q: %q, %t", tt.s, got, ok)
+		}
+	}
+}
+
+// TestNonFiniteFlagMatrix runs documents spelling NaN and infinities in
+// strings through every output format, with and without the flags that
+// coerce or reinterpret values, checking each run succeeds and that a
+// non-finite value is never taken as a number.
+func TestNonFiniteFlagMatrix(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"t": "NaN", "s": "Infinity", "d": "1s", "n": 1.5}`,
+		"b.json": `{"t": "nan", "s": "-inf", "d": "1000ms", "n": 2.5}`,
+	})
+	units := []string{"--units", "t=duration", "--units", "s=bytes", "--units", "d=duration"}
+	flagSets := []struct {
+		name  string
+		flags []string
+		// changed lists the changed paths; with --units, t and s are
+		// compared as non-finite quantities and d as equal durations.
+		changed string
+	}{
+		{"plain", nil, "d n s t"},
+		{"decimal-strict", []string{"--decimal-strict"}, "d n s t"},
+		{"numeric-strict", []string{"--numeric-strict"}, "d n s t"},
+		{"units", units, "n s t"},
+		{"units decimal-strict", append([]string{"--decimal-strict"}, units...), "n s t"},
+		{"units numeric-strict", append([]string{"--numeric-strict"}, units...), "n s t"},
+	}
+	for _, fs := range flagSets {
+		for _, format := range rendererNames() {
+			t.Run(fs.name+"/"+format, func(t *testing.T) {
+				args := append(append([]string{"-f", format, "-o", "out"}, fs.flags...), "a.json", "b.json")
+				res := runCLI(t, dir, args...)
+				if res.exit != exitOK {
+					t.Fatalf("exit %d: %s", res.exit, res.stderr)
+				}
+				out := readFile(t, dir, "out")
+				if out == "" {
+					t.Fatal("empty output")
+				}
+				if format != "json" {
+					return
+				}
+				wellFormed["json"](t, out)
+				var report jsonReport
+				if err := json.Unmarshal([]byte(out), &report); err != nil {
+					t.Fatal(err)
+				}
+				var changed []string
+				for _, s := range report.Sections {
+					for _, c := range s.Changes {
+						changed = append(changed, c.Path)
+						if c.Path != "n" && c.Delta != nil {
+							t.Errorf("%s has a delta %v", c.Path, *c.Delta)
+						}
+						if (c.Path == "s" || c.Path == "t") && (c.Unit != "") != (c.NonFinite != "") {
+							t.Errorf("%s: unit %q, non-finite %q", c.Path, c.Unit, c.NonFinite)
+						}
+					}
+				}
+				sort.Strings(changed)
+				if got := strings.Join(changed, " "); got != fs.changed {
+					t.Errorf("changed %s, want %s", got, fs.changed)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package jsondiff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/r3labs/diff/v3"
+	"golang.org/x/text/unicode/norm"
+)
+
+// stringTransform rewrites a string value before comparison. Two values that
+// are equal after every applicable transform are reported as unchanged.
+type stringTransform struct {
+	name  string
+	apply func(string) string
+}
+
+var (
+	trimSpaceTransform     = stringTransform{"trim-space", strings.TrimSpace}
+	collapseSpaceTransform = stringTransform{"collapse-space", collapseSpace}
+	ignoreCaseTransform    = stringTransform{"ignore-case", strings.ToLower}
+)
+
+func unicodeTransform(form norm.Form) stringTransform {
+	name := "nfc"
+	if form == norm.NFD {
+		name = "nfd"
+	}
+	return stringTransform{"normalize-unicode " + name, form.String}
+}
+
+// collapseSpace replaces every run of whitespace with a single space.
+func collapseSpace(s string) string {
+	var b strings.Builder
+	inSpace := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\v' || r == '\f' {
+			if !inSpace {
+				b.WriteByte(' ')
+			}
+			inSpace = true
+			continue
+		}
+		inSpace = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// transformOrder fixes the order in which transforms run so the result does
+// not depend on the order flags or config entries were given in.
+var transformOrder = []string{"normalize-unicode nfc", "normalize-unicode nfd", "trim-space", "collapse-space", "ignore-case"}
+
+// TransformRule enables string transforms for values matching Path.
+type TransformRule struct {
+	Path          string `json:"path"`
+	IgnoreCase    bool   `json:"ignoreCase"`
+	TrimSpace     bool   `json:"trimSpace"`
+	CollapseSpace bool   `json:"collapseSpace"`
+}
+
+func (r TransformRule) transforms() []stringTransform {
+	var out []stringTransform
+	if r.TrimSpace {
+		out = append(out, trimSpaceTransform)
+	}
+	if r.CollapseSpace {
+		out = append(out, collapseSpaceTransform)
+	}
+	if r.IgnoreCase {
+		out = append(out, ignoreCaseTransform)
+	}
+	return out
+}
+
+// comparisonTransforms decides which string transforms apply at each path
+// and, optionally, how object keys are normalized.
+type comparisonTransforms struct {
+	global []stringTransform
+	rules  []TransformRule
+	key    func(string) string
+}
+
+func (t *comparisonTransforms) empty() bool {
+	return len(t.global) == 0 && len(t.rules) == 0 && t.key == nil
+}
+
+// forPath returns the transforms applicable at path in canonical order.
+func (t *comparisonTransforms) forPath(path []string) []stringTransform {
+	byName := make(map[string]stringTransform)
+	for _, tr := range t.global {
+		byName[tr.name] = tr
+	}
+	for _, r := range t.rules {
+		if matchPath(splitPath(r.Path), path) {
+			for _, tr := range r.transforms() {
+				byName[tr.name] = tr
+			}
+		}
+	}
+	chain := make([]stringTransform, 0, len(byName))
+	for _, name := range transformOrder {
+		if tr, ok := byName[name]; ok {
+			chain = append(chain, tr)
+		}
+	}
+	return chain
+}
+
+// apply returns a transformed copy of v; v itself is not modified. For every
+// string the transforms changed, originals records the untransformed value
+// by (transformed) path. If two keys of one object normalize to the same
+// string, the first in sorted order wins and warn is called.
+func (t *comparisonTransforms) apply(v interface{}, originals map[string]string, warn func(string)) interface{} {
+	return t.applyAt(v, nil, originals, warn)
+}
+
+func (t *comparisonTransforms) applyAt(v interface{}, path []string, originals map[string]string, warn func(string)) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for _, k := range sortedKeys(val) {
+			nk := k
+			if t.key != nil {
+				nk = t.key(k)
+				if _, dup := out[nk]; dup {
+					warn(fmt.Sprintf("keys %q and another key under %q are equal after normalization; keeping the first", k, joinPath(path)))
+					continue
+				}
+			}
+			out[nk] = t.applyAt(val[k], append(path[:len(path):len(path)], nk), originals, warn)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, vv := range val {
+			out[i] = t.applyAt(vv, append(path[:len(path):len(path)], indexSegment(i)), originals, warn)
+		}
+		return out
+	case string:
+		s := val
+		for _, tr := range t.forPath(path) {
+			s = tr.apply(s)
+		}
+		if s != val {
+			originals[joinPath(path)] = val
+		}
+		return s
+	}
+	return v
+}
+
+// approxEqualPaths returns the paths whose original strings differ but whose
+// transformed values are equal, i.e. where a transform hid a difference.
+func approxEqualPaths(cmp1, cmp2 interface{}, originals1, originals2 map[string]string) map[string]bool {
+	approx := make(map[string]bool)
+	check := func(p string) {
+		a, okA := lookupPath(cmp1, splitPath(p))
+		b, okB := lookupPath(cmp2, splitPath(p))
+		sa, isStrA := a.(string)
+		sb, isStrB := b.(string)
+		if !okA || !okB || !isStrA || !isStrB || sa != sb {
+			return
+		}
+		oa, ob := sa, sb
+		if o, ok := originals1[p]; ok {
+			oa = o
+		}
+		if o, ok := originals2[p]; ok {
+			ob = o
+		}
+		if oa != ob {
+			approx[p] = true
+		}
+	}
+	for p := range originals1 {
+		check(p)
+	}
+	for p := range originals2 {
+		check(p)
+	}
+	return approx
+}
+
+// suppress records in suppressed the difference hidden at each approx
+// path, giving its original strings and, as the reason, the first transform
+// after which they are equal. cmp1 is document A as compared.
+func (t *comparisonTransforms) suppress(approx map[string]bool, cmp1 interface{}, originals1, originals2 map[string]string, suppressed *suppressionLog) {
+	for p := range approx {
+		segs := splitPath(p)
+		v, _ := lookupPath(cmp1, segs)
+		oa, ob := v.(string), v.(string)
+		if o, ok := originals1[p]; ok {
+			oa = o
+		}
+		if o, ok := originals2[p]; ok {
+			ob = o
+		}
+		a, b := oa, ob
+		for _, tr := range t.forPath(segs) {
+			a, b = tr.apply(a), tr.apply(b)
+			if a == b {
+				suppressed.add("equal after "+tr.name, diff.Change{Type: diff.UPDATE, Path: segs, From: oa, To: ob})
+				break
+			}
+		}
+	}
+}
+
+// parseNormalizationForm maps a --normalize-unicode value to a form. The
+// boolean is false for "none".
+func parseNormalizationForm(name string) (norm.Form, bool, error) {
+	switch name {
+	case "", "none":
+		return 0, false, nil
+	case "nfc":
+		return norm.NFC, true, nil
+	case "nfd":
+		return norm.NFD, true, nil
+	}
+	return 0, false, fmt.Errorf("invalid --normalize-unicode %q: must be nfc, nfd or none", name)
+}
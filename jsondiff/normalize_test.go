@@ -0,0 +1,189 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Canonically equivalent spellings: each pair is one text, precomposed (NFC)
+// and decomposed (NFD).
+var combiningFixtures = []struct {
+	name     string
+	nfc, nfd string
+}{
+	{"acute", "caf\u00e9", "cafe\u0301"},
+	{"two marks", "\u1ec7", "e\u0323\u0302"},
+	{"hangul", "\uac00", "\u1100\u1161"},
+	{"ring above", "\u00c5", "A\u030a"},
+}
+
+func TestUnicodeTransform(t *testing.T) {
+	nfc, nfd := unicodeTransform(norm.NFC), unicodeTransform(norm.NFD)
+	if nfc.name != "normalize-unicode nfc" || nfd.name != "normalize-unicode nfd" {
+		t.Errorf("names %q, %q", nfc.name, nfd.name)
+	}
+	for _, f := range combiningFixtures {
+		if f.nfc == f.nfd {
+			t.Fatalf("%s: fixture spellings are the same bytes", f.name)
+		}
+		for _, s := range []string{f.nfc, f.nfd} {
+			if got := nfc.apply(s); got != f.nfc {
+				t.Errorf("%s: NFC of %+q is %+q, want %+q", f.name, s, got, f.nfc)
+			}
+			if got := nfd.apply(s); got != f.nfd {
+				t.Errorf("%s: NFD of %+q is %+q, want %+q", f.name, s, got, f.nfd)
+			}
+		}
+	}
+	// U+212B ANGSTROM SIGN is a singleton decomposition of U+00C5.
+	if got := nfc.apply("\u212b"); got != "\u00c5" {
+		t.Errorf("NFC of U+212B is %+q", got)
+	}
+	// Letters that only look alike are not equivalent.
+	if nfc.apply("\u0430") == nfc.apply("a") {
+		t.Error("Cyrillic a normalizes to Latin a")
+	}
+}
+
+// TestNormalizeUnicodeCLI compares documents spelling the fixtures in
+// different forms, in values and in keys.
+func TestNormalizeUnicodeCLI(t *testing.T) {
+	for _, f := range combiningFixtures {
+		t.Run(f.name, func(t *testing.T) {
+			enc := func(s string) string {
+				data, _ := json.Marshal(map[string]string{"v": s, s: "k"})
+				return string(data)
+			}
+			dir := cliDir(t, map[string]string{"a.json": enc(f.nfc), "b.json": enc(f.nfd)})
+			total := func(args ...string) int {
+				t.Helper()
+				res := runCLI(t, dir, append(append([]string{"-f", "json", "-o", "out.json"}, args...), "a.json", "b.json")...)
+				if res.exit != exitOK {
+					t.Fatalf("exit %d: %s", res.exit, res.stderr)
+				}
+				var report jsonReport
+				if err := json.Unmarshal([]byte(readFile(t, dir, "out.json")), &report); err != nil {
+					t.Fatal(err)
+				}
+				return report.Total
+			}
+			// The value changes, and the key is removed and added.
+			if n := total(); n != 3 {
+				t.Errorf("without normalization: %d changes, want 3", n)
+			}
+			if n := total("--normalize-unicode", "nfc"); n != 2 {
+				t.Errorf("nfc: %d changes, want the key's 2", n)
+			}
+			if n := total("--normalize-unicode", "nfd", "--normalize-keys"); n != 0 {
+				t.Errorf("nfd with keys: %d changes, want none", n)
+			}
+		})
+	}
+}
+
+func TestNormalizeKeysCollision(t *testing.T) {
+	tr := comparisonTransforms{key: norm.NFC.String}
+	var warnings []string
+	got := tr.apply(map[string]interface{}{"caf\u00e9": 1.0, "cafe\u0301": 2.0}, make(map[string]string), func(msg string) { warnings = append(warnings, msg) })
+	m := got.(map[string]interface{})
+	if len(m) != 1 || len(warnings) != 1 {
+		t.Fatalf("got %v, warnings %q", m, warnings)
+	}
+	// The decomposed key sorts first, as "e" is before U+00E9, so its value
+	// is kept.
+	if m["caf\u00e9"] != 2.0 {
+		t.Errorf("kept %v", m)
+	}
+}
+
+func TestStringTransforms(t *testing.T) {
+	tests := []struct {
+		tr       stringTransform
+		in, want string
+	}{
+		{trimSpaceTransform, "  a b \t\n", "a b"},
+		{trimSpaceTransform, " a ", "a"},
+		{trimSpaceTransform, "", ""},
+		{collapseSpaceTransform, "a  \t b\n\nc", "a b c"},
+		{collapseSpaceTransform, "  a  ", " a "},
+		{collapseSpaceTransform, "a  b", "a  b"},
+		{collapseSpaceTransform, "\r\n\v\f", " "},
+		{ignoreCaseTransform, "MiXeD", "mixed"},
+		{ignoreCaseTransform, "ÉCOLE", "école"},
+		{ignoreCaseTransform, "123 _-", "123 _-"},
+	}
+	for _, tt := range tests {
+		if got := tt.tr.apply(tt.in); got != tt.want {
+			t.Errorf("%s(%q) = %q, want %q", tt.tr.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTransformsForPath(t *testing.T) {
+	tr := comparisonTransforms{
+		global: []stringTransform{ignoreCaseTransform, unicodeTransform(norm.NFC)},
+		rules: []TransformRule{
+			{Path: "spec.*.name", TrimSpace: true},
+			{Path: "spec.a.name", CollapseSpace: true, IgnoreCase: true},
+		},
+	}
+	names := func(path string) []string {
+		var out []string
+		for _, s := range tr.forPath(splitPath(path)) {
+			out = append(out, s.name)
+		}
+		return out
+	}
+	// Transforms run in transformOrder, whatever order they were given in,
+	// and each only once.
+	if got := names("spec.a.name"); !reflect.DeepEqual(got, []string{"normalize-unicode nfc", "trim-space", "collapse-space", "ignore-case"}) {
+		t.Errorf("spec.a.name: %q", got)
+	}
+	if got := names("spec.b.name"); !reflect.DeepEqual(got, []string{"normalize-unicode nfc", "trim-space", "ignore-case"}) {
+		t.Errorf("spec.b.name: %q", got)
+	}
+	if got := names("other"); !reflect.DeepEqual(got, []string{"normalize-unicode nfc", "ignore-case"}) {
+		t.Errorf("other: %q", got)
+	}
+}
+
+func TestComparisonTransformsApply(t *testing.T) {
+	tr := comparisonTransforms{rules: []TransformRule{{Path: "tags[*]", IgnoreCase: true}, {Path: "note", TrimSpace: true}}}
+	a := mustDecode(t, `{"tags": ["Web", "api"], "note": " hi ", "name": "X", "n": 1}`)
+	b := mustDecode(t, `{"tags": ["web", "API"], "note": "hi", "name": "x", "n": 1}`)
+	originals1, originals2 := make(map[string]string), make(map[string]string)
+	cmp1 := tr.apply(a, originals1, nil)
+	cmp2 := tr.apply(b, originals2, nil)
+
+	if want := mustDecode(t, `{"tags": ["web", "api"], "note": "hi", "name": "X", "n": 1}`); !reflect.DeepEqual(cmp1, want) {
+		t.Errorf("transformed %v, want %v", cmp1, want)
+	}
+	if want := map[string]string{"tags[0]": "Web", "note": " hi "}; !reflect.DeepEqual(originals1, want) {
+		t.Errorf("originals %v, want %v", originals1, want)
+	}
+	if a.(map[string]interface{})["note"] != " hi " {
+		t.Error("apply modified its input")
+	}
+	// name is outside every rule, so its difference is not hidden.
+	approx := approxEqualPaths(cmp1, cmp2, originals1, originals2)
+	if want := map[string]bool{"tags[0]": true, "tags[1]": true, "note": true}; !reflect.DeepEqual(approx, want) {
+		t.Errorf("approx %v, want %v", approx, want)
+	}
+}
+
+func TestParseNormalizationForm(t *testing.T) {
+	for _, name := range []string{"", "none"} {
+		if _, ok, err := parseNormalizationForm(name); ok || err != nil {
+			t.Errorf("%q: %t, %v", name, ok, err)
+		}
+	}
+	if form, ok, err := parseNormalizationForm("nfd"); form != norm.NFD || !ok || err != nil {
+		t.Errorf("nfd: %v, %t, %v", form, ok, err)
+	}
+	if _, _, err := parseNormalizationForm("NFC"); err == nil {
+		t.Error("NFC: no error")
+	}
+}
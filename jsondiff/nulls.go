@@ -0,0 +1,41 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Kinds of updates where one side is null. A field going null is often a
+// regression, so these are told apart from other changes.
+const (
+	kindNulled   = "nulled"
+	kindUnNulled = "un-nulled"
+)
+
+// nullKind returns the Kind of an update from from to to: nulled when only
+// the new value is null, un-nulled when only the old one is, otherwise "".
+func nullKind(from, to interface{}) string {
+	switch {
+	case from != nil && to == nil:
+		return kindNulled
+	case from == nil && to != nil:
+		return kindUnNulled
+	}
+	return ""
+}
+
+// formatValue formats a change's old or new value for the table, spelling
+// null as JSON does and writing objects and arrays as compact JSON. The side
+// a created or deleted value is missing from is left empty by the caller,
+// so it never reads as null.
+func formatValue(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}, []interface{}:
+		if data, err := json.Marshal(v); err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprintf("%v", v)
+}
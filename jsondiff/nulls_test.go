@@ -0,0 +1,129 @@
+package jsondiff
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`null`, "null"},
+		{`"text"`, "text"},
+		{`1.5`, "1.5"},
+		{`true`, "true"},
+		{`{"c":2,"b":1}`, `{"b":1,"c":2}`},
+		{`[1,null,"x"]`, `[1,null,"x"]`},
+		{`{}`, `{}`},
+	}
+	for _, tt := range tests {
+		var v interface{}
+		if err := json.Unmarshal([]byte(tt.in), &v); err != nil {
+			t.Fatal(err)
+		}
+		if got := formatValue(v); got != tt.want {
+			t.Errorf("formatValue(%s) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// diffTable compares two JSON documents as the CLI does and returns the
+// table rows by path.
+func diffTable(t *testing.T, a, b string) map[string]DiffResult {
+	t.Helper()
+	var va, vb interface{}
+	if err := json.Unmarshal([]byte(a), &va); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(b), &vb); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := collectChanges(diffSeq(context.Background(), va, vb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := make(map[string]DiffResult)
+	for _, r := range buildDiffTable(changes, va, vb) {
+		rows[r.Path] = r
+	}
+	return rows
+}
+
+func TestNullKinds(t *testing.T) {
+	type row struct{ typ, kind, from, to string }
+	tests := []struct {
+		name string
+		a, b string
+		want map[string]row
+	}{
+		{
+			name: "value goes null",
+			a:    `{"x":1}`,
+			b:    `{"x":null}`,
+			want: map[string]row{"x": {"update", kindNulled, "1", "null"}},
+		},
+		{
+			name: "null gets a value",
+			a:    `{"x":null}`,
+			b:    `{"x":"set"}`,
+			want: map[string]row{"x": {"update", kindUnNulled, "null", "set"}},
+		},
+		{
+			name: "subtree replaced by null",
+			a:    `{"o":{"p":{"b":1,"c":2}}}`,
+			b:    `{"o":null}`,
+			want: map[string]row{"o": {"update", kindNulled, `{"p":{"b":1,"c":2}}`, "null"}},
+		},
+		{
+			name: "nulls inside an array",
+			a:    `{"x":[1,null,3]}`,
+			b:    `{"x":[1,2,null]}`,
+			want: map[string]row{
+				"x[1]": {"update", kindUnNulled, "null", "2"},
+				"x[2]": {"update", kindNulled, "3", "null"},
+			},
+		},
+		{
+			name: "reordered nulls",
+			a:    `{"x":[null,1]}`,
+			b:    `{"x":[1,null]}`,
+			want: map[string]row{},
+		},
+		{
+			name: "null added to an array",
+			a:    `{"x":[1]}`,
+			b:    `{"x":[1,null]}`,
+			want: map[string]row{"x[1]": {"create", "", "", "null"}},
+		},
+		{
+			name: "created and deleted members leave the missing side empty",
+			a:    `{"gone":[1,2]}`,
+			b:    `{"new":{"k":true}}`,
+			want: map[string]row{
+				"gone": {"delete", "", "[1,2]", ""},
+				"new":  {"create", "", "", `{"k":true}`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := diffTable(t, tt.a, tt.b)
+			if len(rows) != len(tt.want) {
+				t.Errorf("got %d changes, want %d: %+v", len(rows), len(tt.want), rows)
+			}
+			for path, want := range tt.want {
+				r, ok := rows[path]
+				if !ok {
+					t.Errorf("no change at %s", path)
+					continue
+				}
+				if got := (row{r.Type, r.Kind, r.From, r.To}); got != want {
+					t.Errorf("%s: got %+v, want %+v", path, got, want)
+				}
+			}
+		})
+	}
+}
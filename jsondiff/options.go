@@ -0,0 +1,446 @@
+package jsondiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Options holds every setting of the diff command. Each field is bound to
+// the flag named by its JSON tag (-o for output), and --options-json reads
+// a whole Options document at once; flags given on the command line
+// override it.
+type Options struct {
+	Output              string     `json:"output"`
+	Format              string     `json:"format"`
+	Sort                string     `json:"sort"`
+	CollapseThreshold   int        `json:"collapse-threshold"`
+	EmitChangedA        string     `json:"emit-changed-a"`
+	EmitChangedB        string     `json:"emit-changed-b"`
+	EmitArrayMode       string     `json:"emit-array-mode"`
+	Verbose             bool       `json:"verbose"`
+	LogFormat           string     `json:"log-format"`
+	Profile             string     `json:"profile"`
+	Quiet               bool       `json:"quiet"`
+	SummaryOnly         bool       `json:"summary-only"`
+	StatsOnly           bool       `json:"stats-only"`
+	SummaryOut          string     `json:"summary-out"`
+	Bundle              string     `json:"bundle"`
+	Compress            string     `json:"compress"`
+	MinifyHTML          bool       `json:"minify-html"`
+	Paginate            int        `json:"paginate"`
+	GraphMaxNodes       int        `json:"graph-max-nodes"`
+	ArrayContext        int        `json:"array-context"`
+	ExpandAll           bool       `json:"expand-all"`
+	LazyDepth           int        `json:"lazy-depth"`
+	TemplateDir         string     `json:"template-dir"`
+	Editable            bool       `json:"editable"`
+	ShowGhosts          bool       `json:"show-ghosts"`
+	NumberLocale        string     `json:"display-number-locale"`
+	DisplayDates        string     `json:"display-dates"`
+	FailOn              string     `json:"fail-on"`
+	FailOnPath          stringList `json:"fail-on-path"`
+	ExitCodeMap         string     `json:"exit-code-map"`
+	DetectMoves         bool       `json:"detect-moves"`
+	DetectKeyReorder    bool       `json:"detect-key-reorder"`
+	ArrayMatchThreshold float64    `json:"array-match-threshold"`
+	ShowSuppressed      bool       `json:"show-suppressed"`
+	Subset              bool       `json:"subset"`
+	Superset            bool       `json:"superset"`
+	IgnoreValueRegex    stringList `json:"ignore-value-regex"`
+	IgnoreValues        string     `json:"ignore-values"`
+	Units               stringList `json:"units"`
+	ArrayGranularity    stringList `json:"array-granularity"`
+	Multiset            stringList `json:"multiset"`
+	MapAsSet            stringList `json:"map-as-set"`
+	Include             stringList `json:"include"`
+	IncludeUnchanged    bool       `json:"json-include-unchanged"`
+	SchemaAware         bool       `json:"json-schema-aware"`
+	EmptyEqualsAbsent   bool       `json:"empty-equals-absent"`
+	Deep                bool       `json:"deep"`
+	NormalizeUnicode    string     `json:"normalize-unicode"`
+	NormalizeKeys       bool       `json:"normalize-keys"`
+	IgnoreCase          bool       `json:"ignore-case"`
+	IgnoreKeyCase       bool       `json:"ignore-key-case"`
+	TrimSpace           bool       `json:"trim-space"`
+	CollapseSpace       bool       `json:"collapse-space"`
+	NumericStrict       bool       `json:"numeric-strict"`
+	DecimalStrict       bool       `json:"decimal-strict"`
+	LineNumbers         bool       `json:"line-numbers"`
+	ValidateOutput      bool       `json:"validate-output"`
+	StdinPair           bool       `json:"stdin-pair"`
+	InputFormat         string     `json:"input-format"`
+	PathA               string     `json:"path-a"`
+	PathB               string     `json:"path-b"`
+	Project             string     `json:"project"`
+	ProjectA            string     `json:"project-a"`
+	ProjectB            string     `json:"project-b"`
+	ExecA               string     `json:"exec-a"`
+	ExecB               string     `json:"exec-b"`
+	Swap                bool       `json:"swap"`
+	NoSwapWarning       bool       `json:"no-swap-warning"`
+	Shell               bool       `json:"shell"`
+	Timeout             Duration   `json:"timeout"`
+	ExecTimeout         Duration   `json:"exec-timeout"`
+	MaxInputSize        string     `json:"max-input-size"`
+	MaxDepth            int        `json:"max-depth"`
+	MaxArrayLength      int        `json:"max-array-length"`
+	AutoRedact          bool       `json:"auto-redact"`
+	Config              string     `json:"config"`
+	Aggregate           stringList `json:"aggregate"`
+	Schema              string     `json:"schema"`
+	Ack                 string     `json:"ack"`
+	AckFile             string     `json:"ack-file"`
+	KeyMap              string     `json:"key-map"`
+}
+
+// DefaultOptions returns the options in effect when no flag is given.
+func DefaultOptions() Options {
+	var o Options
+	o.RegisterFlags(flag.NewFlagSet("defaults", flag.ContinueOnError))
+	return o
+}
+
+// RegisterFlags defines the diff flags on fs, storing their values in o and
+// setting o's fields to the flag defaults.
+func (o *Options) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Output, "o", "", "Output file, or - for stdout (default diff.<ext> for the chosen --format); a directory gets a timestamped report and an index.html of all reports in it. A name with {{.LabelA}}, {{.LabelB}}, {{.BaseA}}, {{.BaseB}}, {{.Date}} or {{.Time}} is a template, and gets -1, -2, ... if the file exists")
+	fs.StringVar(&o.Format, "format", "html", "Output format: html, json, csv, fragment, ... (list shows all)")
+	fs.StringVar(&o.Format, "f", "html", "Shorthand for --format")
+	fs.BoolVar(&o.IncludeUnchanged, "json-include-unchanged", false, "With --format json or jsonl, list every leaf of both documents with its path, pointer, status (unchanged included) and value on each side; jsonl streams one leaf per line and otherwise lists only changed leaves")
+	fs.StringVar(&o.Sort, "sort", "path", "Order of changes: path, delta (largest numeric change first) or document (as they appear in the tree)")
+	fs.IntVar(&o.CollapseThreshold, "collapse-threshold", 50, "Collapse table sections with more than this many changes (0 never collapses)")
+	fs.StringVar(&o.EmitChangedA, "emit-changed-a", "", "Write file1 pruned to the subtrees involved in changes to this file")
+	fs.StringVar(&o.EmitChangedB, "emit-changed-b", "", "Write file2 pruned to the subtrees involved in changes to this file")
+	fs.StringVar(&o.EmitArrayMode, "emit-array-mode", emitArrayNulls, "How --emit-changed-* keeps array indices: nulls (pad with null) or keyed (object keyed by index)")
+	fs.BoolVar(&o.Verbose, "verbose", false, "Log timed pipeline phases to stderr")
+	fs.BoolVar(&o.Verbose, "v", false, "Shorthand for --verbose")
+	fs.StringVar(&o.LogFormat, "log-format", "text", "Format of --verbose logs: text or json")
+	fs.StringVar(&o.Profile, "profile", "", "Write a CPU profile of the diff and render phases to this file")
+	fs.BoolVar(&o.Quiet, "quiet", false, "Print nothing to stdout; only errors and warnings reach stderr")
+	fs.BoolVar(&o.SummaryOnly, "summary-only", false, "Print a one-line summary of the changes and skip the report unless -o is given")
+	fs.BoolVar(&o.StatsOnly, "stats-only", false, "Compare and print only the summary line (and --summary-out), skipping the diff table's values and all rendering; for scanning many pairs")
+	fs.StringVar(&o.SummaryOut, "summary-out", "", "Write a machine-readable JSON summary (counts, similarity, exit code, ...) to this file, even with --quiet")
+	fs.StringVar(&o.Bundle, "bundle", "", "Write a reproducible zip of the HTML report, JSON changes, canonicalized inputs and summary to this file")
+	fs.StringVar(&o.Compress, "compress", "", "Compress the report: gzip or none (default gzip when -o ends in .gz)")
+	fs.BoolVar(&o.MinifyHTML, "minify-html", false, "Strip the indentation between tags of HTML output")
+	fs.IntVar(&o.Paginate, "paginate", 0, "Split the HTML report into pages of roughly N tree nodes, grouped by top-level key, plus an index page (0 writes a single file)")
+	fs.IntVar(&o.GraphMaxNodes, "graph-max-nodes", defaultGraphMaxNodes, "With --format dot, draw at most this many nodes, replacing the changed members left out with a marker")
+	fs.IntVar(&o.ArrayContext, "array-context", 0, "In the trees, show only changed array elements and N unchanged neighbours on each side, folding the rest (0 shows everything)")
+	fs.BoolVar(&o.ExpandAll, "expand-all", false, "Show every array element, overriding --array-context")
+	fs.IntVar(&o.LazyDepth, "lazy-depth", 0, "In the HTML trees, render the members of unchanged objects and arrays N levels deep only when they are first expanded, keeping large reports quick to open (0 renders everything up front)")
+	fs.StringVar(&o.TemplateDir, "template-dir", "", "Directory of *.html partials overriding parts of template.html (tree-styles, table-styles, diff-table, tree-script, tree, ...)")
+	fs.BoolVar(&o.Editable, "editable", false, "Make changed values in the Modified pane editable, with a button exporting the corrected document")
+	fs.BoolVar(&o.ShowGhosts, "show-ghosts", false, "Show removed members as ghosts in the Modified pane and added members as ghosts in the Original pane")
+	fs.StringVar(&o.NumberLocale, "display-number-locale", "", "Show numbers in the HTML report grouped and punctuated for this BCP 47 locale, e.g. en or de; display only, raw values stay in tooltips and copies")
+	fs.StringVar(&o.DisplayDates, "display-dates", datesISO, "Show RFC 3339 timestamps in the HTML report as written (iso), in the local time zone (local) or relative to now (relative); display only")
+	fs.StringVar(&o.FailOn, "fail-on", "", "Exit with status 2 when changes of these comma-separated kinds remain: "+strings.Join(failOnKinds, ", "))
+	fs.Var(&o.FailOnPath, "fail-on-path", "Exit with status 2 when changes remain at paths matching these comma-separated patterns (e.g. security.**, items[*].id), however many changes there are elsewhere; repeatable")
+	fs.StringVar(&o.ExitCodeMap, "exit-code-map", "", `Exit with these statuses when changes of each kind remain, as comma-separated kind=code pairs such as "added=0,removed=3,changed=4"; the highest applicable status wins, including 2 for --fail-on and 3 for schema violations`)
+	fs.BoolVar(&o.DetectMoves, "detect-moves", false, "Report a value removed at one path and added, deeply equal, at another as a single move")
+	fs.Float64Var(&o.ArrayMatchThreshold, "array-match-threshold", 0, "Pair an array element removed at one index with one added at another when at least this fraction (0 to 1) of their leaves are equal, reporting the differences between them instead of a removal and an addition; 0 disables pairing")
+	fs.BoolVar(&o.DetectKeyReorder, "detect-key-reorder", false, "Report objects whose members appear in a different order, without counting them as changes")
+	fs.BoolVar(&o.ShowSuppressed, "show-suppressed", false, "List every change a filter (--subset, --ignore-values, --units, ...) dropped, with its reason, in a collapsed report section and the JSON output")
+	fs.BoolVar(&o.Subset, "subset", false, "Ignore additions: only require everything in file1 to be present and equal in file2")
+	fs.BoolVar(&o.Superset, "superset", false, "Ignore removals: only require everything in file2 to be present and equal in file1")
+	fs.Var(&o.IgnoreValueRegex, "ignore-value-regex", "Ignore changes where the old and new string values both match this regular expression (repeatable)")
+	fs.StringVar(&o.IgnoreValues, "ignore-values", "", "Comma-separated value presets to ignore like --ignore-value-regex: "+strings.Join(valuePresetNames(), ", "))
+	fs.Var(&o.Units, "units", "Compare strings at a path pattern as quantities, given as pattern=duration (30s) or pattern=bytes (512Mi, 1GB) (repeatable)")
+	fs.Var(&o.ArrayGranularity, "array-granularity", "Report array differences element by element (elements) or as one change per array (whole), for all arrays or, as pattern=whole|elements, those at matching paths (repeatable)")
+	fs.Var(&o.Multiset, "multiset", `Path pattern of arrays of primitives compared as multisets, reporting how often each value occurs ("a": 2→1) instead of index changes (repeatable)`)
+	fs.Var(&o.MapAsSet, "map-as-set", "Path pattern of objects compared only by their key sets, ignoring values (repeatable)")
+	fs.Var(&o.Include, "include", `Compare only the values at and below these comma-separated path patterns, such as "spec.**,metadata.name"; everything else is out of scope and never reported (repeatable)`)
+	fs.BoolVar(&o.SchemaAware, "json-schema-aware", false, "Treat JSON Schema metadata as such: ignore $comment members, list changes to $schema and $id apart without counting them, and warn when the documents declare different $schema values")
+	fs.BoolVar(&o.EmptyEqualsAbsent, "empty-equals-absent", false, "Treat an empty array or object as equal to the key being absent")
+	fs.BoolVar(&o.Deep, "deep", false, "With --empty-equals-absent, also treat containers holding only empty containers as empty")
+	fs.StringVar(&o.NormalizeUnicode, "normalize-unicode", "none", "Unicode normalization applied to strings before comparing: nfc, nfd or none")
+	fs.BoolVar(&o.NormalizeKeys, "normalize-keys", false, "With --normalize-unicode, also normalize object keys so keys differing only in encoding are unified")
+	fs.BoolVar(&o.IgnoreCase, "ignore-case", false, "Compare string values case-insensitively")
+	fs.BoolVar(&o.IgnoreKeyCase, "ignore-key-case", false, "Pair object members whose keys differ only in case (UserId vs userId) instead of reporting a removal and an addition")
+	fs.BoolVar(&o.TrimSpace, "trim-space", false, "Ignore leading and trailing whitespace in string values")
+	fs.BoolVar(&o.CollapseSpace, "collapse-space", false, "Treat runs of whitespace in string values as a single space")
+	fs.BoolVar(&o.NumericStrict, "numeric-strict", false, "Compare numbers by their literal tokens, so 1e3, 1000 and 1000.0 all differ")
+	fs.BoolVar(&o.DecimalStrict, "decimal-strict", false, "Compare numbers as exact decimals (1.50 equals 1.5, but digits beyond float64 precision count) and show them as written")
+	fs.BoolVar(&o.LineNumbers, "line-numbers", false, "Record source line numbers of changed values (holds each input in memory while it is parsed)")
+	fs.BoolVar(&o.ValidateOutput, "validate-output", false, "Check the JSON report and --summary-out file against their published schemas (see jsondiff schema) and fail on any mismatch; for development")
+	fs.BoolVar(&o.StdinPair, "stdin-pair", false, `Read both documents from stdin as one object {"a": ..., "b": ..., "labelA": ..., "labelB": ...}, labels optional, instead of from files`)
+	fs.StringVar(&o.InputFormat, "input-format", inputJSON, "Input format: json, json-seq (RFC 7464) or concat (concatenated values); streams are compared as arrays of their values")
+	fs.StringVar(&o.PathA, "path-a", "", "Compare only the subtree at this path of the first document (e.g. spec.template)")
+	fs.StringVar(&o.PathB, "path-b", "", "Compare only the subtree at this path of the second document")
+	fs.StringVar(&o.Project, "project", "", "Compare both documents through a jq-like projection: paths (.a.b[0], .items[].image), del(path, ...), map(f) and |")
+	fs.StringVar(&o.ProjectA, "project-a", "", "Projection for the first document only, overriding --project")
+	fs.StringVar(&o.ProjectB, "project-b", "", "Projection for the second document only, overriding --project")
+	fs.StringVar(&o.ExecA, "exec-a", "", "Command whose stdout is used as the first document instead of file1")
+	fs.StringVar(&o.ExecB, "exec-b", "", "Command whose stdout is used as the second document instead of file2")
+	fs.BoolVar(&o.Swap, "swap", false, "Compare the second input to the first: swap file1 and file2, with their --exec-*, --path-* and --project-* options")
+	fs.BoolVar(&o.NoSwapWarning, "no-swap-warning", false, "Do not warn when many more values are removed than added and file2 is older than file1, or the labels name environments out of the config's environmentOrder")
+	fs.BoolVar(&o.Shell, "shell", false, "Run --exec-a/--exec-b commands through sh -c (pipes, redirects, ...)")
+	fs.Var(&o.Timeout, "timeout", "Abort a comparison running longer than this `duration`, reporting how far it got (0 waits forever)")
+	o.ExecTimeout = Duration(time.Minute)
+	fs.Var(&o.ExecTimeout, "exec-timeout", "Kill --exec-a/--exec-b commands running longer than this `duration` (0 waits forever)")
+	fs.StringVar(&o.MaxInputSize, "max-input-size", "512MB", "Refuse inputs larger than this (e.g. 64MB, 2GB)")
+	fs.IntVar(&o.MaxDepth, "max-depth", defaultInputLimits.maxDepth, "Refuse inputs nested deeper than this")
+	fs.IntVar(&o.MaxArrayLength, "max-array-length", defaultInputLimits.maxArrayLength, "Refuse inputs containing longer arrays than this")
+	fs.BoolVar(&o.AutoRedact, "auto-redact", false, "Mask values that look like secrets (by key name or shape) in every output; diffing still uses the real values")
+	fs.StringVar(&o.Config, "config", "", "JSON config file (per-path transforms, ...)")
+	fs.Var(&o.Aggregate, "aggregate", "Path pattern whose numeric values are summed on each side and reported as a roll-up (repeatable)")
+	fs.StringVar(&o.Schema, "schema", "", "JSON Schema used to annotate changes and flag ones that make file2 invalid")
+	fs.StringVar(&o.Ack, "ack", "", "Comma-separated change IDs to acknowledge")
+	fs.StringVar(&o.AckFile, "ack-file", "", "File of change IDs to acknowledge, one per line")
+	fs.StringVar(&o.KeyMap, "key-map", "", "JSON file of {\"from\", \"to\"} key renames applied to file1 before diffing; paths are from the root of the file, even with --path-a")
+}
+
+// Duration is a time.Duration usable as a flag value that encodes to JSON
+// as a string such as "30s".
+type Duration time.Duration
+
+func (d Duration) Duration() time.Duration { return time.Duration(d) }
+func (d Duration) String() string          { return time.Duration(d).String() }
+
+func (d *Duration) Set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\"")
+	}
+	return d.Set(s)
+}
+
+// Validate checks every value in o and the combinations that conflict or
+// can never take effect, returning all problems found joined into one
+// error.
+func (o *Options) Validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if _, ok := renderers[o.Format]; !ok {
+		fail("invalid --format %q: must be one of %s", o.Format, strings.Join(rendererNames(), ", "))
+	}
+	if o.Sort != "path" && o.Sort != "delta" && o.Sort != "document" {
+		fail("invalid --sort %q: must be path, delta or document", o.Sort)
+	}
+	_, normalize, err := parseNormalizationForm(o.NormalizeUnicode)
+	check(err)
+	if err == nil && o.NormalizeKeys && !normalize {
+		fail("--normalize-keys requires --normalize-unicode nfc or nfd")
+	}
+	if o.NumericStrict && o.DecimalStrict {
+		fail("--numeric-strict cannot be combined with --decimal-strict")
+	}
+	if o.Editable && o.NormalizeKeys {
+		fail("--editable cannot be combined with --normalize-keys")
+	}
+	check(parseInputFormat(o.InputFormat))
+	_, err = parseArrayGranularity(o.ArrayGranularity)
+	check(err)
+	_, err = parseUnitRules(o.Units)
+	check(err)
+	failOn, err := parseFailOn(o.FailOn)
+	check(err)
+	if failOn[kindReordered] && !o.DetectKeyReorder {
+		fail("--fail-on %s requires --detect-key-reorder", kindReordered)
+	}
+	_, err = parseGatePatterns(o.FailOnPath)
+	check(err)
+	codes, err := parseExitCodeMap(o.ExitCodeMap)
+	check(err)
+	if _, ok := codes[kindReordered]; ok && !o.DetectKeyReorder {
+		fail("--exit-code-map %s requires --detect-key-reorder", kindReordered)
+	}
+	if o.Subset && failOn[string(Added)] {
+		fail("--fail-on %s can never match with --subset, which ignores additions", Added)
+	}
+	if o.Superset && failOn[string(Removed)] {
+		fail("--fail-on %s can never match with --superset, which ignores removals", Removed)
+	}
+	_, err = parseValuePatterns(o.IgnoreValues, o.IgnoreValueRegex)
+	check(err)
+	_, err = parseIncludeScope(o.Include)
+	check(err)
+	_, err = parseDisplayFormat(o.NumberLocale, o.DisplayDates, time.Time{})
+	check(err)
+	check(parseEmitArrayMode(o.EmitArrayMode))
+	if o.Subset && o.Superset {
+		fail("--subset and --superset are mutually exclusive")
+	}
+	if o.Deep && !o.EmptyEqualsAbsent {
+		fail("--deep requires --empty-equals-absent")
+	}
+	if o.ArrayMatchThreshold < 0 || o.ArrayMatchThreshold > 1 {
+		fail("invalid --array-match-threshold %g: must be between 0 and 1", o.ArrayMatchThreshold)
+	}
+	if o.ArrayContext < 0 {
+		fail("invalid --array-context %d: must not be negative", o.ArrayContext)
+	}
+	if o.LazyDepth < 0 {
+		fail("invalid --lazy-depth %d: must not be negative", o.LazyDepth)
+	}
+	if o.Paginate < 0 {
+		fail("invalid --paginate %d: must not be negative", o.Paginate)
+	}
+	if o.GraphMaxNodes < 1 {
+		fail("invalid --graph-max-nodes %d: must be positive", o.GraphMaxNodes)
+	}
+	if o.Paginate > 0 && o.Format != "html" {
+		fail("--paginate requires --format html")
+	}
+	_, err = parseOutputTemplate(o.Output)
+	check(err)
+	compress, err := parseCompression(o.Compress, o.Output)
+	check(err)
+	if o.IncludeUnchanged && o.Format != "json" && o.Format != "jsonl" {
+		fail("--json-include-unchanged requires --format json or jsonl")
+	}
+	if o.MinifyHTML && o.Format != "html" && o.Format != "fragment" {
+		fail("--minify-html requires --format html or fragment")
+	}
+	if o.Paginate > 0 && (compress != "" || o.MinifyHTML) {
+		fail("--paginate cannot be combined with --compress or --minify-html")
+	}
+	if o.Paginate > 0 && o.Output == "-" {
+		fail("--paginate writes one file per page and cannot write to stdout (-o -)")
+	}
+	if _, err := parseByteSize(o.MaxInputSize); err != nil {
+		fail("invalid --max-input-size: %v", err)
+	}
+	if o.StatsOnly {
+		var with []string
+		for _, f := range []struct {
+			set  bool
+			name string
+		}{
+			{o.Output != "", "-o"},
+			{o.Bundle != "", "--bundle"},
+			{o.Paginate > 0, "--paginate"},
+			{o.EmitChangedA != "" || o.EmitChangedB != "", "--emit-changed-a/-b"},
+			{o.DetectMoves, "--detect-moves"},
+			{o.ArrayMatchThreshold > 0, "--array-match-threshold"},
+			{o.Ack != "" || o.AckFile != "", "--ack/--ack-file"},
+		} {
+			if f.set {
+				with = append(with, f.name)
+			}
+		}
+		if len(with) > 0 {
+			fail("--stats-only writes no report and cannot be combined with %s", strings.Join(with, ", "))
+		}
+	}
+	if o.Quiet && o.SummaryOnly {
+		fail("--quiet cannot be combined with --summary-only, which prints the summary to stdout")
+	}
+	if o.StdinPair {
+		if o.ExecA != "" || o.ExecB != "" {
+			fail("--stdin-pair reads both documents from stdin and takes no --exec-a or --exec-b")
+		}
+		if o.InputFormat != inputJSON {
+			fail("--stdin-pair requires --input-format %s", inputJSON)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// flagAliases maps the flags whose names differ from their field's JSON
+// name to that name.
+var flagAliases = map[string]string{"o": "output", "f": "format", "v": "verbose"}
+
+// optionField returns the field of o bound to the named flag, or an invalid
+// Value for flags outside Options.
+func optionField(o reflect.Value, flagName string) reflect.Value {
+	if alias, ok := flagAliases[flagName]; ok {
+		flagName = alias
+	}
+	t := o.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("json") == flagName {
+			return o.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
+
+// applyOptionsJSON replaces o with the Options document in filename, or
+// stdin for "-". Fields the document omits keep their defaults, and flags
+// set on fs override the document.
+func (o *Options) applyOptionsJSON(filename string, fs *flag.FlagSet) error {
+	var data []byte
+	var err error
+	if filename == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		return err
+	}
+	loaded := DefaultOptions()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&loaded); err != nil {
+		return err
+	}
+	explicit := reflect.ValueOf(*o)
+	*o = loaded
+	fs.Visit(func(f *flag.Flag) {
+		if dst := optionField(reflect.ValueOf(o).Elem(), f.Name); dst.IsValid() {
+			dst.Set(optionField(explicit, f.Name))
+		}
+	})
+	return nil
+}
+
+// effectiveOptions returns the options of o that differ from the defaults,
+// masking ones first, each group by name.
+func effectiveOptions(o Options) []EffectiveOption {
+	v, defaults := reflect.ValueOf(o), reflect.ValueOf(DefaultOptions())
+	t := v.Type()
+	var opts []EffectiveOption
+	for i := 0; i < t.NumField(); i++ {
+		if reflect.DeepEqual(v.Field(i).Interface(), defaults.Field(i).Interface()) {
+			continue
+		}
+		name := t.Field(i).Tag.Get("json")
+		value := fmt.Sprint(v.Field(i).Interface())
+		if list, ok := v.Field(i).Interface().(stringList); ok {
+			value = strings.Join(list, ",")
+		}
+		if name == "output" {
+			// The only field without a flag of its own name.
+			name = "o"
+		}
+		opts = append(opts, EffectiveOption{Name: name, Value: value, Masking: maskingFlags[name]})
+	}
+	sort.SliceStable(opts, func(i, j int) bool {
+		if opts[i].Masking != opts[j].Masking {
+			return opts[i].Masking
+		}
+		return opts[i].Name < opts[j].Name
+	})
+	return opts
+}
@@ -0,0 +1,151 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDefaultOptionsValid(t *testing.T) {
+	o := DefaultOptions()
+	if err := o.Validate(); err != nil {
+		t.Errorf("defaults invalid: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		set  func(o *Options)
+		err  string
+	}{
+		{"unknown format", func(o *Options) { o.Format = "yaml" }, `invalid --format "yaml"`},
+		{"unknown sort", func(o *Options) { o.Sort = "size" }, `invalid --sort "size"`},
+		{"unknown normalization", func(o *Options) { o.NormalizeUnicode = "nfkc" }, "nfkc"},
+		{"normalize keys without a form", func(o *Options) { o.NormalizeKeys = true }, "--normalize-keys requires --normalize-unicode"},
+		{"numeric and decimal strict", func(o *Options) { o.NumericStrict, o.DecimalStrict = true, true }, "--numeric-strict cannot be combined with --decimal-strict"},
+		{"editable with normalized keys", func(o *Options) { o.Editable, o.NormalizeKeys, o.NormalizeUnicode = true, true, "nfc" }, "--editable cannot be combined with --normalize-keys"},
+		{"unknown input format", func(o *Options) { o.InputFormat = "yaml" }, "yaml"},
+		{"unknown array granularity", func(o *Options) { o.ArrayGranularity = stringList{"items=rows"} }, "rows"},
+		{"unknown fail-on kind", func(o *Options) { o.FailOn = "any" }, `invalid --fail-on "any"`},
+		{"fail on reorders undetected", func(o *Options) { o.FailOn = "reordered" }, "--fail-on reordered requires --detect-key-reorder"},
+		{"exit code for reorders undetected", func(o *Options) { o.ExitCodeMap = "reordered=4" }, "--exit-code-map reordered requires --detect-key-reorder"},
+		{"fail on additions with subset", func(o *Options) { o.Subset, o.FailOn = true, "added" }, "--fail-on added can never match with --subset"},
+		{"fail on removals with superset", func(o *Options) { o.Superset, o.FailOn = true, "removed" }, "--fail-on removed can never match with --superset"},
+		{"subset and superset", func(o *Options) { o.Subset, o.Superset = true, true }, "--subset and --superset are mutually exclusive"},
+		{"deep without empty-equals-absent", func(o *Options) { o.Deep = true }, "--deep requires --empty-equals-absent"},
+		{"array match threshold above 1", func(o *Options) { o.ArrayMatchThreshold = 1.5 }, "invalid --array-match-threshold 1.5"},
+		{"negative array match threshold", func(o *Options) { o.ArrayMatchThreshold = -0.1 }, "invalid --array-match-threshold -0.1"},
+		{"negative array context", func(o *Options) { o.ArrayContext = -1 }, "invalid --array-context -1"},
+		{"negative lazy depth", func(o *Options) { o.LazyDepth = -2 }, "invalid --lazy-depth -2"},
+		{"negative paginate", func(o *Options) { o.Paginate = -1 }, "invalid --paginate -1"},
+		{"no graph nodes", func(o *Options) { o.GraphMaxNodes = 0 }, "invalid --graph-max-nodes 0"},
+		{"paginate without html", func(o *Options) { o.Paginate, o.Format = 100, "json" }, "--paginate requires --format html"},
+		{"unchanged without json", func(o *Options) { o.IncludeUnchanged = true }, "--json-include-unchanged requires --format json or jsonl"},
+		{"minify without html", func(o *Options) { o.MinifyHTML, o.Format = true, "csv" }, "--minify-html requires --format html or fragment"},
+		{"paginate compressed", func(o *Options) { o.Paginate, o.Compress = 100, "gzip" }, "--paginate cannot be combined with --compress or --minify-html"},
+		{"paginate minified", func(o *Options) { o.Paginate, o.MinifyHTML = 100, true }, "--paginate cannot be combined with --compress or --minify-html"},
+		{"paginate to stdout", func(o *Options) { o.Paginate, o.Output = 100, "-" }, "--paginate writes one file per page and cannot write to stdout (-o -)"},
+		{"unknown compression", func(o *Options) { o.Compress = "zstd" }, "zstd"},
+		{"bad input size", func(o *Options) { o.MaxInputSize = "lots" }, "invalid --max-input-size"},
+		{"stats only with output", func(o *Options) { o.StatsOnly, o.Output, o.DetectMoves = true, "out.html", true }, "--stats-only writes no report and cannot be combined with -o, --detect-moves"},
+		{"stats only with acks", func(o *Options) { o.StatsOnly, o.Ack = true, "abc" }, "cannot be combined with --ack/--ack-file"},
+		{"quiet summary", func(o *Options) { o.Quiet, o.SummaryOnly = true, true }, "--quiet cannot be combined with --summary-only"},
+		{"stdin pair with exec", func(o *Options) { o.StdinPair, o.ExecA = true, "cat a.json" }, "--stdin-pair reads both documents from stdin"},
+		{"stdin pair with sequences", func(o *Options) { o.StdinPair, o.InputFormat = true, inputJSONSeq }, "--stdin-pair requires --input-format json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := DefaultOptions()
+			tt.set(&o)
+			err := o.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Validate() = %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	o := DefaultOptions()
+	o.Subset, o.Superset, o.Sort, o.ArrayContext = true, true, "size", -1
+	err := o.Validate()
+	if err == nil {
+		t.Fatal("invalid options accepted")
+	}
+	for _, want := range []string{"--sort", "--subset and --superset", "--array-context"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
+
+// TestOptionsFlags checks that every Options field is bound to the flag its
+// JSON tag names and survives a JSON round trip.
+func TestOptionsFlags(t *testing.T) {
+	var o Options
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	o.RegisterFlags(fs)
+	typ := reflect.TypeOf(o)
+	for i := 0; i < typ.NumField(); i++ {
+		name := typ.Field(i).Tag.Get("json")
+		if name == "output" {
+			name = "o"
+		}
+		if fs.Lookup(name) == nil {
+			t.Errorf("field %s has no flag %s", typ.Field(i).Name, name)
+		}
+	}
+	fs.VisitAll(func(f *flag.Flag) {
+		if !optionField(reflect.ValueOf(o), f.Name).IsValid() {
+			t.Errorf("flag %s is bound to no field", f.Name)
+		}
+	})
+
+	if err := fs.Parse([]string{"-f", "json", "--fail-on", "added,removed", "--multiset", "a", "--multiset", "b", "--timeout", "3s", "--array-match-threshold", "0.5"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back Options
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, o) {
+		t.Errorf("round trip gave %+v, want %+v", back, o)
+	}
+}
+
+func TestApplyOptionsJSON(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "options.json")
+	if err := os.WriteFile(file, []byte(`{"format": "json", "sort": "delta", "multiset": ["tags"], "output": "x.json"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var o Options
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	o.RegisterFlags(fs)
+	if err := fs.Parse([]string{"--sort", "document", "--quiet"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.applyOptionsJSON(file, fs); err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultOptions()
+	want.Format, want.Sort, want.Multiset, want.Output, want.Quiet = "json", "document", stringList{"tags"}, "x.json", true
+	if !reflect.DeepEqual(o, want) {
+		t.Errorf("options %+v, want %+v", o, want)
+	}
+
+	if err := os.WriteFile(file, []byte(`{"formatt": "json"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.applyOptionsJSON(file, fs); err == nil {
+		t.Error("unknown option accepted")
+	}
+}
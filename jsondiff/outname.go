@@ -0,0 +1,110 @@
+package jsondiff
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// outputNameFields are the fields an -o template such as
+// "reports/{{.LabelA}}_vs_{{.LabelB}}_{{.Date}}.html" can use. Every field
+// is reduced by sanitizeLabel, so no input can add a directory to the name
+// or characters some file systems reject; the template's own text is kept
+// as written.
+type outputNameFields struct {
+	// LabelA and LabelB are the input labels, as shown on the panes.
+	LabelA, LabelB string
+	// BaseA and BaseB are the base names of the input files without their
+	// extension, or the labels of commands and stdin.
+	BaseA, BaseB string
+	// Date is the start of the run as YYYYMMDD and Time as HHMMSS, in local
+	// time.
+	Date, Time string
+}
+
+// parseOutputTemplate parses -o as a template when it contains "{{", and
+// returns nil otherwise. A template using an unknown field is rejected
+// here, before any input is read.
+func parseOutputTemplate(output string) (*template.Template, error) {
+	if !strings.Contains(output, "{{") {
+		return nil, nil
+	}
+	tpl, err := template.New("output").Option("missingkey=error").Parse(output)
+	if err == nil {
+		err = tpl.Execute(io.Discard, outputNameFields{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid -o template %q: %v", output, err)
+	}
+	return tpl, nil
+}
+
+// newOutputNameFields returns the template fields for inputs a and b and a
+// run started at start.
+func newOutputNameFields(a, b inputSource, start time.Time) outputNameFields {
+	base := func(s inputSource) string {
+		if s.command != "" || s.display != "" || s.path == "" {
+			return sanitizeLabel(s.label())
+		}
+		name := filepath.Base(s.path)
+		return sanitizeLabel(strings.TrimSuffix(name, filepath.Ext(name)))
+	}
+	return outputNameFields{
+		LabelA: sanitizeLabel(a.label()),
+		LabelB: sanitizeLabel(b.label()),
+		BaseA:  base(a),
+		BaseB:  base(b),
+		Date:   start.Format("20060102"),
+		Time:   start.Format("150405"),
+	}
+}
+
+// renderOutputName executes tpl with fields. A name ending in a path
+// separator is an output directory and is returned as it is; any other is
+// a file, whose directory is created and which gets -1, -2, ... before its
+// extension when a file of that name already exists, so scheduled runs
+// never overwrite each other's reports. Like historyReportName, it gives up
+// after maxReportSuffix names.
+func renderOutputName(tpl *template.Template, fields outputNameFields) (string, error) {
+	var sb strings.Builder
+	if err := tpl.Execute(&sb, fields); err != nil {
+		return "", err
+	}
+	name := sb.String()
+	if name == "" {
+		return "", errors.New("-o template renders an empty name")
+	}
+	if isOutputDir(name) {
+		return name, nil
+	}
+	if dir := filepath.Dir(name); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", err
+		}
+	}
+	stem, ext := name, ""
+	if strings.HasSuffix(stem, ".gz") {
+		stem, ext = strings.TrimSuffix(stem, ".gz"), ".gz"
+	}
+	ext = filepath.Ext(stem) + ext
+	stem = strings.TrimSuffix(name, ext)
+	for n := 0; n <= maxReportSuffix; n++ {
+		candidate := name
+		if n > 0 {
+			candidate = fmt.Sprintf("%s-%d%s", stem, n, ext)
+		}
+		_, err := os.Stat(candidate)
+		if os.IsNotExist(err) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("%s and %d numbered variants already exist", name, maxReportSuffix)
+}
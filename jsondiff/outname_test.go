@@ -0,0 +1,174 @@
+package jsondiff
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseOutputTemplate(t *testing.T) {
+	for _, plain := range []string{"", "-", "out.html", "reports/", "a{b}.json"} {
+		if tpl, err := parseOutputTemplate(plain); tpl != nil || err != nil {
+			t.Errorf("%q: %v, %v", plain, tpl, err)
+		}
+	}
+	if tpl, err := parseOutputTemplate("{{.LabelA}}_vs_{{.LabelB}}_{{.Date}}-{{.Time}}.html"); tpl == nil || err != nil {
+		t.Errorf("valid template: %v", err)
+	}
+	for _, bad := range []string{"{{.Host}}.html", "{{.LabelA", "{{range}}"} {
+		if _, err := parseOutputTemplate(bad); err == nil || !strings.Contains(err.Error(), "invalid -o template") {
+			t.Errorf("%q: error %v", bad, err)
+		}
+	}
+}
+
+// TestOutputNameFieldsSanitized checks that no input, however it is named,
+// can put a separator, a parent directory or an unsafe character into a
+// field.
+func TestOutputNameFieldsSanitized(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          inputSource
+		label, base string
+	}{
+		{"plain file", inputSource{path: "configs/prod.json"}, "prod.json", "prod"},
+		{"spaces and accents", inputSource{path: "Café Config v2.json"}, "Cafe-Config-v2.json", "Cafe-Config-v2"},
+		{"parent directory", inputSource{path: "../.."}, "input", "input"},
+		{"dot file", inputSource{path: "/tmp/.env"}, "env", "input"},
+		{"command", inputSource{command: "curl -s https://host/a?b=1 | jq ."}, "curl--s-https-host-a-b-1-jq", "curl--s-https-host-a-b-1-jq"},
+		{"stdin pair", inputSource{path: "/tmp/x123", display: "stdin[0]"}, "stdin-0", "stdin-0"},
+		{"subpath", inputSource{path: "a.json", subpath: "spec/../../etc"}, "a.json-spec-..-..-etc", "a"},
+		{"backslashes", inputSource{path: `..\..\win.json`}, "win.json", `win`},
+		{"long name", inputSource{path: strings.Repeat("a", 100) + ".json"}, strings.Repeat("a", 60), strings.Repeat("a", 60)},
+	}
+	for _, tt := range tests {
+		fields := newOutputNameFields(tt.in, tt.in, historyTime)
+		if fields.LabelA != tt.label || fields.BaseA != tt.base {
+			t.Errorf("%s: label %q, base %q, want %q, %q", tt.name, fields.LabelA, fields.BaseA, tt.label, tt.base)
+		}
+		for _, f := range []string{fields.LabelA, fields.BaseA} {
+			if strings.ContainsAny(f, `/\:*?"<>| `) || f == "." || f == ".." {
+				t.Errorf("%s: unsafe field %q", tt.name, f)
+			}
+		}
+	}
+	fields := newOutputNameFields(inputSource{}, inputSource{}, historyTime)
+	if fields.Date != "20240301" || fields.Time != "123000" {
+		t.Errorf("date %s, time %s", fields.Date, fields.Time)
+	}
+}
+
+func TestRenderOutputName(t *testing.T) {
+	dir := t.TempDir()
+	render := func(output string, fields outputNameFields) (string, error) {
+		t.Helper()
+		tpl, err := parseOutputTemplate(dir + string(filepath.Separator) + output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return renderOutputName(tpl, fields)
+	}
+	touch := func(name string) {
+		t.Helper()
+		if err := os.WriteFile(name, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hostile := newOutputNameFields(inputSource{path: "../../../etc/passwd"}, inputSource{command: "cat /etc/shadow"}, historyTime)
+
+	// The fields cannot leave the directory the template names, which is
+	// created.
+	name, err := render("reports/{{.LabelA}}_vs_{{.LabelB}}.json", hostile)
+	if want := filepath.Join(dir, "reports", "passwd_vs_cat-etc-shadow.json"); name != want || err != nil {
+		t.Fatalf("got %s, %v, want %s", name, err, want)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "reports")); err != nil || !info.IsDir() {
+		t.Errorf("directory not created: %v", err)
+	}
+
+	// Names in use get numbered before the extension, after .gz is set
+	// aside.
+	tests := []struct {
+		template string
+		existing []string
+		want     string
+	}{
+		{"r.json", nil, "r.json"},
+		{"r.json", []string{"r.json"}, "r-1.json"},
+		{"r.json", []string{"r.json", "r-1.json", "r-2.json"}, "r-3.json"},
+		{"r.json", []string{"r-1.json"}, "r.json"},
+		{"r.json.gz", []string{"r.json.gz"}, "r-1.json.gz"},
+		{"r.tar.gz", []string{"r.tar.gz"}, "r-1.tar.gz"},
+		{"r", []string{"r"}, "r-1"},
+		{"{{.Date}}", []string{"20240301"}, "20240301-1"},
+	}
+	for i, tt := range tests {
+		sub := fmt.Sprintf("case%d", i)
+		if err := os.Mkdir(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range tt.existing {
+			touch(filepath.Join(dir, sub, e))
+		}
+		// The empty action makes a template of names without fields.
+		got, err := render(filepath.Join(sub, tt.template)+"{{/* */}}", hostile)
+		if want := filepath.Join(dir, sub, tt.want); got != want || err != nil {
+			t.Errorf("%s with %q: %s, %v, want %s", tt.template, tt.existing, got, err, want)
+		}
+	}
+
+	// A name ending in a separator is an output directory, left to
+	// historyReportName.
+	if got, err := render("{{.Date}}/", hostile); got != filepath.Join(dir, "20240301")+"/" || err != nil {
+		t.Errorf("directory: %s, %v", got, err)
+	}
+
+	tpl, _ := parseOutputTemplate("{{.LabelA}}{{/* */}}")
+	if _, err := renderOutputName(tpl, outputNameFields{}); err == nil {
+		t.Error("empty name: no error")
+	}
+}
+
+func TestRenderOutputNameGivesUp(t *testing.T) {
+	dir := t.TempDir()
+	for n := 0; n <= maxReportSuffix; n++ {
+		name := "r.json"
+		if n > 0 {
+			name = fmt.Sprintf("r-%d.json", n)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tpl, err := parseOutputTemplate(filepath.Join(dir, "{{.LabelA}}.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = renderOutputName(tpl, outputNameFields{LabelA: "r"})
+	if err == nil || !strings.Contains(err.Error(), "already exist") {
+		t.Errorf("error %v, want the suffixes used up", err)
+	}
+}
+
+// TestOutputTemplateCLI runs the same comparison twice with a templated -o
+// and checks that the second report does not overwrite the first.
+func TestOutputTemplateCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"prod env.json": `{"a": 1}`,
+		"dev.json":      `{"a": 2}`,
+	})
+	for i := 0; i < 2; i++ {
+		res := runCLI(t, dir, "-f", "json", "-o", "out/{{.BaseA}}_vs_{{.BaseB}}.json", "prod env.json", "dev.json")
+		if res.exit != exitOK {
+			t.Fatalf("exit %d: %s", res.exit, res.stderr)
+		}
+	}
+	for _, name := range []string{"prod-env_vs_dev.json", "prod-env_vs_dev-1.json"} {
+		wellFormed["json"](t, readFile(t, dir, filepath.Join("out", name)))
+	}
+	if res := runCLI(t, dir, "-o", "{{.Nope}}.html", "prod env.json", "dev.json"); res.exit != exitError || !strings.Contains(res.stderr, "invalid -o template") {
+		t.Errorf("unknown field: exit %d: %s", res.exit, res.stderr)
+	}
+}
@@ -0,0 +1,115 @@
+package jsondiff
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// outputOptions controls how report files are encoded.
+type outputOptions struct {
+	// compress is "gzip" or empty for none.
+	compress string
+	// minify drops the indentation between tags of HTML output.
+	minify bool
+}
+
+// parseCompression validates --compress, inferring gzip from a .gz output
+// name when the flag is not given.
+func parseCompression(name, output string) (string, error) {
+	switch name {
+	case "":
+		if strings.HasSuffix(output, ".gz") {
+			return "gzip", nil
+		}
+		return "", nil
+	case "none":
+		return "", nil
+	case "gzip":
+		return name, nil
+	}
+	return "", fmt.Errorf("invalid --compress %q: must be gzip or none", name)
+}
+
+// createOutput opens name for writing, or stdout for "-", wrapped in the
+// encoders opts asks for. The returned close function flushes them and
+// closes the file.
+func createOutput(name string, opts outputOptions) (io.Writer, func() error, error) {
+	var w io.Writer = os.Stdout
+	closers := []func() error{}
+	if name != "-" {
+		f, err := os.Create(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		w = f
+		closers = append(closers, f.Close)
+	}
+	if opts.compress == "gzip" {
+		gz := gzip.NewWriter(w)
+		w = gz
+		closers = append(closers, gz.Close)
+	}
+	if opts.minify {
+		m := &minifyWriter{w: w}
+		w = m
+		closers = append(closers, m.Close)
+	}
+	closeAll := func() error {
+		var err error
+		for i := len(closers) - 1; i >= 0; i-- {
+			if cerr := closers[i](); err == nil {
+				err = cerr
+			}
+		}
+		return err
+	}
+	return w, closeAll, nil
+}
+
+// minifyWriter removes whitespace-only runs between a '>' and the next '<',
+// i.e. the indentation of the template and of renderJSON's markup. It works
+// on the stream, so output is never held in memory. Text containing anything
+// besides whitespace, such as rendered string values, which always sit
+// between their quotes, passes through untouched.
+type minifyWriter struct {
+	w        io.Writer
+	afterTag bool
+	pending  []byte
+	buf      []byte
+}
+
+func (m *minifyWriter) Write(p []byte) (int, error) {
+	m.buf = m.buf[:0]
+	for _, c := range p {
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if m.afterTag {
+				m.pending = append(m.pending, c)
+				continue
+			}
+		case c == '<':
+			m.pending = m.pending[:0]
+		default:
+			m.buf = append(m.buf, m.pending...)
+			m.pending = m.pending[:0]
+		}
+		m.buf = append(m.buf, c)
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			m.afterTag = c == '>'
+		}
+	}
+	if _, err := m.w.Write(m.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close writes any whitespace still held back.
+func (m *minifyWriter) Close() error {
+	_, err := m.w.Write(m.pending)
+	m.pending = nil
+	return err
+}
@@ -0,0 +1,140 @@
+package jsondiff
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestMinifyWriter(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"<ul>\n  <li>a</li>\n  <li>b</li>\n</ul>\n", "<ul><li>a</li><li>b</li></ul>\n"},
+		{`<span>"  two  spaces  "</span>`, `<span>"  two  spaces  "</span>`},
+		{"<p>\n  text\n</p>", "<p>\n  text\n</p>"},
+		{"<b>x</b> <i>y</i>", "<b>x</b><i>y</i>"},
+		{"a > b\n<c>", "a > b\n<c>"},
+		{"<br>\r\n\t<br>", "<br><br>"},
+		{"  <a>", "  <a>"},
+		{"<a>  ", "<a>  "},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		// Every split of the input into two writes gives the same output.
+		for cut := 0; cut <= len(tt.in); cut++ {
+			var out bytes.Buffer
+			m := &minifyWriter{w: &out}
+			for _, part := range []string{tt.in[:cut], tt.in[cut:]} {
+				if n, err := m.Write([]byte(part)); n != len(part) || err != nil {
+					t.Fatalf("%q: Write returned %d, %v", tt.in, n, err)
+				}
+			}
+			if err := m.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("%q cut at %d: %q, want %q", tt.in, cut, out.String(), tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestParseCompression(t *testing.T) {
+	tests := []struct{ name, output, want string }{
+		{"", "out.html", ""},
+		{"", "out.html.gz", "gzip"},
+		{"none", "out.html.gz", ""},
+		{"gzip", "out.html", "gzip"},
+	}
+	for _, tt := range tests {
+		if got, err := parseCompression(tt.name, tt.output); got != tt.want || err != nil {
+			t.Errorf("%q, %s: %q, %v", tt.name, tt.output, got, err)
+		}
+	}
+	if _, err := parseCompression("zstd", "out"); err == nil {
+		t.Error("zstd: no error")
+	}
+}
+
+// TestOutputSizes writes the same HTML report plain, minified, gzipped and
+// both, and checks that each option makes it smaller while the report
+// itself is unchanged.
+func TestOutputSizes(t *testing.T) {
+	a, b := largeDocs(t, 2700, 30)
+	files := make(map[string]string)
+	for name, v := range map[string]interface{}{"a.json": a, "b.json": b} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[name] = string(data)
+	}
+	dir := cliDir(t, files)
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(data, []byte("\x1f\x8b")) {
+			return string(data)
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		plain, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		return string(plain)
+	}
+	size := make(map[string]int)
+	for name, flags := range map[string][]string{
+		"plain.html":       nil,
+		"min.html":         {"--minify-html"},
+		"plain.html.gz":    nil,
+		"min.html.gz":      {"--minify-html"},
+		"forced-gzip.html": {"--compress", "gzip"},
+	} {
+		if res := runCLI(t, dir, append(append([]string{"-o", name}, flags...), "a.json", "b.json")...); res.exit != exitOK {
+			t.Fatalf("%s: exit %d: %s", name, res.exit, res.stderr)
+		}
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		size[name] = int(info.Size())
+	}
+
+	for _, smaller := range [][2]string{
+		{"min.html", "plain.html"},
+		{"plain.html.gz", "plain.html"},
+		{"forced-gzip.html", "plain.html"},
+		{"min.html.gz", "plain.html.gz"},
+		{"min.html.gz", "min.html"},
+	} {
+		if size[smaller[0]] >= size[smaller[1]] {
+			t.Errorf("%s is %d bytes, %s %d", smaller[0], size[smaller[0]], smaller[1], size[smaller[1]])
+		}
+	}
+
+	plain, minified := read("plain.html"), read("min.html")
+	if read("plain.html.gz") != plain || read("forced-gzip.html") != plain {
+		t.Error("the gzipped report differs from the plain one")
+	}
+	if read("min.html.gz") != minified {
+		t.Error("the gzipped minified report differs from the minified one")
+	}
+	// Minifying only drops whitespace between tags.
+	betweenTags := regexp.MustCompile(`>[ \t\r\n]+<`)
+	if betweenTags.ReplaceAllString(plain, "><") != minified {
+		t.Error("minifying changed more than the whitespace between tags")
+	}
+	wellFormed["html"](t, minified)
+}
@@ -0,0 +1,83 @@
+package jsondiff
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// The schemas of differ's machine-readable outputs, printed by the schema
+// command and checked by --validate-output.
+//
+//go:embed schemas/*.schema.json
+var outputSchemas embed.FS
+
+// outputSchemaNames lists the outputs with a published schema: json is the
+// --format json report and summary the --summary-out file.
+var outputSchemaNames = []string{"json", "summary"}
+
+func outputSchemaFile(name string) ([]byte, error) {
+	for _, n := range outputSchemaNames {
+		if n == name {
+			return outputSchemas.ReadFile("schemas/" + name + ".schema.json")
+		}
+	}
+	return nil, fmt.Errorf("unknown schema %q: must be one of %s", name, strings.Join(outputSchemaNames, ", "))
+}
+
+// runSchema implements the "schema" subcommand, which prints the JSON Schema
+// of one output.
+func runSchema(args []string) int {
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: jsondiff schema %s\n", strings.Join(outputSchemaNames, "|"))
+		fs.PrintDefaults()
+	}
+	names, err := parseInterspersed(fs, args)
+	if err != nil {
+		return flagExit(err)
+	}
+	if len(names) != 1 {
+		fs.Usage()
+		return exitError
+	}
+	data, err := outputSchemaFile(names[0])
+	if err != nil {
+		return fatal(err)
+	}
+	os.Stdout.Write(data)
+	return exitOK
+}
+
+// validateOutput checks v, encoded as JSON, against the named output schema.
+func validateOutput(name string, v interface{}) error {
+	data, err := outputSchemaFile(name)
+	if err != nil {
+		return err
+	}
+	root, err := parseJSON(data)
+	if err != nil {
+		return fmt.Errorf("schema %s: %v", name, err)
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+	doc, err := parseJSON(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	violations := (&Schema{root: root}).Validate(doc)
+	if len(violations) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(violations))
+	for i, v := range violations {
+		msgs[i] = "  " + v.String()
+	}
+	return fmt.Errorf("output does not match the %s schema:\n%s", name, strings.Join(msgs, "\n"))
+}
@@ -0,0 +1,69 @@
+package jsondiff
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestOutputsMatchSchemas compares every pair of testdata documents with
+// --validate-output, which fails the run if the JSON report or the summary
+// drifts from its published schema.
+func TestOutputsMatchSchemas(t *testing.T) {
+	corpus, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, f := range corpus {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[filepath.Base(f)] = string(data)
+	}
+	dir := cliDir(t, files)
+	for a := range files {
+		for b := range files {
+			if a == b {
+				continue
+			}
+			res := runCLI(t, dir, "-f", "json", "-o", "out.json", "--summary-out", "summary.json", "--validate-output",
+				"--detect-moves", "--line-numbers", a, b)
+			if res.exit != exitOK {
+				t.Errorf("%s vs %s: exit %d: %s", a, b, res.exit, res.stderr)
+			}
+		}
+	}
+}
+
+func TestValidateOutputRejectsDrift(t *testing.T) {
+	if err := validateOutput("json", map[string]interface{}{"version": 1}); err == nil {
+		t.Error("report without its required fields accepted")
+	}
+	if err := validateOutput("summary", map[string]interface{}{"version": "1"}); err == nil {
+		t.Error("summary with a string version accepted")
+	}
+	if err := validateOutput("patch", map[string]interface{}{}); err == nil {
+		t.Error("unknown schema accepted")
+	}
+}
+
+func TestSchemaCommand(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range outputSchemaNames {
+		want, err := outputSchemas.ReadFile("schemas/" + name + ".schema.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res := runCLI(t, dir, "schema", name); res.exit != exitOK || res.stdout != string(want) {
+			t.Errorf("schema %s: exit %d, printed %d bytes of %d", name, res.exit, len(res.stdout), len(want))
+		}
+		if _, err := parseJSON(want); err != nil {
+			t.Errorf("schema %s is not JSON: %v", name, err)
+		}
+	}
+	if res := runCLI(t, dir, "schema", "nope"); res.exit == exitOK {
+		t.Error("unknown schema printed")
+	}
+}
@@ -0,0 +1,198 @@
+package jsondiff
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// reportPage is one file of a paginated HTML report. It holds whole
+// top-level keys so no object is ever split across pages.
+type reportPage struct {
+	Number   int
+	File     string
+	Keys     []string
+	Nodes    int
+	Total    int
+	Sections []DiffSection
+}
+
+// pageNav is passed to template.html as .Page when rendering a page of a
+// paginated report.
+type pageNav struct {
+	Number int
+	Count  int
+	Index  string
+	Prev   string
+	Next   string
+}
+
+// paginateKeys groups the top-level keys of both documents, in sorted order,
+// into pages of roughly size rendered nodes. A key larger than size gets a
+// page to itself.
+func paginateKeys(a, b map[string]interface{}, size int) []reportPage {
+	var pages []reportPage
+	cur := reportPage{}
+	for _, key := range mergedKeys(a, b) {
+		nodes := countNodes(a[key]) + countNodes(b[key])
+		if len(cur.Keys) > 0 && cur.Nodes+nodes > size {
+			pages = append(pages, cur)
+			cur = reportPage{}
+		}
+		cur.Keys = append(cur.Keys, key)
+		cur.Nodes += nodes
+	}
+	if len(cur.Keys) > 0 || len(pages) == 0 {
+		pages = append(pages, cur)
+	}
+	return pages
+}
+
+// countNodes returns the number of tree nodes renderJSON emits for v.
+func countNodes(v interface{}) int {
+	switch val := v.(type) {
+	case nil:
+		return 0
+	case map[string]interface{}:
+		n := 1
+		for _, child := range val {
+			n += countNodes(child)
+		}
+		return n
+	case []interface{}:
+		n := 1
+		for _, child := range val {
+			n += countNodes(child)
+		}
+		return n
+	}
+	return 1
+}
+
+// subsetObject returns the members of m named in keys.
+func subsetObject(m map[string]interface{}, keys []string) map[string]interface{} {
+	sub := make(map[string]interface{}, len(keys))
+	for _, k := range keys {
+		if v, ok := m[k]; ok {
+			sub[k] = v
+		}
+	}
+	return sub
+}
+
+// pageFileName derives the name of page n from the index file name, e.g.
+// diff.html -> diff_001.html.
+func pageFileName(index string, n int) string {
+	ext := filepath.Ext(index)
+	return fmt.Sprintf("%s_%03d%s", strings.TrimSuffix(index, ext), n, ext)
+}
+
+// writePaginatedHTML splits the report into pages of roughly size rendered
+// nodes, each holding the trees and table sections of the same top-level
+// keys, and writes an index page to indexFile linking them. It returns the
+// files written.
+func writePaginatedHTML(indexFile string, r *Report, size int) ([]string, error) {
+	a, okA := r.A.(map[string]interface{})
+	b, okB := r.B.(map[string]interface{})
+	if !okA || !okB {
+		fmt.Fprintln(os.Stderr, "Warning: --paginate needs objects at the top level of both files; writing a single page")
+		return []string{indexFile}, writeReportFile(indexFile, htmlRenderer{}, r, outputOptions{})
+	}
+
+	pages := paginateKeys(a, b, size)
+	sectionsByKey := make(map[string]DiffSection, len(r.Sections))
+	for _, sec := range r.Sections {
+		sectionsByKey[sec.Name] = sec
+	}
+	for i := range pages {
+		p := &pages[i]
+		p.Number = i + 1
+		p.File = pageFileName(indexFile, p.Number)
+		for _, key := range p.Keys {
+			if sec, ok := sectionsByKey[key]; ok {
+				p.Sections = append(p.Sections, sec)
+				p.Total += sec.Count
+			}
+		}
+	}
+
+	files := []string{indexFile}
+	for i, p := range pages {
+		page := *r
+		page.A, page.B = subsetObject(a, p.Keys), subsetObject(b, p.Keys)
+		page.Sections, page.Total = p.Sections, p.Total
+		page.Acknowledged = filterByTopLevelKey(r.Acknowledged, p.Keys)
+		page.Metadata = filterByTopLevelKey(r.Metadata, p.Keys)
+		page.Aggregates, page.Suppressed, page.Remapped = nil, nil, nil
+
+		nav := pageNav{Number: p.Number, Count: len(pages), Index: filepath.Base(indexFile)}
+		if i > 0 {
+			nav.Prev = filepath.Base(pages[i-1].File)
+		}
+		if i < len(pages)-1 {
+			nav.Next = filepath.Base(pages[i+1].File)
+		}
+		data := page.htmlData()
+		data.Page = &nav
+
+		f, err := os.Create(p.File)
+		if err != nil {
+			return files, err
+		}
+		err = writeHTMLReport(f, page.A, page.B, page.render, data)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return files, err
+		}
+		files = append(files, p.File)
+	}
+
+	for i := range pages {
+		pages[i].File = filepath.Base(pages[i].File)
+	}
+	tpl, err := parseReportTemplate(r.A, r.B, r.render)
+	if err != nil {
+		return files, err
+	}
+	f, err := os.Create(indexFile)
+	if err != nil {
+		return files, err
+	}
+	err = tpl.ExecuteTemplate(f, "index", map[string]interface{}{
+		"Pages":        pages,
+		"Total":        r.Total,
+		"Acknowledged": len(r.Acknowledged),
+		"Aggregates":   r.Aggregates,
+		"Suppressed":   r.Suppressed,
+		"Redacted":     r.Redacted,
+		"Remapped":     r.Remapped,
+		"LabelA":       r.LabelA,
+		"LabelB":       r.LabelB,
+		"Include":      r.Include,
+		"SwapWarning":  r.SwapWarning,
+		"SchemaBanner": r.SchemaBanner,
+	})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return files, err
+}
+
+// filterByTopLevelKey returns the results whose first path segment is one
+// of keys.
+func filterByTopLevelKey(results []DiffResult, keys []string) []DiffResult {
+	want := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		want[k] = true
+	}
+	var out []DiffResult
+	for _, r := range results {
+		if want[topLevelKey(r.Path)] {
+			out = append(out, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,186 @@
+package jsondiff
+
+import (
+	"context"
+	"sort"
+)
+
+// kindPairedElement is the Kind of a result pairing an array element removed
+// at one index with a similar element added at another.
+const kindPairedElement = "paired-element"
+
+// pairSimilarElements pairs array elements that diffing left removed and
+// added, such as a record whose id changed so that no equal element was
+// found for it. A removal and an addition of the same type in the same
+// array are paired when elementSimilarity rates them at least threshold,
+// most similar pairs first. Each pair replaces the two results with one
+// paired-element result at the new path, whose MovedFrom is the old one,
+// followed by the differences between the two elements, each with its path
+// in the new element and MovedFrom in the old.
+func pairSimilarElements(ctx context.Context, results []DiffResult, a, b interface{}, m *DiffMap, threshold float64) ([]DiffResult, error) {
+	type element struct {
+		result int
+		value  interface{}
+	}
+	type candidate struct {
+		removed, added int
+		similarity     float64
+	}
+	removed := make(map[string][]element)
+	added := make(map[string][]element)
+	for i, r := range results {
+		if r.Kind != "" || (r.Type != "delete" && r.Type != "create") {
+			continue
+		}
+		segs := splitPath(r.Path)
+		if len(segs) == 0 {
+			continue
+		}
+		if _, ok := parseIndexSegment(segs[len(segs)-1]); !ok {
+			continue
+		}
+		parent := joinPath(segs[:len(segs)-1])
+		if r.Type == "delete" {
+			if v, ok := lookupPath(a, segs); ok && isComposite(v) {
+				removed[parent] = append(removed[parent], element{i, v})
+			}
+		} else if v, ok := lookupPath(b, segs); ok && isComposite(v) {
+			added[parent] = append(added[parent], element{i, v})
+		}
+	}
+
+	var candidates []candidate
+	for parent, rs := range removed {
+		for _, r := range rs {
+			for _, ad := range added[parent] {
+				// An object and an array share no leaves, so only elements
+				// of the same type are rated.
+				if jsonTypeName(r.value) != jsonTypeName(ad.value) {
+					continue
+				}
+				if s := elementSimilarity(r.value, ad.value); s >= threshold {
+					candidates = append(candidates, candidate{r.result, ad.result, s})
+				}
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		ci, cj := candidates[i], candidates[j]
+		if ci.similarity != cj.similarity {
+			return ci.similarity > cj.similarity
+		}
+		if ci.removed != cj.removed {
+			return ci.removed < cj.removed
+		}
+		return ci.added < cj.added
+	})
+	// pairOf maps the index of a paired addition to its removal.
+	pairOf := make(map[int]int)
+	dropped := make(map[int]bool)
+	for _, c := range candidates {
+		if dropped[c.removed] {
+			continue
+		}
+		if _, ok := pairOf[c.added]; ok {
+			continue
+		}
+		pairOf[c.added] = c.removed
+		dropped[c.removed] = true
+	}
+	if len(pairOf) == 0 {
+		return results, nil
+	}
+
+	out := make([]DiffResult, 0, len(results))
+	for i, r := range results {
+		if dropped[i] {
+			continue
+		}
+		j, ok := pairOf[i]
+		if !ok {
+			out = append(out, r)
+			continue
+		}
+		old := results[j]
+		va, _ := lookupPath(a, splitPath(old.Path))
+		vb, _ := lookupPath(b, splitPath(r.Path))
+		changes, err := collectChanges(diffSeq(ctx, va, vb))
+		if err != nil {
+			return nil, err
+		}
+		r.ID = changeID(r.Path, "update", old.Path, vb)
+		r.Type, r.Kind, r.MovedFrom = "update", kindPairedElement, old.Path
+		r.From, r.FromType, r.FromLine, r.FromOffset = old.From, old.FromType, old.FromLine, old.FromOffset
+		r.FromJSON = old.FromJSON
+		m.Set(old.Path, Changed)
+		m.Set(r.Path, Changed)
+		out = append(out, r)
+		for _, inner := range buildDiffTable(changes, va, vb) {
+			segs := splitPath(inner.Path)
+			inner.MovedFrom = joinPath(append(splitPath(old.Path), segs...))
+			inner.Path = joinPath(append(splitPath(r.Path), segs...))
+			inner.Pointer = r.Pointer + inner.Pointer
+			inner.ID = changeID(inner.Path, inner.Type, inner.From, inner.To)
+			m.Add(inner.MovedFrom, resultChangeType(inner.Type))
+			m.Add(inner.Path, resultChangeType(inner.Type))
+			out = append(out, inner)
+		}
+	}
+	return out, nil
+}
+
+// elementSimilarity returns the fraction of the leaves of a and b that have
+// an equal value at the same path in the other: 1 for equal values, 0 for
+// values sharing nothing. Leaves are scalars and empty objects and arrays,
+// as counted by documentStats.
+func elementSimilarity(a, b interface{}) float64 {
+	la, lb := leafValues(a), leafValues(b)
+	if len(la)+len(lb) == 0 {
+		return 1
+	}
+	equal := 0
+	for p, v := range la {
+		if w, ok := lb[p]; ok && w == v {
+			equal++
+		}
+	}
+	return 2 * float64(equal) / float64(len(la)+len(lb))
+}
+
+// leafValues maps the path of every leaf in v to its canonical encoding.
+func leafValues(v interface{}) map[string]string {
+	leaves := make(map[string]string)
+	var walk func(v interface{}, path string)
+	walk = func(v interface{}, path string) {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			if len(val) == 0 {
+				break
+			}
+			for k, child := range val {
+				walk(child, pathKey(path, k))
+			}
+			return
+		case []interface{}:
+			if len(val) == 0 {
+				break
+			}
+			for i, child := range val {
+				walk(child, indexKey(path, i))
+			}
+			return
+		}
+		leaves[path] = canonicalKey(v)
+	}
+	walk(v, "")
+	return leaves
+}
+
+// isComposite reports whether v is an object or an array.
+func isComposite(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestElementSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{`{"id": 1, "v": [1, 2]}`, `{"v": [1, 2], "id": 1}`, 1},
+		{`{"id": 1, "name": "ann", "role": "admin"}`, `{"id": 9, "name": "ann", "role": "admin"}`, 2.0 / 3},
+		{`{"id": 1}`, `{"id": 1, "extra": {}}`, 2.0 / 3},
+		{`[1, 2]`, `[2, 1]`, 0},
+		{`{}`, `{}`, 1},
+	}
+	for _, tt := range tests {
+		if got := elementSimilarity(mustDecode(t, tt.a), mustDecode(t, tt.b)); got != tt.want {
+			t.Errorf("%s, %s: %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// TestArrayMatchThresholdCLI checks that a removed and an added element are
+// reported as one paired element, followed by the differences between
+// them, once they are similar enough.
+func TestArrayMatchThresholdCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"users": [{"id": 1, "name": "ann", "role": "admin"}, {"id": 2, "name": "bob", "role": "dev"}]}`,
+		"b.json": `{"users": [{"id": 2, "name": "bob", "role": "dev"}, {"id": 9, "name": "ann", "role": "admin"}, {"id": 5, "name": "cy", "role": "ops"}]}`,
+	})
+	tests := []struct {
+		threshold string
+		want      []string
+	}{
+		{"0.6", []string{
+			"update paired-element users[0] -> users[1]",
+			"update  users[0].id -> users[1].id",
+			"create   -> users[2]",
+		}},
+		{"0.7", []string{
+			"delete   -> users[0]",
+			"create   -> users[1]",
+			"create   -> users[2]",
+		}},
+	}
+	for _, tt := range tests {
+		res := runCLI(t, dir, "--array-match-threshold", tt.threshold, "-f", "json", "-o", "-", "a.json", "b.json")
+		if res.exit != exitOK {
+			t.Fatalf("%s: exit %d: %s", tt.threshold, res.exit, res.stderr)
+		}
+		var report jsonReport
+		if err := json.Unmarshal([]byte(res.stdout), &report); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, s := range report.Sections {
+			for _, c := range s.Changes {
+				got = append(got, c.Type+" "+c.Kind+" "+c.MovedFrom+" -> "+c.Path)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: changes %q, want %q", tt.threshold, got, tt.want)
+		}
+	}
+}
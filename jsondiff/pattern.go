@@ -0,0 +1,187 @@
+package jsondiff
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Paths join object members with "." and write array elements in brackets,
+// e.g. items[0].name, so the member "0" of an object (items.0) is never
+// confused with the first element of an array (items[0]). Split into
+// segments, an array element keeps its brackets.
+
+// pathKey returns the path of the member key of the object at base.
+func pathKey(base, key string) string {
+	if base == "" {
+		return key
+	}
+	return base + "." + key
+}
+
+// indexKey returns the path of element i of the array at base.
+func indexKey(base string, i int) string {
+	return base + indexSegment(i)
+}
+
+func indexSegment(i int) string {
+	return "[" + strconv.Itoa(i) + "]"
+}
+
+// parseIndexSegment returns the index of a bracketed array segment.
+func parseIndexSegment(seg string) (int, bool) {
+	if len(seg) < 3 || seg[0] != '[' || seg[len(seg)-1] != ']' {
+		return 0, false
+	}
+	i, err := strconv.Atoi(seg[1 : len(seg)-1])
+	return i, err == nil && i >= 0
+}
+
+func isIndexSegment(seg string) bool {
+	return len(seg) >= 3 && seg[0] == '[' && seg[len(seg)-1] == ']'
+}
+
+// splitPath breaks a path into its segments: "items[0].name" becomes
+// "items", "[0]", "name". A bracketed "*" is kept as an index wildcard.
+func splitPath(p string) []string {
+	if p == "" {
+		return nil
+	}
+	var segs []string
+	start, afterIndex := 0, false
+	for i := 0; i < len(p); i++ {
+		switch p[i] {
+		case '.':
+			if i > start || !afterIndex {
+				segs = append(segs, p[start:i])
+			}
+			start, afterIndex = i+1, false
+		case '[':
+			end := strings.IndexByte(p[i:], ']')
+			if end < 2 {
+				continue
+			}
+			inner := p[i+1 : i+end]
+			if _, err := strconv.Atoi(inner); err != nil && inner != "*" {
+				continue
+			}
+			if i > start {
+				segs = append(segs, p[start:i])
+			}
+			segs = append(segs, p[i:i+end+1])
+			i += end
+			start, afterIndex = i+1, true
+		}
+	}
+	if start < len(p) || !afterIndex {
+		segs = append(segs, p[start:])
+	}
+	return segs
+}
+
+// joinPath is the inverse of splitPath.
+func joinPath(segs []string) string {
+	var sb strings.Builder
+	for i, seg := range segs {
+		if i > 0 && !isIndexSegment(seg) {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(seg)
+	}
+	return sb.String()
+}
+
+// typedPath brackets the segments of a diff.Change path that address array
+// elements, judging by the container found at each level of a or b.
+// Segments already bracketed, as in a path given by the user, are kept.
+func typedPath(segs []string, a, b interface{}) []string {
+	out := make([]string, len(segs))
+	okA, okB := true, true
+	for i, seg := range segs {
+		_, arrA := a.([]interface{})
+		_, arrB := b.([]interface{})
+		if ((okA && arrA) || (okB && arrB)) && !isIndexSegment(seg) {
+			seg = "[" + seg + "]"
+		}
+		out[i] = seg
+		if okA {
+			a, okA = lookupPath(a, out[i:i+1])
+		}
+		if okB {
+			b, okB = lookupPath(b, out[i:i+1])
+		}
+	}
+	return out
+}
+
+// matchPath reports whether path matches pattern segment by segment.
+// A "*" segment in the pattern matches any single key or array index and
+// "[*]" matches any array index. A "**" segment matches any number of
+// segments, including none, so spec.** matches spec and everything below
+// it. A bare number in the pattern also matches the array index of that
+// number, so items.0.price still matches items[0].price.
+func matchPath(pattern, path []string) bool {
+	return matchSegments(pattern, path, false)
+}
+
+// matchPathPrefix reports whether path, or some path below it, matches
+// pattern.
+func matchPathPrefix(pattern, path []string) bool {
+	return matchSegments(pattern, path, true)
+}
+
+func matchSegments(pattern, path []string, prefix bool) bool {
+	for i, seg := range pattern {
+		if seg == "**" {
+			for j := 0; j <= len(path); j++ {
+				if matchSegments(pattern[i+1:], path[j:], prefix) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 {
+			return prefix
+		}
+		switch {
+		case seg == "*" || seg == path[0]:
+		case seg == "[*]" && isIndexSegment(path[0]):
+		case isIndexSegment(path[0]) && "["+seg+"]" == path[0]:
+		default:
+			return false
+		}
+		path = path[1:]
+	}
+	return len(path) == 0
+}
+
+// lookupPath returns the value at path within a decoded JSON document. An
+// array may also be indexed by an unbracketed number, as in diff.Change
+// paths, but a bracketed segment never matches an object member.
+func lookupPath(v interface{}, path []string) (interface{}, bool) {
+	for _, seg := range path {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			if isIndexSegment(seg) {
+				return nil, false
+			}
+			next, ok := val[seg]
+			if !ok {
+				return nil, false
+			}
+			v = next
+		case []interface{}:
+			i, ok := parseIndexSegment(seg)
+			if !ok {
+				n, err := strconv.Atoi(seg)
+				i, ok = n, err == nil
+			}
+			if !ok || i < 0 || i >= len(val) {
+				return nil, false
+			}
+			v = val[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
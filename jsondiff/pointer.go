@@ -0,0 +1,24 @@
+package jsondiff
+
+import "strings"
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// jsonPointer returns the RFC 6901 JSON Pointer of the change path segs,
+// resolved against a and b like typedPath: a segment is a numeric token
+// only where it indexes an array, and keys are escaped, so keys containing
+// "/", "~" or "." and empty keys are unambiguous.
+func jsonPointer(segs []string, a, b interface{}) string {
+	typed := typedPath(segs, a, b)
+	var sb strings.Builder
+	for i, seg := range segs {
+		sb.WriteByte('/')
+		if typed[i] != seg {
+			// An array index, which typedPath wrapped in brackets.
+			sb.WriteString(seg)
+			continue
+		}
+		sb.WriteString(pointerEscaper.Replace(seg))
+	}
+	return sb.String()
+}
@@ -0,0 +1,82 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// pointerDoc has keys that need escaping in a pointer, keys that look like
+// path syntax, and empty keys.
+const pointerDoc = `{
+  "a/b": {"c~d": [{"x.y": 1, "": {"": 2}}, "~1"]},
+  "": 3,
+  "0": {"1": 4},
+  "~0/~1": 5
+}`
+
+// TestJSONPointerRoundTrip checks that the pointer of every leaf resolves
+// back to that leaf, and that leafPointer agrees with jsonPointer.
+func TestJSONPointerRoundTrip(t *testing.T) {
+	doc := mustDecode(t, pointerDoc)
+	tests := []struct {
+		segs []string
+		want string
+	}{
+		{[]string{"a/b", "c~d", "0", "x.y"}, "/a~1b/c~0d/0/x.y"},
+		{[]string{"a/b", "c~d", "0", "", ""}, "/a~1b/c~0d/0//"},
+		{[]string{"a/b", "c~d", "1"}, "/a~1b/c~0d/1"},
+		{[]string{""}, "/"},
+		{[]string{"0", "1"}, "/0/1"},
+		{[]string{"~0/~1"}, "/~00~1~01"},
+	}
+	for _, tt := range tests {
+		got := jsonPointer(tt.segs, doc, doc)
+		if got != tt.want {
+			t.Errorf("%q: pointer %s, want %s", tt.segs, got, tt.want)
+		}
+		if leaf := leafPointer(typedPath(tt.segs, doc, doc)); leaf != got {
+			t.Errorf("%q: leafPointer %s, jsonPointer %s", tt.segs, leaf, got)
+		}
+		want, _ := lookupPath(doc, typedPath(tt.segs, doc, doc))
+		v, path, ok := resolvePointer(doc, got, &renderContext{})
+		if !ok || !reflect.DeepEqual(v, want) {
+			t.Errorf("%s resolved to %v, %t, want %v", got, v, ok, want)
+		}
+		if want := joinPath(typedPath(tt.segs, doc, doc)); path != want {
+			t.Errorf("%s resolved at %s, want %s", got, path, want)
+		}
+	}
+
+	for _, bad := range []string{"a~1b", "/a/b", "/a~1b/c~0d/01", "/a~1b/c~0d/-", "/a~1b/c~0d/2", "/0/1/x"} {
+		if _, _, ok := resolvePointer(doc, bad, &renderContext{}); ok {
+			t.Errorf("%s resolved", bad)
+		}
+	}
+}
+
+// TestJSONPointerInReport checks the pointers the JSON report gives changes
+// at such keys.
+func TestJSONPointerInReport(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"a/b": 1, "c~d": [1], "e.f": {"": 1}}`,
+		"b.json": `{"a/b": 2, "c~d": [2], "e.f": {"": 2}}`,
+	})
+	if res := runCLI(t, dir, "-f", "json", "-o", "out.json", "a.json", "b.json"); res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	var report jsonReport
+	if err := json.Unmarshal([]byte(readFile(t, dir, "out.json")), &report); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]bool)
+	for _, s := range report.Sections {
+		for _, r := range s.Changes {
+			got[r.Pointer] = true
+		}
+	}
+	want := map[string]bool{"/a~1b": true, "/c~0d/0": true, "/e.f/": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pointers %v, want %v", got, want)
+	}
+}
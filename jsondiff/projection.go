@@ -0,0 +1,440 @@
+package jsondiff
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A projection is a jq-like expression reducing a document to the view that
+// is compared. The supported subset is
+//
+//	.                   the whole document
+//	.a.b[0]  .["a b"]   path extraction; missing keys and indices give null
+//	.items[].image      [] maps the rest of the path over an array (or an
+//	                    object's values, in key order) and collects the
+//	                    results into an array
+//	del(.a, .b[].c)     the input without the given paths
+//	map(f)              f applied to each element of an array
+//	f | g               g applied to the result of f
+type projection struct {
+	source string
+	pipe   []projTerm
+}
+
+type projTerm struct {
+	path []projStep
+	// del and mapped are set for del(paths) and map(pipe).
+	del    [][]projStep
+	mapped []projTerm
+}
+
+// projStep is one path step: a key, an index, or iteration when iterate is
+// set.
+type projStep struct {
+	key     string
+	index   int
+	isIndex bool
+	iterate bool
+}
+
+// parseProjection parses expr, reporting the offset of any syntax error.
+func parseProjection(expr string) (*projection, error) {
+	p := &projParser{src: expr}
+	pipe, err := p.pipe()
+	if err == nil {
+		p.space()
+		if p.pos < len(p.src) {
+			err = p.errorf("unexpected %q", p.src[p.pos:p.pos+1])
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid projection %q: %v", expr, err)
+	}
+	return &projection{source: expr, pipe: pipe}, nil
+}
+
+type projParser struct {
+	src string
+	pos int
+}
+
+func (p *projParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s at offset %d", fmt.Sprintf(format, args...), p.pos)
+}
+
+func (p *projParser) space() {
+	for p.pos < len(p.src) && strings.ContainsRune(" \t\r\n", rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+// peek skips spaces and reports whether the input continues with s.
+func (p *projParser) peek(s string) bool {
+	p.space()
+	return strings.HasPrefix(p.src[p.pos:], s)
+}
+
+func (p *projParser) expect(s string) error {
+	if !p.peek(s) {
+		if p.pos == len(p.src) {
+			return p.errorf("expected %q, found end of expression", s)
+		}
+		return p.errorf("expected %q", s)
+	}
+	p.pos += len(s)
+	return nil
+}
+
+func (p *projParser) pipe() ([]projTerm, error) {
+	var terms []projTerm
+	for {
+		t, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, t)
+		if !p.peek("|") {
+			return terms, nil
+		}
+		p.pos++
+	}
+}
+
+func (p *projParser) term() (projTerm, error) {
+	switch {
+	case p.peek("del("):
+		p.pos += len("del(")
+		var t projTerm
+		for {
+			path, err := p.path()
+			if err != nil {
+				return t, err
+			}
+			t.del = append(t.del, path)
+			if !p.peek(",") {
+				break
+			}
+			p.pos++
+		}
+		return t, p.expect(")")
+	case p.peek("map("):
+		p.pos += len("map(")
+		pipe, err := p.pipe()
+		if err != nil {
+			return projTerm{}, err
+		}
+		return projTerm{mapped: pipe}, p.expect(")")
+	}
+	path, err := p.path()
+	return projTerm{path: path}, err
+}
+
+// path parses a path starting with ".". The result is empty for ".".
+func (p *projParser) path() ([]projStep, error) {
+	if err := p.expect("."); err != nil {
+		if p.pos < len(p.src) {
+			return nil, p.errorf("expected a path starting with \".\", del(...) or map(...)")
+		}
+		return nil, err
+	}
+	var steps []projStep
+	first := true
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '[':
+			step, err := p.bracket()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+		case first && (isIdentStart(c) || c == '"'):
+			key, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, projStep{key: key})
+		case c == '.' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '[':
+			p.pos++
+		case c == '.':
+			p.pos++
+			if p.pos == len(p.src) || !isIdentStart(p.src[p.pos]) && p.src[p.pos] != '"' {
+				return nil, p.errorf("expected a key after \".\"")
+			}
+			key, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, projStep{key: key})
+		default:
+			return steps, nil
+		}
+		first = false
+	}
+	return steps, nil
+}
+
+// key parses an identifier or a quoted key.
+func (p *projParser) key() (string, error) {
+	if p.src[p.pos] == '"' {
+		return p.quoted()
+	}
+	start := p.pos
+	for p.pos < len(p.src) && (isIdentStart(p.src[p.pos]) || p.src[p.pos] >= '0' && p.src[p.pos] <= '9') {
+		p.pos++
+	}
+	return p.src[start:p.pos], nil
+}
+
+func (p *projParser) quoted() (string, error) {
+	start := p.pos
+	for p.pos++; p.pos < len(p.src); p.pos++ {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos++
+		case '"':
+			p.pos++
+			s, err := strconv.Unquote(p.src[start:p.pos])
+			if err != nil {
+				p.pos = start
+				return "", p.errorf("invalid string %s", p.src[start:])
+			}
+			return s, nil
+		}
+	}
+	p.pos = start
+	return "", p.errorf("unterminated string")
+}
+
+// bracket parses [], [n] or ["key"].
+func (p *projParser) bracket() (projStep, error) {
+	p.pos++
+	var step projStep
+	switch {
+	case p.peek("]"):
+		step.iterate = true
+	case p.peek(`"`):
+		key, err := p.quoted()
+		if err != nil {
+			return step, err
+		}
+		step.key = key
+	default:
+		start := p.pos
+		if p.pos < len(p.src) && p.src[p.pos] == '-' {
+			p.pos++
+		}
+		for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+			p.pos++
+		}
+		n, err := strconv.Atoi(p.src[start:p.pos])
+		if err != nil {
+			p.pos = start
+			return step, p.errorf("expected an index, a quoted key or ]")
+		}
+		step.index, step.isIndex = n, true
+	}
+	return step, p.expect("]")
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// String returns the expression, or "" for no projection.
+func (pr *projection) String() string {
+	if pr == nil {
+		return ""
+	}
+	return pr.source
+}
+
+// Apply evaluates the projection on v. The input is not modified.
+func (pr *projection) Apply(v interface{}) (interface{}, error) {
+	return applyPipe(pr.pipe, v)
+}
+
+func applyPipe(pipe []projTerm, v interface{}) (interface{}, error) {
+	var err error
+	for _, t := range pipe {
+		if v, err = t.apply(v); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+func (t projTerm) apply(v interface{}) (interface{}, error) {
+	switch {
+	case t.mapped != nil:
+		arr, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("map: cannot iterate over %s", jsonTypeName(v))
+		}
+		out := make([]interface{}, len(arr))
+		for i, elem := range arr {
+			r, err := applyPipe(t.mapped, elem)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	case t.del != nil:
+		var err error
+		for _, path := range t.del {
+			if v, err = deleteSteps(v, path, ""); err != nil {
+				return nil, fmt.Errorf("del: %v", err)
+			}
+		}
+		return v, nil
+	}
+	return selectSteps(v, t.path, "")
+}
+
+// selectSteps follows steps from v; at is the path walked so far, for
+// errors.
+func selectSteps(v interface{}, steps []projStep, at string) (interface{}, error) {
+	if len(steps) == 0 || v == nil && !steps[0].iterate {
+		return v, nil
+	}
+	s := steps[0]
+	switch {
+	case s.iterate:
+		elems, err := iterateValue(v, at)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]interface{}, len(elems))
+		for i, elem := range elems {
+			if out[i], err = selectSteps(elem, steps[1:], at+"[]"); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case s.isIndex:
+		arr, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot index %s with %d at %s", jsonTypeName(v), s.index, stepsPath(at))
+		}
+		i := s.index
+		if i < 0 {
+			i += len(arr)
+		}
+		if i < 0 || i >= len(arr) {
+			return nil, nil
+		}
+		return selectSteps(arr[i], steps[1:], fmt.Sprintf("%s[%d]", at, s.index))
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot index %s with %q at %s", jsonTypeName(v), s.key, stepsPath(at))
+	}
+	return selectSteps(obj[s.key], steps[1:], at+"."+s.key)
+}
+
+// deleteSteps returns v without the value at steps, copying the containers
+// it changes.
+func deleteSteps(v interface{}, steps []projStep, at string) (interface{}, error) {
+	if len(steps) == 0 || v == nil {
+		return nil, nil
+	}
+	s, last := steps[0], len(steps) == 1
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if s.isIndex {
+			return nil, fmt.Errorf("cannot index object with %d at %s", s.index, stepsPath(at))
+		}
+		out := make(map[string]interface{}, len(val))
+		for k, child := range val {
+			out[k] = child
+		}
+		if s.iterate {
+			if last {
+				return map[string]interface{}{}, nil
+			}
+			for k, child := range val {
+				r, err := deleteSteps(child, steps[1:], at+"[]")
+				if err != nil {
+					return nil, err
+				}
+				out[k] = r
+			}
+			return out, nil
+		}
+		if _, ok := val[s.key]; !ok {
+			return v, nil
+		}
+		if last {
+			delete(out, s.key)
+			return out, nil
+		}
+		r, err := deleteSteps(val[s.key], steps[1:], at+"."+s.key)
+		out[s.key] = r
+		return out, err
+	case []interface{}:
+		switch {
+		case s.iterate:
+			if last {
+				return []interface{}{}, nil
+			}
+			out := make([]interface{}, len(val))
+			for i, child := range val {
+				r, err := deleteSteps(child, steps[1:], at+"[]")
+				if err != nil {
+					return nil, err
+				}
+				out[i] = r
+			}
+			return out, nil
+		case s.isIndex:
+			i := s.index
+			if i < 0 {
+				i += len(val)
+			}
+			if i < 0 || i >= len(val) {
+				return v, nil
+			}
+			out := make([]interface{}, 0, len(val))
+			out = append(out, val[:i]...)
+			if last {
+				return append(out, val[i+1:]...), nil
+			}
+			r, err := deleteSteps(val[i], steps[1:], fmt.Sprintf("%s[%d]", at, s.index))
+			out = append(append(out, r), val[i+1:]...)
+			return out, err
+		}
+		return nil, fmt.Errorf("cannot index array with %q at %s", s.key, stepsPath(at))
+	}
+	return nil, fmt.Errorf("cannot delete from %s at %s", jsonTypeName(v), stepsPath(at))
+}
+
+// iterateValue returns the elements of an array, or the values of an object
+// in key order.
+func iterateValue(v interface{}, at string) ([]interface{}, error) {
+	switch val := v.(type) {
+	case []interface{}:
+		return val, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out := make([]interface{}, len(keys))
+		for i, k := range keys {
+			out[i] = val[k]
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("cannot iterate over %s at %s", jsonTypeName(v), stepsPath(at))
+}
+
+func stepsPath(at string) string {
+	if at == "" {
+		return "."
+	}
+	return at
+}
@@ -0,0 +1,133 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const projectionDoc = `{
+  "name": "svc",
+  "a b": 1,
+  "items": [
+    {"image": "nginx", "tags": ["x", "y"], "meta": {"id": 1}},
+    {"image": "redis", "tags": [], "meta": {"id": 2}}
+  ],
+  "env": {"B": "2", "A": "1"}
+}`
+
+func TestProjectionApply(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{".", ``},
+		{".name", `"svc"`},
+		{`.["a b"]`, `1`},
+		{`."a b"`, `1`},
+		{".items[0].image", `"nginx"`},
+		{".items[-1].image", `"redis"`},
+		{".items[5]", `null`},
+		{".missing.deeper", `null`},
+		{".items[].image", `["nginx","redis"]`},
+		{".items[].tags[]", `[["x","y"],[]]`},
+		{".env[]", `["1","2"]`},
+		{"del(.items, .env)", `{"a b":1,"name":"svc"}`},
+		{"del(.items[].meta, .items[].tags) | .items", `[{"image":"nginx"},{"image":"redis"}]`},
+		{"del(.items[0]) | .items[].image", `["redis"]`},
+		{"del(.nope) | .name", `"svc"`},
+		{".items | map(.meta.id)", `[1,2]`},
+		// | binds looser than map's argument, so the whole pipe is mapped.
+		{".items | map(.meta | .id)", `[1,2]`},
+		{".items | map(.meta) | map(.id)", `[1,2]`},
+		{" .items[0]  |  .image ", `"nginx"`},
+	}
+	doc := mustDecode(t, projectionDoc)
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			pr, err := parseProjection(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := pr.Apply(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tt.want
+			if want == "" {
+				want = projectionDoc
+			}
+			if got := mustMarshal(t, got); !reflect.DeepEqual(mustDecode(t, got), mustDecode(t, want)) {
+				t.Errorf("got %s, want %s", got, want)
+			}
+		})
+	}
+	if !reflect.DeepEqual(doc, mustDecode(t, projectionDoc)) {
+		t.Errorf("projections modified the input: %s", mustMarshal(t, doc))
+	}
+}
+
+func TestProjectionSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"", `expected ".", found end of expression`},
+		{"name", `expected a path starting with ".", del(...) or map(...) at offset 0`},
+		{".a.", `expected a key after "." at offset 3`},
+		{".a[", `expected an index, a quoted key or ] at offset 3`},
+		{".a[x]", `expected an index, a quoted key or ] at offset 3`},
+		{".a[0", `expected "]", found end of expression`},
+		{`.["a`, "unterminated string at offset 2"},
+		{`.["\q"]`, "invalid string"},
+		{".a |", `expected ".", found end of expression`},
+		{"del(.a", `expected ")", found end of expression`},
+		{"map(.a", `expected ")", found end of expression`},
+		{".a .b", `unexpected "." at offset 3`},
+		{".a)", `unexpected ")" at offset 2`},
+	}
+	for _, tt := range tests {
+		_, err := parseProjection(tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: error %v, want %s", tt.expr, err, tt.want)
+		}
+	}
+}
+
+func TestProjectionTypeErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{".name.first", `cannot index string with "first" at .name`},
+		{".name[0]", "cannot index string with 0 at .name"},
+		{".items.image", `cannot index array with "image" at .items`},
+		{".name[]", "cannot iterate over string at .name"},
+		{".items[].image[]", "cannot iterate over string at .items[].image"},
+		{".name | map(.a)", "map: cannot iterate over string"},
+		{"del(.env[0])", "del: cannot index object with 0 at .env"},
+		{`del(.items["a"])`, `del: cannot index array with "a" at .items`},
+		{"del(.name.x)", "del: cannot delete from string at .name"},
+	}
+	doc := mustDecode(t, projectionDoc)
+	for _, tt := range tests {
+		pr, err := parseProjection(tt.expr)
+		if err != nil {
+			t.Errorf("%q: %v", tt.expr, err)
+			continue
+		}
+		if _, err := pr.Apply(doc); err == nil || err.Error() != tt.want {
+			t.Errorf("%q: error %v, want %s", tt.expr, err, tt.want)
+		}
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
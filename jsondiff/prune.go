@@ -0,0 +1,87 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Array modes for --emit-array-mode.
+const (
+	// emitArrayNulls keeps kept elements at their index, filling the
+	// elements in between with null and dropping unchanged trailing ones.
+	emitArrayNulls = "nulls"
+	// emitArrayKeyed writes an array as an object keyed by original index.
+	emitArrayKeyed = "keyed"
+)
+
+// pruneToChanges returns the parts of v, the document on one side, that are
+// involved in a change: changed values whole, plus the containers leading
+// to them. segment maps an object key of v to its compared path segment.
+// The second result reports whether anything under path was kept.
+func pruneToChanges(v interface{}, m *DiffMap, path string, segment func(parent, key string) string, arrayMode string) (interface{}, bool) {
+	if _, changed := m.Lookup(path); changed {
+		return v, true
+	}
+	if !m.HasChangedDescendant(path) {
+		return nil, false
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{})
+		for k, vv := range val {
+			if pruned, ok := pruneToChanges(vv, m, pathKey(path, segment(path, k)), segment, arrayMode); ok {
+				out[k] = pruned
+			}
+		}
+		return out, len(out) > 0
+	case []interface{}:
+		if arrayMode == emitArrayKeyed {
+			out := make(map[string]interface{})
+			for i, vv := range val {
+				if pruned, ok := pruneToChanges(vv, m, indexKey(path, i), segment, arrayMode); ok {
+					out[strconv.Itoa(i)] = pruned
+				}
+			}
+			return out, len(out) > 0
+		}
+		var out []interface{}
+		for i, vv := range val {
+			if pruned, ok := pruneToChanges(vv, m, indexKey(path, i), segment, arrayMode); ok {
+				for len(out) < i {
+					out = append(out, nil)
+				}
+				out = append(out, pruned)
+			}
+		}
+		return out, out != nil
+	}
+	return nil, false
+}
+
+// emitChanged writes the pruned form of doc to filename. A document without
+// changes is written as an empty container of the same kind.
+func emitChanged(filename string, doc interface{}, m *DiffMap, segment func(parent, key string) string, arrayMode string) error {
+	pruned, ok := pruneToChanges(doc, m, "", segment, arrayMode)
+	if !ok {
+		switch doc.(type) {
+		case map[string]interface{}:
+			pruned = map[string]interface{}{}
+		case []interface{}:
+			pruned = []interface{}{}
+		}
+	}
+	data, err := json.MarshalIndent(pruned, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
+
+func parseEmitArrayMode(mode string) error {
+	if mode != emitArrayNulls && mode != emitArrayKeyed {
+		return fmt.Errorf("invalid --emit-array-mode %q: must be nulls or keyed", mode)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package jsondiff
+
+import (
+	"testing"
+)
+
+// pruneDocs cover a changed leaf deep in an object, changes inside array
+// elements with unchanged elements around and after them, a removed and an
+// added member, a member changing type and a key differing only in case.
+var pruneDocs = map[string]string{
+	"a.json": `{
+  "name": "svc",
+  "spec": {"replicas": 2, "image": "web:1", "limits": {"cpu": "1", "mem": "1Gi"}},
+  "ports": [{"port": 80}, {"port": 443}, {"port": 8080}, {"port": 9090}],
+  "tags": ["a", "b", "c"],
+  "old": {"x": 1},
+  "Owner": "ops",
+  "mode": [1]
+}`,
+	"b.json": `{
+  "name": "svc",
+  "spec": {"replicas": 3, "image": "web:1", "limits": {"cpu": "1", "mem": "2Gi"}},
+  "ports": [{"port": 80}, {"port": 444}, {"port": 8080}, {"port": 9090}],
+  "tags": ["a", "b", "c", "d"],
+  "new": true,
+  "owner": "ops",
+  "mode": "fast"
+}`,
+}
+
+// TestEmitChangedGolden pins the documents --emit-changed-a and
+// --emit-changed-b write, in both --emit-array-mode forms and with keys
+// paired case-insensitively.
+func TestEmitChangedGolden(t *testing.T) {
+	dir := cliDir(t, pruneDocs)
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"nulls", []string{"--emit-array-mode", "nulls"}},
+		{"keyed", []string{"--emit-array-mode", "keyed"}},
+		{"ignore-key-case", []string{"--ignore-key-case"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append(append([]string{"-o", "out.html", "--emit-changed-a", "pruned-a.json", "--emit-changed-b", "pruned-b.json"}, tt.args...), "a.json", "b.json")
+			if res := runCLI(t, dir, args...); res.exit != exitOK {
+				t.Fatalf("exit %d: %s", res.exit, res.stderr)
+			}
+			checkGolden(t, "prune-"+tt.name+"-a.json", readFile(t, dir, "pruned-a.json"))
+			checkGolden(t, "prune-"+tt.name+"-b.json", readFile(t, dir, "pruned-b.json"))
+		})
+	}
+}
+
+func TestEmitChangedNoChanges(t *testing.T) {
+	dir := cliDir(t, map[string]string{"obj.json": `{"a": [1]}`, "arr.json": `[{"a": 1}]`})
+	for name, want := range map[string]string{"obj.json": "{}\n", "arr.json": "[]\n"} {
+		if res := runCLI(t, dir, "-o", "out.html", "--emit-changed-a", "pruned.json", name, name); res.exit != exitOK {
+			t.Fatalf("exit %d: %s", res.exit, res.stderr)
+		}
+		if got := readFile(t, dir, "pruned.json"); got != want {
+			t.Errorf("%s: %q, want %q", name, got, want)
+		}
+	}
+}
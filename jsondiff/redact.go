@@ -0,0 +1,173 @@
+package jsondiff
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/r3labs/diff/v3"
+)
+
+// redactedText replaces values hidden by --auto-redact in every output.
+const redactedText = "[REDACTED]"
+
+// defaultRedactKeys are the key names --auto-redact treats as secrets unless
+// the config supplies its own list.
+var defaultRedactKeys = []string{"password", "passwd", "secret", "token", "apikey", "privatekey", "accesskey", "credentials"}
+
+var (
+	jwtPattern    = regexp.MustCompile(`^eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*$`)
+	base64Pattern = regexp.MustCompile(`^[A-Za-z0-9+/_-]{40,}={0,2}$`)
+	digitPattern  = regexp.MustCompile(`[0-9]`)
+	letterPattern = regexp.MustCompile(`[A-Za-z]`)
+)
+
+// Redaction records a value --auto-redact masked and why.
+type Redaction struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// redactor detects secret-looking values by key name and value shape.
+// Comparison still runs on the real values; only what is shown is masked.
+type redactor struct {
+	keys  []string
+	found map[string]string
+	// secrets holds the masked values as text, for scrubbing messages
+	// that quote them.
+	secrets map[string]bool
+}
+
+func newRedactor(keys []string) *redactor {
+	if len(keys) == 0 {
+		keys = defaultRedactKeys
+	}
+	norm := make([]string, len(keys))
+	for i, k := range keys {
+		norm[i] = normalizeKeyName(k)
+	}
+	return &redactor{keys: norm, found: make(map[string]string), secrets: make(map[string]bool)}
+}
+
+// normalizeKeyName lowercases a key and drops separators, so api_key,
+// api-key and apiKey all read as apikey.
+func normalizeKeyName(k string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '_', '-', '.', ' ':
+			return -1
+		}
+		return r
+	}, strings.ToLower(k))
+}
+
+// secretKey reports whether key names a secret: its normalized form equals
+// or ends with a listed name, so dbPassword matches but tokenizer does not.
+func (r *redactor) secretKey(key string) bool {
+	k := normalizeKeyName(key)
+	for _, name := range r.keys {
+		if strings.HasSuffix(k, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// secretShape reports why s looks like a credential on its own, or "".
+func secretShape(s string) string {
+	switch {
+	case jwtPattern.MatchString(s):
+		return "JWT"
+	case base64Pattern.MatchString(s) && digitPattern.MatchString(s) && letterPattern.MatchString(s):
+		return "long base64"
+	}
+	return ""
+}
+
+// redact returns a copy of v at path with secret values masked. underSecret
+// is set when an enclosing key already names a secret, so everything below
+// it is hidden.
+func (r *redactor) redact(v interface{}, path []string, underSecret bool) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, vv := range val {
+			out[k] = r.redact(vv, append(path[:len(path):len(path)], k), underSecret || r.secretKey(k))
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, vv := range val {
+			out[i] = r.redact(vv, append(path[:len(path):len(path)], indexSegment(i)), underSecret)
+		}
+		return out
+	case nil:
+		return nil
+	}
+	reason := ""
+	if underSecret {
+		reason = "key name"
+	} else if s, ok := v.(string); ok {
+		reason = secretShape(s)
+	}
+	if reason == "" {
+		return v
+	}
+	r.found[joinPath(path)] = reason
+	r.secrets[fmt.Sprint(v)] = true
+	return redactedText
+}
+
+// scrub masks every known secret quoted in s.
+func (r *redactor) scrub(s string) string {
+	for secret := range r.secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, redactedText)
+		}
+	}
+	return s
+}
+
+// schemaErrors masks secrets quoted in schema violation messages, which
+// are produced from the real values.
+func (r *redactor) schemaErrors(results []DiffResult) {
+	for i := range results {
+		for j, msg := range results[i].SchemaErrors {
+			results[i].SchemaErrors[j] = r.scrub(msg)
+		}
+	}
+}
+
+// document returns a masked copy of a whole input document.
+func (r *redactor) document(v interface{}) interface{} {
+	return r.redact(v, nil, false)
+}
+
+// changes masks the From and To values of each change in place. a and b are
+// the compared documents, used to tell array indices from object keys.
+func (r *redactor) changes(changes []diff.Change, a, b interface{}) {
+	for i := range changes {
+		c := &changes[i]
+		path := typedPath(c.Path, a, b)
+		underSecret := false
+		for _, seg := range path {
+			if !isIndexSegment(seg) && r.secretKey(seg) {
+				underSecret = true
+				break
+			}
+		}
+		c.From = r.redact(c.From, path, underSecret)
+		c.To = r.redact(c.To, path, underSecret)
+	}
+}
+
+// list returns the masked paths in sorted order.
+func (r *redactor) list() []Redaction {
+	out := make([]Redaction, 0, len(r.found))
+	for p, reason := range r.found {
+		out = append(out, Redaction{Path: p, Reason: reason})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
@@ -0,0 +1,249 @@
+package jsondiff
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Report is everything a Renderer needs to write one diff.
+type Report struct {
+	// File names of the two inputs, or the commands that produced them.
+	Original string
+	Modified string
+	// Short names shown on the panes and in source locations.
+	LabelA, LabelB string
+	// The --project expressions applied to each side, if any.
+	ProjectionA, ProjectionB string
+	// Include lists the --include patterns the comparison was restricted
+	// to, if any.
+	Include []string
+	// SwapWarning, if set, says why the inputs may have been passed in the
+	// wrong order.
+	SwapWarning string
+	// SchemaBanner, set by --json-schema-aware, says that the documents
+	// declare different schemas.
+	SchemaBanner string
+	// AllLeaves is set by --json-include-unchanged: the JSON and jsonl
+	// formats then list every leaf, not only the changed ones.
+	AllLeaves bool
+	// MaxNodes caps the nodes of the dot format's graph.
+	MaxNodes int
+	// The decoded documents, after key remapping but before comparison
+	// transforms.
+	A, B interface{}
+
+	Total        int
+	Legend       []LegendEntry
+	Arrays       []ArrayStats
+	TOC          []TOCEntry
+	HeatMap      []HeatMarker
+	Sections     []DiffSection
+	Acknowledged []DiffResult
+	Aggregates   []Aggregate
+	Suppressed   []Suppression
+	Redacted     []Redaction
+	Remapped     []RemappedKey
+	// Metadata lists the changes to $schema and $id members, with
+	// --json-schema-aware; they are not counted in Total.
+	Metadata []DiffResult
+	// Reordered lists the objects found by --detect-key-reorder; they are
+	// not counted in Total.
+	Reordered []KeyReorder
+	// InputStats describes both inputs as parsed.
+	InputStats [2]InputStats
+	// Schema is true when changes carry JSON Schema annotations.
+	Schema bool
+	// Editable is set by --editable; the report then embeds B for export.
+	Editable bool
+	// Inputs labels and hashes both documents; the hashes are empty unless
+	// the HTML report or a summary needs them.
+	Inputs     [2]summaryInput
+	Similarity float64
+	// Scores breaks Similarity down by section and weighs it.
+	Scores similarityScores
+	// Options lists the diff flags set for the run.
+	Options []EffectiveOption
+
+	// render holds the tree rendering state used by the HTML renderer.
+	render renderContext
+}
+
+// Renderer writes a Report in one output format.
+type Renderer interface {
+	Render(w io.Writer, r *Report) error
+	// Name is the value selecting the renderer with --format.
+	Name() string
+	// DefaultExtension is the file extension, without the dot, of the
+	// default output file.
+	DefaultExtension() string
+}
+
+var renderers = make(map[string]Renderer)
+
+// RegisterRenderer makes a renderer available to --format. A program adding
+// its own registers them, from an init function or main, before calling
+// Run. It panics if the name is already taken.
+func RegisterRenderer(r Renderer) {
+	if _, dup := renderers[r.Name()]; dup {
+		panic(fmt.Sprintf("renderer %q registered twice", r.Name()))
+	}
+	renderers[r.Name()] = r
+}
+
+// rendererNames returns the registered format names in sorted order.
+func rendererNames() []string {
+	names := make([]string, 0, len(renderers))
+	for name := range renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterRenderer(htmlRenderer{})
+	RegisterRenderer(jsonRenderer{})
+	RegisterRenderer(csvRenderer{})
+	RegisterRenderer(jsonlRenderer{})
+	RegisterRenderer(fragmentRenderer{})
+	RegisterRenderer(dotRenderer{})
+}
+
+type htmlRenderer struct{}
+
+func (htmlRenderer) Name() string             { return "html" }
+func (htmlRenderer) DefaultExtension() string { return "html" }
+
+func (htmlRenderer) Render(w io.Writer, r *Report) error {
+	return writeHTMLReport(w, r.A, r.B, r.render, r.htmlData())
+}
+
+// htmlData returns the template data for r, apart from the documents
+// writeHTMLReport adds itself.
+func (r *Report) htmlData() *ReportContext {
+	return &ReportContext{
+		APIVersion:   templateAPIVersion,
+		Sections:     r.Sections,
+		Total:        r.Total,
+		Legend:       r.Legend,
+		TOC:          r.TOC,
+		HeatMap:      r.HeatMap,
+		Acknowledged: r.Acknowledged,
+		Metadata:     r.Metadata,
+		Aggregates:   r.Aggregates,
+		Suppressed:   r.Suppressed,
+		Redacted:     r.Redacted,
+		Remapped:     r.Remapped,
+		Reordered:    r.Reordered,
+		InputStats:   r.InputStats,
+		Schema:       r.Schema,
+		LabelA:       r.LabelA,
+		LabelB:       r.LabelB,
+		ProjectionA:  r.ProjectionA,
+		ProjectionB:  r.ProjectionB,
+		Include:      r.Include,
+		SwapWarning:  r.SwapWarning,
+		SchemaBanner: r.SchemaBanner,
+		Editable:     r.Editable,
+		Inputs:       r.Inputs,
+		Similarity:   r.Similarity,
+		Scores:       r.Scores,
+		Options:      r.Options,
+	}
+}
+
+// writeReportFile renders r into the named file, followed by any assets the
+// renderer ships next to it.
+// The name "-" writes to stdout, without assets.
+func writeReportFile(name string, renderer Renderer, r *Report, opts outputOptions) error {
+	w, closeOutput, err := createOutput(name, opts)
+	if err != nil {
+		return err
+	}
+	err = renderer.Render(w, r)
+	if cerr := closeOutput(); err == nil {
+		err = cerr
+	}
+	if err != nil || name == "-" {
+		return err
+	}
+	ar, ok := renderer.(AssetRenderer)
+	if !ok {
+		return nil
+	}
+	assets, err := ar.Assets(r)
+	if err != nil {
+		return err
+	}
+	// Assets are compressed like the report but never minified.
+	assetOpts := outputOptions{compress: opts.compress}
+	base, suffix := name, ""
+	if opts.compress == "gzip" {
+		base, suffix = strings.TrimSuffix(name, ".gz"), ".gz"
+	}
+	for _, a := range assets {
+		w, closeOutput, err := createOutput(strings.TrimSuffix(base, filepath.Ext(base))+a.Ext+suffix, assetOpts)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(a.Data)
+		if cerr := closeOutput(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type jsonRenderer struct{}
+
+func (jsonRenderer) Name() string             { return "json" }
+func (jsonRenderer) DefaultExtension() string { return "json" }
+
+func (jsonRenderer) Render(w io.Writer, r *Report) error {
+	return writeJSONReport(w, r.jsonReport())
+}
+
+// jsonReport returns the document written by the JSON renderer.
+func (r *Report) jsonReport() jsonReport {
+	inputs := r.InputStats
+	for i := range inputs {
+		inputs[i].SHA256 = r.Inputs[i].SHA256
+	}
+	var leaves []LeafEntry
+	if r.AllLeaves {
+		// Decoded documents always encode, so this cannot fail.
+		leaves, _ = r.leafEntries()
+	}
+	return jsonReport{
+		Version:      jsonReportVersion,
+		Original:     r.Original,
+		Modified:     r.Modified,
+		Include:      r.Include,
+		Inputs:       inputs,
+		Total:        r.Total,
+		Sections:     r.Sections,
+		Acknowledged: r.Acknowledged,
+		Metadata:     r.Metadata,
+		Aggregates:   r.Aggregates,
+		Suppressed:   r.Suppressed,
+		Redacted:     r.Redacted,
+		Arrays:       r.Arrays,
+		Reordered:    r.Reordered,
+		Leaves:       leaves,
+	}
+}
+
+type csvRenderer struct{}
+
+func (csvRenderer) Name() string             { return "csv" }
+func (csvRenderer) DefaultExtension() string { return "csv" }
+
+func (csvRenderer) Render(w io.Writer, r *Report) error {
+	return writeCSVReport(w, r.Sections)
+}
@@ -0,0 +1,396 @@
+package jsondiff
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fixtureReport returns the report of a small comparison covering every
+// change type, built as runDiff builds it.
+func fixtureReport(t *testing.T) *Report {
+	t.Helper()
+	return reportFor(t,
+		`{"name": "svc", "replicas": 2, "ports": [80, 443], "old": {"x": 1}, "tags": ["a", "b"]}`,
+		`{"name": "svc", "replicas": 3, "ports": [80, 8443], "new": true, "tags": ["a", "b"]}`)
+}
+
+// reportFor returns the report comparing the JSON documents docA and docB.
+func reportFor(t *testing.T, docA, docB string) *Report {
+	t.Helper()
+	a, b := mustDecode(t, docA), mustDecode(t, docB)
+	changes, err := collectChanges(diffSeq(context.Background(), a, b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffMap := buildDiffMap(changes, a, b)
+	results := buildDiffTable(changes, a, b)
+	render := renderContext{diffMap: diffMap}
+	render.ordinals = nodeOrdinals(a, b, render)
+	render.nodeIDs = nodeIDs(b, render)
+	setTargets(results, render.nodeIDs)
+	return &Report{
+		Original:   "a.json",
+		Modified:   "b.json",
+		LabelA:     "a.json",
+		LabelB:     "b.json",
+		MaxNodes:   defaultGraphMaxNodes,
+		A:          a,
+		B:          b,
+		Total:      len(results),
+		Legend:     buildLegend(results),
+		TOC:        buildTOC(a, b, render),
+		HeatMap:    buildHeatMap(results, render.ordinals, render.showGhosts),
+		Sections:   buildSections(results, 0),
+		InputStats: [2]InputStats{documentStats(a), documentStats(b)},
+		Similarity: similarity(a, b, diffMap),
+		render:     render,
+	}
+}
+
+// wellFormed checks the output of the named registered renderer.
+var wellFormed = map[string]func(t *testing.T, out string){
+	"html": func(t *testing.T, out string) {
+		if !strings.HasPrefix(out, "<!DOCTYPE html>") || !strings.HasSuffix(strings.TrimSpace(out), "</html>") {
+			t.Error("not a complete HTML document")
+		}
+		checkHTMLStructure(t, out)
+	},
+	"fragment": func(t *testing.T, out string) {
+		if strings.Contains(out, "<html") {
+			t.Error("fragment contains a whole document")
+		}
+	},
+	"json": func(t *testing.T, out string) {
+		if !json.Valid([]byte(out)) {
+			t.Error("invalid JSON")
+		}
+		if err := validateOutput("json", json.RawMessage(out)); err != nil {
+			t.Errorf("does not match its schema: %v", err)
+		}
+	},
+	"jsonl": func(t *testing.T, out string) {
+		for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+			if !json.Valid([]byte(line)) {
+				t.Errorf("invalid JSON line %s", line)
+			}
+		}
+	},
+	"csv": func(t *testing.T, out string) {
+		rows, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 5 {
+			t.Errorf("%d rows, want a header and 4 changes", len(rows))
+		}
+	},
+	"dot": func(t *testing.T, out string) {
+		if !strings.HasPrefix(out, "digraph differ {\n") || !strings.HasSuffix(out, "}\n") {
+			t.Error("not a digraph")
+		}
+		if strings.Count(out, "{") != strings.Count(out, "}") {
+			t.Error("unbalanced braces")
+		}
+	},
+}
+
+func TestRenderers(t *testing.T) {
+	r := fixtureReport(t)
+	for _, name := range rendererNames() {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := renderers[name].Render(&buf, r); err != nil {
+				t.Fatal(err)
+			}
+			if buf.Len() == 0 {
+				t.Fatal("empty output")
+			}
+			check, ok := wellFormed[name]
+			if !ok {
+				t.Fatalf("no well-formedness check for renderer %q", name)
+			}
+			check(t, buf.String())
+		})
+	}
+}
+
+// upperRenderer is a custom renderer listing the changed paths in upper
+// case, registered by TestMain's command line too (see init below).
+type upperRenderer struct{}
+
+func (upperRenderer) Name() string             { return "upper-paths" }
+func (upperRenderer) DefaultExtension() string { return "txt" }
+
+func (upperRenderer) Render(w io.Writer, r *Report) error {
+	for _, s := range r.Sections {
+		for _, c := range s.Changes {
+			if _, err := io.WriteString(w, strings.ToUpper(c.Path)+"\n"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func init() {
+	RegisterRenderer(upperRenderer{})
+	wellFormed["upper-paths"] = func(t *testing.T, out string) {
+		if out != "NEW\nOLD\nPORTS[1]\nREPLICAS\n" {
+			t.Errorf("output %q", out)
+		}
+	}
+}
+
+func TestRegisterRendererTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a name twice did not panic")
+		}
+	}()
+	RegisterRenderer(upperRenderer{})
+}
+
+// TestCustomRendererFromCLI checks that a renderer registered before Run is
+// listed by --format list and selected by --format.
+func TestCustomRendererFromCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"a": 1, "b": 2}`,
+		"b.json": `{"a": 1, "b": 3, "c": 4}`,
+	})
+	res := runCLI(t, dir, "--format", "list")
+	if !strings.Contains(res.stdout, "upper-paths\t.txt\n") {
+		t.Errorf("--format list does not list the custom renderer:\n%s", res.stdout)
+	}
+	res = runCLI(t, dir, "-f", "upper-paths", "a.json", "b.json")
+	if res.exit != exitChangesFound && res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	if got := readFile(t, dir, "diff.txt"); got != "B\nC\n" {
+		t.Errorf("output %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "diff.html")); err == nil {
+		t.Error("the HTML report was written as well")
+	}
+}
+
+func TestParseReportTemplateErrors(t *testing.T) {
+	write := func(name, text string) string {
+		dir := t.TempDir()
+		if name != "" {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return dir
+	}
+	tests := []struct {
+		name string
+		dir  string
+		want string
+	}{
+		{"no partials", write("", ""), "holds no *.html partials"},
+		{"bad syntax", write("bad.html", `{{define "tree"}}{{if}}{{end}}`), "missing value for if"},
+		{"newer API", write("new.html", `{{/* differ:template-api 99 */}}`), "targets template API v99"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseReportTemplate(nil, nil, renderContext{templateDir: tt.dir})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	dir := write("table.html", `{{define "diff-table"}}custom table{{end}}`)
+	r := fixtureReport(t)
+	r.render.templateDir = dir
+	var buf bytes.Buffer
+	if err := r.RenderTableHTML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "custom table") {
+		t.Errorf("partial from the template directory not used: %s", buf.String())
+	}
+}
+
+// htmlTag matches a start or end tag, a comment or a doctype.
+var htmlTag = regexp.MustCompile(`(?s)<!--.*?-->|<!DOCTYPE[^>]*>|<(/?)([a-zA-Z][a-zA-Z0-9-]*)((?:\s+[^\s=/>]+(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'>]+))?)*)\s*(/?)>`)
+
+// htmlAttr matches one attribute of a tag matched by htmlTag.
+var htmlAttr = regexp.MustCompile(`([^\s=/>]+)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// htmlElement is an element open while checkHTMLStructure walks a document.
+type htmlElement struct {
+	tag   string
+	attrs map[string]string
+	// treeitems counts the tree items among the element and its ancestors.
+	treeitems int
+	// group is set on a tree item once a group is opened inside it.
+	group bool
+	// focusable counts the items with tabindex 0 inside a tree.
+	focusable int
+}
+
+// checkHTMLStructure walks the tags of a report without an HTML parser and
+// checks that they nest, that ids are unique and that the panes are
+// well-formed ARIA trees: tree items are list items of a tree or group, at
+// the aria-level of their nesting, only those owning a group are
+// expandable, and each tree has a single item in the tab order.
+func checkHTMLStructure(t *testing.T, out string) {
+	t.Helper()
+	var stack []*htmlElement
+	ids := make(map[string]bool)
+	errorf := func(offset int, format string, args ...interface{}) {
+		t.Helper()
+		t.Errorf("at byte %d: %s", offset, fmt.Sprintf(format, args...))
+	}
+	closeElement := func(e *htmlElement, offset int) {
+		t.Helper()
+		if e.attrs["role"] == "tree" && e.focusable != 1 {
+			errorf(offset, "tree with %d items in the tab order", e.focusable)
+		}
+		if e.attrs["role"] == "treeitem" {
+			if _, ok := e.attrs["aria-expanded"]; ok != e.group {
+				errorf(offset, "tree item with aria-expanded %t owns a group %t", ok, e.group)
+			}
+		}
+	}
+	for pos := 0; ; {
+		loc := htmlTag.FindStringSubmatchIndex(out[pos:])
+		if loc == nil {
+			break
+		}
+		base, start := pos, pos+loc[0]
+		m := func(i int) string {
+			if loc[2*i] < 0 {
+				return ""
+			}
+			return out[base+loc[2*i] : base+loc[2*i+1]]
+		}
+		pos += loc[1]
+		tag := strings.ToLower(m(2))
+		if tag == "" {
+			continue
+		}
+		if m(1) == "/" {
+			if len(stack) == 0 || stack[len(stack)-1].tag != tag {
+				var open []string
+				for _, e := range stack {
+					open = append(open, e.tag)
+				}
+				t.Fatalf("at byte %d: </%s> closes %q", start, tag, open)
+			}
+			closeElement(stack[len(stack)-1], start)
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		e := &htmlElement{tag: tag, attrs: make(map[string]string)}
+		for _, a := range htmlAttr.FindAllStringSubmatch(m(3), -1) {
+			e.attrs[strings.ToLower(a[1])] = a[2] + a[3] + a[4]
+		}
+		if id, ok := e.attrs["id"]; ok {
+			if ids[id] {
+				errorf(start, "duplicate id %q", id)
+			}
+			ids[id] = true
+		}
+		var parent *htmlElement
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1]
+			e.treeitems = parent.treeitems
+		}
+		switch e.attrs["role"] {
+		case "treeitem":
+			e.treeitems++
+			if tag != "li" || parent == nil || parent.tag != "ul" || parent.attrs["role"] != "tree" && parent.attrs["role"] != "group" {
+				errorf(start, "tree item <%s> outside a tree or group list", tag)
+			}
+			if level := e.attrs["aria-level"]; level != strconv.Itoa(e.treeitems) {
+				errorf(start, "tree item at depth %d has aria-level %s", e.treeitems, level)
+			}
+			if e.attrs["tabindex"] == "0" {
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i].attrs["role"] == "tree" {
+						stack[i].focusable++
+						break
+					}
+				}
+			}
+		case "group":
+			if tag == "ul" {
+				owner := -1
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i].attrs["role"] == "treeitem" {
+						owner = i
+						break
+					}
+				}
+				if owner < 0 {
+					errorf(start, "group list outside a tree item")
+				} else {
+					stack[owner].group = true
+				}
+			}
+		}
+
+		if voidElements[tag] || m(4) == "/" {
+			continue
+		}
+		if tag == "script" || tag == "style" {
+			end := strings.Index(out[pos:], "</"+tag+">")
+			if end < 0 {
+				t.Fatalf("at byte %d: <%s> is never closed", start, tag)
+			}
+			pos += end + len("</"+tag+">")
+			continue
+		}
+		stack = append(stack, e)
+	}
+	if len(stack) > 0 {
+		t.Errorf("%d elements left open, the first <%s>", len(stack), stack[0].tag)
+	}
+}
+
+// TestHTMLStructure checks the structure of reports rendered with the
+// options that change the trees: folded gaps, ghosts, editing, deferred
+// subtrees and all of them at once.
+func TestHTMLStructure(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"name": "svc", "ports": [80, 443, 8080], "nested": {"deep": {"x": [1, {"y": 2}], "z": {"k": [1]}}}, "old": {"x": [1, {"y": 1}]}, "list": [0, 1, {"a": [1, 2]}, 3, 4, 5, 6, 7, 8, 9]}`,
+		"b.json": `{"name": "app", "ports": [80, 8443], "nested": {"deep": {"x": [1, {"y": 3}], "z": {"k": [1]}}}, "new": [true, {"n": [1]}], "list": [0, 1, {"a": [1, 2]}, 3, 4, 5, 6, 7, 8, 10]}`,
+	})
+	flagSets := [][]string{
+		nil,
+		{"--array-context", "1"},
+		{"--show-ghosts"},
+		{"--editable"},
+		{"--lazy-depth", "2"},
+		{"--array-context", "1", "--show-ghosts", "--editable", "--lazy-depth", "3", "--line-numbers"},
+	}
+	for _, flags := range flagSets {
+		t.Run(strings.Join(flags, " "), func(t *testing.T) {
+			res := runCLI(t, dir, append(append([]string{"-o", "out.html"}, flags...), "a.json", "b.json")...)
+			if res.exit != exitOK {
+				t.Fatalf("exit %d: %s", res.exit, res.stderr)
+			}
+			out := readFile(t, dir, "out.html")
+			wellFormed["html"](t, expandLazy(t, out))
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package jsondiff
+
+import (
+	"sort"
+	"strings"
+)
+
+// kindReordered is the --fail-on kind matching objects reported by
+// --detect-key-reorder.
+const kindReordered = "reordered"
+
+// KeyReorder is an object whose shared members appear in a different order
+// in the two documents. JSON objects are unordered, so a reorder is not a
+// change; it is only reported when --detect-key-reorder asks for it.
+type KeyReorder struct {
+	Path string `json:"path"`
+	// From and To list the members both documents have, in source order.
+	From []string `json:"from"`
+	To   []string `json:"to"`
+}
+
+// DisplayPath returns the object's path, naming the root explicitly.
+func (r KeyReorder) DisplayPath() string {
+	if r.Path == "" {
+		return rootSectionName
+	}
+	return r.Path
+}
+
+func (r KeyReorder) FromText() string { return strings.Join(r.From, ", ") }
+func (r KeyReorder) ToText() string   { return strings.Join(r.To, ", ") }
+
+// detectKeyReorders compares the member order of every object present in
+// both a and b, reading the order from the source offsets in posA and posB.
+// Only members common to both objects are compared, so additions and
+// removals alone never count as a reorder. Without positions for both
+// documents nothing is reported.
+func detectKeyReorders(a, b interface{}, posA, posB map[string]Position) []KeyReorder {
+	if posA == nil || posB == nil {
+		return nil
+	}
+	var out []KeyReorder
+	var walk func(a, b interface{}, path string)
+	walk = func(a, b interface{}, path string) {
+		switch va := a.(type) {
+		case map[string]interface{}:
+			vb, ok := b.(map[string]interface{})
+			if !ok {
+				return
+			}
+			var common []string
+			for _, k := range sortedKeys(va) {
+				if _, ok := vb[k]; ok {
+					common = append(common, k)
+				}
+			}
+			from, okA := memberOrder(common, path, posA)
+			to, okB := memberOrder(common, path, posB)
+			if okA && okB && strings.Join(from, "\x00") != strings.Join(to, "\x00") {
+				out = append(out, KeyReorder{Path: path, From: from, To: to})
+			}
+			for _, k := range common {
+				walk(va[k], vb[k], pathKey(path, k))
+			}
+		case []interface{}:
+			vb, ok := b.([]interface{})
+			if !ok {
+				return
+			}
+			for i := 0; i < len(va) && i < len(vb); i++ {
+				walk(va[i], vb[i], indexKey(path, i))
+			}
+		}
+	}
+	walk(a, b, "")
+	return out
+}
+
+// memberOrder returns keys, members of the object at path, sorted by their
+// offset in positions. It fails if any member's position is unknown.
+func memberOrder(keys []string, path string, positions map[string]Position) ([]string, bool) {
+	offsets := make(map[string]int64, len(keys))
+	for _, k := range keys {
+		pos, ok := positions[pathKey(path, k)]
+		if !ok {
+			return nil, false
+		}
+		offsets[k] = pos.Offset
+	}
+	ordered := append([]string(nil), keys...)
+	sort.SliceStable(ordered, func(i, j int) bool { return offsets[ordered[i]] < offsets[ordered[j]] })
+	return ordered, true
+}
+
+// reorderSet indexes reorders by path for the tree badges.
+func reorderSet(reorders []KeyReorder) map[string]KeyReorder {
+	if len(reorders) == 0 {
+		return nil
+	}
+	m := make(map[string]KeyReorder, len(reorders))
+	for _, r := range reorders {
+		m[r.Path] = r
+	}
+	return m
+}
+
+// reorderBadge marks the object at path when its members were reordered.
+func (ctx *renderContext) reorderBadge(path string) string {
+	r, ok := ctx.reordered[path]
+	if !ok {
+		return ""
+	}
+	return `<span class="` + ctx.cls("badge reordered") + `"` + attr("title", "Members reordered: "+r.FromText()+" → "+r.ToText()) + `>reordered</span>`
+}
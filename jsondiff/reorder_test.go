@@ -0,0 +1,51 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestKeyReorderCLI checks that only the order of members both objects
+// share is compared, at every depth, and that a reorder alone is not a
+// change for --fail-on unless it asks for reorders.
+func TestKeyReorderCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"name": "svc", "spec": {"b": 1, "a": 2, "gone": 0}, "list": [{"x": 1, "y": 2}], "same": {"p": 1, "q": 2}}`,
+		"b.json": `{"spec": {"new": 0, "a": 2, "b": 1}, "name": "svc", "list": [{"y": 2, "x": 1}], "same": {"p": 1, "r": 0, "q": 2}}`,
+		"c.json": `{"b": 1, "a": 2}`,
+		"d.json": `{"a": 2, "b": 1}`,
+	})
+	// spec and same also gain and lose members, which are changes.
+	res := runCLI(t, dir, "--detect-key-reorder", "-f", "json", "-o", "-", "a.json", "b.json")
+	if res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	var report jsonReport
+	if err := json.Unmarshal([]byte(res.stdout), &report); err != nil {
+		t.Fatal(err)
+	}
+	want := []KeyReorder{
+		{Path: "", From: []string{"name", "spec", "list", "same"}, To: []string{"spec", "name", "list", "same"}},
+		{Path: "list[0]", From: []string{"x", "y"}, To: []string{"y", "x"}},
+		{Path: "spec", From: []string{"b", "a"}, To: []string{"a", "b"}},
+	}
+	if !reflect.DeepEqual(report.Reordered, want) {
+		t.Errorf("reordered %+v, want %+v", report.Reordered, want)
+	}
+
+	for _, tt := range []struct {
+		failOn string
+		exit   int
+	}{{"changed", exitOK}, {"reordered", exitChangesFound}} {
+		res = runCLI(t, dir, "--detect-key-reorder", "--fail-on", tt.failOn, "-o", "out.html", "c.json", "d.json")
+		if res.exit != tt.exit {
+			t.Errorf("--fail-on %s: exit %d, want %d: %s", tt.failOn, res.exit, tt.exit, res.stderr)
+		}
+	}
+	html := readFile(t, dir, "out.html")
+	if !strings.Contains(html, `title="Members reordered: b, a → a, b">reordered</span>`) {
+		t.Error("no reorder badge on the root")
+	}
+}
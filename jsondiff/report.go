@@ -0,0 +1,178 @@
+package jsondiff
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DiffSection groups the changes that share a top-level path segment.
+type DiffSection struct {
+	Name      string       `json:"name"`
+	Anchor    string       `json:"-"`
+	Count     int          `json:"count"`
+	Collapsed bool         `json:"-"`
+	Changes   []DiffResult `json:"changes"`
+}
+
+const rootSectionName = "(root)"
+
+// buildSections groups results by their first path segment, sorted by
+// section name and keeping the order of results within a section. Sections with more than
+// collapseThreshold changes are marked collapsed; a threshold of 0 never
+// collapses.
+func buildSections(results []DiffResult, collapseThreshold int) []DiffSection {
+	byName := make(map[string][]DiffResult)
+	for _, r := range results {
+		name := topLevelKey(r.Path)
+		byName[name] = append(byName[name], r)
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sections := make([]DiffSection, 0, len(names))
+	for _, name := range names {
+		changes := byName[name]
+		sections = append(sections, DiffSection{
+			Name:      name,
+			Anchor:    anchorID("section-", name),
+			Count:     len(changes),
+			Collapsed: collapseThreshold > 0 && len(changes) > collapseThreshold,
+			Changes:   changes,
+		})
+	}
+	return sections
+}
+
+func topLevelKey(path string) string {
+	if path == "" {
+		return rootSectionName
+	}
+	return splitPath(path)[0]
+}
+
+// jsonReportVersion is bumped whenever a field of jsonReport changes
+// meaning or is removed; adding fields keeps the version.
+const jsonReportVersion = 1
+
+// jsonReport is the document written by --format json. Sections are sorted
+// by name and every change carries its stable ID, so reports of the same
+// inputs are identical and reports of different runs can be compared with
+// diff-of-diffs.
+type jsonReport struct {
+	Version  int    `json:"version"`
+	Original string `json:"original"`
+	Modified string `json:"modified"`
+	// Include lists the --include patterns; changes elsewhere were not
+	// compared.
+	Include  []string      `json:"include,omitempty"`
+	Inputs   [2]InputStats `json:"inputs"`
+	Total    int           `json:"total"`
+	Sections []DiffSection `json:"sections"`
+
+	Acknowledged []DiffResult  `json:"acknowledged,omitempty"`
+	Metadata     []DiffResult  `json:"metadata,omitempty"`
+	Aggregates   []Aggregate   `json:"aggregates,omitempty"`
+	Suppressed   []Suppression `json:"suppressed,omitempty"`
+	Redacted     []Redaction   `json:"redacted,omitempty"`
+	Arrays       []ArrayStats  `json:"arrays,omitempty"`
+	Reordered    []KeyReorder  `json:"reordered,omitempty"`
+	// Leaves lists every leaf of both documents, with
+	// --json-include-unchanged.
+	Leaves []LeafEntry `json:"leaves,omitempty"`
+}
+
+func writeCSVReport(w io.Writer, sections []DiffSection) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "path", "pointer", "type", "from", "to", "delta", "delta_percent"})
+	for _, sec := range sections {
+		for _, r := range sec.Changes {
+			var delta, pct string
+			if r.Delta != nil {
+				delta = strconv.FormatFloat(*r.Delta, 'f', -1, 64)
+			}
+			if r.DeltaPercent != nil {
+				pct = strconv.FormatFloat(*r.DeltaPercent, 'f', -1, 64)
+			}
+			cw.Write([]string{r.ID, r.Path, r.Pointer, r.Type, r.From, r.To, delta, pct})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeJSONReport(w io.Writer, r jsonReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// diffSummary is the one-line overview printed by --summary-only.
+type diffSummary struct {
+	Added      int
+	Removed    int
+	Changed    int
+	Similarity float64
+	// Include, if set, is printed so that no changes within the --include
+	// scope is not mistaken for equal documents.
+	Include []string
+	// Gating is the number of changes matching --fail-on-path, when given.
+	Gating *int
+}
+
+func (s diffSummary) String() string {
+	line := fmt.Sprintf("added=%d removed=%d changed=%d similarity=%.2f", s.Added, s.Removed, s.Changed, s.Similarity)
+	if s.Gating != nil {
+		line += fmt.Sprintf(" gating=%d", *s.Gating)
+	}
+	if len(s.Include) > 0 {
+		line += " include=" + strings.Join(s.Include, ",")
+	}
+	return line
+}
+
+func summarize(results []DiffResult, similarity float64) diffSummary {
+	s := diffSummary{Similarity: similarity}
+	for _, r := range results {
+		switch r.Type {
+		case "create":
+			s.Added++
+		case "delete":
+			s.Removed++
+		case "update":
+			s.Changed++
+		}
+	}
+	return s
+}
+
+// similarity returns the fraction of leaf values, over both documents, that
+// are not at or below a change. Two empty documents are identical.
+func similarity(a, b interface{}, m *DiffMap) float64 {
+	leaves := make(map[string]bool)
+	collect := func(path []string, _ interface{}) { leaves[joinPath(path)] = true }
+	walkLeaves(a, nil, collect)
+	walkLeaves(b, nil, collect)
+	if len(leaves) == 0 {
+		return 1
+	}
+	unchanged := 0
+	for p := range leaves {
+		if _, changed := m.Lookup(p); changed {
+			continue
+		}
+		if _, _, changed := m.ChangedAncestor(p); changed {
+			continue
+		}
+		unchanged++
+	}
+	return float64(unchanged) / float64(len(leaves))
+}
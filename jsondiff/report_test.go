@@ -0,0 +1,121 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestBuildSections(t *testing.T) {
+	results := []DiffResult{
+		{Path: "spec.b", Type: "changed"},
+		{Path: "name", Type: "changed"},
+		{Path: "spec.a", Type: "added"},
+		{Path: "", Type: "changed"},
+		{Path: "spec", Type: "removed"},
+	}
+	type section struct {
+		name, anchor string
+		paths        []string
+		collapsed    bool
+	}
+	summarize := func(sections []DiffSection) []section {
+		var got []section
+		for _, s := range sections {
+			var paths []string
+			for _, r := range s.Changes {
+				paths = append(paths, r.Path)
+			}
+			if s.Count != len(s.Changes) {
+				t.Errorf("%s: count %d, %d changes", s.Name, s.Count, len(s.Changes))
+			}
+			got = append(got, section{s.Name, s.Anchor, paths, s.Collapsed})
+		}
+		return got
+	}
+
+	// Sections are sorted by name and keep the order of their changes.
+	want := []section{
+		{"(root)", "section-(root)", []string{""}, false},
+		{"name", "section-name", []string{"name"}, false},
+		{"spec", "section-spec", []string{"spec.b", "spec.a", "spec"}, false},
+	}
+	if got := summarize(buildSections(results, 0)); !reflect.DeepEqual(got, want) {
+		t.Errorf("threshold 0:\n%+v\nwant\n%+v", got, want)
+	}
+	want[2].collapsed = true
+	if got := summarize(buildSections(results, 2)); !reflect.DeepEqual(got, want) {
+		t.Errorf("threshold 2:\n%+v\nwant\n%+v", got, want)
+	}
+	want[2].collapsed = false
+	if got := summarize(buildSections(results, 3)); !reflect.DeepEqual(got, want) {
+		t.Errorf("threshold 3:\n%+v\nwant\n%+v", got, want)
+	}
+	if got := buildSections(nil, 0); len(got) != 0 {
+		t.Errorf("no results: %+v", got)
+	}
+}
+
+// TestSectionsCLI checks that sections over --collapse-threshold start closed
+// in the HTML report, and that --format json writes the same sections to
+// diff.json by default.
+func TestSectionsCLI(t *testing.T) {
+	var big []string
+	for i := 0; i < 4; i++ {
+		big = append(big, fmt.Sprintf(`"k%d": %d`, i, i))
+	}
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"big": {` + strings.Join(big, ", ") + `}, "small": 1}`,
+		"b.json": `{"big": {}, "small": 2}`,
+	})
+
+	open := regexp.MustCompile(`<details class="[^"]*diff-section[^"]*" id="(section-[a-z]+)"( open)?>`)
+	for _, tt := range []struct {
+		threshold string
+		want      map[string]bool
+	}{
+		{"0", map[string]bool{"section-big": true, "section-small": true}},
+		{"3", map[string]bool{"section-big": false, "section-small": true}},
+		{"4", map[string]bool{"section-big": true, "section-small": true}},
+	} {
+		if res := runCLI(t, dir, "--collapse-threshold", tt.threshold, "-o", "out.html", "a.json", "b.json"); res.exit != exitOK {
+			t.Fatalf("exit %d: %s", res.exit, res.stderr)
+		}
+		got := make(map[string]bool)
+		for _, m := range open.FindAllStringSubmatch(readFile(t, dir, "out.html"), -1) {
+			got[m[1]] = m[2] != ""
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("threshold %s: open %v, want %v", tt.threshold, got, tt.want)
+		}
+	}
+
+	if res := runCLI(t, dir, "--format", "json", "a.json", "b.json"); res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "diff.html")); err == nil {
+		t.Error("--format json wrote diff.html")
+	}
+	var report jsonReport
+	if err := json.Unmarshal([]byte(readFile(t, dir, "diff.json")), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Version != jsonReportVersion || report.Total != 5 || len(report.Sections) != 2 {
+		t.Fatalf("report %+v", report)
+	}
+	if s := report.Sections[0]; s.Name != "big" || s.Count != 4 || s.Changes[0].Path != "big.k0" || s.Changes[0].Type != "delete" {
+		t.Errorf("big: %+v", s)
+	}
+	if s := report.Sections[1]; s.Name != "small" || s.Count != 1 || s.Changes[0].From != "1" || s.Changes[0].To != "2" {
+		t.Errorf("small: %+v", s)
+	}
+
+	if res := runCLI(t, dir, "--format", "xml", "a.json", "b.json"); res.exit != exitError || !strings.Contains(res.stderr, `invalid --format "xml"`) {
+		t.Errorf("--format xml: exit %d: %s", res.exit, res.stderr)
+	}
+}
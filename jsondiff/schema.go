@@ -0,0 +1,448 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a parsed JSON Schema document. Only the commonly used subset of
+// the draft 2020-12 / draft-07 vocabulary is understood: type, enum, const,
+// properties, required, additionalProperties, items, length and range
+// bounds, pattern, allOf/anyOf/oneOf/not and local $ref pointers.
+type Schema struct {
+	root interface{}
+}
+
+// SchemaViolation describes a single place where a document fails its schema.
+type SchemaViolation struct {
+	Path    string
+	Message string
+}
+
+func (v SchemaViolation) String() string {
+	if v.Path == "" {
+		return "(root): " + v.Message
+	}
+	return v.Path + ": " + v.Message
+}
+
+func loadSchema(filename string) (*Schema, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	root, err := parseJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+	if _, ok := root.(map[string]interface{}); !ok {
+		if _, ok := root.(bool); !ok {
+			return nil, fmt.Errorf("invalid schema: must be an object or boolean")
+		}
+	}
+	return &Schema{root: root}, nil
+}
+
+// Validate checks doc against the schema and returns every violation found.
+func (s *Schema) Validate(doc interface{}) []SchemaViolation {
+	var out []SchemaViolation
+	s.validate(s.root, doc, "", &out, 0)
+	return out
+}
+
+// NodeAt returns the schema object that describes the value at path, following
+// $ref, properties, additionalProperties, items and allOf branches. It returns
+// nil when the schema says nothing about that location.
+func (s *Schema) NodeAt(path []string) map[string]interface{} {
+	return s.nodeAt(s.root, path, 0)
+}
+
+func (s *Schema) nodeAt(schema interface{}, path []string, depth int) map[string]interface{} {
+	sc, ok := schema.(map[string]interface{})
+	if !ok || depth > maxSchemaRefDepth {
+		return nil
+	}
+	if ref, ok := sc["$ref"].(string); ok {
+		if target, found := s.resolveRef(ref); found {
+			if len(path) == 0 {
+				// Keep annotations from the referring node, falling back to the target's.
+				if node := s.nodeAt(target, nil, depth+1); node != nil {
+					if _, hasTitle := sc["title"]; !hasTitle {
+						if _, hasDesc := sc["description"]; !hasDesc {
+							return node
+						}
+					}
+				}
+				return sc
+			}
+			if node := s.nodeAt(target, path, depth+1); node != nil {
+				return node
+			}
+		}
+	}
+	if len(path) == 0 {
+		return sc
+	}
+
+	seg, rest := path[0], path[1:]
+	if props, ok := sc["properties"].(map[string]interface{}); ok {
+		if ps, ok := props[seg]; ok {
+			if node := s.nodeAt(ps, rest, depth); node != nil {
+				return node
+			}
+		}
+	}
+	if idx, ok := parseIndexSegment(seg); ok {
+		switch items := sc["items"].(type) {
+		case []interface{}:
+			if idx >= 0 && idx < len(items) {
+				if node := s.nodeAt(items[idx], rest, depth); node != nil {
+					return node
+				}
+			}
+		case map[string]interface{}:
+			if node := s.nodeAt(items, rest, depth); node != nil {
+				return node
+			}
+		}
+	}
+	if ap, ok := sc["additionalProperties"].(map[string]interface{}); ok {
+		if node := s.nodeAt(ap, rest, depth); node != nil {
+			return node
+		}
+	}
+	if all, ok := sc["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			if node := s.nodeAt(sub, path, depth+1); node != nil {
+				return node
+			}
+		}
+	}
+	return nil
+}
+
+// resolveRef follows a local "#/..." JSON Pointer reference within the schema.
+func (s *Schema) resolveRef(ref string) (interface{}, bool) {
+	if ref == "#" {
+		return s.root, true
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, false
+	}
+	node := s.root
+	for _, tok := range strings.Split(ref[2:], "/") {
+		tok = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+		switch n := node.(type) {
+		case map[string]interface{}:
+			next, ok := n[tok]
+			if !ok {
+				return nil, false
+			}
+			node = next
+		case []interface{}:
+			i, err := strconv.Atoi(tok)
+			if err != nil || i < 0 || i >= len(n) {
+				return nil, false
+			}
+			node = n[i]
+		default:
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+const maxSchemaRefDepth = 64
+
+func (s *Schema) validate(schema, v interface{}, path string, out *[]SchemaViolation, depth int) {
+	if depth > maxSchemaRefDepth {
+		*out = append(*out, SchemaViolation{path, "schema $ref recursion too deep"})
+		return
+	}
+	switch sc := schema.(type) {
+	case bool:
+		if !sc {
+			*out = append(*out, SchemaViolation{path, "no value is allowed here"})
+		}
+		return
+	case map[string]interface{}:
+		s.validateObject(sc, v, path, out, depth)
+	}
+}
+
+func (s *Schema) validateObject(sc map[string]interface{}, v interface{}, path string, out *[]SchemaViolation, depth int) {
+	add := func(format string, args ...interface{}) {
+		*out = append(*out, SchemaViolation{path, fmt.Sprintf(format, args...)})
+	}
+	// Numbers kept exact by --decimal-strict are checked as float64.
+	if n, ok := v.(json.Number); ok {
+		v, _ = numericValue(n)
+	}
+
+	if ref, ok := sc["$ref"].(string); ok {
+		target, found := s.resolveRef(ref)
+		if !found {
+			add("unresolvable $ref %q", ref)
+		} else {
+			s.validate(target, v, path, out, depth+1)
+		}
+	}
+
+	if t, ok := sc["type"]; ok && !matchesSchemaType(t, v) {
+		add("expected type %s, got %s", describeSchemaType(t), jsonTypeName(v))
+		return
+	}
+
+	if enum, ok := sc["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if reflect.DeepEqual(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			add("value is not one of the allowed enum values")
+		}
+	}
+	if c, ok := sc["const"]; ok && !reflect.DeepEqual(c, v) {
+		add("value does not equal the required const")
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if req, ok := sc["required"].([]interface{}); ok {
+			for _, r := range req {
+				if name, ok := r.(string); ok {
+					if _, present := val[name]; !present {
+						*out = append(*out, SchemaViolation{pathKey(path, name), "required property is missing"})
+					}
+				}
+			}
+		}
+		props, _ := sc["properties"].(map[string]interface{})
+		for _, k := range sortedKeys(val) {
+			p := pathKey(path, k)
+			if ps, ok := props[k]; ok {
+				s.validate(ps, val[k], p, out, depth)
+				continue
+			}
+			if ap, ok := sc["additionalProperties"]; ok {
+				if b, isBool := ap.(bool); isBool && !b {
+					*out = append(*out, SchemaViolation{p, "additional property is not allowed"})
+				} else {
+					s.validate(ap, val[k], p, out, depth)
+				}
+			}
+		}
+		checkCount(sc, "minProperties", "maxProperties", len(val), "properties", add)
+
+	case []interface{}:
+		if items, ok := sc["items"]; ok {
+			if tuple, isTuple := items.([]interface{}); isTuple {
+				for i, ts := range tuple {
+					if i < len(val) {
+						s.validate(ts, val[i], indexKey(path, i), out, depth)
+					}
+				}
+			} else {
+				for i, vv := range val {
+					s.validate(items, vv, indexKey(path, i), out, depth)
+				}
+			}
+		}
+		checkCount(sc, "minItems", "maxItems", len(val), "items", add)
+
+	case string:
+		checkCount(sc, "minLength", "maxLength", utf8.RuneCountInString(val), "characters", add)
+		if pat, ok := sc["pattern"].(string); ok {
+			re, err := regexp.Compile(pat)
+			if err != nil {
+				add("invalid pattern %q in schema", pat)
+			} else if !re.MatchString(val) {
+				add("value %q does not match pattern %q", val, pat)
+			}
+		}
+
+	case float64:
+		if min, ok := sc["minimum"].(float64); ok && val < min {
+			add("value %v is less than minimum %v", val, min)
+		}
+		if max, ok := sc["maximum"].(float64); ok && val > max {
+			add("value %v is greater than maximum %v", val, max)
+		}
+		if min, ok := sc["exclusiveMinimum"].(float64); ok && val <= min {
+			add("value %v must be greater than %v", val, min)
+		}
+		if max, ok := sc["exclusiveMaximum"].(float64); ok && val >= max {
+			add("value %v must be less than %v", val, max)
+		}
+		if m, ok := sc["multipleOf"].(float64); ok && m > 0 {
+			if q := val / m; math.Abs(q-math.Round(q)) > 1e-9 {
+				add("value %v is not a multiple of %v", val, m)
+			}
+		}
+	}
+
+	if all, ok := sc["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			s.validate(sub, v, path, out, depth+1)
+		}
+	}
+	if anyOf, ok := sc["anyOf"].([]interface{}); ok {
+		if s.countMatching(anyOf, v, path, depth) == 0 {
+			add("value does not match any schema in anyOf")
+		}
+	}
+	if one, ok := sc["oneOf"].([]interface{}); ok {
+		if n := s.countMatching(one, v, path, depth); n != 1 {
+			add("value matches %d schemas in oneOf, expected exactly 1", n)
+		}
+	}
+	if not, ok := sc["not"]; ok {
+		var sub []SchemaViolation
+		s.validate(not, v, path, &sub, depth+1)
+		if len(sub) == 0 {
+			add("value must not match the schema in not")
+		}
+	}
+}
+
+func (s *Schema) countMatching(schemas []interface{}, v interface{}, path string, depth int) int {
+	n := 0
+	for _, sub := range schemas {
+		var errs []SchemaViolation
+		s.validate(sub, v, path, &errs, depth+1)
+		if len(errs) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func checkCount(sc map[string]interface{}, minKey, maxKey string, n int, unit string, add func(string, ...interface{})) {
+	if min, ok := sc[minKey].(float64); ok && float64(n) < min {
+		add("has %d %s, fewer than %s %v", n, unit, minKey, min)
+	}
+	if max, ok := sc[maxKey].(float64); ok && float64(n) > max {
+		add("has %d %s, more than %s %v", n, unit, maxKey, max)
+	}
+}
+
+func matchesSchemaType(t interface{}, v interface{}) bool {
+	switch tt := t.(type) {
+	case string:
+		return matchesTypeName(tt, v)
+	case []interface{}:
+		for _, x := range tt {
+			if name, ok := x.(string); ok && matchesTypeName(name, v) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesTypeName(name string, v interface{}) bool {
+	actual := jsonTypeName(v)
+	if name == "integer" {
+		f, ok := numericValue(v)
+		return ok && f == math.Trunc(f)
+	}
+	return name == actual
+}
+
+func describeSchemaType(t interface{}) string {
+	if list, ok := t.([]interface{}); ok {
+		names := make([]string, 0, len(list))
+		for _, x := range list {
+			names = append(names, fmt.Sprint(x))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+// jsonTypeName returns the JSON Schema type name of a decoded value.
+func jsonTypeName(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64, json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// annotateSchema fills in the schema title/description for each changed path
+// and marks changes that introduce a violation into the modified document,
+// i.e. one that file2 has but file1 does not.
+func annotateSchema(results []DiffResult, s *Schema, original, modified interface{}) {
+	before := make(map[string]bool)
+	for _, v := range s.Validate(original) {
+		before[v.String()] = true
+	}
+	var introduced []SchemaViolation
+	for _, v := range s.Validate(modified) {
+		if !before[v.String()] {
+			introduced = append(introduced, v)
+		}
+	}
+
+	for i := range results {
+		r := &results[i]
+		if node := s.NodeAt(splitPath(r.Path)); node != nil {
+			r.SchemaTitle, _ = node["title"].(string)
+			r.SchemaDescription, _ = node["description"].(string)
+		}
+		for _, v := range introduced {
+			if violationTouches(v.Path, r.Path) {
+				r.SchemaErrors = append(r.SchemaErrors, v.String())
+			}
+		}
+	}
+}
+
+func countSchemaInvalid(results []DiffResult) int {
+	n := 0
+	for _, r := range results {
+		if len(r.SchemaErrors) > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// violationTouches reports whether a violation at violationPath concerns the
+// change at changePath: the same location, or one nested inside the other.
+func violationTouches(violationPath, changePath string) bool {
+	if violationPath == changePath {
+		return true
+	}
+	if violationPath == "" || changePath == "" {
+		return false
+	}
+	return isPathPrefix(changePath, violationPath) || isPathPrefix(violationPath, changePath)
+}
+
+// isPathPrefix reports whether path lies strictly below prefix.
+func isPathPrefix(prefix, path string) bool {
+	return strings.HasPrefix(path, prefix+".") || strings.HasPrefix(path, prefix+"[")
+}
@@ -0,0 +1,134 @@
+package jsondiff
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// mustSchema parses a schema document.
+func mustSchema(t *testing.T, src string) *Schema {
+	t.Helper()
+	return &Schema{root: mustDecode(t, src)}
+}
+
+const refSchema = `{
+  "definitions": {
+    "port": {"type": "integer", "title": "Port", "maximum": 65535},
+    "a/b": {"title": "Slash"},
+    "a~b": {"title": "Tilde"},
+    "list": [{"title": "First"}, {"title": "Second"}],
+    "service": {
+      "type": "object",
+      "properties": {"port": {"$ref": "#/definitions/port"}},
+      "required": ["port"]
+    }
+  },
+  "type": "object",
+  "properties": {
+    "svc": {"$ref": "#/definitions/service"},
+    "ports": {"type": "array", "items": {"$ref": "#/definitions/port"}},
+    "renamed": {"$ref": "#/definitions/port", "title": "Renamed port"},
+    "broken": {"$ref": "#/definitions/nope"}
+  }
+}`
+
+func TestResolveRef(t *testing.T) {
+	s := mustSchema(t, refSchema)
+	tests := []struct {
+		ref   string
+		title string
+		found bool
+	}{
+		{"#/definitions/port", "Port", true},
+		{"#/definitions/a~1b", "Slash", true},
+		{"#/definitions/a~0b", "Tilde", true},
+		{"#/definitions/list/1", "Second", true},
+		{"#/definitions/list/2", "", false},
+		{"#/definitions/list/-1", "", false},
+		{"#/definitions/nope", "", false},
+		{"#/definitions/port/type/x", "", false},
+		{"other.json#/definitions/port", "", false},
+	}
+	for _, tt := range tests {
+		node, found := s.resolveRef(tt.ref)
+		if found != tt.found {
+			t.Errorf("%s: found %t", tt.ref, found)
+			continue
+		}
+		if m, _ := node.(map[string]interface{}); found && m["title"] != tt.title {
+			t.Errorf("%s: resolved to %v", tt.ref, node)
+		}
+	}
+	if root, found := s.resolveRef("#"); !found || !reflect.DeepEqual(root, s.root) {
+		t.Errorf("# resolved to %v", root)
+	}
+}
+
+func TestSchemaNodeAtFollowsRefs(t *testing.T) {
+	s := mustSchema(t, refSchema)
+	tests := []struct {
+		path  string
+		title string
+	}{
+		{"svc.port", "Port"},
+		{"ports[3]", "Port"},
+		{"renamed", "Renamed port"},
+	}
+	for _, tt := range tests {
+		node := s.NodeAt(splitPath(tt.path))
+		if node == nil || node["title"] != tt.title {
+			t.Errorf("%s: node %v, want title %q", tt.path, node, tt.title)
+		}
+	}
+	for _, path := range []string{"broken.x", "svc.nope"} {
+		if node := s.NodeAt(splitPath(path)); node != nil {
+			t.Errorf("%s: node %v, want none", path, node)
+		}
+	}
+}
+
+func TestSchemaValidateRefs(t *testing.T) {
+	s := mustSchema(t, refSchema)
+	got := s.Validate(mustDecode(t, `{"svc": {}, "ports": [80, 70000], "broken": 1}`))
+	want := []SchemaViolation{
+		{"broken", `unresolvable $ref "#/definitions/nope"`},
+		{"ports[1]", "value 70000 is greater than maximum 65535"},
+		{"svc.port", "required property is missing"},
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i].Path < got[j].Path })
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("violations %v, want %v", got, want)
+	}
+}
+
+// TestSchemaRefCycles checks that references leading back to themselves
+// end, in validation and in NodeAt, instead of recursing forever.
+func TestSchemaRefCycles(t *testing.T) {
+	for name, src := range map[string]string{
+		"self":   `{"$ref": "#"}`,
+		"mutual": `{"definitions": {"a": {"$ref": "#/definitions/b"}, "b": {"$ref": "#/definitions/a"}}, "$ref": "#/definitions/a"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := mustSchema(t, src)
+			got := s.Validate(mustDecode(t, `{"x": 1}`))
+			if len(got) == 0 || !strings.Contains(got[len(got)-1].Message, "recursion too deep") {
+				t.Errorf("violations %v, want the recursion reported", got)
+			}
+			s.NodeAt(splitPath("x.y"))
+		})
+	}
+}
+
+func TestAnnotateSchema(t *testing.T) {
+	s := mustSchema(t, refSchema)
+	results := []DiffResult{{Path: "svc.port"}, {Path: "ports[0]"}}
+	annotateSchema(results, s, mustDecode(t, `{"svc": {"port": 80}, "ports": [1]}`), mustDecode(t, `{"svc": {"port": 99999}, "ports": [2]}`))
+	if results[0].SchemaTitle != "Port" || len(results[0].SchemaErrors) != 1 {
+		t.Errorf("svc.port: %+v, want the title and the new violation", results[0])
+	}
+	if results[1].SchemaTitle != "Port" || len(results[1].SchemaErrors) != 0 {
+		t.Errorf("ports[0]: %+v, want the title only", results[1])
+	}
+}
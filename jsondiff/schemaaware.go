@@ -0,0 +1,71 @@
+package jsondiff
+
+import (
+	"fmt"
+
+	"github.com/r3labs/diff/v3"
+)
+
+// --json-schema-aware treats the JSON Schema keywords documents carry about
+// themselves as metadata rather than data: $comment members are ignored
+// like any other filtered change, changes to $schema and $id members are
+// listed apart from the others and not counted, and documents declaring
+// different schemas get a warning.
+
+const reasonSchemaComment = "$comment (--json-schema-aware)"
+
+// metadataKeys are the members whose changes --json-schema-aware reports as
+// metadata.
+var metadataKeys = map[string]bool{"$schema": true, "$id": true}
+
+// filterSchemaComments drops the changes at or below a $comment member.
+func filterSchemaComments(changes []diff.Change, a, b interface{}, suppressed *suppressionLog) []diff.Change {
+	suppressed.note(reasonSchemaComment)
+	kept := changes[:0:0]
+	for _, c := range changes {
+		if hasSegment(typedPath(c.Path, a, b), "$comment") {
+			suppressed.add(reasonSchemaComment, c)
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+// hasSegment reports whether the object key seg is one of path's segments.
+// Array segments are bracketed, so they never equal a key.
+func hasSegment(path []string, seg string) bool {
+	for _, s := range path {
+		if s == seg {
+			return true
+		}
+	}
+	return false
+}
+
+// splitMetadata separates the changes to $schema and $id members from the
+// rest.
+func splitMetadata(results []DiffResult) (active, metadata []DiffResult) {
+	for _, r := range results {
+		segs := splitPath(r.Path)
+		if len(segs) > 0 && metadataKeys[segs[len(segs)-1]] {
+			metadata = append(metadata, r)
+		} else {
+			active = append(active, r)
+		}
+	}
+	return active, metadata
+}
+
+// schemaMismatch returns a warning when both documents declare a $schema
+// and the two differ, or "".
+func schemaMismatch(a, b interface{}) string {
+	ma, _ := a.(map[string]interface{})
+	mb, _ := b.(map[string]interface{})
+	sa, okA := ma["$schema"].(string)
+	sb, okB := mb["$schema"].(string)
+	if !okA || !okB || sa == sb {
+		return ""
+	}
+	return fmt.Sprintf("the documents declare different schemas, %q and %q; comparing documents of different schemas is usually a mistake", sa, sb)
+}
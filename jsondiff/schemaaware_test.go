@@ -0,0 +1,75 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSchemaMismatch(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{`{"$schema": "v1"}`, `{"$schema": "v2"}`, `different schemas, "v1" and "v2"`},
+		{`{"$schema": "v1"}`, `{"$schema": "v1"}`, ""},
+		{`{"$schema": "v1"}`, `{}`, ""},
+		{`[{"$schema": "v1"}]`, `[{"$schema": "v2"}]`, ""},
+	}
+	for _, tt := range tests {
+		got := schemaMismatch(mustDecode(t, tt.a), mustDecode(t, tt.b))
+		if tt.want == "" && got != "" || !strings.Contains(got, tt.want) {
+			t.Errorf("%s, %s: %q", tt.a, tt.b, got)
+		}
+	}
+}
+
+// TestSchemaAwareCLI checks that $comment changes are suppressed, $schema
+// and $id changes listed apart and not counted, and other changes reported
+// as usual, keys named like keywords in arrays included.
+func TestSchemaAwareCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"$schema": "v1", "$id": "a", "props": {"$comment": "old", "n": {"$id": "x", "v": 1}}, "tags": ["$comment"]}`,
+		"b.json": `{"$schema": "v2", "$id": "b", "props": {"$comment": "new", "n": {"$id": "y", "v": 1}}, "tags": ["$id"]}`,
+	})
+	res := runCLI(t, dir, "--json-schema-aware", "--show-suppressed", "-f", "json", "-o", "-", "a.json", "b.json")
+	if res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	if !strings.Contains(res.stderr, `Warning: the documents declare different schemas, "v1" and "v2"`) {
+		t.Errorf("no schema warning: %s", res.stderr)
+	}
+	var report jsonReport
+	if err := json.Unmarshal([]byte(res.stdout), &report); err != nil {
+		t.Fatal(err)
+	}
+	var active, metadata []string
+	for _, s := range report.Sections {
+		for _, c := range s.Changes {
+			active = append(active, c.Path)
+		}
+	}
+	for _, c := range report.Metadata {
+		metadata = append(metadata, c.Path)
+	}
+	if want := []string{"tags[0]"}; report.Total != 1 || !reflect.DeepEqual(active, want) {
+		t.Errorf("total %d, changes %q, want %q", report.Total, active, want)
+	}
+	if want := []string{"$id", "$schema", "props.n.$id"}; !reflect.DeepEqual(metadata, want) {
+		t.Errorf("metadata %q, want %q", metadata, want)
+	}
+	if len(report.Suppressed) != 1 || report.Suppressed[0].Reason != reasonSchemaComment || report.Suppressed[0].Count != 1 {
+		t.Errorf("suppressed %+v", report.Suppressed)
+	}
+
+	// Without the flag every change counts.
+	res = runCLI(t, dir, "-f", "json", "-o", "-", "a.json", "b.json")
+	var plain jsonReport
+	if err := json.Unmarshal([]byte(res.stdout), &plain); err != nil {
+		t.Fatal(err)
+	}
+	if plain.Total != 5 {
+		t.Errorf("without --json-schema-aware: total %d, want 5", plain.Total)
+	}
+}
@@ -0,0 +1,123 @@
+package jsondiff
+
+import (
+	"fmt"
+	"sort"
+)
+
+// WeightRule sets how much the values at and below paths matching Path
+// count towards the weighted similarity, relative to the default weight of
+// 1: a cosmetic section might weigh 0.1, a critical one 10.
+type WeightRule struct {
+	Path   string  `json:"path"`
+	Weight float64 `json:"weight"`
+}
+
+type weightRule struct {
+	pattern []string
+	weight  float64
+}
+
+// weightRules are the compiled "weights" of the config.
+type weightRules []weightRule
+
+func compileWeightRules(rules []WeightRule) (weightRules, error) {
+	out := make(weightRules, 0, len(rules))
+	for _, r := range rules {
+		if r.Path == "" {
+			return nil, fmt.Errorf("weight rule without a path")
+		}
+		if r.Weight < 0 {
+			return nil, fmt.Errorf("invalid weight %g for %s: must not be negative", r.Weight, r.Path)
+		}
+		out = append(out, weightRule{splitPath(r.Path), r.Weight})
+	}
+	return out, nil
+}
+
+// weight returns the weight of the leaf at path: that of the first rule
+// matching its deepest matching ancestor or the leaf itself, or 1.
+func (rules weightRules) weight(path []string) float64 {
+	for n := len(path); n >= 0 && len(rules) > 0; n-- {
+		for _, r := range rules {
+			if matchPath(r.pattern, path[:n]) {
+				return r.weight
+			}
+		}
+	}
+	return 1
+}
+
+// SectionScore is the similarity of one top-level section, the part of
+// both documents below one top-level key.
+type SectionScore struct {
+	Name string `json:"name"`
+	// Leaves counts the leaf values of the section over both documents,
+	// Changed those at or below a change.
+	Leaves     int     `json:"leaves"`
+	Changed    int     `json:"changed"`
+	Similarity float64 `json:"similarity"`
+	// Weight is the total weight of the section's leaves.
+	Weight float64 `json:"weight"`
+}
+
+// similarityScores breaks the similarity down by section and weighs it by
+// the config's "weights".
+type similarityScores struct {
+	// Weighted is the weight of the unchanged leaves over that of all
+	// leaves; without weight rules it equals the plain similarity.
+	Weighted float64
+	Sections []SectionScore
+}
+
+// scoreSimilarity scores a against b like similarity, per section and
+// weighted by rules.
+func scoreSimilarity(a, b interface{}, m *DiffMap, rules weightRules) similarityScores {
+	type leaf struct {
+		section string
+		weight  float64
+	}
+	leaves := make(map[string]leaf)
+	collect := func(path []string, _ interface{}) {
+		p := joinPath(path)
+		leaves[p] = leaf{topLevelKey(p), rules.weight(path)}
+	}
+	walkLeaves(a, nil, collect)
+	walkLeaves(b, nil, collect)
+	if len(leaves) == 0 {
+		return similarityScores{Weighted: 1}
+	}
+
+	bySection := make(map[string]*SectionScore)
+	var total, unchanged float64
+	for p, l := range leaves {
+		sec := bySection[l.section]
+		if sec == nil {
+			sec = &SectionScore{Name: l.section}
+			bySection[l.section] = sec
+		}
+		sec.Leaves++
+		sec.Weight += l.weight
+		total += l.weight
+		_, changed := m.Lookup(p)
+		if !changed {
+			_, _, changed = m.ChangedAncestor(p)
+		}
+		if changed {
+			sec.Changed++
+			continue
+		}
+		unchanged += l.weight
+	}
+
+	scores := similarityScores{Weighted: 1}
+	if total > 0 {
+		scores.Weighted = unchanged / total
+	}
+	for _, sec := range bySection {
+		sec.Similarity = float64(sec.Leaves-sec.Changed) / float64(sec.Leaves)
+		scores.Sections = append(scores.Sections, *sec)
+	}
+	sort.Slice(scores.Sections, func(i, j int) bool { return scores.Sections[i].Name < scores.Sections[j].Name })
+	return scores
+}
@@ -0,0 +1,80 @@
+package jsondiff
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWeightRules(t *testing.T) {
+	rules, err := compileWeightRules([]WeightRule{
+		{Path: "meta", Weight: 0.1},
+		{Path: "spec.*", Weight: 10},
+		{Path: "spec.limits", Weight: 5},
+		{Path: "meta.labels", Weight: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]float64{
+		"meta.name":       0.1,
+		"meta.labels.app": 3,
+		// spec.* comes before spec.limits, which both match.
+		"spec.limits.cpu":    10,
+		"spec.replicas":      10,
+		"status.ready":       1,
+		"items[0].spec.name": 1,
+	}
+	for path, want := range tests {
+		if got := rules.weight(splitPath(path)); got != want {
+			t.Errorf("%s: weight %v, want %v", path, got, want)
+		}
+	}
+	for _, bad := range []WeightRule{{Weight: 1}, {Path: "a", Weight: -1}} {
+		if _, err := compileWeightRules([]WeightRule{bad}); err == nil {
+			t.Errorf("%+v: no error", bad)
+		}
+	}
+}
+
+func TestScoreSimilarity(t *testing.T) {
+	a := mustDecode(t, `{"meta": {"x": 1, "y": 2}, "spec": {"r": 1}, "gone": true}`)
+	b := mustDecode(t, `{"meta": {"x": 1, "y": 3}, "spec": {"r": 2}}`)
+	changes, err := collectChanges(diffSeq(context.Background(), a, b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := compileWeightRules([]WeightRule{{Path: "spec", Weight: 2}, {Path: "gone", Weight: 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scores := scoreSimilarity(a, b, buildDiffMap(changes, a, b), rules)
+	// Of the weight 4 of the counted leaves, meta.x's 1 is unchanged.
+	if scores.Weighted != 0.25 {
+		t.Errorf("weighted %v, want 0.25", scores.Weighted)
+	}
+	want := []SectionScore{
+		{Name: "gone", Leaves: 1, Changed: 1, Similarity: 0, Weight: 0},
+		{Name: "meta", Leaves: 2, Changed: 1, Similarity: 0.5, Weight: 2},
+		{Name: "spec", Leaves: 1, Changed: 1, Similarity: 0, Weight: 2},
+	}
+	if !reflect.DeepEqual(scores.Sections, want) {
+		t.Errorf("sections %+v, want %+v", scores.Sections, want)
+	}
+	if s := scoreSimilarity(map[string]interface{}{}, map[string]interface{}{}, newDiffMap(), nil); s.Weighted != 1 || s.Sections != nil {
+		t.Errorf("empty documents: %+v", s)
+	}
+}
+
+func TestWeightsConfigCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json":   `{"a": 1}`,
+		"b.json":   `{"a": 2}`,
+		"bad.json": `{"weights": [{"path": "a", "weight": -2}]}`,
+	})
+	res := runCLI(t, dir, "--config", "bad.json", "-o", "out.html", "a.json", "b.json")
+	if res.exit != exitError || !strings.Contains(res.stderr, "invalid config: invalid weight -2 for a: must not be negative") {
+		t.Errorf("exit %d: %s", res.exit, res.stderr)
+	}
+}
@@ -0,0 +1,72 @@
+package jsondiff
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+)
+
+// sniffSize is how much of an input is inspected for binary content.
+const sniffSize = 8 << 10
+
+// magicNumbers identifies common binary formats by their leading bytes.
+var magicNumbers = []struct {
+	prefix []byte
+	name   string
+}{
+	{[]byte("\x89PNG\r\n\x1a\n"), "PNG image"},
+	{[]byte("\xff\xd8\xff"), "JPEG image"},
+	{[]byte("GIF87a"), "GIF image"},
+	{[]byte("GIF89a"), "GIF image"},
+	{[]byte("%PDF-"), "PDF document"},
+	{[]byte("PK\x03\x04"), "zip archive"},
+	{[]byte("\x1f\x8b"), "gzip archive"},
+	{[]byte("BZh"), "bzip2 archive"},
+	{[]byte("\xfd7zXZ\x00"), "xz archive"},
+	{[]byte("7z\xbc\xaf\x27\x1c"), "7z archive"},
+	{[]byte("\x7fELF"), "ELF executable"},
+	{[]byte("SQLite format 3\x00"), "SQLite database"},
+	{[]byte("\x00asm"), "WebAssembly module"},
+}
+
+// sniffMagic returns an error naming the format of head, the start of an
+// input, if it begins with a known magic number. Unlike sniffBinary it
+// passes UTF-16 and UTF-32 text, whose NUL bytes are expected.
+func sniffMagic(head []byte) error {
+	for _, m := range magicNumbers {
+		if bytes.HasPrefix(head, m.prefix) {
+			return fmt.Errorf("file appears to be binary (%s?); differ compares JSON documents", m.name)
+		}
+	}
+	return nil
+}
+
+// sniffBinary returns an error describing head, the start of an input, if
+// it looks like binary data rather than a text document: a known magic
+// number, a NUL byte, or mostly control characters or invalid UTF-8.
+func sniffBinary(head []byte) error {
+	if err := sniffMagic(head); err != nil {
+		return err
+	}
+	if len(head) == 0 {
+		return nil
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return fmt.Errorf("file appears to be binary (contains NUL bytes); differ compares JSON documents")
+	}
+	odd, total := 0, 0
+	for len(head) > 0 {
+		r, size := utf8.DecodeRune(head)
+		if r == utf8.RuneError && size == 1 && len(head) >= utf8.UTFMax {
+			odd++
+		} else if r < 0x20 && r != '\t' && r != '\n' && r != '\r' && r != recordSeparator || r == 0x7f {
+			odd++
+		}
+		total++
+		head = head[size:]
+	}
+	if odd*10 > total {
+		return fmt.Errorf("file appears to be binary (%d%% non-text bytes); differ compares JSON documents", odd*100/total)
+	}
+	return nil
+}
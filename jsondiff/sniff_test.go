@@ -0,0 +1,99 @@
+package jsondiff
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// binaryCorpus returns real files of the formats people most often pass by
+// mistake, each with the name sniffBinary should give it.
+func binaryCorpus(t *testing.T) map[string][]byte {
+	t.Helper()
+	doc := []byte(`{"name": "svc", "replicas": 3}`)
+
+	var pngFile bytes.Buffer
+	if err := png.Encode(&pngFile, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	var gzFile bytes.Buffer
+	gz := gzip.NewWriter(&gzFile)
+	gz.Write(doc)
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var zipFile bytes.Buffer
+	zw := zip.NewWriter(&zipFile)
+	w, err := zw.Create("doc.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(doc)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return map[string][]byte{
+		"PNG image":    pngFile.Bytes(),
+		"gzip archive": gzFile.Bytes(),
+		"zip archive":  zipFile.Bytes(),
+	}
+}
+
+// TestReadInputBinaryCorpus runs real binary files through the whole input
+// path, as the CLI reads them.
+func TestReadInputBinaryCorpus(t *testing.T) {
+	for name, data := range binaryCorpus(t) {
+		t.Run(name, func(t *testing.T) {
+			_, err := readInput(inputSource{path: writeInput(t, data)}, execOptions{}, defaultInputLimits, decodeOptions{})
+			if err == nil || !strings.Contains(err.Error(), "appears to be binary ("+name+"?)") {
+				t.Errorf("error %v, want the file named a %s", err, name)
+			}
+		})
+	}
+}
+
+func TestSniffBinaryNearBinary(t *testing.T) {
+	// ten is ten runes, one of them a control character: 10% non-text,
+	// which is the most sniffBinary allows.
+	ten := "\x01" + strings.Repeat("a", 9)
+	tests := []struct {
+		name   string
+		head   string
+		binary bool
+	}{
+		{"empty", "", false},
+		{"JSON", `{"a": [1, 2]}`, false},
+		{"JSON with tabs and CRLF", "{\r\n\t\"a\": 1\r\n}", false},
+		{"JSON text sequence", "\x1e{\"a\": 1}\n\x1e{\"a\": 2}\n", false},
+		{"UTF-8 text", `{"name": "café 日本 𝄞"}`, false},
+		{"rune cut by the sniff window", `{"a": "caf` + "\xc3", false},
+		{"control characters at the limit", strings.Repeat(ten, 10), false},
+		{"control characters past the limit", strings.Repeat(ten, 10) + "\x02", true},
+		{"one NUL", `{"a": "b` + "\x00" + `"}`, true},
+		{"DEL characters", strings.Repeat("\x7fab", 10), true},
+		{"Latin-1 accented letters", strings.Repeat("\xe9\xe8\xe0", 8), true},
+		{"JSON with a few Latin-1 bytes", `{"name": "caf` + "\xe9" + `", "city": "Montr` + "\xe9" + `al and more text"}`, false},
+	}
+	for _, tt := range tests {
+		err := sniffBinary([]byte(tt.head))
+		if (err != nil) != tt.binary {
+			t.Errorf("%s: error %v, want binary %t", tt.name, err, tt.binary)
+		}
+	}
+}
+
+func TestSniffMagicAllowsUTF16(t *testing.T) {
+	for _, enc := range []textEncoding{encodingUTF16LE, encodingUTF16BE, encodingUTF32LE} {
+		head := encodeText(`{"a": 1}`, enc)
+		if err := sniffMagic(head); err != nil {
+			t.Errorf("%v: %v", enc, err)
+		}
+		if err := sniffBinary(head); err == nil {
+			t.Errorf("%v: sniffBinary passed NUL bytes", enc)
+		}
+	}
+}
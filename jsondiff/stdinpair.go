@@ -0,0 +1,84 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// stdinPairRequest is the object read by --stdin-pair: both documents and,
+// optionally, the labels to show for them.
+type stdinPairRequest struct {
+	A      json.RawMessage `json:"a"`
+	B      json.RawMessage `json:"b"`
+	LabelA string          `json:"labelA"`
+	LabelB string          `json:"labelB"`
+}
+
+// stdinPair holds the two documents of a --stdin-pair request, each written
+// to a temporary file so they are read like any other input.
+type stdinPair struct {
+	inputs [2]inputSource
+}
+
+// readStdinPair reads a stdinPairRequest from r, which may be at most
+// limits.maxBytes long. Unlabelled documents are called "a" and "b".
+func readStdinPair(r io.Reader, limits inputLimits) (*stdinPair, error) {
+	if limits.maxBytes > 0 {
+		r = &limitedReader{r: r, remaining: limits.maxBytes, limit: limits.maxBytes}
+	}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var req stdinPairRequest
+	if err := dec.Decode(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("stdin is empty")
+		}
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the request object")
+	}
+	switch {
+	case req.A == nil && req.B == nil:
+		return nil, errors.New(`missing "a" and "b"`)
+	case req.A == nil:
+		return nil, errors.New(`missing "a"`)
+	case req.B == nil:
+		return nil, errors.New(`missing "b"`)
+	}
+
+	p := &stdinPair{}
+	for i, doc := range []json.RawMessage{req.A, req.B} {
+		label := []string{req.LabelA, req.LabelB}[i]
+		if label == "" {
+			label = []string{"a", "b"}[i]
+		}
+		f, err := os.CreateTemp("", "jsondiff-pair-*.json")
+		if err != nil {
+			p.remove()
+			return nil, err
+		}
+		_, err = f.Write(doc)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		p.inputs[i] = inputSource{path: f.Name(), display: label}
+		if err != nil {
+			p.remove()
+			return nil, fmt.Errorf("writing %s: %v", label, err)
+		}
+	}
+	return p, nil
+}
+
+// remove deletes the temporary files.
+func (p *stdinPair) remove() {
+	for _, in := range p.inputs {
+		if in.path != "" {
+			os.Remove(in.path)
+		}
+	}
+}
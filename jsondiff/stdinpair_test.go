@@ -0,0 +1,68 @@
+package jsondiff
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestStdinPairRemovesTempFiles checks that the temporary files holding the
+// --stdin-pair documents are removed however the run ends.
+func TestStdinPairRemovesTempFiles(t *testing.T) {
+	const pair = `{"a": {"n": 1}, "b": {"n": 2}}`
+	tests := []struct {
+		name string
+		args []string
+		exit int
+	}{
+		{"no changes asked for", []string{"--format", "json", "-o", "-"}, exitOK},
+		{"changes found", []string{"--fail-on", "changed", "--format", "json", "-o", "-"}, exitChangesFound},
+		{"failure after reading", []string{"--config", "missing.json", "-o", "-"}, exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			cmd := exec.Command(os.Args[0], append([]string{"--stdin-pair"}, tt.args...)...)
+			cmd.Stdin = strings.NewReader(pair)
+			cmd.Env = []string{"TMPDIR=" + tmp}
+			res := runCLIWith(t, cmd, t.TempDir())
+			if res.exit != tt.exit {
+				t.Fatalf("exit %d, want %d: %s", res.exit, tt.exit, res.stderr)
+			}
+			left, err := os.ReadDir(tmp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range left {
+				t.Errorf("left %s in $TMPDIR", e.Name())
+			}
+		})
+	}
+}
+
+func TestReadStdinPair(t *testing.T) {
+	p, err := readStdinPair(strings.NewReader(`{"a": [1], "b": [2], "labelB": "after"}`), defaultInputLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.remove()
+	if p.inputs[0].display != "a" || p.inputs[1].display != "after" {
+		t.Errorf("labels %q, %q", p.inputs[0].display, p.inputs[1].display)
+	}
+	if got := readFile(t, "", p.inputs[1].path); got != "[2]" {
+		t.Errorf("b holds %q", got)
+	}
+
+	for input, want := range map[string]string{
+		``:                         "stdin is empty",
+		`{"a": 1}`:                 `missing "b"`,
+		`{}`:                       `missing "a" and "b"`,
+		`{"a": 1, "b": 2} {}`:      "unexpected data after the request object",
+		`{"a": 1, "b": 2, "c": 3}`: `unknown field "c"`,
+	} {
+		if _, err := readStdinPair(strings.NewReader(input), defaultInputLimits); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: error %v, want %s", input, err, want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package jsondiff
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// summaryVersion is bumped whenever a field of summaryFile changes meaning
+// or is removed; adding fields keeps the version.
+const summaryVersion = 1
+
+// summaryFile is the document written by --summary-out for pipelines that
+// need the outcome of a run without parsing the report.
+type summaryFile struct {
+	Version    int                `json:"version"`
+	Inputs     [2]summaryInput    `json:"inputs"`
+	Counts     map[ChangeType]int `json:"counts"`
+	Similarity float64            `json:"similarity"`
+	// Include lists the --include patterns the comparison was restricted
+	// to, if any.
+	Include []string `json:"include,omitempty"`
+	// WeightedSimilarity weighs leaves by the config's "weights".
+	WeightedSimilarity float64        `json:"weightedSimilarity"`
+	SectionScores      []SectionScore `json:"sectionScores"`
+	ExitCode           int            `json:"exitCode"`
+	// Gating counts the changes matching --fail-on-path, when given.
+	Gating *int `json:"gating,omitempty"`
+	// ExitReason explains a non-zero ExitCode.
+	ExitReason string `json:"exitReason,omitempty"`
+	DurationMS int64  `json:"durationMs"`
+	// Sections lists the top-level keys with at least one change.
+	Sections []string `json:"sections"`
+}
+
+type summaryInput struct {
+	Label string `json:"label"`
+	// SHA256 hashes the document re-encoded with sorted keys, so inputs
+	// differing only in formatting or key order hash alike.
+	SHA256 string `json:"sha256"`
+}
+
+// documentHash returns the hex SHA-256 of v's canonical JSON encoding.
+func documentHash(v interface{}) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func newSummaryFile(inputs [2]summaryInput, results []DiffResult, sections []DiffSection, similarity float64, scores similarityScores, exitCode int, exitReason string, elapsed time.Duration) summaryFile {
+	s := summaryFile{
+		Version:            summaryVersion,
+		Inputs:             inputs,
+		Counts:             map[ChangeType]int{Added: 0, Removed: 0, Changed: 0},
+		Similarity:         similarity,
+		WeightedSimilarity: scores.Weighted,
+		SectionScores:      scores.Sections,
+		ExitCode:           exitCode,
+		ExitReason:         exitReason,
+		DurationMS:         elapsed.Milliseconds(),
+		Sections:           make([]string, 0, len(sections)),
+	}
+	if s.SectionScores == nil {
+		s.SectionScores = []SectionScore{}
+	}
+	counts := summarize(results, similarity)
+	s.Counts[Added], s.Counts[Removed], s.Counts[Changed] = counts.Added, counts.Removed, counts.Changed
+	for _, sec := range sections {
+		s.Sections = append(s.Sections, sec.Name)
+	}
+	return s
+}
+
+// writeSummaryFile writes s as indented JSON to filename.
+func writeSummaryFile(filename string, s summaryFile) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0644)
+}
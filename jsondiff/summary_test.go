@@ -0,0 +1,121 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// durationMS matches the one field of the summary that varies between runs.
+var durationMS = regexp.MustCompile(`"durationMs": \d+`)
+
+// TestSummaryGolden pins the --summary-out document, which is written even
+// with --quiet and for an empty diff.
+func TestSummaryGolden(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"a": 1, "b": [1, 2], "c": "x", "same": {"k": true}}`,
+		"b.json": `{"a": 2, "b": [1, 3], "d": "x", "same": {"k": true}}`,
+	})
+	tests := []struct {
+		golden string
+		args   []string
+		exit   int
+	}{
+		{"summary-changes.json", []string{"--fail-on", "changed", "--fail-on-path", "b[*]", "a.json", "b.json"}, exitChangesFound},
+		{"summary-empty.json", []string{"a.json", "a.json"}, exitOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			args := append([]string{"--quiet", "--summary-out", "summary.json"}, tt.args...)
+			res := runCLI(t, dir, args...)
+			if res.exit != tt.exit {
+				t.Fatalf("exit %d, want %d: %s", res.exit, tt.exit, res.stderr)
+			}
+			if res.stdout != "" {
+				t.Errorf("--quiet printed %q", res.stdout)
+			}
+			got := readFile(t, dir, "summary.json")
+			var v interface{}
+			if err := json.Unmarshal([]byte(got), &v); err != nil {
+				t.Fatal(err)
+			}
+			if err := validateOutput("summary", v); err != nil {
+				t.Errorf("does not match its schema: %v", err)
+			}
+			checkGolden(t, tt.golden, durationMS.ReplaceAllString(got, `"durationMs": 0`))
+		})
+	}
+}
+
+// TestStdoutByMode checks what each combination of the output flags prints
+// to stdout and which report files it writes.
+func TestStdoutByMode(t *testing.T) {
+	docs := map[string]string{
+		"a.json": `{"a": 1, "b": [1, 2], "c": "x", "same": {"k": true}}`,
+		"b.json": `{"a": 2, "b": [1, 3], "d": "x", "same": {"k": true}}`,
+	}
+	// Of the six leaves, b[0] and same.k are unchanged.
+	const summary = "added=1 removed=1 changed=2 similarity=0.33\n"
+	tests := []struct {
+		name string
+		args []string
+		exit int
+		// stdout is the exact output, or, when json is set, the JSON
+		// report followed by it.
+		stdout string
+		json   bool
+		files  []string
+	}{
+		{"default", nil, exitOK, "Diff written to diff.html\n", false, []string{"diff.html"}},
+		{"output file", []string{"-o", "out.json", "-f", "json"}, exitOK, "Diff written to out.json\n", false, []string{"out.json"}},
+		{"output to stdout", []string{"-o", "-", "-f", "json"}, exitOK, "", true, nil},
+		{"quiet", []string{"--quiet"}, exitOK, "", false, []string{"diff.html"}},
+		{"quiet with output file", []string{"--quiet", "-o", "out.html"}, exitOK, "", false, []string{"out.html"}},
+		{"quiet failing", []string{"--quiet", "--fail-on", "changed"}, exitChangesFound, "", false, []string{"diff.html"}},
+		{"summary only", []string{"--summary-only"}, exitOK, summary, false, nil},
+		{"summary only with output file", []string{"--summary-only", "-o", "out.html"}, exitOK, summary, false, []string{"out.html"}},
+		{"summary only to stdout", []string{"--summary-only", "-o", "-", "-f", "json"}, exitOK, summary, true, nil},
+		{"summary only with gating", []string{"--summary-only", "--fail-on-path", "b[*]"}, exitChangesFound, "added=1 removed=1 changed=2 similarity=0.33 gating=1\n", false, nil},
+		{"stats only", []string{"--stats-only"}, exitOK, summary, false, nil},
+		{"quiet and summary only", []string{"--quiet", "--summary-only"}, exitError, "", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := cliDir(t, docs)
+			res := runCLI(t, dir, append(tt.args, "a.json", "b.json")...)
+			if res.exit != tt.exit {
+				t.Fatalf("exit %d, want %d: %s", res.exit, tt.exit, res.stderr)
+			}
+			stdout := res.stdout
+			if tt.json {
+				dec := json.NewDecoder(strings.NewReader(stdout))
+				var report jsonReport
+				if err := dec.Decode(&report); err != nil {
+					t.Fatalf("stdout does not start with the JSON report: %v", err)
+				}
+				if report.Total != 4 {
+					t.Errorf("report of %d changes", report.Total)
+				}
+				stdout = strings.TrimLeft(stdout[dec.InputOffset():], "\n")
+			}
+			if stdout != tt.stdout {
+				t.Errorf("stdout %q, want %q", stdout, tt.stdout)
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var files []string
+			for _, e := range entries {
+				if name := e.Name(); name != "a.json" && name != "b.json" {
+					files = append(files, name)
+				}
+			}
+			if strings.Join(files, " ") != strings.Join(tt.files, " ") {
+				t.Errorf("wrote %q, want %q", files, tt.files)
+			}
+		})
+	}
+}
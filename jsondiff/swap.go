@@ -0,0 +1,84 @@
+package jsondiff
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// The inputs look swapped only when the comparison finds at least
+// swapMinRemoved removals and swapRemovedRatio times as many as additions, and
+// there is independent evidence of the order: file2 is older than file1, or
+// the labels name environments in the reverse of the config's
+// environmentOrder. A large removal alone is a legitimate change.
+const (
+	swapMinRemoved   = 10
+	swapRemovedRatio = 10
+)
+
+// swapEvidence is what swapSuspicion judges the order of the inputs by.
+type swapEvidence struct {
+	added, removed int
+	// modTimes are the modification times of the input files, zero for
+	// commands and stdin.
+	modTimes [2]time.Time
+	labels   [2]string
+	// order is the config's environmentOrder.
+	order []string
+}
+
+// swapSuspicion returns a warning when the inputs were probably passed in
+// the wrong order, or "".
+func swapSuspicion(e swapEvidence) string {
+	if e.removed < swapMinRemoved || e.removed < swapRemovedRatio*max(e.added, 1) {
+		return ""
+	}
+	var why string
+	a, b := e.modTimes[0], e.modTimes[1]
+	envA, envB := environmentIndex(e.labels[0], e.order), environmentIndex(e.labels[1], e.order)
+	switch {
+	case !a.IsZero() && !b.IsZero() && b.Before(a):
+		why = fmt.Sprintf("%s was modified before %s", e.labels[1], e.labels[0])
+	case envA >= 0 && envB >= 0 && envA > envB:
+		why = fmt.Sprintf("%s is normally compared to %s, not the other way round", e.order[envB], e.order[envA])
+	default:
+		return ""
+	}
+	return fmt.Sprintf("the diff removes %d values but adds only %d, and %s; the inputs may be swapped (rerun with --swap, or silence this with --no-swap-warning)", e.removed, e.added, why)
+}
+
+// environmentIndex returns the position in order of the one environment
+// the words of label name, or -1 when it names none or several.
+func environmentIndex(label string, order []string) int {
+	found := -1
+	for _, word := range strings.FieldsFunc(strings.ToLower(label), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		for i, env := range order {
+			if word != strings.ToLower(env) || i == found {
+				continue
+			}
+			if found >= 0 {
+				return -1
+			}
+			found = i
+		}
+	}
+	return found
+}
+
+// modTime returns the modification time of the regular file an input
+// reads, as stat reports it, or the zero time for commands, --stdin-pair and
+// pipes. Run passes os.Stat.
+func (s inputSource) modTime(stat func(string) (os.FileInfo, error)) time.Time {
+	if s.command != "" || s.display != "" || s.path == "" {
+		return time.Time{}
+	}
+	info, err := stat(s.path)
+	if err != nil || !info.Mode().IsRegular() {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
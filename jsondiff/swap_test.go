@@ -0,0 +1,165 @@
+package jsondiff
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeInfo is the metadata a fake stat reports for a file.
+type fakeInfo struct {
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (f fakeInfo) Name() string       { return "fake" }
+func (f fakeInfo) Size() int64        { return 0 }
+func (f fakeInfo) Mode() fs.FileMode  { return f.mode }
+func (f fakeInfo) ModTime() time.Time { return f.modTime }
+func (f fakeInfo) IsDir() bool        { return f.mode.IsDir() }
+func (f fakeInfo) Sys() interface{}   { return nil }
+
+// fakeStat reports the metadata in files, and not-exist for other paths.
+func fakeStat(files map[string]fakeInfo) func(string) (os.FileInfo, error) {
+	return func(name string) (os.FileInfo, error) {
+		info, ok := files[name]
+		if !ok {
+			return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+		}
+		return info, nil
+	}
+}
+
+func TestInputModTime(t *testing.T) {
+	older := historyTime.Add(-time.Hour)
+	stat := fakeStat(map[string]fakeInfo{
+		"a.json": {modTime: historyTime},
+		"dir":    {mode: fs.ModeDir, modTime: historyTime},
+		"fifo":   {mode: fs.ModeNamedPipe, modTime: historyTime},
+		"tmp":    {modTime: older},
+	})
+	tests := []struct {
+		name string
+		in   inputSource
+		want time.Time
+	}{
+		{"regular file", inputSource{path: "a.json"}, historyTime},
+		{"with a subpath", inputSource{path: "a.json", subpath: "spec"}, historyTime},
+		{"directory", inputSource{path: "dir"}, time.Time{}},
+		{"pipe", inputSource{path: "fifo"}, time.Time{}},
+		{"missing", inputSource{path: "gone.json"}, time.Time{}},
+		{"command", inputSource{path: "a.json", command: "cat a.json"}, time.Time{}},
+		{"stdin pair", inputSource{path: "tmp", display: "stdin[0]"}, time.Time{}},
+		{"stdin", inputSource{}, time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := tt.in.modTime(stat); !got.Equal(tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	failing := func(string) (os.FileInfo, error) { return nil, errors.New("permission denied") }
+	if got := (inputSource{path: "a.json"}).modTime(failing); !got.IsZero() {
+		t.Errorf("stat error: %v", got)
+	}
+}
+
+func TestSwapSuspicion(t *testing.T) {
+	older := historyTime.Add(-time.Hour)
+	order := []string{"dev", "staging", "prod"}
+	tests := []struct {
+		name string
+		e    swapEvidence
+		// want is a substring of the warning, or "" for none.
+		want string
+	}{
+		{"older file2", swapEvidence{removed: 20, modTimes: [2]time.Time{historyTime, older}, labels: [2]string{"a.json", "b.json"}}, "b.json was modified before a.json"},
+		{"newer file2", swapEvidence{removed: 20, modTimes: [2]time.Time{older, historyTime}, labels: [2]string{"a.json", "b.json"}}, ""},
+		{"same time", swapEvidence{removed: 20, modTimes: [2]time.Time{historyTime, historyTime}}, ""},
+		{"one time unknown", swapEvidence{removed: 20, modTimes: [2]time.Time{historyTime, {}}}, ""},
+		{"too few removals", swapEvidence{removed: swapMinRemoved - 1, modTimes: [2]time.Time{historyTime, older}}, ""},
+		{"removals at the ratio", swapEvidence{added: 2, removed: 2 * swapRemovedRatio, modTimes: [2]time.Time{historyTime, older}}, "removes 20 values but adds only 2"},
+		{"removals under the ratio", swapEvidence{added: 3, removed: 2 * swapRemovedRatio, modTimes: [2]time.Time{historyTime, older}}, ""},
+		{"environments reversed", swapEvidence{removed: 20, labels: [2]string{"prod.json", "staging.json"}, order: order}, "staging is normally compared to prod"},
+		{"environments in order", swapEvidence{removed: 20, labels: [2]string{"dev.json", "prod.json"}, order: order}, ""},
+		{"environments without an order", swapEvidence{removed: 20, labels: [2]string{"prod.json", "dev.json"}}, ""},
+		{"label naming two environments", swapEvidence{removed: 20, labels: [2]string{"prod-vs-dev.json", "dev.json"}, order: order}, ""},
+	}
+	for _, tt := range tests {
+		got := swapSuspicion(tt.e)
+		if tt.want == "" && got != "" || !strings.Contains(got, tt.want) {
+			t.Errorf("%s: %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEnvironmentIndex(t *testing.T) {
+	order := []string{"dev", "Staging", "prod"}
+	tests := []struct {
+		label string
+		want  int
+	}{
+		{"prod.json", 2},
+		{"config-STAGING.json", 1},
+		{"dev/dev.json", 0},
+		{"production.json", -1},
+		{"dev-to-prod.json", -1},
+		{"", -1},
+	}
+	for _, tt := range tests {
+		if got := environmentIndex(tt.label, order); got != tt.want {
+			t.Errorf("%q: %d, want %d", tt.label, got, tt.want)
+		}
+	}
+}
+
+// TestSwapWarningCLI gives the inputs real modification times and checks the
+// warning on stderr and in the report, and that --swap and --no-swap-warning
+// silence it.
+func TestSwapWarningCLI(t *testing.T) {
+	var members []string
+	for i := 0; i < swapMinRemoved+2; i++ {
+		members = append(members, fmt.Sprintf(`"k%d": %d`, i, i))
+	}
+	dir := cliDir(t, map[string]string{
+		"a.json": "{" + strings.Join(members, ", ") + "}",
+		"b.json": `{"k0": 0}`,
+	})
+	older := historyTime.Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "a.json"), historyTime, historyTime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(dir, "b.json"), older, older); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		warn bool
+	}{
+		{"older file2", nil, true},
+		{"no-swap-warning", []string{"--no-swap-warning"}, false},
+		{"swap", []string{"--swap"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := runCLI(t, dir, append(append([]string{"-o", "out.html"}, tt.args...), "a.json", "b.json")...)
+			if res.exit != exitOK {
+				t.Fatalf("exit %d: %s", res.exit, res.stderr)
+			}
+			warning := "removes 11 values but adds only 0, and b.json was modified before a.json"
+			if got := strings.Contains(res.stderr, "Warning: the diff "+warning); got != tt.warn {
+				t.Errorf("stderr warns %t, want %t: %s", got, tt.warn, res.stderr)
+			}
+			if got := strings.Contains(readFile(t, dir, "out.html"), `swap-warning" role="alert"`); got != tt.warn {
+				t.Errorf("report banner %t, want %t", got, tt.warn)
+			}
+		})
+	}
+}
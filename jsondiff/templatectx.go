@@ -0,0 +1,158 @@
+package jsondiff
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// templateAPIVersion is the version of ReportContext, the data the report
+// templates are executed with. It is bumped whenever a field is renamed or
+// removed, so custom templates can declare the version they were written
+// for with a comment such as {{/* differ:template-api 1 */}}.
+//
+// Version 0 is the original ad-hoc data: .Original, .Modified and a flat
+// .Diffs list, with renderJSON taking no side. ReportContext still provides
+// those, so v0 templates keep working.
+const templateAPIVersion = 1
+
+// ReportContext is the data passed to template.html and its partials.
+type ReportContext struct {
+	APIVersion int
+
+	// The two documents, for renderJSON.
+	Original, Modified interface{}
+	// Short names shown on the panes.
+	LabelA, LabelB string
+	// The projections the documents were compared through, if any.
+	ProjectionA, ProjectionB string
+	// Include lists the --include patterns the comparison was restricted
+	// to; nothing else was compared.
+	Include []string
+	// SwapWarning, if set, says why the inputs may have been passed in the
+	// wrong order.
+	SwapWarning string
+	// SchemaBanner, if set, says that the documents declare different
+	// schemas.
+	SchemaBanner string
+
+	Total        int
+	Legend       []LegendEntry
+	TOC          []TOCEntry
+	HeatMap      []HeatMarker
+	Sections     []DiffSection
+	Acknowledged []DiffResult
+	Aggregates   []Aggregate
+	Suppressed   []Suppression
+	Redacted     []Redaction
+	Remapped     []RemappedKey
+	Reordered    []KeyReorder
+	InputStats   [2]InputStats
+	// Metadata lists the changes to $schema and $id members, not counted
+	// in Total.
+	Metadata []DiffResult
+	// Schema is true when changes carry JSON Schema annotations.
+	Schema bool
+	// Editable is set by --editable; ModifiedJSON then holds the Modified
+	// document for export.
+	Editable     bool
+	ModifiedJSON template.JS
+	// Inputs holds the label and canonical hash of each document, and
+	// Options the diff flags set, for the equivalence report.
+	Inputs     [2]summaryInput
+	Similarity float64
+	Options    []EffectiveOption
+	// Scores holds the weighted similarity and that of every section.
+	Scores similarityScores
+	// Page is set on the pages of a --paginate report.
+	Page *pageNav
+}
+
+// Diffs lists the changes of every section in order, as the flat list v0
+// templates range over.
+func (c *ReportContext) Diffs() []DiffResult {
+	var out []DiffResult
+	for _, sec := range c.Sections {
+		out = append(out, sec.Changes...)
+	}
+	return out
+}
+
+// SuppressedChanges counts the suppressed changes listed individually,
+// which --show-suppressed enables.
+func (c *ReportContext) SuppressedChanges() int {
+	n := 0
+	for _, s := range c.Suppressed {
+		n += len(s.Changes)
+	}
+	return n
+}
+
+var templateAPIComment = regexp.MustCompile(`differ:template-api\s+(\d+)`)
+
+// checkTemplateAPI fails when one of the template files declares a template
+// API version newer than this build provides. Files without a declaration
+// are assumed to match.
+func checkTemplateAPI(files []string) error {
+	for _, name := range files {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		m := templateAPIComment.FindSubmatch(data)
+		if m == nil {
+			continue
+		}
+		if v, _ := strconv.Atoi(string(m[1])); v > templateAPIVersion {
+			return fmt.Errorf("%s targets template API v%d, but this jsondiff provides v%d", name, v, templateAPIVersion)
+		}
+	}
+	return nil
+}
+
+var unknownTemplateField = regexp.MustCompile(`can't evaluate field (\w+) in type ` + regexp.QuoteMeta(reflect.TypeOf(&ReportContext{}).String()))
+
+// templateDataError rewrites an execution error caused by a template
+// referring to data ReportContext does not have into one listing what is
+// available.
+func templateDataError(err error) error {
+	m := unknownTemplateField.FindStringSubmatch(err.Error())
+	if m == nil {
+		return err
+	}
+	return fmt.Errorf("template references .%s, which template API v%d does not provide; available: %s",
+		m[1], templateAPIVersion, strings.Join(templateFields(), ", "))
+}
+
+// templateFields lists the fields and methods of ReportContext.
+func templateFields() []string {
+	t := reflect.TypeOf(&ReportContext{})
+	var names []string
+	for i := 0; i < t.Elem().NumField(); i++ {
+		names = append(names, t.Elem().Field(i).Name)
+	}
+	for i := 0; i < t.NumMethod(); i++ {
+		names = append(names, t.Method(i).Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sameDocument reports whether x and y are the same decoded document, by
+// identity for objects and arrays.
+func sameDocument(x, y interface{}) bool {
+	vx, vy := reflect.ValueOf(x), reflect.ValueOf(y)
+	if vx.Kind() != vy.Kind() {
+		return false
+	}
+	switch vx.Kind() {
+	case reflect.Map, reflect.Slice:
+		return vx.Pointer() == vy.Pointer()
+	}
+	return x == y
+}
@@ -0,0 +1,81 @@
+package jsondiff
+
+import (
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTemplateDataError(t *testing.T) {
+	tpl := template.Must(template.New("t").Parse(`{{.NoSuchField}}`))
+	err := templateDataError(tpl.Execute(io.Discard, &ReportContext{}))
+	if err == nil || !strings.Contains(err.Error(), "template references .NoSuchField") || !strings.Contains(err.Error(), "available: ") {
+		t.Fatalf("error %v does not name the field and what is available", err)
+	}
+	for _, field := range []string{"Sections", "LabelA"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("available fields do not include %s: %v", field, err)
+		}
+	}
+}
+
+func TestTemplateDirUnknownField(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"a": 1}`,
+		"b.json": `{"a": 2}`,
+	})
+	partials := filepath.Join(dir, "partials")
+	if err := os.Mkdir(partials, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(partials, "stats.html"), []byte(`{{define "input-stats"}}{{.NoSuchField}}{{end}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	res := runCLI(t, dir, "--template-dir", "partials", "-o", "out.html", "a.json", "b.json")
+	if res.exit == exitOK || !strings.Contains(res.stderr, "template references .NoSuchField") {
+		t.Errorf("exit %d, stderr %s", res.exit, res.stderr)
+	}
+}
+
+// TestTemplateDirCLI replaces one partial and checks that the report uses
+// it while keeping the default of every other one.
+func TestTemplateDirCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"a": 1}`,
+		"b.json": `{"a": 2}`,
+	})
+	partials := filepath.Join(dir, "partials")
+	if err := os.Mkdir(partials, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, text := range map[string]string{
+		"table.html": `{{define "diff-table"}}<p id="custom-table">{{.Total}} change(s)</p>{{end}}`,
+		// Only *.html files are partials.
+		"notes.txt": `{{define "tree"}}not a partial{{end}}`,
+	} {
+		if err := os.WriteFile(filepath.Join(partials, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	res := runCLI(t, dir, "--template-dir", "partials", "-o", "out.html", "a.json", "b.json")
+	if res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	html := readFile(t, dir, "out.html")
+	if !strings.Contains(html, `<p id="custom-table">1 change(s)</p>`) {
+		t.Error("the diff-table partial was not replaced")
+	}
+	if strings.Contains(html, "not a partial") || !strings.Contains(html, `id="tree-b-a"`) {
+		t.Error("the default tree partial was not kept")
+	}
+	if strings.Contains(html, `class="diff-section"`) {
+		t.Error("the default diff table was rendered as well")
+	}
+
+	if res := runCLI(t, dir, "--template-dir", "missing", "-o", "out.html", "a.json", "b.json"); res.exit != exitError || !strings.Contains(res.stderr, "holds no *.html partials") {
+		t.Errorf("missing directory: exit %d: %s", res.exit, res.stderr)
+	}
+}
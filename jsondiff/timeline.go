@@ -0,0 +1,167 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/r3labs/diff/v3"
+)
+
+// absentMarker stands for a path missing from a snapshot in timeline JSON
+// output, where null would be ambiguous.
+var absentMarker = map[string]bool{"$absent": true}
+
+// TimelineCell is the value of one path in one snapshot.
+type TimelineCell struct {
+	Value   interface{}
+	Present bool
+	// Changed is set when the cell differs from the previous snapshot.
+	Changed bool
+}
+
+// Text is the cell as compact JSON, or "absent".
+func (c TimelineCell) Text() string {
+	if !c.Present {
+		return "absent"
+	}
+	data, _ := json.Marshal(c.Value)
+	return string(data)
+}
+
+// TimelineRow is the history of one path across all snapshots.
+type TimelineRow struct {
+	Path  string
+	Cells []TimelineCell
+}
+
+// buildTimeline diffs each consecutive pair of docs and returns, for every
+// path changed anywhere in the sequence, its value in each snapshot.
+func buildTimeline(docs []interface{}) ([]TimelineRow, error) {
+	changed := make(map[string]bool)
+	for i := 1; i < len(docs); i++ {
+		changes, err := diff.Diff(docs[i-1], docs[i], diff.AllowTypeMismatch(true), diff.CustomValueDiffers(&unorderedDiffer{}))
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range changes {
+			changed[changePath(c, docs[i-1], docs[i])] = true
+		}
+	}
+
+	paths := make([]string, 0, len(changed))
+	for p := range changed {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	rows := make([]TimelineRow, 0, len(paths))
+	for _, p := range paths {
+		row := TimelineRow{Path: p}
+		for i, doc := range docs {
+			v, ok := lookupPath(doc, splitPath(p))
+			cell := TimelineCell{Value: v, Present: ok}
+			if i > 0 {
+				cell.Changed = cell.Text() != row.Cells[i-1].Text()
+			}
+			row.Cells = append(row.Cells, cell)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// runTimeline implements the "timeline" subcommand: it shows how every
+// changed path evolves across a sequence of snapshots.
+func runTimeline(args []string) int {
+	fs := flag.NewFlagSet("timeline", flag.ContinueOnError)
+	var outputFile, format string
+	fs.StringVar(&outputFile, "o", "", "Output file, or - for stdout (default timeline.<format>)")
+	fs.StringVar(&format, "format", "html", "Output format: html or json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: jsondiff timeline [-o output] [--format html|json] snap1.json snap2.json...")
+		fs.PrintDefaults()
+	}
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return flagExit(err)
+	}
+	if len(files) < 2 {
+		fs.Usage()
+		return exitError
+	}
+	if format != "html" && format != "json" {
+		return fatalf("Invalid --format %q: must be html or json", format)
+	}
+	if outputFile == "" {
+		outputFile = "timeline." + format
+	}
+
+	docs := make([]interface{}, len(files))
+	labels := make([]string, len(files))
+	for i, filename := range files {
+		doc, err := readJSONFile(filename, defaultInputLimits)
+		if err != nil {
+			return fatalf("Failed to read %s: %v", filename, err)
+		}
+		docs[i], labels[i] = doc, filepath.Base(filename)
+	}
+	rows, err := buildTimeline(docs)
+	if err != nil {
+		return fatalf("Failed to diff: %v", err)
+	}
+
+	w := io.Writer(os.Stdout)
+	var f *os.File
+	if outputFile != "-" {
+		if f, err = os.Create(outputFile); err != nil {
+			return fatalf("Failed to create output file: %v", err)
+		}
+		w = f
+	}
+	if format == "json" {
+		err = writeTimelineJSON(w, rows)
+	} else {
+		var tpl *template.Template
+		if tpl, err = parseReportTemplate(nil, nil, renderContext{}); err == nil {
+			err = tpl.ExecuteTemplate(w, "timeline", map[string]interface{}{
+				"Snapshots": labels,
+				"Rows":      rows,
+			})
+		}
+	}
+	if err == nil && f != nil {
+		err = f.Close()
+	}
+	if err != nil {
+		return fatalf("Failed to write timeline: %v", err)
+	}
+	if f != nil {
+		fmt.Printf("Timeline written to %s\n", outputFile)
+	}
+	return exitOK
+}
+
+// writeTimelineJSON writes {path: [v1, v2, ...]} for the changed paths, with
+// {"$absent": true} where a snapshot lacks the path.
+func writeTimelineJSON(w io.Writer, rows []TimelineRow) error {
+	out := make(map[string][]interface{}, len(rows))
+	for _, row := range rows {
+		values := make([]interface{}, len(row.Cells))
+		for i, c := range row.Cells {
+			values[i] = c.Value
+			if !c.Present {
+				values[i] = absentMarker
+			}
+		}
+		out[row.Path] = values
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
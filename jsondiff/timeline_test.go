@@ -0,0 +1,85 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildTimeline(t *testing.T) {
+	docs := []interface{}{
+		map[string]interface{}{"v": 1.0, "tags": []interface{}{"a"}, "same": true},
+		map[string]interface{}{"v": 2.0, "tags": []interface{}{"a", "b"}, "same": true, "new": nil},
+		map[string]interface{}{"v": 2.0, "tags": []interface{}{"a", "b"}, "same": true},
+	}
+	rows, err := buildTimeline(docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string][]string)
+	for _, row := range rows {
+		for _, c := range row.Cells {
+			mark := ""
+			if c.Changed {
+				mark = "*"
+			}
+			got[row.Path] = append(got[row.Path], c.Text()+mark)
+		}
+	}
+	want := map[string][]string{
+		// A null member is present, unlike an absent one.
+		"new":     {"absent", "null*", "absent*"},
+		"tags[1]": {"absent", `"b"*`, `"b"`},
+		"v":       {"1", "2*", "2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("timeline %q, want %q", got, want)
+	}
+	if rows[0].Path != "new" || rows[2].Path != "v" {
+		t.Errorf("rows not sorted by path: %+v", rows)
+	}
+}
+
+// TestTimelineCLI checks the JSON and HTML timelines of three snapshots.
+func TestTimelineCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"1.json": `{"replicas": 1, "image": "web:1"}`,
+		"2.json": `{"replicas": 2, "image": "web:1"}`,
+		"3.json": `{"replicas": 2}`,
+	})
+	res := runCLI(t, dir, "timeline", "--format", "json", "-o", "-", "1.json", "2.json", "3.json")
+	if res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	var got map[string][]interface{}
+	if err := json.Unmarshal([]byte(res.stdout), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]interface{}{
+		"image":    {"web:1", "web:1", map[string]interface{}{"$absent": true}},
+		"replicas": {1.0, 2.0, 2.0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("timeline %v, want %v", got, want)
+	}
+
+	res = runCLI(t, dir, "timeline", "1.json", "2.json", "3.json")
+	if res.exit != exitOK || res.stdout != "Timeline written to timeline.html\n" {
+		t.Fatalf("exit %d, stdout %q: %s", res.exit, res.stdout, res.stderr)
+	}
+	html := readFile(t, dir, "timeline.html")
+	// The page follows the newline after its template's define.
+	wellFormed["html"](t, strings.TrimSpace(html))
+	for _, want := range []string{"1.json", "3.json", "replicas", "absent"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("timeline.html has no %q", want)
+		}
+	}
+
+	for _, args := range [][]string{{"1.json"}, {"--format", "csv", "1.json", "2.json"}, {"1.json", "missing.json"}} {
+		if res := runCLI(t, dir, append([]string{"timeline"}, args...)...); res.exit != exitError {
+			t.Errorf("%q: exit %d, want %d", args, res.exit, exitError)
+		}
+	}
+}
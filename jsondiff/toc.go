@@ -0,0 +1,69 @@
+package jsondiff
+
+import "sort"
+
+// TOCEntry is one top-level key in the table of contents, over the merged
+// key set of both documents.
+type TOCEntry struct {
+	Key string
+	// Status is a ChangeType, or "contains-changes" when only members
+	// below the key changed.
+	Status   string
+	InA, InB bool
+	// AnchorA and AnchorB are the ids of the key's items in the trees.
+	AnchorA, AnchorB string
+}
+
+// DisplayKey is Key as shown in the table of contents.
+func (e TOCEntry) DisplayKey() string {
+	return middleTruncate(e.Key, maxDisplayKey)
+}
+
+// treeAnchor is the id of the tree item for the top-level key at path on
+// the given side.
+func treeAnchor(side Side, path string) string {
+	return anchorID("tree-"+string(side)+"-", path)
+}
+
+// buildTOC lists the top-level keys of a and b with their change status.
+// Keys are merged in the path space of the DiffMap, so members paired by
+// --ignore-key-case or key normalization appear once. It returns nil unless
+// both documents are objects.
+func buildTOC(a, b interface{}, base renderContext) []TOCEntry {
+	objA, okA := a.(map[string]interface{})
+	objB, okB := b.(map[string]interface{})
+	if !okA || !okB {
+		return nil
+	}
+	byPath := make(map[string]*TOCEntry)
+	add := func(obj map[string]interface{}, side Side) {
+		ctx := base
+		ctx.side = side
+		for k := range obj {
+			p := ctx.segment("", k)
+			e, ok := byPath[p]
+			if !ok {
+				e = &TOCEntry{Key: k}
+				byPath[p] = e
+			}
+			if side == SideA {
+				e.InA, e.AnchorA = true, treeAnchor(side, p)
+			} else {
+				e.Key, e.InB, e.AnchorB = k, true, treeAnchor(side, p)
+			}
+		}
+	}
+	add(objA, SideA)
+	add(objB, SideB)
+
+	toc := make([]TOCEntry, 0, len(byPath))
+	for p, e := range byPath {
+		e.Status = getChangeType(base.diffMap, p)
+		if e.Status == string(Unchanged) && base.diffMap.HasChangedDescendant(p) {
+			e.Status = "contains-changes"
+		}
+		toc = append(toc, *e)
+	}
+	sort.Slice(toc, func(i, j int) bool { return toc[i].Key < toc[j].Key })
+	return toc
+}
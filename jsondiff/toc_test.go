@@ -0,0 +1,60 @@
+package jsondiff
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestBuildTOC(t *testing.T) {
+	a := map[string]interface{}{"keep": 1.0, "chg": 1.0, "gone": 1.0, "obj": map[string]interface{}{"x": 1.0}, "UserId": 1.0}
+	b := map[string]interface{}{"keep": 1.0, "chg": 2.0, "new": 1.0, "obj": map[string]interface{}{"x": 2.0}, "userId": 1.0}
+	cmp1, folds := foldKeyCase(a, b, func(string) {})
+	changes, err := collectChanges(diffSeq(context.Background(), cmp1, b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := renderContext{diffMap: buildDiffMap(changes, cmp1, b), folds: folds}
+
+	// UserId and userId are paired, and listed once under B's key.
+	want := []TOCEntry{
+		{Key: "chg", Status: "changed", InA: true, InB: true, AnchorA: "tree-a-chg", AnchorB: "tree-b-chg"},
+		{Key: "gone", Status: "removed", InA: true, AnchorA: "tree-a-gone"},
+		{Key: "keep", Status: "unchanged", InA: true, InB: true, AnchorA: "tree-a-keep", AnchorB: "tree-b-keep"},
+		{Key: "new", Status: "added", InB: true, AnchorB: "tree-b-new"},
+		{Key: "obj", Status: "contains-changes", InA: true, InB: true, AnchorA: "tree-a-obj", AnchorB: "tree-b-obj"},
+		{Key: "userId", Status: "unchanged", InA: true, InB: true, AnchorA: "tree-a-userId", AnchorB: "tree-b-userId"},
+	}
+	if got := buildTOC(a, b, base); !reflect.DeepEqual(got, want) {
+		t.Errorf("toc\n%+v\nwant\n%+v", got, want)
+	}
+
+	if got := buildTOC([]interface{}{}, b, base); got != nil {
+		t.Errorf("array document: %+v", got)
+	}
+}
+
+// TestTOCLinksCLI checks that every link of the table of contents leads to
+// an item of the trees, for keys paired by --ignore-key-case too.
+func TestTOCLinksCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"UserId": 1, "gone": {"x": 1}, "same key": [1]}`,
+		"b.json": `{"userId": 2, "new": true, "same key": [1, 2]}`,
+	})
+	if res := runCLI(t, dir, "--ignore-key-case", "-o", "out.html", "a.json", "b.json"); res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	html := readFile(t, dir, "out.html")
+	toc := regexp.MustCompile(`(?s)<nav class="doc-toc".*?</nav>`).FindString(html)
+	links := regexp.MustCompile(`href="#([^"]+)"`).FindAllStringSubmatch(toc, -1)
+	if len(links) != 6 {
+		t.Fatalf("%d links in %s", len(links), toc)
+	}
+	for _, l := range links {
+		if !strings.Contains(html, ` id="`+l[1]+`"`) {
+			t.Errorf("link to #%s has no target", l[1])
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package jsondiff
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxDisplayKey is the number of runes of a key or path shown in the
+	// HTML report before it is middle-truncated.
+	maxDisplayKey = 80
+	// maxDisplayValue is the number of runes of a From or To value shown
+	// in the HTML table before it is middle-truncated; its copy button
+	// still copies the whole value.
+	maxDisplayValue = 200
+	// maxAnchorLength caps the length of generated element ids.
+	maxAnchorLength = 64
+)
+
+// middleTruncate shortens s to at most max runes by replacing its middle
+// with an ellipsis, keeping both ends since long keys such as URLs tend to
+// differ at either end. It never cuts inside a rune.
+func middleTruncate(s string, max int) string {
+	n := utf8.RuneCountInString(s)
+	if n <= max || max < 3 {
+		return s
+	}
+	keep := max - 1
+	head, tail := (keep+1)/2, keep/2
+	runes := []rune(s)
+	return string(runes[:head]) + "…" + string(runes[n-tail:])
+}
+
+// DisplayPath is the path as shown in the HTML table; the full path goes in
+// a title attribute.
+func (r DiffResult) DisplayPath() string {
+	return middleTruncate(r.Path, maxDisplayKey)
+}
+
+// Truncated reports whether DisplayPath shortens the path.
+func (r DiffResult) Truncated() bool {
+	return utf8.RuneCountInString(r.Path) > maxDisplayKey
+}
+
+// DisplayFrom and DisplayTo are the old and new value as shown in the HTML
+// table, formatted for display if the options ask for it.
+func (r DiffResult) DisplayFrom() string { return displayValue(r.From, r.FromFormatted) }
+func (r DiffResult) DisplayTo() string   { return displayValue(r.To, r.ToFormatted) }
+
+func displayValue(raw, formatted string) string {
+	if formatted != "" {
+		return formatted
+	}
+	return middleTruncate(raw, maxDisplayValue)
+}
+
+// DisplayName is the section name as shown in the HTML report.
+func (s DiffSection) DisplayName() string {
+	return middleTruncate(s.Name, maxDisplayKey)
+}
+
+// keyHTML renders an object key for the tree, truncating long keys and
+// keeping the full key in a title attribute.
+func (ctx *renderContext) keyHTML(k string) string {
+	shown := middleTruncate(k, maxDisplayKey)
+	if shown == k {
+		return `<span class="` + ctx.cls("key") + `">"` + escapeHTML(k) + `"</span>`
+	}
+	return `<span class="` + ctx.cls("key") + `"` + attr("title", k) + `>"` + escapeHTML(shown) + `"</span>`
+}
+
+// anchorID builds an element id from prefix and name. Ids longer than
+// maxAnchorLength are cut and suffixed with a hash of the full name so
+// they stay unique.
+func anchorID(prefix, name string) string {
+	id := prefix + strings.Join(strings.Fields(name), "-")
+	if len(id) <= maxAnchorLength {
+		return id
+	}
+	sum := sha256.Sum256([]byte(name))
+	hash := hex.EncodeToString(sum[:])[:12]
+	cut := maxAnchorLength - len(hash) - 1
+	for cut > 0 && !utf8.RuneStart(id[cut]) {
+		cut--
+	}
+	return id[:cut] + "-" + hash
+}
@@ -0,0 +1,77 @@
+package jsondiff
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// multibyteKeys mix runes of every UTF-8 length so that some cut lands
+// inside each kind of rune.
+var multibyteKeys = []string{
+	strings.Repeat("é", 100),
+	strings.Repeat("日本", 60),
+	strings.Repeat("𝄞a", 70),
+	"a" + strings.Repeat("é日𝄞", 40) + "z",
+}
+
+func TestMiddleTruncateKeepsRunes(t *testing.T) {
+	for _, s := range multibyteKeys {
+		runes := []rune(s)
+		for max := 3; max <= len(runes)+1; max++ {
+			got := middleTruncate(s, max)
+			if !utf8.ValidString(got) {
+				t.Fatalf("max %d: %+q is not valid UTF-8", max, got)
+			}
+			n := utf8.RuneCountInString(got)
+			if n > max {
+				t.Fatalf("max %d: %d runes", max, n)
+			}
+			if len(runes) <= max {
+				if got != s {
+					t.Fatalf("max %d: shortened a string that fits", max)
+				}
+				continue
+			}
+			head, tail, ok := strings.Cut(got, "…")
+			if !ok || n != max || !strings.HasPrefix(s, head) || !strings.HasSuffix(s, tail) {
+				t.Fatalf("max %d: %q does not keep both ends of the string", max, got)
+			}
+		}
+	}
+	if got := middleTruncate("abcdef", 2); got != "abcdef" {
+		t.Errorf("max 2: %q", got)
+	}
+}
+
+func TestAnchorIDKeepsRunes(t *testing.T) {
+	seen := make(map[string]string)
+	for _, s := range multibyteKeys {
+		for n := 1; n <= len(s); n++ {
+			name := s[:n]
+			if !utf8.ValidString(name) {
+				continue
+			}
+			id := anchorID("sec-", name)
+			if !utf8.ValidString(id) || len(id) > maxAnchorLength {
+				t.Fatalf("%q: id %+q", name, id)
+			}
+			if prev, ok := seen[id]; ok && prev != name {
+				t.Fatalf("%q and %q share the id %s", prev, name, id)
+			}
+			seen[id] = name
+		}
+	}
+}
+
+func TestKeyHTMLTruncation(t *testing.T) {
+	ctx := &renderContext{}
+	long := strings.Repeat("日", maxDisplayKey+10)
+	got := ctx.keyHTML(long)
+	if !strings.Contains(got, `title="`+long+`"`) || !strings.Contains(got, "…") || !utf8.ValidString(got) {
+		t.Errorf("long key: %s", got)
+	}
+	if got := ctx.keyHTML("short"); strings.Contains(got, "title=") {
+		t.Errorf("short key: %s", got)
+	}
+}
@@ -0,0 +1,204 @@
+package jsondiff
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/r3labs/diff/v3"
+)
+
+// Quantity kinds accepted by --units.
+const (
+	unitDuration = "duration"
+	unitBytes    = "bytes"
+)
+
+// unitRule compares the strings at paths matching pattern as quantities of
+// unit, e.g. "30s" or "512Mi".
+type unitRule struct {
+	pattern []string
+	unit    string
+}
+
+// parseUnitRules parses --units values of the form path-pattern=unit.
+func parseUnitRules(values []string) ([]unitRule, error) {
+	rules := make([]unitRule, 0, len(values))
+	for _, v := range values {
+		pattern, unit, ok := strings.Cut(v, "=")
+		if !ok || pattern == "" || (unit != unitDuration && unit != unitBytes) {
+			return nil, fmt.Errorf("invalid --units %q: must be path-pattern=duration or path-pattern=bytes", v)
+		}
+		rules = append(rules, unitRule{splitPath(pattern), unit})
+	}
+	return rules, nil
+}
+
+// byteUnits maps lower-cased byte suffixes to their multipliers: SI powers
+// of 1000 and binary powers of 1024, with or without a trailing "b".
+var byteUnits = map[string]float64{"": 1, "b": 1}
+
+func init() {
+	for i, prefix := range []string{"k", "m", "g", "t", "p", "e"} {
+		si, bin := math.Pow(1000, float64(i+1)), math.Pow(1024, float64(i+1))
+		byteUnits[prefix], byteUnits[prefix+"b"] = si, si
+		byteUnits[prefix+"i"], byteUnits[prefix+"ib"] = bin, bin
+	}
+}
+
+// quantity is a --units value parsed in its unit's base: seconds for
+// durations, bytes for sizes. A string spelling NaN or an infinity parses
+// to its nonFinite sentinel instead of an amount.
+type quantity struct {
+	amount  float64
+	special nonFinite
+}
+
+// equal reports whether q and o are the same finite quantity.
+func (q quantity) equal(o quantity) bool {
+	return q.special == "" && o.special == "" && q.amount == o.amount
+}
+
+func (q quantity) format(unit string) string {
+	if q.special != "" {
+		return q.special.String()
+	}
+	return formatQuantity(unit, q.amount)
+}
+
+// quantityNumber matches the decimal number, possibly in scientific
+// notation, that starts a byte size, so that the "e" of "1e5" is not taken
+// for the exa prefix.
+var quantityNumber = regexp.MustCompile(`^[+-]?(?:[0-9]+(?:\.[0-9]*)?|\.[0-9]+)(?:[eE][+-]?[0-9]+)?`)
+
+// parseQuantity parses s as a quantity of unit. Byte suffixes are
+// case-insensitive.
+func parseQuantity(unit, s string) (quantity, error) {
+	s = strings.TrimSpace(s)
+	if n, ok := parseNonFinite(s); ok {
+		return quantity{special: n}, nil
+	}
+	if unit == unitDuration {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return quantity{}, err
+		}
+		return quantity{amount: d.Seconds()}, nil
+	}
+	num := quantityNumber.FindString(s)
+	if num == "" {
+		return quantity{}, fmt.Errorf("invalid byte size %q", s)
+	}
+	suffix := strings.TrimSpace(s[len(num):])
+	mult, ok := byteUnits[strings.ToLower(suffix)]
+	if !ok {
+		return quantity{}, fmt.Errorf("unknown byte unit %q in %q", suffix, s)
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || !isFinite(n*mult) {
+		return quantity{}, fmt.Errorf("byte size %q out of range", s)
+	}
+	return quantity{amount: n * mult}, nil
+}
+
+// formatQuantity formats a quantity of unit for the delta column.
+func formatQuantity(unit string, q float64) string {
+	if unit == unitDuration {
+		return time.Duration(q * float64(time.Second)).String()
+	}
+	abs := math.Abs(q)
+	for i, suffix := range []string{"Ei", "Pi", "Ti", "Gi", "Mi", "Ki"} {
+		if m := math.Pow(1024, float64(6-i)); abs >= m {
+			return strconv.FormatFloat(q/m, 'f', -1, 64) + suffix
+		}
+	}
+	return strconv.FormatFloat(q, 'f', -1, 64) + "B"
+}
+
+// unitQuantities holds the parsed old and new quantities of the updates
+// that --units compared, by path.
+type unitQuantities map[string]unitChange
+
+type unitChange struct {
+	unit     string
+	from, to quantity
+}
+
+// filterUnits compares string updates at paths matching rules as
+// quantities. Updates between equal quantities are dropped and their paths
+// added to approx; the others are recorded in the returned map so the table
+// can show a normalized delta. An update from or to NaN or an infinity is
+// always kept. Values that do not parse are compared as strings, with a
+// warning.
+func filterUnits(changes []diff.Change, rules []unitRule, a, b interface{}, approx map[string]bool, suppressed *suppressionLog) ([]diff.Change, unitQuantities) {
+	quantities := make(unitQuantities)
+	kept := changes[:0:0]
+	for _, c := range changes {
+		from, okFrom := c.From.(string)
+		to, okTo := c.To.(string)
+		if c.Type != diff.UPDATE || !okFrom || !okTo {
+			kept = append(kept, c)
+			continue
+		}
+		path := changePath(c, a, b)
+		rule, ok := matchingUnitRule(rules, splitPath(path))
+		if !ok {
+			kept = append(kept, c)
+			continue
+		}
+		qFrom, err := parseQuantity(rule.unit, from)
+		var qTo quantity
+		if err == nil {
+			qTo, err = parseQuantity(rule.unit, to)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %v; compared as strings\n", path, err)
+			kept = append(kept, c)
+			continue
+		}
+		if qFrom.equal(qTo) {
+			suppressed.add("equal "+rule.unit, c)
+			approx[path] = true
+			continue
+		}
+		quantities[path] = unitChange{rule.unit, qFrom, qTo}
+		kept = append(kept, c)
+	}
+	return kept, quantities
+}
+
+func matchingUnitRule(rules []unitRule, path []string) (unitRule, bool) {
+	for _, r := range rules {
+		if matchPath(r.pattern, path) {
+			return r, true
+		}
+	}
+	return unitRule{}, false
+}
+
+// setUnitDeltas gives the results compared by --units a delta in their
+// unit, or, when either side is not finite, a NonFinite description.
+func setUnitDeltas(results []DiffResult, quantities unitQuantities) {
+	for i := range results {
+		q, ok := quantities[results[i].Path]
+		if !ok {
+			continue
+		}
+		r := &results[i]
+		r.Unit = q.unit
+		if q.from.special != "" || q.to.special != "" {
+			r.NonFinite = q.from.format(q.unit) + " → " + q.to.format(q.unit)
+			continue
+		}
+		d := q.to.amount - q.from.amount
+		r.Delta = &d
+		if q.from.amount != 0 {
+			pct := d / math.Abs(q.from.amount) * 100
+			r.DeltaPercent = &pct
+		}
+	}
+}
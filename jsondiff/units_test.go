@@ -0,0 +1,157 @@
+package jsondiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/r3labs/diff/v3"
+)
+
+func TestParseUnitRules(t *testing.T) {
+	rules, err := parseUnitRules([]string{"spec.*.timeout=duration", "limits.memory=bytes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0].unit != unitDuration || joinPath(rules[1].pattern) != "limits.memory" {
+		t.Errorf("rules %+v", rules)
+	}
+	for _, v := range []string{"timeout", "=duration", "timeout=", "timeout=seconds", "timeout=Duration"} {
+		if _, err := parseUnitRules([]string{v}); err == nil {
+			t.Errorf("%q: no error", v)
+		}
+	}
+}
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		unit, s string
+		want    float64
+		special nonFinite
+	}{
+		{unitDuration, "30s", 30, ""},
+		{unitDuration, "1m30s", 90, ""},
+		{unitDuration, " 1.5h ", 5400, ""},
+		{unitDuration, "-250ms", -0.25, ""},
+		{unitDuration, "0", 0, ""},
+		{unitBytes, "512", 512, ""},
+		{unitBytes, "512b", 512, ""},
+		{unitBytes, "1k", 1000, ""},
+		{unitBytes, "1KB", 1000, ""},
+		{unitBytes, "1Ki", 1024, ""},
+		{unitBytes, "1kib", 1024, ""},
+		{unitBytes, "512Mi", 512 << 20, ""},
+		{unitBytes, "1.5 Gi", 1.5 * (1 << 30), ""},
+		{unitBytes, "1e3", 1000, ""},
+		{unitBytes, "1e3k", 1e6, ""},
+		{unitBytes, "1E", 1e18, ""},
+		{unitBytes, ".5k", 500, ""},
+		{unitBytes, "-Infinity", 0, negInfinity},
+		{unitDuration, "NaN", 0, notANumber},
+	}
+	for _, tt := range tests {
+		q, err := parseQuantity(tt.unit, tt.s)
+		if err != nil {
+			t.Errorf("%s %q: %v", tt.unit, tt.s, err)
+			continue
+		}
+		if q.amount != tt.want || q.special != tt.special {
+			t.Errorf("%s %q: %+v, want %v%s", tt.unit, tt.s, q, tt.want, tt.special)
+		}
+	}
+}
+
+func TestParseQuantityInvalid(t *testing.T) {
+	tests := []struct {
+		unit, s, want string
+	}{
+		{unitDuration, "", `invalid duration ""`},
+		{unitDuration, "30", `missing unit in duration "30"`},
+		{unitDuration, "30 s", `unknown unit " s" in duration "30 s"`},
+		{unitDuration, "1d", `unknown unit "d" in duration "1d"`},
+		{unitBytes, "", `invalid byte size ""`},
+		{unitBytes, "Mi", `invalid byte size "Mi"`},
+		{unitBytes, "1.2.3", `unknown byte unit ".3" in "1.2.3"`},
+		{unitBytes, "5XB", `unknown byte unit "XB" in "5XB"`},
+		{unitBytes, "1iB", `unknown byte unit "iB" in "1iB"`},
+		{unitBytes, "1e400", `byte size "1e400" out of range`},
+		{unitBytes, "1e300E", `byte size "1e300E" out of range`},
+	}
+	for _, tt := range tests {
+		_, err := parseQuantity(tt.unit, tt.s)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s %q: error %v, want %s", tt.unit, tt.s, err, tt.want)
+		}
+	}
+}
+
+func TestQuantityEqual(t *testing.T) {
+	parse := func(unit, s string) quantity {
+		q, err := parseQuantity(unit, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return q
+	}
+	if !parse(unitBytes, "1Gi").equal(parse(unitBytes, "1024Mi")) {
+		t.Error("1Gi != 1024Mi")
+	}
+	if parse(unitBytes, "1G").equal(parse(unitBytes, "1Gi")) {
+		t.Error("1G == 1Gi")
+	}
+	if !parse(unitDuration, "90s").equal(parse(unitDuration, "1m30s")) {
+		t.Error("90s != 1m30s")
+	}
+	if nan := parse(unitDuration, "NaN"); nan.equal(nan) {
+		t.Error("NaN equals itself")
+	}
+}
+
+func TestFormatQuantity(t *testing.T) {
+	tests := []struct {
+		unit string
+		q    float64
+		want string
+	}{
+		{unitDuration, 90, "1m30s"},
+		{unitDuration, -0.25, "-250ms"},
+		{unitBytes, 512, "512B"},
+		{unitBytes, 1536, "1.5Ki"},
+		{unitBytes, -(1 << 30), "-1Gi"},
+		{unitBytes, 1 << 60, "1Ei"},
+	}
+	for _, tt := range tests {
+		if got := formatQuantity(tt.unit, tt.q); got != tt.want {
+			t.Errorf("%s %v: %s, want %s", tt.unit, tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestFilterUnits(t *testing.T) {
+	a := mustDecode(t, `{"mem": "1Gi", "cpu": "2s", "t": "30s", "bad": "lots"}`)
+	b := mustDecode(t, `{"mem": "1024Mi", "cpu": "3s", "t": "30 s", "bad": "more"}`)
+	update := func(path, from, to string) diff.Change {
+		return diff.Change{Type: diff.UPDATE, Path: []string{path}, From: from, To: to}
+	}
+	changes := []diff.Change{update("mem", "1Gi", "1024Mi"), update("cpu", "2s", "3s"), update("t", "30s", "30 s"), update("bad", "lots", "more")}
+	rules, _ := parseUnitRules([]string{"mem=bytes", "cpu=duration", "t=duration", "bad=bytes"})
+	approx := make(map[string]bool)
+	suppressed := newSuppressionLog()
+	kept, quantities := filterUnits(changes, rules, a, b, approx, suppressed)
+
+	if len(kept) != 3 {
+		t.Errorf("kept %v, want all but mem", kept)
+	}
+	if !approx["mem"] || len(suppressed.changes["equal bytes"]) != 1 {
+		t.Errorf("mem not suppressed: approx %v, suppressed %v", approx, suppressed.changes)
+	}
+	if q, ok := quantities["cpu"]; !ok || q.from.amount != 2 || q.to.amount != 3 {
+		t.Errorf("cpu quantities %+v", q)
+	}
+	// Values that do not parse are compared as strings.
+	if _, ok := quantities["bad"]; ok {
+		t.Error("bad has quantities")
+	}
+	if _, ok := quantities["t"]; ok {
+		t.Error(`"30 s" parsed as a duration`)
+	}
+}
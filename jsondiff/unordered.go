@@ -0,0 +1,228 @@
+package jsondiff
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/r3labs/diff/v3"
+)
+
+// unorderedDiffer compares arrays as unordered collections in place of the
+// library's matcher, so that duplicates are matched by count and the result
+// never depends on which equal element was tried first:
+//
+//  1. Elements are keyed by a canonical encoding that sorts object keys and,
+//     arrays being unordered too, array elements, so equal values share a
+//     key.
+//  2. Each element of a, in index order, is matched to the lowest-indexed
+//     unmatched element of b with the same key. Matched elements are equal
+//     and produce no change.
+//  3. Leftovers at the same index on both sides are compared with each
+//     other, so an element edited in place is reported as an update, or as
+//     changes inside it, provided they are of the same JSON type (see
+//     pairInPlace).
+//  4. Every other leftover is reported as removed at its index in a or
+//     added at its index in b.
+//
+// Nulls are matched last, and a null facing an unmatched value at its own
+// index on the other side is left unmatched, so the two are compared in
+// place by step 3: [1, null, 3] against [1, 2, null] un-nulls the second
+// element and nulls the third rather than removing 3 and adding 2.
+//
+// A leftover facing one of another type is not compared with it while a
+// leftover of its own type waits elsewhere on the other side: [1, "x"]
+// against ["y", 2] removes and adds all four elements rather than turning 1
+// into "y" and "x" into 2, leaving like elements to --array-match-threshold
+// and --detect-moves, which pair across indices.
+//
+// Duplicates therefore only count as far as their numbers differ: ["x",
+// "x", "y"] against ["x", "y", "y"] removes one "x" and adds one "y",
+// however the elements are ordered.
+//
+// ctx, when set, is checked as the elements are keyed, so that --timeout
+// stops a walk spending all its time matching one long array.
+type unorderedDiffer struct {
+	ctx    context.Context
+	parent func(path []string, a, b reflect.Value, p interface{}) error
+}
+
+func (d *unorderedDiffer) Match(a, b reflect.Value) bool {
+	return a.Kind() == reflect.Slice && b.Kind() == reflect.Slice
+}
+
+func (d *unorderedDiffer) Diff(dt diff.DiffType, df diff.DiffFunc, cl *diff.Changelog, path []string, a, b reflect.Value, parent interface{}) error {
+	matchedA, matchedB, err := matchElements(d.ctx, a, b)
+	if err != nil {
+		return err
+	}
+	inPlace := pairInPlace(a, b, matchedA, matchedB)
+	for i := 0; i < max(a.Len(), b.Len()); i++ {
+		leftA := i < a.Len() && !matchedA[i]
+		leftB := i < b.Len() && !matchedB[i]
+		elemPath := append(append([]string(nil), path...), strconv.Itoa(i))
+		if leftA && leftB && inPlace[i] {
+			if err := d.parent(elemPath, a.Index(i), b.Index(i), nil); err != nil {
+				return err
+			}
+			continue
+		}
+		if leftA {
+			cl.Add(diff.DELETE, elemPath, a.Index(i).Interface(), nil)
+		}
+		if leftB {
+			cl.Add(diff.CREATE, elemPath, nil, b.Index(i).Interface())
+		}
+	}
+	return nil
+}
+
+func (d *unorderedDiffer) InsertParentDiffer(dfunc func(path []string, a, b reflect.Value, p interface{}) error) {
+	d.parent = dfunc
+}
+
+// matchElements pairs equal elements of a and b as described on
+// unorderedDiffer, reporting which elements of each side found a partner.
+// It returns ctx.Err() once a non-nil ctx is done.
+func matchElements(ctx context.Context, a, b reflect.Value) (matchedA, matchedB []bool, err error) {
+	done := func() error {
+		if ctx == nil {
+			return nil
+		}
+		return ctx.Err()
+	}
+	unmatched := make(map[string][]int)
+	for j := 0; j < b.Len(); j++ {
+		if err := done(); err != nil {
+			return nil, nil, err
+		}
+		if isNullElem(b, j) {
+			continue
+		}
+		k := canonicalKey(b.Index(j).Interface())
+		unmatched[k] = append(unmatched[k], j)
+	}
+	matchedA, matchedB = make([]bool, a.Len()), make([]bool, b.Len())
+	for i := 0; i < a.Len(); i++ {
+		if err := done(); err != nil {
+			return nil, nil, err
+		}
+		if isNullElem(a, i) {
+			continue
+		}
+		k := canonicalKey(a.Index(i).Interface())
+		if js := unmatched[k]; len(js) > 0 {
+			matchedA[i], matchedB[js[0]] = true, true
+			unmatched[k] = js[1:]
+		}
+	}
+	matchNulls(a, b, matchedA, matchedB)
+	return matchedA, matchedB, nil
+}
+
+// matchNulls pairs the nulls of a and b once every other element has been
+// matched, skipping those facing an unmatched value at their own index.
+func matchNulls(a, b reflect.Value, matchedA, matchedB []bool) {
+	facesValue := func(other reflect.Value, matched []bool, i int) bool {
+		return i < other.Len() && !matched[i] && !isNullElem(other, i)
+	}
+	var nullsB []int
+	for j := 0; j < b.Len(); j++ {
+		if isNullElem(b, j) && !facesValue(a, matchedA, j) {
+			nullsB = append(nullsB, j)
+		}
+	}
+	for i := 0; i < a.Len() && len(nullsB) > 0; i++ {
+		if isNullElem(a, i) && !facesValue(b, matchedB, i) {
+			matchedA[i], matchedB[nullsB[0]] = true, true
+			nullsB = nullsB[1:]
+		}
+	}
+}
+
+// pairInPlace reports, for each index below the shorter length, whether
+// the leftovers of a and b there are compared in place. They are when they
+// share a JSON type or either is null. Otherwise they are only when neither
+// has a leftover of its own type waiting on the other side, so that an
+// element changing type in place is still reported as an update.
+func pairInPlace(a, b reflect.Value, matchedA, matchedB []bool) []bool {
+	n := min(a.Len(), b.Len())
+	inPlace := make([]bool, n)
+	typeAt := func(v reflect.Value, i int) string {
+		return jsonTypeName(v.Index(i).Interface())
+	}
+	// waitingA and waitingB count the leftovers of each type not compared
+	// in place.
+	waitingA, waitingB := make(map[string]int), make(map[string]int)
+	for i := 0; i < max(a.Len(), b.Len()); i++ {
+		leftA := i < a.Len() && !matchedA[i]
+		leftB := i < b.Len() && !matchedB[i]
+		if leftA && leftB {
+			ta, tb := typeAt(a, i), typeAt(b, i)
+			if ta == tb || ta == "null" || tb == "null" {
+				inPlace[i] = true
+				continue
+			}
+		}
+		if leftA {
+			waitingA[typeAt(a, i)]++
+		}
+		if leftB {
+			waitingB[typeAt(b, i)]++
+		}
+	}
+	for i := 0; i < n; i++ {
+		if inPlace[i] || matchedA[i] || matchedB[i] {
+			continue
+		}
+		ta, tb := typeAt(a, i), typeAt(b, i)
+		if waitingB[ta] == 0 && waitingA[tb] == 0 {
+			inPlace[i] = true
+			waitingA[ta]--
+			waitingB[tb]--
+		}
+	}
+	return inPlace
+}
+
+// isNullElem reports whether element i of the slice v is null.
+func isNullElem(v reflect.Value, i int) bool {
+	return v.Index(i).Interface() == nil
+}
+
+// canonicalKey returns an encoding of v that equal values share, treating
+// arrays as unordered.
+func canonicalKey(v interface{}) string {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		var sb strings.Builder
+		sb.WriteByte('{')
+		for i, k := range sortedKeys(val) {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			key, _ := json.Marshal(k)
+			sb.Write(key)
+			sb.WriteByte(':')
+			sb.WriteString(canonicalKey(val[k]))
+		}
+		sb.WriteByte('}')
+		return sb.String()
+	case []interface{}:
+		keys := make([]string, len(val))
+		for i, elem := range val {
+			keys[i] = canonicalKey(elem)
+		}
+		sort.Strings(keys)
+		return "[" + strings.Join(keys, ",") + "]"
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%T:%v", v, v)
+	}
+	return string(data)
+}
@@ -0,0 +1,198 @@
+package jsondiff
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// fixtureTable compares two files of testdata as the CLI does and returns
+// the table rows by path.
+func fixtureTable(t *testing.T, a, b string) map[string]DiffResult {
+	t.Helper()
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	return diffTable(t, read(a), read(b))
+}
+
+// tableRow is the part of a table row the pairing tests check.
+type tableRow struct{ typ, from, to, fromType, toType string }
+
+func tableRows(rows map[string]DiffResult) map[string]tableRow {
+	got := make(map[string]tableRow, len(rows))
+	for p, r := range rows {
+		got[p] = tableRow{r.Type, r.From, r.To, r.FromType, r.ToType}
+	}
+	return got
+}
+
+func TestMixedTypeArrays(t *testing.T) {
+	rows := fixtureTable(t, "mixed-types-a.json", "mixed-types-b.json")
+	want := map[string]tableRow{
+		"id":        {"update", "7", "7", "number", "string"},
+		"values[3]": {"update", "null", "false", "null", "boolean"},
+		"values[4]": {"update", "true", "true", "boolean", "string"},
+		"values[6]": {"create", "", "2", "", "number"},
+	}
+	if got := tableRows(rows); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows %v, want %v", got, want)
+	}
+	for p, r := range rows {
+		if r.TypeChanged() != (r.Type == "update") {
+			t.Errorf("%s: TypeChanged() = %t", p, r.TypeChanged())
+		}
+	}
+}
+
+func TestNumericKeysAgainstIndices(t *testing.T) {
+	rows := fixtureTable(t, "numeric-keys-array.json", "numeric-keys-object.json")
+	want := map[string]tableRow{
+		"items":   {"update", `["a","b"]`, `{"0":"a","1":"b"}`, "array", "object"},
+		"list[1]": {"update", "z", "y", "string", "string"},
+		"nested":  {"update", `[{"v":1}]`, `{"0":{"v":1}}`, "array", "object"},
+	}
+	if got := tableRows(rows); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows %v, want %v", got, want)
+	}
+}
+
+func TestPairInPlaceByType(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []string
+	}{
+		{
+			name: "same types edited in place",
+			a:    `[1, "x"]`,
+			b:    `[2, "y"]`,
+			want: []string{"update [0]", "update [1]"},
+		},
+		{
+			name: "like elements at other indices",
+			a:    `[1, "x"]`,
+			b:    `["y", 2]`,
+			want: []string{"create [0]", "create [1]", "delete [0]", "delete [1]"},
+		},
+		{
+			name: "no like element to pair with",
+			a:    `[1, "x"]`,
+			b:    `["1", "x"]`,
+			want: []string{"update [0]"},
+		},
+		{
+			name: "null changed in place",
+			a:    `[null, 1]`,
+			b:    `["n", 1]`,
+			want: []string{"update [0]"},
+		},
+		{
+			name: "one like element left over",
+			a:    `[true, "s", 3]`,
+			b:    `["t", false, {}]`,
+			want: []string{"create [0]", "create [1]", "delete [0]", "delete [1]", "update [2]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := mustDecode(t, tt.a), mustDecode(t, tt.b)
+			changes, err := collectChanges(diffSeq(context.Background(), a, b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range buildDiffTable(changes, a, b) {
+				got = append(got, r.Type+" "+r.Path)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changes %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPairSimilarElementsByType checks that elements displaced by a type
+// mismatch are left for --array-match-threshold to pair with their like.
+func TestPairSimilarElementsByType(t *testing.T) {
+	a := mustDecode(t, `{"list": [{"id": 1, "v": 1}, [1, 2]]}`)
+	b := mustDecode(t, `{"list": [[1, 3], {"id": 1, "v": 2}]}`)
+	changes, err := collectChanges(diffSeq(context.Background(), a, b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := buildDiffMap(changes, a, b)
+	results, err := pairSimilarElements(context.Background(), buildDiffTable(changes, a, b), a, b, m, 0.4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Type+" "+r.MovedFrom+" -> "+r.Path)
+	}
+	want := []string{
+		"update list[1] -> list[0]",
+		"update list[1][1] -> list[0][1]",
+		"update list[0] -> list[1]",
+		"update list[0].v -> list[1].v",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("results %q, want %q", got, want)
+	}
+}
+
+// TestUnorderedDuplicates checks that duplicates count only as far as their
+// numbers differ, whichever order the elements are in.
+func TestUnorderedDuplicates(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want map[string]tableRow
+	}{
+		{"counts differ", `{"l": ["x", "x", "y"]}`, `{"l": ["x", "y", "y"]}`, map[string]tableRow{
+			"l[1]": {"delete", "x", "", "string", ""},
+			"l[2]": {"create", "", "y", "", "string"},
+		}},
+		{"shuffled", `{"l": ["x", "y", "x", "z"]}`, `{"l": ["z", "x", "x", "y"]}`, map[string]tableRow{}},
+		{"one duplicate removed", `{"l": ["x", "y", "x"]}`, `{"l": ["y", "x"]}`, map[string]tableRow{
+			"l[2]": {"delete", "x", "", "string", ""},
+		}},
+		{"nested arrays unordered", `{"l": [{"t": [1, 2]}, {"t": [3]}]}`, `{"l": [{"t": [3]}, {"t": [2, 1]}]}`, map[string]tableRow{}},
+	}
+	for _, tt := range tests {
+		if got := tableRows(diffTable(t, tt.a, tt.b)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: rows %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalKey(t *testing.T) {
+	same := [][2]interface{}{
+		{map[string]interface{}{"a": 1.0, "b": []interface{}{"x", "y"}}, map[string]interface{}{"b": []interface{}{"y", "x"}, "a": 1.0}},
+		{[]interface{}{[]interface{}{2.0, 1.0}, 3.0}, []interface{}{3.0, []interface{}{1.0, 2.0}}},
+	}
+	for _, p := range same {
+		if canonicalKey(p[0]) != canonicalKey(p[1]) {
+			t.Errorf("%v and %v have different keys", p[0], p[1])
+		}
+	}
+	different := [][2]interface{}{
+		{"1", 1.0},
+		{[]interface{}{"x", "x"}, []interface{}{"x"}},
+		{map[string]interface{}{"a,b": 1.0}, map[string]interface{}{"a": 1.0, "b": 1.0}},
+		{nil, false},
+	}
+	for _, p := range different {
+		if canonicalKey(p[0]) == canonicalKey(p[1]) {
+			t.Errorf("%v and %v share a key", p[0], p[1])
+		}
+	}
+}
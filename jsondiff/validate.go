@@ -0,0 +1,76 @@
+package jsondiff
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// runValidate implements the "validate" subcommand: it parses every file
+// argument, optionally checks each against a JSON Schema, and prints a
+// per-file OK/FAIL table.
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	var schemaFile string
+	fs.StringVar(&schemaFile, "schema", "", "JSON Schema file to validate each document against")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: jsondiff validate [--schema schema.json] file.json...")
+		fs.PrintDefaults()
+	}
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return flagExit(err)
+	}
+	if len(files) == 0 {
+		fs.Usage()
+		return exitError
+	}
+
+	var schema *Schema
+	if schemaFile != "" {
+		var err error
+		schema, err = loadSchema(schemaFile)
+		if err != nil {
+			return fatalf("Failed to load schema %s: %v", schemaFile, err)
+		}
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tSTATUS\tDETAILS")
+	violations := make(map[string][]SchemaViolation)
+	failed := 0
+	for _, filename := range files {
+		status, details := "OK", ""
+		doc, err := readJSONFile(filename, defaultInputLimits)
+		if err == nil {
+			if schema != nil {
+				if v := schema.Validate(doc); len(v) > 0 {
+					violations[filename] = v
+					err = fmt.Errorf("%d schema violation(s)", len(v))
+				}
+			}
+		}
+		if err != nil {
+			status, details = "FAIL", err.Error()
+			failed++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", filename, status, details)
+	}
+	tw.Flush()
+
+	for _, filename := range files {
+		if len(violations[filename]) == 0 {
+			continue
+		}
+		fmt.Printf("\n%s:\n", filename)
+		for _, v := range violations[filename] {
+			fmt.Printf("  %s\n", v)
+		}
+	}
+
+	if failed > 0 {
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,54 @@
+package jsondiff
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestValidateCommand validates a valid document, one with a syntax error
+// and one breaking the schema in one run, and checks the table and the
+// violations listed under it.
+func TestValidateCommand(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"schema.json": `{"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}, "replicas": {"type": "integer", "minimum": 1}}}`,
+		"ok.json":     `{"name": "svc", "replicas": 2}`,
+		"syntax.json": "{\n  \"name\": \"svc\",\n  \"replicas\": 2,\n}",
+		"bad.json":    `{"replicas": 0}`,
+	})
+	res := runCLI(t, dir, "validate", "--schema", "schema.json", "ok.json", "syntax.json", "bad.json")
+	if res.exit != exitError {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	if !strings.HasPrefix(res.stdout, "FILE ") {
+		t.Fatalf("stdout:\n%s", res.stdout)
+	}
+	// Rows may be followed by the excerpt of a syntax error.
+	rows := map[string]string{}
+	for _, line := range strings.Split(res.stdout, "\n") {
+		if fields := strings.Fields(line); len(fields) > 1 && strings.HasSuffix(fields[0], ".json") {
+			rows[fields[0]] = strings.Join(fields[1:], " ")
+		}
+	}
+	if rows["ok.json"] != "OK" {
+		t.Errorf("ok.json: %q", rows["ok.json"])
+	}
+	if got := rows["syntax.json"]; !strings.HasPrefix(got, "FAIL ") || !strings.Contains(got, "line 3, column 16") {
+		t.Errorf("syntax.json: %q, want a FAIL at line 3, column 16", got)
+	}
+	if got := rows["bad.json"]; got != "FAIL 2 schema violation(s)" {
+		t.Errorf("bad.json: %q", got)
+	}
+	violations := res.stdout[strings.Index(res.stdout, "\nbad.json:\n"):]
+	for _, want := range []string{"  name: required property is missing\n", "  replicas: value 0 is less than minimum 1\n"} {
+		if !strings.Contains(violations, want) {
+			t.Errorf("violations lack %q:\n%s", want, violations)
+		}
+	}
+
+	if res := runCLI(t, dir, "validate", "ok.json", "bad.json"); res.exit != exitOK || strings.Contains(res.stdout, "FAIL") {
+		t.Errorf("without a schema: exit %d:\n%s", res.exit, res.stdout)
+	}
+	if res := runCLI(t, dir, "validate", "--schema", "missing.json", "ok.json"); res.exit != exitError || !strings.Contains(res.stderr, "Failed to load schema missing.json") {
+		t.Errorf("missing schema: exit %d: %s", res.exit, res.stderr)
+	}
+}
@@ -0,0 +1,92 @@
+package jsondiff
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/r3labs/diff/v3"
+)
+
+// valuePattern matches volatile string values whose changes are ignored.
+type valuePattern struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// valuePresets are the patterns selectable by name with --ignore-values.
+var valuePresets = map[string]*regexp.Regexp{
+	// 8-4-4-4-12 hex digits, any version, either case.
+	"uuid": regexp.MustCompile(`^(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`),
+	// ISO 8601 date, optionally with a time, fractional seconds and a zone:
+	// 2024-05-01, 2024-05-01T12:30:00Z, 2024-05-01 12:30:00.123+02:00.
+	"timestamp": regexp.MustCompile(`^\d{4}-\d{2}-\d{2}([T ]\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}:?\d{2})?)?$`),
+	// Dotted-quad IPv4 or colon-separated IPv6, including "::" shorthand.
+	"ipaddr": regexp.MustCompile(`^((25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)$|^(?i)[0-9a-f]{0,4}(:[0-9a-f]{0,4}){2,7}$`),
+}
+
+func valuePresetNames() []string {
+	names := make([]string, 0, len(valuePresets))
+	for name := range valuePresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseValuePatterns combines --ignore-values presets and
+// --ignore-value-regex expressions.
+func parseValuePatterns(presets string, regexes []string) ([]valuePattern, error) {
+	var patterns []valuePattern
+	for _, name := range strings.Split(presets, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		re, ok := valuePresets[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown --ignore-values preset %q: must be one of %s", name, strings.Join(valuePresetNames(), ", "))
+		}
+		patterns = append(patterns, valuePattern{name, re})
+	}
+	for _, expr := range regexes {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --ignore-value-regex %q: %v", expr, err)
+		}
+		patterns = append(patterns, valuePattern{expr, re})
+	}
+	return patterns, nil
+}
+
+// filterValuePatterns drops updates whose old and new values are both
+// strings matching the same pattern. A value that only starts or stops
+// matching is still reported.
+func filterValuePatterns(changes []diff.Change, patterns []valuePattern, suppressed *suppressionLog) []diff.Change {
+	kept := changes[:0:0]
+	for _, c := range changes {
+		if p, ok := matchingPattern(c, patterns); ok {
+			suppressed.add("value matches "+p.name, c)
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+func matchingPattern(c diff.Change, patterns []valuePattern) (valuePattern, bool) {
+	if c.Type != diff.UPDATE {
+		return valuePattern{}, false
+	}
+	from, okFrom := c.From.(string)
+	to, okTo := c.To.(string)
+	if !okFrom || !okTo {
+		return valuePattern{}, false
+	}
+	for _, p := range patterns {
+		if p.re.MatchString(from) && p.re.MatchString(to) {
+			return p, true
+		}
+	}
+	return valuePattern{}, false
+}
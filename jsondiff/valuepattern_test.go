@@ -0,0 +1,96 @@
+package jsondiff
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValuePresets(t *testing.T) {
+	tests := map[string]struct{ match, other []string }{
+		"uuid": {
+			[]string{"123e4567-e89b-12d3-a456-426614174000", "123E4567-E89B-12D3-A456-426614174000"},
+			[]string{"123e4567e89b12d3a456426614174000", "123e4567-e89b-12d3-a456-42661417400g", " 123e4567-e89b-12d3-a456-426614174000"},
+		},
+		"timestamp": {
+			[]string{"2024-05-01", "2024-05-01T12:30:00Z", "2024-05-01 12:30:00.123+02:00", "2024-05-01T12:30+0200"},
+			[]string{"2024-5-1", "01/05/2024", "2024-05-01T12", "2024-05-01T12:30:00Zulu"},
+		},
+		"ipaddr": {
+			[]string{"10.0.0.1", "255.255.255.255", "::1", "2001:db8::ff00:42:8329", "FE80::1"},
+			[]string{"256.0.0.1", "10.0.0", "01.2.3.4", "host:80", "1.2.3.4.5"},
+		},
+	}
+	for name, tt := range tests {
+		re := valuePresets[name]
+		for _, s := range tt.match {
+			if !re.MatchString(s) {
+				t.Errorf("%s does not match %q", name, s)
+			}
+		}
+		for _, s := range tt.other {
+			if re.MatchString(s) {
+				t.Errorf("%s matches %q", name, s)
+			}
+		}
+	}
+	if got := strings.Join(valuePresetNames(), ","); got != "ipaddr,timestamp,uuid" {
+		t.Errorf("preset names %s", got)
+	}
+}
+
+func TestParseValuePatterns(t *testing.T) {
+	patterns, err := parseValuePatterns(" uuid, ,ipaddr", []string{`^build-\d+$`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range patterns {
+		names = append(names, p.name)
+	}
+	if want := []string{"uuid", "ipaddr", `^build-\d+$`}; !reflect.DeepEqual(names, want) {
+		t.Errorf("patterns %q, want %q", names, want)
+	}
+	if _, err := parseValuePatterns("mac", nil); err == nil || !strings.Contains(err.Error(), "must be one of ipaddr, timestamp, uuid") {
+		t.Errorf("unknown preset: %v", err)
+	}
+	if _, err := parseValuePatterns("", []string{"("}); err == nil || !strings.Contains(err.Error(), `invalid --ignore-value-regex "("`) {
+		t.Errorf("bad regex: %v", err)
+	}
+}
+
+// TestIgnoreValuesCLI checks that only updates between two matching strings
+// are dropped, counted under the pattern that matched.
+func TestIgnoreValuesCLI(t *testing.T) {
+	dir := cliDir(t, map[string]string{
+		"a.json": `{"id": "123e4567-e89b-12d3-a456-426614174000", "at": "2024-05-01", "build": "build-7", "tag": "build-1", "n": "build-2", "new": "x"}`,
+		"b.json": `{"id": "00000000-0000-0000-0000-000000000000", "at": "tomorrow", "build": "build-8", "tag": "release", "n": 3, "added": "build-9"}`,
+	})
+	res := runCLI(t, dir, "--ignore-values", "uuid,timestamp", "--ignore-value-regex", `^build-\d+$`, "--show-suppressed", "-f", "json", "-o", "-", "a.json", "b.json")
+	if res.exit != exitOK {
+		t.Fatalf("exit %d: %s", res.exit, res.stderr)
+	}
+	var report jsonReport
+	if err := json.Unmarshal([]byte(res.stdout), &report); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, s := range report.Sections {
+		for _, c := range s.Changes {
+			paths = append(paths, c.Path)
+		}
+	}
+	// A value that stops matching, changes type, or is added or removed is
+	// still reported.
+	if want := []string{"added", "at", "n", "new", "tag"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("changes %q, want %q", paths, want)
+	}
+	got := make(map[string]int)
+	for _, s := range report.Suppressed {
+		got[s.Reason] = s.Count
+	}
+	if want := map[string]int{"value matches uuid": 1, `value matches ^build-\d+$`: 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("suppressed %v, want %v", got, want)
+	}
+}